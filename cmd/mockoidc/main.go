@@ -0,0 +1,37 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/ischenkx/vk-test-task/internal/impl/identity/oidc/oidctest"
+	"log"
+	"net/http"
+)
+
+var AddrFlag = flag.String("addr", "localhost:9090", "the address to listen on")
+var ClientIDFlag = flag.String("client-id", "vk-test-task", "the id of the only client")
+var SubjectFlag = flag.String("sub", "mock-user", "the subject users are logged in as by default")
+
+// mockoidc runs an OpenID Connect provider approving every login, so that
+// the login flow can be tried without a real one (see the "oidc" section of config.yml)
+func main() {
+	flag.Parse()
+
+	issuer := fmt.Sprintf("http://%s", *AddrFlag)
+
+	server, err := oidctest.New(issuer, *ClientIDFlag, oidctest.Identity{
+		Subject:  *SubjectFlag,
+		Username: *SubjectFlag,
+		Email:    *SubjectFlag + "@example.com",
+	})
+
+	if err != nil {
+		log.Fatalln("failed to create the provider:", err)
+		return
+	}
+
+	log.Printf("starting the mock provider (%s)...\n", issuer)
+	if err := http.ListenAndServe(*AddrFlag, server); err != nil {
+		log.Fatalln("failed to start the server")
+	}
+}
@@ -10,20 +10,109 @@ import (
 	"time"
 )
 
+// OIDCProvider is an OpenID Connect issuer users can log in with,
+// the server must be registered there as a client
+type OIDCProvider struct {
+	// Name identifies the provider in the API, e.g. "google"
+	Name         string `json:"name" yaml:"name"`
+	Issuer       string `json:"issuer" yaml:"issuer"`
+	ClientID     string `json:"client_id" yaml:"client_id"`
+	ClientSecret string `json:"client_secret" yaml:"client_secret"`
+	// RedirectURL is the page of the client completing the login, it must be registered at the issuer
+	RedirectURL string `json:"redirect_url" yaml:"redirect_url"`
+	// Scopes are "openid profile email" by default
+	Scopes []string `json:"scopes" yaml:"scopes"`
+}
+
 type Config struct {
 	Postgres struct {
 		URL string `json:"url" yaml:"url"`
 	} `json:"postgres" yaml:"postgres"`
 
 	JWT struct {
-		Key            string `json:"key" yaml:"key"`
-		ExpirationTime int64  `json:"expiration_time" yaml:"expiration_time"`
+		Key string `json:"key" yaml:"key"`
+		// ExpirationTime is the lifetime of access tokens in milliseconds
+		ExpirationTime int64 `json:"expiration_time" yaml:"expiration_time"`
+		// RefreshExpirationTime is how long (in milliseconds) a refresh token stays valid if it's not used
+		RefreshExpirationTime int64 `json:"refresh_expiration_time" yaml:"refresh_expiration_time"`
+		// Algorithm is "HS256" (default, signs with Key), "RS256" or "EdDSA" (sign with rotated keys)
+		Algorithm string `json:"algorithm" yaml:"algorithm"`
+		// KeyEncryptionKey is the secret the rotated keys are encrypted with in the database,
+		// it's required for "RS256" and "EdDSA"
+		KeyEncryptionKey string `json:"key_encryption_key" yaml:"key_encryption_key"`
+		// RotationInterval is how long (in milliseconds) an asymmetric key signs tokens (a week by default)
+		RotationInterval int64 `json:"rotation_interval" yaml:"rotation_interval"`
+		// GracePeriod is how long (in milliseconds) a retired key still verifies tokens,
+		// it's never shorter than ExpirationTime
+		GracePeriod int64 `json:"grace_period" yaml:"grace_period"`
 	} `json:"jwt" yaml:"jwt"`
 
 	HTTP struct {
 		Addr string `json:"addr" yaml:"addr"`
 		Port uint16 `json:"port" yaml:"port"`
+		// AllowedOrigins are the origins (e.g. "https://chat.example.com") of other sites
+		// that can make requests authorized with the user's cookies
+		AllowedOrigins []string `json:"allowed_origins" yaml:"allowed_origins"`
 	}
+
+	Events struct {
+		// Driver is "memory" (default), "postgres" or "notify"
+		Driver string `json:"driver" yaml:"driver"`
+	} `json:"events" yaml:"events"`
+
+	Webhooks struct {
+		// IncomingRateLimit is how many messages an incoming webhook can post per minute
+		IncomingRateLimit int `json:"incoming_rate_limit" yaml:"incoming_rate_limit"`
+	} `json:"webhooks" yaml:"webhooks"`
+
+	OIDC struct {
+		Providers []OIDCProvider `json:"providers" yaml:"providers"`
+	} `json:"oidc" yaml:"oidc"`
+
+	TwoFactor struct {
+		// Issuer is the name authenticator apps show next to the codes
+		Issuer string `json:"issuer" yaml:"issuer"`
+	} `json:"two_factor" yaml:"two_factor"`
+
+	Passwords struct {
+		// MinLength and MaxLength bound the number of characters of new passwords (8 and 128 by default)
+		MinLength int `json:"min_length" yaml:"min_length"`
+		MaxLength int `json:"max_length" yaml:"max_length"`
+		// DenylistFile lists the breached passwords, one per line, they can't be set
+		DenylistFile string `json:"denylist_file" yaml:"denylist_file"`
+		// Argon2 is the cost of hashing (19 MiB, 2 iterations and 1 thread by default),
+		// the hashes made with other costs are replaced on login
+		Argon2 struct {
+			// Memory is in KiB
+			Memory      uint32 `json:"memory" yaml:"memory"`
+			Iterations  uint32 `json:"iterations" yaml:"iterations"`
+			Parallelism uint8  `json:"parallelism" yaml:"parallelism"`
+		} `json:"argon2" yaml:"argon2"`
+	} `json:"passwords" yaml:"passwords"`
+
+	Logins struct {
+		// UserLockoutThreshold and IPLockoutThreshold are the failed logins that lock a username
+		// or an IP address out (10 and 100 by default), the delays start at half of them
+		UserLockoutThreshold int `json:"user_lockout_threshold" yaml:"user_lockout_threshold"`
+		IPLockoutThreshold   int `json:"ip_lockout_threshold" yaml:"ip_lockout_threshold"`
+		// BaseDelay is the first delay in milliseconds (a second by default), it doubles with every failure
+		BaseDelay int64 `json:"base_delay" yaml:"base_delay"`
+		// LockoutDuration is how long (in milliseconds) the logins are locked out (15 minutes by default)
+		LockoutDuration int64 `json:"lockout_duration" yaml:"lockout_duration"`
+	} `json:"logins" yaml:"logins"`
+
+	Notifier struct {
+		// Driver is "log" or "smtp", the password reset is disabled if it's empty
+		Driver string `json:"driver" yaml:"driver"`
+		SMTP   struct {
+			Addr     string `json:"addr" yaml:"addr"`
+			From     string `json:"from" yaml:"from"`
+			Username string `json:"username" yaml:"username"`
+			Password string `json:"password" yaml:"password"`
+			// Domain makes up the addresses of the users who have no email, "<username>@<domain>"
+			Domain string `json:"domain" yaml:"domain"`
+		} `json:"smtp" yaml:"smtp"`
+	} `json:"notifier" yaml:"notifier"`
 }
 
 func FromFile(filename string) (Config, error) {
@@ -67,6 +156,9 @@ func FromENV() (Config, error) {
 
 	config.HTTP.Port = uint16(port)
 	config.HTTP.Addr = os.Getenv("ADDR")
+	if origins := os.Getenv("ALLOWED_ORIGINS"); origins != "" {
+		config.HTTP.AllowedOrigins = strings.Split(origins, ",")
+	}
 
 	// JWT
 	config.JWT.Key = os.Getenv("JWT_KEY")
@@ -75,6 +167,133 @@ func FromENV() (Config, error) {
 		return config, err
 	}
 	config.JWT.ExpirationTime = expTime.Milliseconds()
+	if refreshExpTime := os.Getenv("JWT_REFRESH_EXP_TIME"); refreshExpTime != "" {
+		refreshExp, err := time.ParseDuration(refreshExpTime)
+		if err != nil {
+			return config, err
+		}
+		config.JWT.RefreshExpirationTime = refreshExp.Milliseconds()
+	}
+	config.JWT.Algorithm = os.Getenv("JWT_ALGORITHM")
+	config.JWT.KeyEncryptionKey = os.Getenv("JWT_KEY_ENCRYPTION_KEY")
+	if rotation := os.Getenv("JWT_ROTATION_INTERVAL"); rotation != "" {
+		rotationInterval, err := time.ParseDuration(rotation)
+		if err != nil {
+			return config, err
+		}
+		config.JWT.RotationInterval = rotationInterval.Milliseconds()
+	}
+	if grace := os.Getenv("JWT_GRACE_PERIOD"); grace != "" {
+		gracePeriod, err := time.ParseDuration(grace)
+		if err != nil {
+			return config, err
+		}
+		config.JWT.GracePeriod = gracePeriod.Milliseconds()
+	}
+
+	// Events
+	config.Events.Driver = os.Getenv("EVENTS_DRIVER")
+
+	// Webhooks
+	if limit := os.Getenv("INCOMING_WEBHOOK_RATE_LIMIT"); limit != "" {
+		config.Webhooks.IncomingRateLimit, err = strconv.Atoi(limit)
+		if err != nil {
+			return config, err
+		}
+	}
+
+	// Two-factor authentication
+	config.TwoFactor.Issuer = os.Getenv("TOTP_ISSUER")
+
+	// Passwords
+	if minLength := os.Getenv("PASSWORD_MIN_LENGTH"); minLength != "" {
+		config.Passwords.MinLength, err = strconv.Atoi(minLength)
+		if err != nil {
+			return config, err
+		}
+	}
+	if maxLength := os.Getenv("PASSWORD_MAX_LENGTH"); maxLength != "" {
+		config.Passwords.MaxLength, err = strconv.Atoi(maxLength)
+		if err != nil {
+			return config, err
+		}
+	}
+	config.Passwords.DenylistFile = os.Getenv("PASSWORD_DENYLIST_FILE")
+	if memory := os.Getenv("PASSWORD_ARGON2_MEMORY"); memory != "" {
+		value, err := strconv.ParseUint(memory, 10, 32)
+		if err != nil {
+			return config, err
+		}
+		config.Passwords.Argon2.Memory = uint32(value)
+	}
+	if iterations := os.Getenv("PASSWORD_ARGON2_ITERATIONS"); iterations != "" {
+		value, err := strconv.ParseUint(iterations, 10, 32)
+		if err != nil {
+			return config, err
+		}
+		config.Passwords.Argon2.Iterations = uint32(value)
+	}
+	if parallelism := os.Getenv("PASSWORD_ARGON2_PARALLELISM"); parallelism != "" {
+		value, err := strconv.ParseUint(parallelism, 10, 8)
+		if err != nil {
+			return config, err
+		}
+		config.Passwords.Argon2.Parallelism = uint8(value)
+	}
+
+	// Logins
+	if threshold := os.Getenv("LOGIN_USER_LOCKOUT_THRESHOLD"); threshold != "" {
+		config.Logins.UserLockoutThreshold, err = strconv.Atoi(threshold)
+		if err != nil {
+			return config, err
+		}
+	}
+	if threshold := os.Getenv("LOGIN_IP_LOCKOUT_THRESHOLD"); threshold != "" {
+		config.Logins.IPLockoutThreshold, err = strconv.Atoi(threshold)
+		if err != nil {
+			return config, err
+		}
+	}
+	if delay := os.Getenv("LOGIN_BASE_DELAY"); delay != "" {
+		baseDelay, err := time.ParseDuration(delay)
+		if err != nil {
+			return config, err
+		}
+		config.Logins.BaseDelay = baseDelay.Milliseconds()
+	}
+	if duration := os.Getenv("LOGIN_LOCKOUT_DURATION"); duration != "" {
+		lockoutDuration, err := time.ParseDuration(duration)
+		if err != nil {
+			return config, err
+		}
+		config.Logins.LockoutDuration = lockoutDuration.Milliseconds()
+	}
+
+	// Notifier
+	config.Notifier.Driver = os.Getenv("NOTIFIER_DRIVER")
+	config.Notifier.SMTP.Addr = os.Getenv("SMTP_ADDR")
+	config.Notifier.SMTP.From = os.Getenv("SMTP_FROM")
+	config.Notifier.SMTP.Username = os.Getenv("SMTP_USERNAME")
+	config.Notifier.SMTP.Password = os.Getenv("SMTP_PASSWORD")
+	config.Notifier.SMTP.Domain = os.Getenv("SMTP_DOMAIN")
+
+	// OIDC, the settings of a provider are read from OIDC_<NAME>_* variables
+	if providers := os.Getenv("OIDC_PROVIDERS"); providers != "" {
+		for _, name := range strings.Split(providers, ",") {
+			prefix := "OIDC_" + strings.ToUpper(name) + "_"
+			provider := OIDCProvider{
+				Name:         name,
+				Issuer:       os.Getenv(prefix + "ISSUER"),
+				ClientID:     os.Getenv(prefix + "CLIENT_ID"),
+				ClientSecret: os.Getenv(prefix + "CLIENT_SECRET"),
+				RedirectURL:  os.Getenv(prefix + "REDIRECT_URL"),
+			}
+			if scopes := os.Getenv(prefix + "SCOPES"); scopes != "" {
+				provider.Scopes = strings.Split(scopes, ",")
+			}
+			config.OIDC.Providers = append(config.OIDC.Providers, provider)
+		}
+	}
 
 	return config, nil
 }
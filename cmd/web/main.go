@@ -1,18 +1,30 @@
 package main
 
 import (
+	"bufio"
 	"context"
 	"flag"
 	"fmt"
 	"github.com/ischenkx/vk-test-task/cmd/web/config"
 	"github.com/ischenkx/vk-test-task/internal/app"
+	"github.com/ischenkx/vk-test-task/internal/app/event"
+	"github.com/ischenkx/vk-test-task/internal/app/security"
 	"github.com/ischenkx/vk-test-task/internal/impl/authorizer/jwtauth"
 	"github.com/ischenkx/vk-test-task/internal/impl/data/postgres"
 	"github.com/ischenkx/vk-test-task/internal/impl/events/evbus"
+	"github.com/ischenkx/vk-test-task/internal/impl/events/evlog"
+	"github.com/ischenkx/vk-test-task/internal/impl/events/evnotify"
+	"github.com/ischenkx/vk-test-task/internal/impl/identity/oidc"
+	"github.com/ischenkx/vk-test-task/internal/impl/notify/notifylog"
+	"github.com/ischenkx/vk-test-task/internal/impl/notify/notifysmtp"
+	"github.com/ischenkx/vk-test-task/internal/impl/passwords/passhash"
+	"github.com/ischenkx/vk-test-task/internal/impl/webhooks"
 	"github.com/ischenkx/vk-test-task/internal/transport/web"
 	"github.com/jackc/pgx/v4/pgxpool"
 	"log"
 	"net/http"
+	"os"
+	"strings"
 	"time"
 )
 
@@ -27,6 +39,129 @@ func getConfig() (config.Config, error) {
 	return config.FromFile(*FileConfigFlag)
 }
 
+func newBus(ctx context.Context, cfg config.Config, pg *pgxpool.Pool) (event.Bus, error) {
+	registry := event.NewRegistry()
+	app.RegisterEvents(registry)
+
+	switch cfg.Events.Driver {
+	case "", "memory":
+		return evbus.NewBus(), nil
+	case "postgres":
+		bus := evlog.New(pg, evlog.WithRegistry(registry))
+		if err := bus.InitializeTables(ctx); err != nil {
+			return nil, err
+		}
+		return bus, nil
+	case "notify":
+		bus := evnotify.New(pg, evnotify.WithRegistry(registry))
+		if err := bus.InitializeTables(ctx); err != nil {
+			return nil, err
+		}
+		if err := bus.Start(ctx); err != nil {
+			return nil, err
+		}
+		return bus, nil
+	default:
+		return nil, fmt.Errorf("unknown events driver: '%s'", cfg.Events.Driver)
+	}
+}
+
+const defaultKeyRotationInterval = 7 * 24 * time.Hour
+
+// newKeySet returns the keys tokens are signed with, the key ring is refreshed in the background
+func newKeySet(ctx context.Context, cfg config.Config, repo *postgres.Repo) (jwtauth.KeySet, error) {
+	switch cfg.JWT.Algorithm {
+	case "", "HS256":
+		return jwtauth.HMACKey([]byte(cfg.JWT.Key)), nil
+	case jwtauth.AlgorithmRS256, jwtauth.AlgorithmEdDSA:
+		rotation := time.Duration(cfg.JWT.RotationInterval) * time.Millisecond
+		if rotation <= 0 {
+			rotation = defaultKeyRotationInterval
+		}
+
+		// retired keys must verify the tokens they have signed till the end
+		grace := time.Duration(cfg.JWT.GracePeriod) * time.Millisecond
+		if expiration := time.Duration(cfg.JWT.ExpirationTime) * time.Millisecond; grace < expiration {
+			grace = expiration
+		}
+
+		ring, err := jwtauth.NewKeyRing(repo, []byte(cfg.JWT.KeyEncryptionKey), cfg.JWT.Algorithm, rotation, grace)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := ring.Refresh(ctx); err != nil {
+			return nil, err
+		}
+
+		go ring.Run(ctx)
+
+		return ring, nil
+	default:
+		return nil, fmt.Errorf("unknown jwt algorithm: '%s'", cfg.JWT.Algorithm)
+	}
+}
+
+func newIdentityProviders(cfg config.Config) []security.IdentityProvider {
+	var providers []security.IdentityProvider
+	for _, provider := range cfg.OIDC.Providers {
+		providers = append(providers, oidc.New(oidc.Config{
+			Name:         provider.Name,
+			Issuer:       provider.Issuer,
+			ClientID:     provider.ClientID,
+			ClientSecret: provider.ClientSecret,
+			RedirectURL:  provider.RedirectURL,
+			Scopes:       provider.Scopes,
+		}))
+	}
+	return providers
+}
+
+// newNotifier returns nil if the driver isn't set, the password reset is disabled then
+func newNotifier(cfg config.Config) (security.Notifier, error) {
+	switch cfg.Notifier.Driver {
+	case "":
+		return nil, nil
+	case "log":
+		return notifylog.New(nil), nil
+	case "smtp":
+		return notifysmtp.New(notifysmtp.Config{
+			Addr:     cfg.Notifier.SMTP.Addr,
+			From:     cfg.Notifier.SMTP.From,
+			Username: cfg.Notifier.SMTP.Username,
+			Password: cfg.Notifier.SMTP.Password,
+			Domain:   cfg.Notifier.SMTP.Domain,
+		}), nil
+	default:
+		return nil, fmt.Errorf("unknown notifier driver: '%s'", cfg.Notifier.Driver)
+	}
+}
+
+// loadPasswordDenylist reads a password per line, the empty lines and the ones starting with "#" are skipped
+func loadPasswordDenylist(filename string) ([]string, error) {
+	if filename == "" {
+		return nil, nil
+	}
+
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var passwords []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		passwords = append(passwords, line)
+	}
+
+	return passwords, scanner.Err()
+}
+
 func main() {
 	flag.Parse()
 
@@ -57,17 +192,77 @@ func main() {
 		log.Println("initialized tables...")
 	}
 
-	auth := jwtauth.New([]byte(cfg.JWT.Key), time.Duration(cfg.JWT.ExpirationTime*1000))
+	keys, err := newKeySet(ctx, cfg, repo)
+
+	if err != nil {
+		log.Fatalln("failed to create the jwt keys:", err)
+		return
+	}
+
+	auth := jwtauth.New(
+		keys,
+		time.Duration(cfg.JWT.ExpirationTime)*time.Millisecond,
+		jwtauth.WithSessions(app.NewSessionStore(repo)),
+	)
+
+	bus, err := newBus(ctx, cfg, pg)
+
+	if err != nil {
+		log.Fatalln("failed to create the event bus:", err)
+		return
+	}
+
+	notifier, err := newNotifier(cfg)
+
+	if err != nil {
+		log.Fatalln("failed to create the notifier:", err)
+		return
+	}
+
+	denylist, err := loadPasswordDenylist(cfg.Passwords.DenylistFile)
 
-	bus := evbus.NewBus()
+	if err != nil {
+		log.Fatalln("failed to load the password denylist:", err)
+		return
+	}
 
 	application := app.New(app.Config{
-		Repo:       repo,
-		Authorizer: auth,
-		Bus:        bus,
+		Repo:                     repo,
+		Authorizer:               auth,
+		Bus:                      bus,
+		IncomingWebhookRateLimit: cfg.Webhooks.IncomingRateLimit,
+		RefreshTokenTTL:          time.Duration(cfg.JWT.RefreshExpirationTime) * time.Millisecond,
+		IdentityProviders:        newIdentityProviders(cfg),
+		TOTPIssuer:               cfg.TwoFactor.Issuer,
+		PasswordPolicy: app.PasswordPolicy{
+			MinLength: cfg.Passwords.MinLength,
+			MaxLength: cfg.Passwords.MaxLength,
+			Denylist:  denylist,
+		},
+		PasswordHasher: passhash.New(passhash.Argon2Params{
+			Memory:      cfg.Passwords.Argon2.Memory,
+			Iterations:  cfg.Passwords.Argon2.Iterations,
+			Parallelism: cfg.Passwords.Argon2.Parallelism,
+		}),
+		Notifier: notifier,
+		LoginThrottling: app.LoginThrottling{
+			UserLockoutThreshold: cfg.Logins.UserLockoutThreshold,
+			IPLockoutThreshold:   cfg.Logins.IPLockoutThreshold,
+			BaseDelay:            time.Duration(cfg.Logins.BaseDelay) * time.Millisecond,
+			LockoutDuration:      time.Duration(cfg.Logins.LockoutDuration) * time.Millisecond,
+		},
 	})
 
-	mux := web.NewRouter(application)
+	go application.RunRelay(ctx)
+
+	dispatcher := webhooks.New(repo, bus, webhooks.WithRegistry(application.Registry()))
+	go func() {
+		if err := dispatcher.Run(ctx); err != nil {
+			log.Println("webhook dispatcher stopped:", err)
+		}
+	}()
+
+	mux := web.NewRouter(application, cfg.HTTP.AllowedOrigins)
 	addr := fmt.Sprintf("%s:%d", cfg.HTTP.Addr, cfg.HTTP.Port)
 
 	log.Printf("starting http server (%s)...\n", addr)
@@ -0,0 +1,152 @@
+package app
+
+import (
+	"crypto/subtle"
+	"github.com/ischenkx/vk-test-task/internal/app/data/models"
+	"github.com/ischenkx/vk-test-task/internal/app/errors"
+	"github.com/ischenkx/vk-test-task/internal/app/forms"
+	"strings"
+	"time"
+)
+
+const apiKeySecretSize = 32
+
+// APIKey lets scripts act on behalf of a user without the password,
+// limited to the scopes of the key (see Context.Allows)
+type APIKey interface {
+	ID() string
+	Model(ctx *Context) (models.APIKey, error)
+	Delete(ctx *Context) error
+}
+
+type apiKey struct {
+	app *App
+	id  string
+}
+
+func (k apiKey) model(ctx *Context) (models.APIKey, error) {
+	return k.app.repo.GetAPIKey(ctx, k.id)
+}
+
+func (k apiKey) exists(ctx *Context) bool {
+	if _, err := k.model(ctx); err != nil {
+		return false
+	}
+	return true
+}
+
+// isWritable reports whether the key belongs to the current user, who isn't using an API key
+func (k apiKey) isWritable(ctx *Context) bool {
+	if ctx.User() == nil || ctx.Restricted() {
+		return false
+	}
+	model, err := k.model(ctx)
+	if err != nil {
+		return false
+	}
+	return model.UserID == ctx.User().ID()
+}
+
+func (k apiKey) ID() string {
+	return k.id
+}
+
+func (k apiKey) Model(ctx *Context) (models.APIKey, error) {
+	if !k.isWritable(ctx) {
+		return models.APIKey{}, errors.ResourceInaccessible
+	}
+	return k.model(ctx)
+}
+
+func (k apiKey) Delete(ctx *Context) error {
+	if !k.isWritable(ctx) {
+		return errors.ResourceInaccessible
+	}
+	return k.app.repo.DeleteAPIKey(ctx, k.id)
+}
+
+// createAPIKey returns the key along with its token of the form "<key id>:<secret>",
+// only the hash of the secret is stored
+func createAPIKey(ctx *Context, app *App, userID string, form forms.APIKeyCreation) (APIKey, string, error) {
+	if err := form.Validate(); err != nil {
+		return nil, "", err
+	}
+
+	secret, err := generateToken(apiKeySecretSize)
+	if err != nil {
+		return nil, "", err
+	}
+
+	model, err := app.repo.CreateAPIKey(ctx, models.APIKey{
+		UserID:    userID,
+		Name:      form.Name,
+		TokenHash: hashToken(secret),
+		Scopes:    form.Scopes,
+		ExpiresAt: form.ExpiresAt,
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	return unsafeAPIKeyFromModel(app, model), model.ID + ":" + secret, nil
+}
+
+// authenticateAPIKey returns the owner of the key along with the scopes of the key
+func authenticateAPIKey(ctx *Context, app *App, token string) (User, []string, error) {
+	keyID, secret, ok := strings.Cut(token, ":")
+	if !ok {
+		return nil, nil, errors.NotAuthorized
+	}
+
+	model, err := app.repo.GetAPIKey(ctx, keyID)
+	if err != nil {
+		return nil, nil, errors.NotAuthorized
+	}
+
+	if subtle.ConstantTimeCompare([]byte(hashToken(secret)), []byte(model.TokenHash)) != 1 {
+		return nil, nil, errors.NotAuthorized
+	}
+
+	if !model.ExpiresAt.IsZero() && time.Now().After(model.ExpiresAt) {
+		return nil, nil, errors.NotAuthorized
+	}
+
+	if err := app.repo.TouchAPIKey(ctx, model.ID); err != nil {
+		return nil, nil, err
+	}
+
+	u, err := newUser(ctx, app, model.UserID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return u, model.Scopes, nil
+}
+
+func apiKeysFromModels(app *App, rawKeys []models.APIKey) []APIKey {
+	keys := make([]APIKey, 0, len(rawKeys))
+	for _, model := range rawKeys {
+		keys = append(keys, unsafeAPIKeyFromModel(app, model))
+	}
+	return keys
+}
+
+func unsafeAPIKeyFromModel(app *App, model models.APIKey) APIKey {
+	return apiKey{
+		app: app,
+		id:  model.ID,
+	}
+}
+
+func newAPIKey(ctx *Context, app *App, id string) (APIKey, error) {
+	k := apiKey{
+		app: app,
+		id:  id,
+	}
+
+	if !k.exists(ctx) {
+		return nil, errors.DoesNotExist
+	}
+
+	return k, nil
+}
@@ -4,18 +4,49 @@ import (
 	"github.com/ischenkx/vk-test-task/internal/app/data"
 	"github.com/ischenkx/vk-test-task/internal/app/event"
 	"github.com/ischenkx/vk-test-task/internal/app/security"
+	"log"
+	"time"
 )
 
 type App struct {
 	repo       data.Repository
 	authorizer security.Authorizer
 	events     event.Bus
+	registry   *event.Registry
+	hooks      *Hooks
+
+	outboxWake         chan struct{}
+	outboxPollInterval time.Duration
+	refreshTokenTTL    time.Duration
+
+	incomingWebhookLimiter *rateLimiter
+	secondFactorLimiter    *rateLimiter
+	passwordResetLimiter   *rateLimiter
+
+	identityProviders map[string]security.IdentityProvider
+	totpIssuer        string
+
+	passwordPolicy    passwordPolicy
+	passwordHasher    security.PasswordHasher
+	dummyPasswordHash []byte
+	notifier          security.Notifier
+	loginThrottle     loginThrottle
 }
 
 func (app *App) Events() event.Bus {
 	return app.events
 }
 
+// Registry binds the names of the application's events to their payloads
+func (app *App) Registry() *event.Registry {
+	return app.registry
+}
+
+// Hooks lets extensions veto or rewrite the domain operations before they are performed
+func (app *App) Hooks() *Hooks {
+	return app.hooks
+}
+
 func (app *App) Auth() security.Authorizer {
 	return app.authorizer
 }
@@ -29,9 +60,61 @@ func (app *App) Chats() ChatManager {
 }
 
 func New(cfg Config) *App {
+	registry := event.NewRegistry()
+	RegisterEvents(registry)
+
+	pollInterval := cfg.OutboxPollInterval
+	if pollInterval <= 0 {
+		pollInterval = defaultOutboxPollInterval
+	}
+
+	incomingWebhookRateLimit := cfg.IncomingWebhookRateLimit
+	if incomingWebhookRateLimit <= 0 {
+		incomingWebhookRateLimit = defaultIncomingWebhookRateLimit
+	}
+
+	refreshTokenTTL := cfg.RefreshTokenTTL
+	if refreshTokenTTL <= 0 {
+		refreshTokenTTL = defaultRefreshTokenTTL
+	}
+
+	totpIssuer := cfg.TOTPIssuer
+	if totpIssuer == "" {
+		totpIssuer = defaultTOTPIssuer
+	}
+
+	// the hash checked in place of the missing ones, see checkDummyPassword
+	dummyPasswordHash, err := cfg.PasswordHasher.Hash("dummy password")
+	if err != nil {
+		log.Println("failed to hash the dummy password:", err)
+	}
+
+	identityProviders := map[string]security.IdentityProvider{}
+	for _, provider := range cfg.IdentityProviders {
+		identityProviders[provider.Name()] = provider
+	}
+
 	return &App{
-		repo:       cfg.Repo,
-		authorizer: cfg.Authorizer,
-		events:     cfg.Bus,
+		repo:               cfg.Repo,
+		authorizer:         cfg.Authorizer,
+		events:             cfg.Bus,
+		registry:           registry,
+		hooks:              &Hooks{},
+		outboxWake:         make(chan struct{}, 1),
+		outboxPollInterval: pollInterval,
+		refreshTokenTTL:    refreshTokenTTL,
+
+		incomingWebhookLimiter: newRateLimiter(incomingWebhookRateLimit, time.Minute),
+		secondFactorLimiter:    newRateLimiter(maxSecondFactorAttempts, time.Minute),
+		passwordResetLimiter:   newRateLimiter(maxPasswordResets, time.Hour),
+
+		identityProviders: identityProviders,
+		totpIssuer:        totpIssuer,
+
+		passwordPolicy:    newPasswordPolicy(cfg.PasswordPolicy),
+		passwordHasher:    cfg.PasswordHasher,
+		dummyPasswordHash: dummyPasswordHash,
+		notifier:          cfg.Notifier,
+		loginThrottle:     newLoginThrottle(cfg.LoginThrottling),
 	}
 }
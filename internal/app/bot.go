@@ -0,0 +1,346 @@
+package app
+
+import (
+	"crypto/subtle"
+	goerrors "errors"
+	"github.com/ischenkx/vk-test-task/internal/app/data"
+	"github.com/ischenkx/vk-test-task/internal/app/data/models"
+	"github.com/ischenkx/vk-test-task/internal/app/errors"
+	"github.com/ischenkx/vk-test-task/internal/app/event"
+	"github.com/ischenkx/vk-test-task/internal/app/forms"
+	"github.com/ischenkx/vk-test-task/internal/app/security"
+	"strings"
+	"time"
+)
+
+const botTokenSecretSize = 32
+const botUpdatesPollInterval = 500 * time.Millisecond
+const botUpdatesRetention = 24 * time.Hour
+const maxBotUpdatesTimeout = 50 * time.Second
+const maxBotUpdatesCount = 100
+
+// Bot is a user driven by a program and owned by a real user.
+//
+// Bots authenticate with their tokens and act through the same User and ChatMember
+// objects as people do. They receive the events of their chats as updates: either
+// by polling Updates or through the webhooks they register (as any user can).
+type Bot interface {
+	ID() string
+	User(ctx *Context) (User, error)
+	Model(ctx *Context) (models.Bot, error)
+
+	Commands(ctx *Context) ([]models.BotCommand, error)
+	// SetCommands replaces the slash commands routed to the bot
+	SetCommands(ctx *Context, form forms.BotCommandsUpdate) error
+
+	// Updates returns the updates starting from offset, the ones before it are confirmed and removed.
+	// If there are none, it waits up to timeout for new ones to come.
+	Updates(ctx *Context, offset int64, count int, timeout time.Duration) ([]models.BotUpdate, error)
+
+	// RegenerateToken revokes the current token and returns a new one
+	RegenerateToken(ctx *Context) (string, error)
+	Delete(ctx *Context) error
+}
+
+type bot struct {
+	app *App
+	id  string
+}
+
+func (b bot) model(ctx *Context) (models.Bot, error) {
+	return b.app.repo.GetBot(ctx, b.id)
+}
+
+func (b bot) exists(ctx *Context) bool {
+	if _, err := b.model(ctx); err != nil {
+		return false
+	}
+	return true
+}
+
+// isOwned reports whether the current user owns the bot
+func (b bot) isOwned(ctx *Context) bool {
+	if ctx.User() == nil {
+		return false
+	}
+	model, err := b.model(ctx)
+	if err != nil {
+		return false
+	}
+	return model.OwnerID == ctx.User().ID()
+}
+
+// isWritable reports whether the current user is the bot itself or its owner
+func (b bot) isWritable(ctx *Context) bool {
+	if ctx.User() == nil {
+		return false
+	}
+	return ctx.User().ID() == b.id || b.isOwned(ctx)
+}
+
+func (b bot) ID() string {
+	return b.id
+}
+
+func (b bot) User(ctx *Context) (User, error) {
+	return newUser(ctx, b.app, b.id)
+}
+
+func (b bot) Model(ctx *Context) (models.Bot, error) {
+	if !b.isWritable(ctx) {
+		return models.Bot{}, errors.ResourceInaccessible
+	}
+	return b.model(ctx)
+}
+
+func (b bot) Commands(ctx *Context) ([]models.BotCommand, error) {
+	if !ctx.Allows(security.ScopeBots) {
+		return nil, errors.NotAuthorized
+	}
+	if !b.isWritable(ctx) {
+		return nil, errors.ResourceInaccessible
+	}
+	return b.app.repo.GetBotCommands(ctx, b.id)
+}
+
+func (b bot) SetCommands(ctx *Context, form forms.BotCommandsUpdate) error {
+	if !ctx.Allows(security.ScopeBots) {
+		return errors.NotAuthorized
+	}
+	if !b.isWritable(ctx) {
+		return errors.ResourceInaccessible
+	}
+
+	if err := form.Validate(); err != nil {
+		return err
+	}
+
+	_, err := b.app.repo.Transaction(ctx, func(tx data.Tx) (interface{}, error) {
+		if err := tx.DeleteBotCommands(ctx, b.id); err != nil {
+			return nil, err
+		}
+		for _, command := range form.Commands {
+			err := tx.CreateBotCommand(ctx, models.BotCommand{
+				BotID:       b.id,
+				Command:     command.Command,
+				Description: command.Description,
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+		return nil, nil
+	})
+
+	return err
+}
+
+func (b bot) Updates(ctx *Context, offset int64, count int, timeout time.Duration) ([]models.BotUpdate, error) {
+	if !ctx.Allows(security.ScopeBots) {
+		return nil, errors.NotAuthorized
+	}
+	if !b.isWritable(ctx) {
+		return nil, errors.ResourceInaccessible
+	}
+
+	if webhooks, err := b.app.repo.GetUserWebhooks(ctx, b.id, 0, 1); err != nil {
+		return nil, err
+	} else if len(webhooks) > 0 {
+		return nil, goerrors.New("the bot receives updates through its webhooks")
+	}
+
+	if count <= 0 || count > maxBotUpdatesCount {
+		count = maxBotUpdatesCount
+	}
+	if timeout > maxBotUpdatesTimeout {
+		timeout = maxBotUpdatesTimeout
+	}
+
+	if err := b.app.repo.DeleteBotUpdates(ctx, b.id, offset, botUpdatesRetention); err != nil {
+		return nil, err
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		updates, err := b.app.repo.GetBotUpdates(ctx, b.id, offset, count)
+		if err != nil || len(updates) > 0 || !time.Now().Before(deadline) {
+			return updates, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(botUpdatesPollInterval):
+		}
+	}
+}
+
+func (b bot) RegenerateToken(ctx *Context) (string, error) {
+	if !ctx.Allows(security.ScopeBots) {
+		return "", errors.NotAuthorized
+	}
+	if !b.isOwned(ctx) {
+		return "", errors.RightsViolation
+	}
+
+	token, err := newBotToken(b.id)
+	if err != nil {
+		return "", err
+	}
+
+	if err := b.app.repo.UpdateBotToken(ctx, b.id, hashToken(token)); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+func (b bot) Delete(ctx *Context) error {
+	if !ctx.Allows(security.ScopeBots) {
+		return errors.NotAuthorized
+	}
+	if !b.isOwned(ctx) {
+		return errors.RightsViolation
+	}
+	return b.app.repo.DeleteUser(ctx, b.id)
+}
+
+// newBotToken returns a token of the form "<bot id>:<secret>",
+// the id lets the token be checked without scanning all the bots
+func newBotToken(botID string) (string, error) {
+	secret, err := generateToken(botTokenSecretSize)
+	if err != nil {
+		return "", err
+	}
+	return botID + ":" + secret, nil
+}
+
+// authenticateBot returns the bot user the token belongs to
+func authenticateBot(ctx *Context, app *App, token string) (User, error) {
+	botID, _, ok := strings.Cut(token, ":")
+	if !ok {
+		return nil, errors.NotAuthorized
+	}
+
+	model, err := app.repo.GetBot(ctx, botID)
+	if err != nil {
+		return nil, errors.NotAuthorized
+	}
+
+	if subtle.ConstantTimeCompare([]byte(hashToken(token)), []byte(model.TokenHash)) != 1 {
+		return nil, errors.NotAuthorized
+	}
+
+	return newUser(ctx, app, model.UserID)
+}
+
+// createBot registers a bot owned by the current user and returns it along with its token
+func createBot(ctx *Context, app *App, form forms.BotCreation) (Bot, string, error) {
+	if err := form.Validate(); err != nil {
+		return nil, "", err
+	}
+
+	var token string
+	res, err := app.repo.Transaction(ctx, func(tx data.Tx) (interface{}, error) {
+		u, err := tx.CreateUser(ctx, models.User{
+			Username:     form.Username,
+			PasswordHash: []byte{},
+			Kind:         models.UserKindBot,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		if token, err = newBotToken(u.ID); err != nil {
+			return nil, err
+		}
+
+		return tx.CreateBot(ctx, models.Bot{
+			UserID:    u.ID,
+			OwnerID:   ctx.User().ID(),
+			TokenHash: hashToken(token),
+		})
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	return unsafeBotFromModel(app, res.(models.Bot)), token, nil
+}
+
+// parseBotCommand splits a message like "/command@somebot args" into its parts
+func parseBotCommand(payload string) (command string, botName string, args string, ok bool) {
+	if !strings.HasPrefix(payload, "/") {
+		return "", "", "", false
+	}
+
+	head, args, _ := strings.Cut(payload[1:], " ")
+	command, botName, _ = strings.Cut(head, "@")
+	if command == "" {
+		return "", "", "", false
+	}
+
+	return strings.ToLower(command), botName, strings.TrimSpace(args), true
+}
+
+// botCommandEvents routes a slash command to the bots of the chat that have registered it.
+// If the command is addressed to a bot ("/command@somebot"), the other bots don't get it.
+func botCommandEvents(ctx *Context, tx data.Tx, mes models.Message) ([]event.Event, error) {
+	command, botName, args, ok := parseBotCommand(mes.Payload)
+	if !ok {
+		return nil, nil
+	}
+
+	commands, err := tx.GetChatBotCommands(ctx, mes.ChatID, command)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []event.Event
+	for _, c := range commands {
+		if botName != "" {
+			u, err := tx.GetUser(ctx, c.BotID)
+			if err != nil || !strings.EqualFold(u.Username, botName) {
+				continue
+			}
+		}
+
+		events = append(events, event.New(BotCommandEventName, BotCommandEvent{
+			BotID:     c.BotID,
+			MessageID: mes.ID,
+			ChatID:    mes.ChatID,
+			SenderID:  mes.UserID,
+			Command:   command,
+			Args:      args,
+		}, event.WithTime(time.Now()), event.ToUsers(c.BotID)))
+	}
+
+	return events, nil
+}
+
+func botsFromModels(app *App, rawBots []models.Bot) []Bot {
+	bots := make([]Bot, 0, len(rawBots))
+	for _, model := range rawBots {
+		bots = append(bots, unsafeBotFromModel(app, model))
+	}
+	return bots
+}
+
+func unsafeBotFromModel(app *App, model models.Bot) Bot {
+	return bot{
+		app: app,
+		id:  model.UserID,
+	}
+}
+
+func newBot(ctx *Context, app *App, id string) (Bot, error) {
+	b := bot{
+		app: app,
+		id:  id,
+	}
+
+	if !b.exists(ctx) {
+		return nil, errors.DoesNotExist
+	}
+
+	return b, nil
+}
@@ -1,9 +1,14 @@
 package app
 
 import (
+	"context"
+	goerrors "errors"
+	"github.com/ischenkx/vk-test-task/internal/app/data"
 	"github.com/ischenkx/vk-test-task/internal/app/data/models"
 	"github.com/ischenkx/vk-test-task/internal/app/errors"
 	"github.com/ischenkx/vk-test-task/internal/app/event"
+	"github.com/ischenkx/vk-test-task/internal/app/forms"
+	"github.com/ischenkx/vk-test-task/internal/app/security"
 	"log"
 	"time"
 )
@@ -13,16 +18,36 @@ type Chat interface {
 	Name(ctx *Context) (string, error)
 	Description(ctx *Context) (string, error)
 	Owner(ctx *Context) (User, error)
+	Visibility(ctx *Context) (int, error)
+	Kind(ctx *Context) (int, error)
+	SetVisibility(ctx *Context, update forms.ChatVisibilityUpdate) error
+	Update(ctx *Context, update forms.ChatUpdate) error
+	TransferOwnership(ctx *Context, userID string) error
 	Members(ctx *Context, offset int, amount int) ([]ChatMember, error)
 	CountMembers(ctx *Context) (int, error)
 	Member(ctx *Context, id string) (ChatMember, error)
 	Add(ctx *Context, id string, status int) (ChatMember, error)
 
+	CreateInvite(ctx *Context, form forms.ChatInviteCreation) (ChatInvite, error)
+	Invites(ctx *Context, offset int, amount int) ([]ChatInvite, error)
+	Invite(ctx *Context, code string) (ChatInvite, error)
+
+	JoinRequests(ctx *Context, offset int, amount int) ([]ChatJoinRequest, error)
+	JoinRequest(ctx *Context, id string) (ChatJoinRequest, error)
+
+	CreateWebhook(ctx *Context, form forms.WebhookCreation) (Webhook, error)
+	Webhooks(ctx *Context, offset int, amount int) ([]Webhook, error)
+
+	CreateIncomingWebhook(ctx *Context, form forms.IncomingWebhookCreation) (IncomingWebhook, string, error)
+	IncomingWebhooks(ctx *Context, offset int, amount int) ([]IncomingWebhook, error)
+	IncomingWebhook(ctx *Context, id string) (IncomingWebhook, error)
+
 	Model(ctx *Context) (models.Chat, error)
 
 	Messages(ctx *Context, offset int, amount int) ([]Message, error)
 	CountMessages(ctx *Context) (int, error)
 
+	Leave(ctx *Context) error
 	Delete(ctx *Context) error
 }
 
@@ -49,8 +74,41 @@ func (c chat) isAccessible(ctx *Context) bool {
 	return true
 }
 
+// isVisible reports whether the chat's info can be seen by the current user:
+// public chats are visible to everyone, private ones only to their members
+func (c chat) isVisible(ctx *Context) bool {
+	m, err := c.app.repo.GetChat(ctx, c.id)
+	if err != nil {
+		return false
+	}
+	if m.Visibility != models.ChatVisibilityPrivate {
+		return true
+	}
+	return c.isAccessible(ctx)
+}
+
+func (c chat) isChannel(ctx *Context) bool {
+	m, err := c.app.repo.GetChat(ctx, c.id)
+	if err != nil {
+		return false
+	}
+	return m.Kind == models.ChatKindChannel
+}
+
+// isManageable reports whether the current user is an admin of the chat
+func (c chat) isManageable(ctx *Context) bool {
+	if ctx.User() == nil {
+		return false
+	}
+	m, err := c.app.repo.GetChatMember(ctx, ctx.User().ID(), c.id)
+	if err != nil {
+		return false
+	}
+	return m.Status >= ChatMemberAdminStatus
+}
+
 func (c chat) Model(ctx *Context) (models.Chat, error) {
-	if !c.isAccessible(ctx) {
+	if !c.isVisible(ctx) {
 		return models.Chat{}, errors.ResourceInaccessible
 	}
 	return c.app.repo.GetChat(ctx, c.id)
@@ -84,11 +142,155 @@ func (c chat) Owner(ctx *Context) (User, error) {
 	}
 }
 
+func (c chat) Visibility(ctx *Context) (int, error) {
+	if m, err := c.Model(ctx); err != nil {
+		return 0, err
+	} else {
+		return m.Visibility, nil
+	}
+}
+
+func (c chat) Kind(ctx *Context) (int, error) {
+	if m, err := c.Model(ctx); err != nil {
+		return 0, err
+	} else {
+		return m.Kind, nil
+	}
+}
+
+func (c chat) SetVisibility(ctx *Context, update forms.ChatVisibilityUpdate) error {
+	if !ctx.Allows(security.ScopeChatsWrite) {
+		return errors.NotAuthorized
+	}
+	if !c.isManageable(ctx) {
+		return errors.RightsViolation
+	}
+
+	if err := update.Validate(); err != nil {
+		return err
+	}
+
+	m, err := c.app.repo.GetChat(ctx, c.id)
+	if err != nil {
+		return err
+	}
+
+	m.Visibility = update.Visibility
+	_, err = c.app.transaction(ctx, func(tx data.Tx) (interface{}, error) {
+		if _, err := tx.UpdateChat(ctx, m); err != nil {
+			return nil, err
+		}
+		return nil, c.app.publish(ctx, tx, c.updateEvent())
+	})
+
+	return err
+}
+
+func (c chat) Update(ctx *Context, update forms.ChatUpdate) error {
+	if !ctx.Allows(security.ScopeChatsWrite) {
+		return errors.NotAuthorized
+	}
+	if !c.isManageable(ctx) {
+		return errors.RightsViolation
+	}
+
+	if err := update.Validate(); err != nil {
+		return err
+	}
+
+	m, err := c.app.repo.GetChat(ctx, c.id)
+	if err != nil {
+		return err
+	}
+
+	m.Name = update.Name
+	m.Description = update.Description
+	_, err = c.app.transaction(ctx, func(tx data.Tx) (interface{}, error) {
+		if _, err := tx.UpdateChat(ctx, m); err != nil {
+			return nil, err
+		}
+		return nil, c.app.publish(ctx, tx, c.updateEvent())
+	})
+
+	return err
+}
+
+// TransferOwnership hands the chat over to another member, who is promoted to an admin.
+// Only the current owner is allowed to do it.
+func (c chat) TransferOwnership(ctx *Context, userID string) error {
+	if !ctx.Allows(security.ScopeChatsWrite) {
+		return errors.NotAuthorized
+	}
+	if ctx.User() == nil {
+		return errors.NotAuthorized
+	}
+
+	m, err := c.app.repo.GetChat(ctx, c.id)
+	if err != nil {
+		return err
+	}
+
+	if m.OwnerID != ctx.User().ID() {
+		return errors.RightsViolation
+	}
+
+	if userID == m.OwnerID {
+		return goerrors.New("already the owner")
+	}
+
+	_, err = c.app.transaction(ctx, func(tx data.Tx) (interface{}, error) {
+		member, err := tx.GetChatMember(ctx, userID, c.id)
+		if err != nil {
+			return nil, goerrors.New("the new owner must be a member of the chat")
+		}
+
+		if u, err := tx.GetUser(ctx, userID); err != nil || u.Kind != models.UserKindRegular {
+			return nil, goerrors.New("integrations can't own chats")
+		}
+
+		if member.Status < ChatMemberAdminStatus {
+			member.Status = ChatMemberAdminStatus
+			if _, err := tx.UpdateChatMember(ctx, member); err != nil {
+				return nil, err
+			}
+		}
+
+		m.OwnerID = userID
+		if _, err := tx.UpdateChat(ctx, m); err != nil {
+			return nil, err
+		}
+
+		e := event.New(ChatOwnerChangedEventName, ChatOwnerChangedEvent{
+			ChatID:          c.id,
+			PreviousOwnerID: ctx.User().ID(),
+			OwnerID:         userID,
+		}, event.WithTime(time.Now()), event.ToChat(c.id), event.ToUsers(ctx.User().ID(), userID))
+
+		return nil, c.app.publish(ctx, tx, e)
+	})
+
+	return err
+}
+
+func (c chat) updateEvent() event.Event {
+	return event.New(ChatUpdatedEventName, ChatUpdatedEvent{
+		ChatID: c.id,
+	}, event.WithTime(time.Now()), event.ToChat(c.id))
+}
+
 func (c chat) Members(ctx *Context, offset int, count int) ([]ChatMember, error) {
+	if !ctx.Allows(security.ScopeChatsRead) {
+		return nil, errors.NotAuthorized
+	}
 	if !c.isAccessible(ctx) {
 		return nil, errors.ResourceInaccessible
 	}
 
+	// subscribers of a channel can't see each other
+	if c.isChannel(ctx) && !c.isManageable(ctx) {
+		return nil, errors.RightsViolation
+	}
+
 	members, err := c.app.repo.GetChatMembers(ctx, c.id, offset, count)
 
 	if err != nil {
@@ -106,7 +308,7 @@ func (c chat) Members(ctx *Context, offset int, count int) ([]ChatMember, error)
 }
 
 func (c chat) CountMembers(ctx *Context) (int, error) {
-	if !c.isAccessible(ctx) {
+	if !c.isVisible(ctx) {
 		return 0, errors.ResourceInaccessible
 	}
 
@@ -118,34 +320,235 @@ func (c chat) Member(ctx *Context, id string) (ChatMember, error) {
 }
 
 func (c chat) Add(ctx *Context, id string, status int) (ChatMember, error) {
+	if !ctx.Allows(security.ScopeChatsWrite) {
+		return nil, errors.NotAuthorized
+	}
 	if !c.isAccessible(ctx) {
 		return nil, errors.ResourceInaccessible
 	}
 
-	_, err := c.app.repo.CreateChatMember(ctx, models.ChatMember{
-		ChatID: c.id,
-		UserID: id,
-		Status: status,
+	action := AddMemberAction{
+		ChatID:  c.id,
+		UserID:  id,
+		ActorID: ctx.User().ID(),
+		Status:  status,
+		Source:  AddMemberDirect,
+	}
+	if err := c.app.hooks.addMember.run(ctx, &action); err != nil {
+		return nil, err
+	}
+
+	_, err := c.app.transaction(ctx, func(tx data.Tx) (interface{}, error) {
+		_, err := tx.CreateChatMember(ctx, models.ChatMember{
+			ChatID: c.id,
+			UserID: id,
+			Status: action.Status,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		e := event.New(ChatMemberCreatedEventName, ChatMemberCreatedEvent{
+			UserID: id,
+			ChatID: c.id,
+		}, event.WithTime(time.Now()), event.ToChat(c.id), event.ToUsers(id))
+
+		return nil, c.app.publish(ctx, tx, e)
 	})
 
 	if err != nil {
 		return nil, err
 	}
 
-	e := event.New(ChatMemberCreatedEventName, ChatMemberCreatedEvent{
-		UserID: id,
-		ChatID: c.id,
-	}, event.WithTime(time.Now()))
+	return newChatMember(ctx, c.app, id, c.id)
+}
 
-	if err := c.app.Events().Send(ctx, e); err != nil {
-		// currently not handled
-		log.Println("failed to send event:", err)
+func (c chat) CreateInvite(ctx *Context, form forms.ChatInviteCreation) (ChatInvite, error) {
+	if !ctx.Allows(security.ScopeChatsWrite) {
+		return nil, errors.NotAuthorized
+	}
+	if !c.isManageable(ctx) {
+		return nil, errors.RightsViolation
 	}
 
-	return newChatMember(ctx, c.app, id, c.id)
+	if err := form.Validate(); err != nil {
+		return nil, err
+	}
+
+	code, err := generateToken(chatInviteCodeSize)
+	if err != nil {
+		return nil, err
+	}
+
+	model, err := c.app.repo.CreateChatInvite(ctx, models.ChatInvite{
+		Code:             code,
+		ChatID:           c.id,
+		CreatorID:        ctx.User().ID(),
+		MaxUses:          form.MaxUses,
+		ExpiresAt:        form.ExpiresAt,
+		RequiresApproval: form.RequiresApproval,
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return unsafeChatInviteFromModel(c.app, model), nil
+}
+
+func (c chat) Invites(ctx *Context, offset int, count int) ([]ChatInvite, error) {
+	if !ctx.Allows(security.ScopeChatsRead) {
+		return nil, errors.NotAuthorized
+	}
+	if !c.isManageable(ctx) {
+		return nil, errors.RightsViolation
+	}
+
+	rawInvites, err := c.app.repo.GetChatInvites(ctx, c.id, offset, count)
+	if err != nil {
+		return nil, err
+	}
+
+	invites := make([]ChatInvite, 0, len(rawInvites))
+	for _, model := range rawInvites {
+		invites = append(invites, unsafeChatInviteFromModel(c.app, model))
+	}
+	return invites, nil
+}
+
+func (c chat) Invite(ctx *Context, code string) (ChatInvite, error) {
+	if !c.isManageable(ctx) {
+		return nil, errors.RightsViolation
+	}
+
+	model, err := c.app.repo.GetChatInvite(ctx, code)
+	if err != nil || model.ChatID != c.id {
+		return nil, errors.DoesNotExist
+	}
+
+	return unsafeChatInviteFromModel(c.app, model), nil
+}
+
+func (c chat) JoinRequests(ctx *Context, offset int, count int) ([]ChatJoinRequest, error) {
+	if !ctx.Allows(security.ScopeChatsRead) {
+		return nil, errors.NotAuthorized
+	}
+	if !c.isManageable(ctx) {
+		return nil, errors.RightsViolation
+	}
+
+	rawRequests, err := c.app.repo.GetChatJoinRequests(ctx, c.id, offset, count)
+	if err != nil {
+		return nil, err
+	}
+
+	requests := make([]ChatJoinRequest, 0, len(rawRequests))
+	for _, model := range rawRequests {
+		requests = append(requests, unsafeChatJoinRequestFromModel(c.app, model))
+	}
+	return requests, nil
+}
+
+func (c chat) JoinRequest(ctx *Context, id string) (ChatJoinRequest, error) {
+	if !c.isManageable(ctx) {
+		return nil, errors.RightsViolation
+	}
+
+	model, err := c.app.repo.GetChatJoinRequest(ctx, id)
+	if err != nil || model.ChatID != c.id {
+		return nil, errors.DoesNotExist
+	}
+
+	return unsafeChatJoinRequestFromModel(c.app, model), nil
+}
+
+// isOwned reports whether the current user owns the chat
+func (c chat) isOwned(ctx *Context) bool {
+	if ctx.User() == nil {
+		return false
+	}
+	model, err := c.app.repo.GetChat(ctx, c.id)
+	return err == nil && model.OwnerID == ctx.User().ID()
+}
+
+// CreateWebhook registers a webhook receiving the events of the chat.
+// Only the owner is allowed to do it.
+func (c chat) CreateWebhook(ctx *Context, form forms.WebhookCreation) (Webhook, error) {
+	if !ctx.Allows(security.ScopeWebhooks) {
+		return nil, errors.NotAuthorized
+	}
+	if !c.isOwned(ctx) {
+		return nil, errors.RightsViolation
+	}
+	return createWebhook(ctx, c.app, c.id, form)
+}
+
+func (c chat) Webhooks(ctx *Context, offset int, count int) ([]Webhook, error) {
+	if !ctx.Allows(security.ScopeWebhooks) {
+		return nil, errors.NotAuthorized
+	}
+	if !c.isOwned(ctx) {
+		return nil, errors.RightsViolation
+	}
+
+	rawWebhooks, err := c.app.repo.GetChatWebhooks(ctx, c.id, offset, count)
+	if err != nil {
+		return nil, err
+	}
+	return webhooksFromModels(c.app, rawWebhooks), nil
+}
+
+// CreateIncomingWebhook lets external services post into the chat,
+// the returned token is shown only once. Only admins are allowed to do it.
+func (c chat) CreateIncomingWebhook(ctx *Context, form forms.IncomingWebhookCreation) (IncomingWebhook, string, error) {
+	if !ctx.Allows(security.ScopeWebhooks) {
+		return nil, "", errors.NotAuthorized
+	}
+	if !c.isManageable(ctx) {
+		return nil, "", errors.RightsViolation
+	}
+	return createIncomingWebhook(ctx, c.app, c.id, form)
+}
+
+func (c chat) IncomingWebhooks(ctx *Context, offset int, count int) ([]IncomingWebhook, error) {
+	if !ctx.Allows(security.ScopeWebhooks) {
+		return nil, errors.NotAuthorized
+	}
+	if !c.isManageable(ctx) {
+		return nil, errors.RightsViolation
+	}
+
+	rawWebhooks, err := c.app.repo.GetChatIncomingWebhooks(ctx, c.id, offset, count)
+	if err != nil {
+		return nil, err
+	}
+
+	webhooks := make([]IncomingWebhook, 0, len(rawWebhooks))
+	for _, model := range rawWebhooks {
+		webhooks = append(webhooks, unsafeIncomingWebhookFromModel(c.app, model))
+	}
+	return webhooks, nil
+}
+
+func (c chat) IncomingWebhook(ctx *Context, id string) (IncomingWebhook, error) {
+	if !ctx.Allows(security.ScopeWebhooks) {
+		return nil, errors.NotAuthorized
+	}
+	if !c.isManageable(ctx) {
+		return nil, errors.RightsViolation
+	}
+
+	model, err := c.app.repo.GetIncomingWebhook(ctx, id)
+	if err != nil || model.ChatID != c.id {
+		return nil, errors.DoesNotExist
+	}
+	return unsafeIncomingWebhookFromModel(c.app, model), nil
 }
 
 func (c chat) Messages(ctx *Context, offset int, count int) ([]Message, error) {
+	if !ctx.Allows(security.ScopeMessagesRead) {
+		return nil, errors.NotAuthorized
+	}
 	if !c.isAccessible(ctx) {
 		return nil, errors.ResourceInaccessible
 	}
@@ -156,6 +559,17 @@ func (c chat) Messages(ctx *Context, offset int, count int) ([]Message, error) {
 		return nil, err
 	}
 
+	if model, err := c.app.repo.GetChat(ctx, c.id); err == nil && model.CountViews && len(messages) > 0 {
+		ids := make([]string, 0, len(messages))
+		for _, m := range messages {
+			ids = append(ids, m.ID)
+		}
+		if err := c.app.repo.MarkMessagesViewed(ctx, ctx.User().ID(), ids); err != nil {
+			// view counters are not critical
+			log.Println("failed to mark messages as viewed:", err)
+		}
+	}
+
 	chatMessages := make([]Message, 0, len(messages))
 
 	for i := 0; i < len(messages); i++ {
@@ -175,6 +589,9 @@ func (c chat) CountMessages(ctx *Context) (int, error) {
 }
 
 func (c chat) Delete(ctx *Context) error {
+	if !ctx.Allows(security.ScopeChatsWrite) {
+		return errors.NotAuthorized
+	}
 	if !c.isAccessible(ctx) {
 		return errors.ResourceInaccessible
 	}
@@ -189,22 +606,116 @@ func (c chat) Delete(ctx *Context) error {
 		return errors.ResourceInaccessible
 	}
 
-	err = c.app.repo.DeleteChat(ctx, c.id)
+	_, err = c.app.transaction(ctx, func(tx data.Tx) (interface{}, error) {
+		if err := tx.DeleteChat(ctx, c.id); err != nil {
+			return nil, err
+		}
+
+		e := event.New(ChatDeletedEventName, ChatDeletedEvent{
+			ChatID: c.id,
+		}, event.WithTime(time.Now()), event.ToChat(c.id))
 
+		return nil, c.app.publish(ctx, tx, e)
+	})
+
+	return err
+}
+
+// Leave removes the current user from the chat.
+//
+// If the user is the owner, the chat is handed over to the oldest admin
+// (or the oldest member if there are no admins). A chat that is left
+// with no members is deleted.
+func (c chat) Leave(ctx *Context) error {
+	if !ctx.Allows(security.ScopeChatsWrite) {
+		return errors.NotAuthorized
+	}
+	if !c.isAccessible(ctx) {
+		return errors.ResourceInaccessible
+	}
+
+	userID := ctx.User().ID()
+
+	_, err := c.app.transaction(ctx, func(tx data.Tx) (interface{}, error) {
+		departure, err := leaveChat(ctx, tx, c.id, userID)
+		if err != nil {
+			return nil, err
+		}
+		return nil, c.app.publish(ctx, tx, departure.events()...)
+	})
+
+	return err
+}
+
+// chatDeparture describes what has happened to a chat after a user left it
+type chatDeparture struct {
+	chatID     string
+	userID     string
+	newOwnerID string
+	deleted    bool
+}
+
+// leaveChat removes the user from the chat within the given transaction
+// taking care of the ownership succession
+func leaveChat(ctx context.Context, tx data.Tx, chatID, userID string) (chatDeparture, error) {
+	departure := chatDeparture{
+		chatID: chatID,
+		userID: userID,
+	}
+
+	model, err := tx.GetChat(ctx, chatID)
 	if err != nil {
-		return err
+		return departure, err
 	}
 
-	e := event.New(ChatDeletedEventName, ChatDeletedEvent{
-		ChatID: c.id,
-	}, event.WithTime(time.Now()))
+	if model.OwnerID == userID {
+		successor, err := tx.GetChatSuccessor(ctx, chatID, userID)
+		if err != nil {
+			// nobody else is left in the chat
+			departure.deleted = true
+			return departure, tx.DeleteChat(ctx, chatID)
+		}
+
+		if successor.Status < ChatMemberAdminStatus {
+			successor.Status = ChatMemberAdminStatus
+			if _, err := tx.UpdateChatMember(ctx, successor); err != nil {
+				return departure, err
+			}
+		}
+
+		model.OwnerID = successor.UserID
+		if _, err := tx.UpdateChat(ctx, model); err != nil {
+			return departure, err
+		}
+		departure.newOwnerID = successor.UserID
+	}
+
+	return departure, tx.DeleteChatMember(ctx, userID, chatID)
+}
+
+func (departure chatDeparture) events() []event.Event {
+	events := []event.Event{
+		event.New(ChatMemberDeletedEventName, ChatMemberDeletedEvent{
+			ChatID: departure.chatID,
+			UserID: departure.userID,
+		}, event.WithTime(time.Now()), event.ToChat(departure.chatID), event.ToUsers(departure.userID)),
+	}
+
+	if departure.newOwnerID != "" {
+		events = append(events, event.New(ChatOwnerChangedEventName, ChatOwnerChangedEvent{
+			ChatID:          departure.chatID,
+			PreviousOwnerID: departure.userID,
+			OwnerID:         departure.newOwnerID,
+		}, event.WithTime(time.Now()), event.ToChat(departure.chatID), event.ToUsers(departure.userID, departure.newOwnerID)))
+	}
 
-	if err := c.app.Events().Send(ctx, e); err != nil {
-		// currently not handled
-		log.Println("failed to send event:", err)
+	if departure.deleted {
+		events = append(events, event.New(ChatDeletedEventName, ChatDeletedEvent{
+			ChatID: departure.chatID,
+		}, event.WithTime(time.Now()), event.ToChat(departure.chatID)))
 	}
 
-	return nil
+	return events
 }
 
 func unsafeChatFromModel(app *App, c models.Chat) chat {
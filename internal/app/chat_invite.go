@@ -0,0 +1,87 @@
+package app
+
+import (
+	"github.com/ischenkx/vk-test-task/internal/app/data/models"
+	"github.com/ischenkx/vk-test-task/internal/app/errors"
+	"github.com/ischenkx/vk-test-task/internal/app/security"
+)
+
+const chatInviteCodeSize = 12
+
+type ChatInvite interface {
+	Code() string
+	Chat(ctx *Context) (Chat, error)
+	Revoke(ctx *Context) error
+	Model(ctx *Context) (models.ChatInvite, error)
+}
+
+type chatInvite struct {
+	app  *App
+	code string
+}
+
+func (inv chatInvite) model(ctx *Context) (models.ChatInvite, error) {
+	return inv.app.repo.GetChatInvite(ctx, inv.code)
+}
+
+func (inv chatInvite) exists(ctx *Context) bool {
+	if _, err := inv.model(ctx); err != nil {
+		return false
+	}
+	return true
+}
+
+func (inv chatInvite) isWritable(ctx *Context) bool {
+	model, err := inv.model(ctx)
+	if err != nil {
+		return false
+	}
+	return chat{app: inv.app, id: model.ChatID}.isManageable(ctx)
+}
+
+func (inv chatInvite) Code() string {
+	return inv.code
+}
+
+func (inv chatInvite) Model(ctx *Context) (models.ChatInvite, error) {
+	if !inv.isWritable(ctx) {
+		return models.ChatInvite{}, errors.ResourceInaccessible
+	}
+	return inv.model(ctx)
+}
+
+func (inv chatInvite) Chat(ctx *Context) (Chat, error) {
+	if m, err := inv.Model(ctx); err != nil {
+		return nil, err
+	} else {
+		return newChat(ctx, inv.app, m.ChatID)
+	}
+}
+
+func (inv chatInvite) Revoke(ctx *Context) error {
+	if !ctx.Allows(security.ScopeChatsWrite) {
+		return errors.NotAuthorized
+	}
+	if !inv.isWritable(ctx) {
+		return errors.ResourceInaccessible
+	}
+	return inv.app.repo.RevokeChatInvite(ctx, inv.code)
+}
+
+func unsafeChatInviteFromModel(app *App, model models.ChatInvite) ChatInvite {
+	return chatInvite{
+		app:  app,
+		code: model.Code,
+	}
+}
+
+func newChatInvite(ctx *Context, app *App, code string) (ChatInvite, error) {
+	inv := chatInvite{
+		app:  app,
+		code: code,
+	}
+	if !inv.exists(ctx) {
+		return nil, errors.DoesNotExist
+	}
+	return inv, nil
+}
@@ -0,0 +1,194 @@
+package app
+
+import (
+	"github.com/ischenkx/vk-test-task/internal/app/data"
+	"github.com/ischenkx/vk-test-task/internal/app/data/models"
+	"github.com/ischenkx/vk-test-task/internal/app/errors"
+	"github.com/ischenkx/vk-test-task/internal/app/event"
+	"github.com/ischenkx/vk-test-task/internal/app/security"
+	"time"
+)
+
+type ChatJoinRequest interface {
+	ID() string
+	Chat(ctx *Context) (Chat, error)
+	User(ctx *Context) (User, error)
+	Approve(ctx *Context) error
+	Reject(ctx *Context) error
+	Model(ctx *Context) (models.ChatJoinRequest, error)
+}
+
+type chatJoinRequest struct {
+	app *App
+	id  string
+}
+
+func (r chatJoinRequest) model(ctx *Context) (models.ChatJoinRequest, error) {
+	return r.app.repo.GetChatJoinRequest(ctx, r.id)
+}
+
+func (r chatJoinRequest) exists(ctx *Context) bool {
+	if _, err := r.model(ctx); err != nil {
+		return false
+	}
+	return true
+}
+
+// isAccessible reports whether the request can be seen by the current user,
+// which is either the requester or an admin of the chat
+func (r chatJoinRequest) isAccessible(ctx *Context) bool {
+	if ctx.User() == nil {
+		return false
+	}
+	model, err := r.model(ctx)
+	if err != nil {
+		return false
+	}
+	if model.UserID == ctx.User().ID() {
+		return true
+	}
+	return chat{app: r.app, id: model.ChatID}.isManageable(ctx)
+}
+
+func (r chatJoinRequest) isWritable(ctx *Context) bool {
+	model, err := r.model(ctx)
+	if err != nil {
+		return false
+	}
+	return chat{app: r.app, id: model.ChatID}.isManageable(ctx)
+}
+
+func (r chatJoinRequest) ID() string {
+	return r.id
+}
+
+func (r chatJoinRequest) Model(ctx *Context) (models.ChatJoinRequest, error) {
+	if !r.isAccessible(ctx) {
+		return models.ChatJoinRequest{}, errors.ResourceInaccessible
+	}
+	return r.model(ctx)
+}
+
+func (r chatJoinRequest) Chat(ctx *Context) (Chat, error) {
+	if m, err := r.Model(ctx); err != nil {
+		return nil, err
+	} else {
+		return newChat(ctx, r.app, m.ChatID)
+	}
+}
+
+func (r chatJoinRequest) User(ctx *Context) (User, error) {
+	if m, err := r.Model(ctx); err != nil {
+		return nil, err
+	} else {
+		return newUser(ctx, r.app, m.UserID)
+	}
+}
+
+func (r chatJoinRequest) Approve(ctx *Context) error {
+	if !ctx.Allows(security.ScopeChatsWrite) {
+		return errors.NotAuthorized
+	}
+	if !r.isWritable(ctx) {
+		return errors.RightsViolation
+	}
+
+	model, err := r.model(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.app.transaction(ctx, func(tx data.Tx) (interface{}, error) {
+		if err := tx.DeleteChatJoinRequest(ctx, model.ID); err != nil {
+			return nil, err
+		}
+
+		// requests made with an invite use it up on approval,
+		// they can't be approved once the invite is revoked, expired or exhausted
+		if model.InviteCode != "" {
+			if _, err := useChatInvite(ctx, tx, model.InviteCode); err != nil {
+				return nil, err
+			}
+		}
+
+		action := AddMemberAction{
+			ChatID:  model.ChatID,
+			UserID:  model.UserID,
+			ActorID: ctx.User().ID(),
+			Status:  ChatMemberRegularStatus,
+			Source:  AddMemberApproval,
+		}
+		if err := r.app.hooks.addMember.run(ctx, &action); err != nil {
+			return nil, err
+		}
+
+		_, err := tx.CreateChatMember(ctx, models.ChatMember{
+			ChatID: model.ChatID,
+			UserID: model.UserID,
+			Status: action.Status,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		e := event.New(ChatMemberCreatedEventName, ChatMemberCreatedEvent{
+			UserID: model.UserID,
+			ChatID: model.ChatID,
+		}, event.WithTime(time.Now()), event.ToChat(model.ChatID), event.ToUsers(model.UserID))
+
+		return nil, r.app.publish(ctx, tx, e, r.updateEvent(model, ChatJoinRequestUpdateApproved))
+	})
+
+	return err
+}
+
+func (r chatJoinRequest) Reject(ctx *Context) error {
+	if !ctx.Allows(security.ScopeChatsWrite) {
+		return errors.NotAuthorized
+	}
+	if !r.isWritable(ctx) {
+		return errors.RightsViolation
+	}
+
+	model, err := r.model(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.app.transaction(ctx, func(tx data.Tx) (interface{}, error) {
+		if err := tx.DeleteChatJoinRequest(ctx, model.ID); err != nil {
+			return nil, err
+		}
+
+		return nil, r.app.publish(ctx, tx, r.updateEvent(model, ChatJoinRequestUpdateRejected))
+	})
+
+	return err
+}
+
+func (r chatJoinRequest) updateEvent(model models.ChatJoinRequest, code int) event.Event {
+	return event.New(ChatJoinRequestUpdateEventName, ChatJoinRequestUpdateEvent{
+		JoinRequestID: model.ID,
+		ChatID:        model.ChatID,
+		UserID:        model.UserID,
+		Code:          code,
+	}, event.WithTime(time.Now()), event.ToChat(model.ChatID), event.ToUsers(model.UserID))
+}
+
+func unsafeChatJoinRequestFromModel(app *App, model models.ChatJoinRequest) ChatJoinRequest {
+	return chatJoinRequest{
+		app: app,
+		id:  model.ID,
+	}
+}
+
+func newChatJoinRequest(ctx *Context, app *App, id string) (ChatJoinRequest, error) {
+	r := chatJoinRequest{
+		app: app,
+		id:  id,
+	}
+	if !r.exists(ctx) {
+		return nil, errors.DoesNotExist
+	}
+	return r, nil
+}
@@ -1,12 +1,19 @@
 package app
 
 import (
+	goerrors "errors"
 	"github.com/ischenkx/vk-test-task/internal/app/data"
 	"github.com/ischenkx/vk-test-task/internal/app/data/models"
 	"github.com/ischenkx/vk-test-task/internal/app/errors"
+	"github.com/ischenkx/vk-test-task/internal/app/event"
 	"github.com/ischenkx/vk-test-task/internal/app/forms"
+	"github.com/ischenkx/vk-test-task/internal/app/security"
+	"time"
 )
 
+var ErrInvalidInvite = goerrors.New("invalid or expired invite")
+var ErrJoinRequestPending = goerrors.New("join request already pending")
+
 type ChatManager struct {
 	app *App
 }
@@ -16,10 +23,18 @@ func (manager ChatManager) Get(ctx *Context, id string) (Chat, error) {
 }
 
 func (manager ChatManager) Create(ctx *Context, form forms.ChatCreationForm) (Chat, error) {
+	if !ctx.Allows(security.ScopeChatsWrite) {
+		return nil, errors.NotAuthorized
+	}
 	if ctx.User() == nil {
 		return nil, errors.NotAuthorized
 	}
 
+	// bots and integrations can't own chats
+	if u, err := ctx.User().Model(ctx); err != nil || u.Kind != models.UserKindRegular {
+		return nil, errors.RightsViolation
+	}
+
 	if err := form.Validate(); err != nil {
 		return nil, err
 	}
@@ -29,6 +44,9 @@ func (manager ChatManager) Create(ctx *Context, form forms.ChatCreationForm) (Ch
 			Name:        form.Name,
 			Description: form.Description,
 			OwnerID:     ctx.User().ID(),
+			Visibility:  form.Visibility,
+			Kind:        form.Kind,
+			CountViews:  form.CountViews,
 		})
 
 		if err != nil {
@@ -38,7 +56,7 @@ func (manager ChatManager) Create(ctx *Context, form forms.ChatCreationForm) (Ch
 		_, err = tx.CreateChatMember(ctx, models.ChatMember{
 			ChatID: c.ID,
 			UserID: ctx.User().ID(),
-			Status: 1,
+			Status: ChatMemberAdminStatus,
 		})
 
 		return c, err
@@ -67,3 +85,244 @@ func (manager ChatManager) GetMessage(ctx *Context, id string) (Message, error)
 
 	return unsafeMessageFromModel(manager.app, mes), nil
 }
+
+// PostIncomingWebhook creates a message on behalf of the incoming webhook.
+// It doesn't need an authorized user, the token is the credential.
+func (manager ChatManager) PostIncomingWebhook(ctx *Context, id, token string, form forms.SendMessage) (Message, error) {
+	return incomingWebhook{app: manager.app, id: id}.post(ctx, token, form)
+}
+
+// JoinByInvite redeems an invite code on behalf of the current user.
+//
+// If the invite requires approval, a join request is created instead
+// of a chat member and the returned member is nil. The use of the invite
+// is then counted when the request is approved, so rejected requests
+// don't exhaust it.
+func (manager ChatManager) JoinByInvite(ctx *Context, code string) (ChatMember, ChatJoinRequest, error) {
+	if !ctx.Allows(security.ScopeChatsWrite) {
+		return nil, nil, errors.NotAuthorized
+	}
+	if ctx.User() == nil {
+		return nil, nil, errors.NotAuthorized
+	}
+
+	if len(code) == 0 {
+		return nil, nil, goerrors.New("empty invite code")
+	}
+
+	userID := ctx.User().ID()
+
+	res, err := manager.app.transaction(ctx, func(tx data.Tx) (interface{}, error) {
+		invite, err := tx.GetChatInvite(ctx, code)
+		if goerrors.Is(err, data.ErrNotFound) {
+			return nil, ErrInvalidInvite
+		} else if err != nil {
+			return nil, err
+		}
+		if !inviteUsable(invite, time.Now()) {
+			return nil, ErrInvalidInvite
+		}
+
+		if _, err := tx.GetChatMember(ctx, userID, invite.ChatID); err == nil {
+			return nil, goerrors.New("already a member")
+		}
+
+		if invite.RequiresApproval {
+			if err := checkNoJoinRequest(ctx, tx, invite.ChatID, userID); err != nil {
+				return nil, err
+			}
+			request, err := tx.CreateChatJoinRequest(ctx, models.ChatJoinRequest{
+				ChatID:     invite.ChatID,
+				UserID:     userID,
+				InviteCode: invite.Code,
+				Time:       time.Now(),
+			})
+			if err != nil {
+				return nil, err
+			}
+			return request, manager.publishJoined(ctx, tx, request)
+		}
+
+		if _, err := useChatInvite(ctx, tx, code); err != nil {
+			return nil, err
+		}
+
+		action := AddMemberAction{
+			ChatID:  invite.ChatID,
+			UserID:  userID,
+			ActorID: userID,
+			Status:  ChatMemberRegularStatus,
+			Source:  AddMemberInvite,
+		}
+		if err := manager.app.hooks.addMember.run(ctx, &action); err != nil {
+			return nil, err
+		}
+
+		member, err := tx.CreateChatMember(ctx, models.ChatMember{
+			ChatID: invite.ChatID,
+			UserID: userID,
+			Status: action.Status,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return member, manager.publishJoined(ctx, tx, member)
+	})
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return manager.joined(ctx, res)
+}
+
+// Join adds the current user to a public chat.
+//
+// Chats that require approval get a join request instead, in which case
+// the returned member is nil.
+func (manager ChatManager) Join(ctx *Context, id string) (ChatMember, ChatJoinRequest, error) {
+	if !ctx.Allows(security.ScopeChatsWrite) {
+		return nil, nil, errors.NotAuthorized
+	}
+	if ctx.User() == nil {
+		return nil, nil, errors.NotAuthorized
+	}
+
+	userID := ctx.User().ID()
+
+	res, err := manager.app.transaction(ctx, func(tx data.Tx) (interface{}, error) {
+		c, err := tx.GetChat(ctx, id)
+		if err != nil || c.Visibility == models.ChatVisibilityPrivate {
+			return nil, errors.ResourceInaccessible
+		}
+
+		if _, err := tx.GetChatMember(ctx, userID, c.ID); err == nil {
+			return nil, goerrors.New("already a member")
+		}
+
+		if c.Visibility == models.ChatVisibilityPublicWithApproval {
+			if err := checkNoJoinRequest(ctx, tx, c.ID, userID); err != nil {
+				return nil, err
+			}
+			request, err := tx.CreateChatJoinRequest(ctx, models.ChatJoinRequest{
+				ChatID: c.ID,
+				UserID: userID,
+				Time:   time.Now(),
+			})
+			if err != nil {
+				return nil, err
+			}
+			return request, manager.publishJoined(ctx, tx, request)
+		}
+
+		action := AddMemberAction{
+			ChatID:  c.ID,
+			UserID:  userID,
+			ActorID: userID,
+			Status:  ChatMemberRegularStatus,
+			Source:  AddMemberJoin,
+		}
+		if err := manager.app.hooks.addMember.run(ctx, &action); err != nil {
+			return nil, err
+		}
+
+		member, err := tx.CreateChatMember(ctx, models.ChatMember{
+			ChatID: c.ID,
+			UserID: userID,
+			Status: action.Status,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return member, manager.publishJoined(ctx, tx, member)
+	})
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return manager.joined(ctx, res)
+}
+
+// SearchPublic looks up public chats whose name or description contains the query.
+// The most populated chats come first.
+func (manager ChatManager) SearchPublic(ctx *Context, query string, offset int, count int) ([]Chat, error) {
+	rawChats, err := manager.app.repo.SearchPublicChats(ctx, query, offset, count)
+	if err != nil {
+		return nil, err
+	}
+
+	chats := make([]Chat, 0, len(rawChats))
+	for _, model := range rawChats {
+		chats = append(chats, unsafeChatFromModel(manager.app, model))
+	}
+	return chats, nil
+}
+
+// checkNoJoinRequest fails with ErrJoinRequestPending if the user already waits to join the chat
+// inviteUsable reports whether the invite can still be used,
+// UseChatInvite checks the same conditions while counting the use
+func inviteUsable(invite models.ChatInvite, now time.Time) bool {
+	if invite.Revoked {
+		return false
+	}
+	if !invite.ExpiresAt.IsZero() && !invite.ExpiresAt.After(now) {
+		return false
+	}
+	return invite.MaxUses == 0 || invite.Uses < invite.MaxUses
+}
+
+// useChatInvite counts a use of the invite, it fails with ErrInvalidInvite if the invite can't be used anymore
+func useChatInvite(ctx *Context, tx data.Tx, code string) (models.ChatInvite, error) {
+	invite, err := tx.UseChatInvite(ctx, code)
+	if goerrors.Is(err, data.ErrNotFound) {
+		return models.ChatInvite{}, ErrInvalidInvite
+	}
+	return invite, err
+}
+
+func checkNoJoinRequest(ctx *Context, tx data.Tx, chatID, userID string) error {
+	_, err := tx.GetUserChatJoinRequest(ctx, chatID, userID)
+	if err == nil {
+		return ErrJoinRequestPending
+	}
+	if goerrors.Is(err, data.ErrNotFound) {
+		return nil
+	}
+	return err
+}
+
+// publishJoined publishes the events for the result of a join transaction
+func (manager ChatManager) publishJoined(ctx *Context, tx data.Tx, res interface{}) error {
+	switch model := res.(type) {
+	case models.ChatJoinRequest:
+		e := event.New(NewChatJoinRequestEventName, NewChatJoinRequestEvent{
+			ID:     model.ID,
+			ChatID: model.ChatID,
+			UserID: model.UserID,
+		}, event.WithTime(time.Now()), event.ToChat(model.ChatID), event.ToUsers(model.UserID))
+
+		return manager.app.publish(ctx, tx, e)
+	case models.ChatMember:
+		e := event.New(ChatMemberCreatedEventName, ChatMemberCreatedEvent{
+			UserID: model.UserID,
+			ChatID: model.ChatID,
+		}, event.WithTime(time.Now()), event.ToChat(model.ChatID), event.ToUsers(model.UserID))
+
+		return manager.app.publish(ctx, tx, e)
+	default:
+		return goerrors.New("unexpected join result")
+	}
+}
+
+// joined wraps the result of a join transaction
+func (manager ChatManager) joined(ctx *Context, res interface{}) (ChatMember, ChatJoinRequest, error) {
+	switch model := res.(type) {
+	case models.ChatJoinRequest:
+		return nil, unsafeChatJoinRequestFromModel(manager.app, model), nil
+	case models.ChatMember:
+		return unsafeChatMemberFromModel(manager.app, model), nil, nil
+	default:
+		return nil, nil, goerrors.New("unexpected join result")
+	}
+}
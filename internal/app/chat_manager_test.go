@@ -0,0 +1,204 @@
+package app
+
+import (
+	"context"
+	goerrors "errors"
+	"github.com/ischenkx/vk-test-task/internal/app/data"
+	"github.com/ischenkx/vk-test-task/internal/app/data/models"
+	"testing"
+)
+
+// inviteRepo answers the lookups of JoinByInvite that precede the creation of a member or a request
+type inviteRepo struct {
+	data.Repository
+
+	invite      models.ChatInvite
+	inviteErr   error
+	requestErr  error
+	createdJoin bool
+}
+
+func (r *inviteRepo) Transaction(ctx context.Context, f func(data.Tx) (interface{}, error)) (interface{}, error) {
+	return f(r)
+}
+
+func (r *inviteRepo) GetChatInvite(ctx context.Context, code string) (models.ChatInvite, error) {
+	return r.invite, r.inviteErr
+}
+
+func (r *inviteRepo) GetChatMember(ctx context.Context, userID, chatID string) (models.ChatMember, error) {
+	return models.ChatMember{}, data.ErrNotFound
+}
+
+func (r *inviteRepo) GetUserChatJoinRequest(ctx context.Context, chatID, userID string) (models.ChatJoinRequest, error) {
+	return models.ChatJoinRequest{ChatID: chatID, UserID: userID}, r.requestErr
+}
+
+func (r *inviteRepo) CreateChatJoinRequest(ctx context.Context, request models.ChatJoinRequest) (models.ChatJoinRequest, error) {
+	r.createdJoin = true
+	return models.ChatJoinRequest{}, goerrors.New("duplicate key value violates unique constraint")
+}
+
+func TestJoinByInviteErrors(t *testing.T) {
+	failure := goerrors.New("connection refused")
+	invite := models.ChatInvite{Code: "code", ChatID: "chat", RequiresApproval: true}
+
+	tests := []struct {
+		name string
+		repo *inviteRepo
+		err  error
+	}{
+		{
+			name: "unusable invite",
+			repo: &inviteRepo{inviteErr: data.ErrNotFound},
+			err:  ErrInvalidInvite,
+		},
+		{
+			name: "exhausted invite",
+			repo: &inviteRepo{invite: models.ChatInvite{Code: "code", ChatID: "chat", MaxUses: 1, Uses: 1}},
+			err:  ErrInvalidInvite,
+		},
+		{
+			name: "failed lookup",
+			repo: &inviteRepo{inviteErr: failure},
+			err:  failure,
+		},
+		{
+			name: "pending request",
+			repo: &inviteRepo{invite: invite},
+			err:  ErrJoinRequestPending,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			app := &App{repo: test.repo, outboxWake: make(chan struct{}, 1)}
+			ctx := NewContext(context.Background())
+			ctx.SetUser(user{app: app, userID: "user"})
+
+			_, _, err := app.Chats().JoinByInvite(ctx, "code")
+			if err != test.err {
+				t.Fatalf("expected %v, got %v", test.err, err)
+			}
+			if test.repo.createdJoin {
+				t.Fatal("a join request has been created")
+			}
+		})
+	}
+}
+
+// approvalRepo keeps an invite, a join request and the chat members in memory
+type approvalRepo struct {
+	data.Repository
+
+	invite  models.ChatInvite
+	request *models.ChatJoinRequest
+	members map[string]models.ChatMember
+}
+
+func (r *approvalRepo) Transaction(ctx context.Context, f func(data.Tx) (interface{}, error)) (interface{}, error) {
+	return f(r)
+}
+
+func (r *approvalRepo) GetChatInvite(ctx context.Context, code string) (models.ChatInvite, error) {
+	return r.invite, nil
+}
+
+func (r *approvalRepo) UseChatInvite(ctx context.Context, code string) (models.ChatInvite, error) {
+	if r.invite.MaxUses > 0 && r.invite.Uses >= r.invite.MaxUses {
+		return models.ChatInvite{}, data.ErrNotFound
+	}
+	r.invite.Uses++
+	return r.invite, nil
+}
+
+func (r *approvalRepo) GetChatMember(ctx context.Context, userID, chatID string) (models.ChatMember, error) {
+	if m, ok := r.members[userID]; ok {
+		return m, nil
+	}
+	return models.ChatMember{}, data.ErrNotFound
+}
+
+func (r *approvalRepo) CreateChatMember(ctx context.Context, member models.ChatMember) (models.ChatMember, error) {
+	r.members[member.UserID] = member
+	return member, nil
+}
+
+func (r *approvalRepo) GetUserChatJoinRequest(ctx context.Context, chatID, userID string) (models.ChatJoinRequest, error) {
+	return r.GetChatJoinRequest(ctx, "")
+}
+
+func (r *approvalRepo) GetChatJoinRequest(ctx context.Context, id string) (models.ChatJoinRequest, error) {
+	if r.request == nil {
+		return models.ChatJoinRequest{}, data.ErrNotFound
+	}
+	return *r.request, nil
+}
+
+func (r *approvalRepo) CreateChatJoinRequest(ctx context.Context, request models.ChatJoinRequest) (models.ChatJoinRequest, error) {
+	request.ID = "request"
+	r.request = &request
+	return request, nil
+}
+
+func (r *approvalRepo) DeleteChatJoinRequest(ctx context.Context, id string) error {
+	r.request = nil
+	return nil
+}
+
+func (r *approvalRepo) CreateOutboxEvent(ctx context.Context, e models.OutboxEvent) (models.OutboxEvent, error) {
+	return e, nil
+}
+
+func (r *approvalRepo) CreateBotUpdates(ctx context.Context, update models.BotUpdate, chatID string, userIDs []string) error {
+	return nil
+}
+
+func TestApprovalInviteCountsUseOnApproval(t *testing.T) {
+	repo := &approvalRepo{
+		invite:  models.ChatInvite{Code: "code", ChatID: "chat", MaxUses: 1, RequiresApproval: true},
+		members: map[string]models.ChatMember{"admin": {UserID: "admin", ChatID: "chat", Status: ChatMemberAdminStatus}},
+	}
+	app := New(Config{Repo: repo, PasswordHasher: plainHasher{}})
+
+	// join asks the admin to approve the request without using the invite up
+	join := func(userID string) ChatJoinRequest {
+		ctx := NewContext(context.Background())
+		ctx.SetUser(user{app: app, userID: userID})
+		_, request, err := app.Chats().JoinByInvite(ctx, "code")
+		if err != nil {
+			t.Fatalf("failed to join: %s", err)
+		}
+		if repo.invite.Uses != 0 {
+			t.Fatalf("expected the request not to use the invite, got %d uses", repo.invite.Uses)
+		}
+		return request
+	}
+
+	admin := NewContext(context.Background())
+	admin.SetUser(user{app: app, userID: "admin"})
+
+	if err := join("rejected").Reject(admin); err != nil {
+		t.Fatalf("failed to reject the request: %s", err)
+	}
+
+	if err := join("approved").Approve(admin); err != nil {
+		t.Fatalf("failed to approve the request: %s", err)
+	}
+	if repo.invite.Uses != 1 {
+		t.Fatalf("expected the approval to use the invite, got %d uses", repo.invite.Uses)
+	}
+	if _, ok := repo.members["approved"]; !ok {
+		t.Fatal("expected the approved user to become a member")
+	}
+
+	// the invite is used up by now, so a request made with it can't be approved anymore
+	repo.request = &models.ChatJoinRequest{ID: "late", ChatID: "chat", UserID: "late", InviteCode: "code"}
+	late := chatJoinRequest{app: app, id: "late"}
+	if err := late.Approve(admin); err != ErrInvalidInvite {
+		t.Fatalf("expected %v, got %v", ErrInvalidInvite, err)
+	}
+	if _, ok := repo.members["late"]; ok {
+		t.Fatal("expected the late user not to become a member")
+	}
+}
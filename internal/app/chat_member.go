@@ -1,14 +1,18 @@
 package app
 
 import (
+	"github.com/ischenkx/vk-test-task/internal/app/data"
 	"github.com/ischenkx/vk-test-task/internal/app/data/models"
 	"github.com/ischenkx/vk-test-task/internal/app/errors"
 	"github.com/ischenkx/vk-test-task/internal/app/event"
 	"github.com/ischenkx/vk-test-task/internal/app/forms"
-	"log"
+	"github.com/ischenkx/vk-test-task/internal/app/security"
 	"time"
 )
 
+const ChatMemberRegularStatus = 0
+const ChatMemberAdminStatus = 1
+
 type ChatMember interface {
 	Chat(ctx *Context) (Chat, error)
 	User(ctx *Context) (User, error)
@@ -69,57 +73,137 @@ func (member chatMember) Status(ctx *Context) (int, error) {
 }
 
 func (member chatMember) SendMessage(ctx *Context, form forms.SendMessage) (Message, error) {
+	if !ctx.Allows(security.ScopeMessagesWrite) {
+		return nil, errors.NotAuthorized
+	}
 	if !member.isWritable(ctx) {
 		return nil, errors.ResourceInaccessible
 	}
+	return member.send(ctx, form)
+}
+
+// isPublisher reports whether the member can post in a channel:
+// admins and integrations of the channel can do it
+func (member chatMember) isPublisher(ctx *Context) bool {
+	m, err := member.app.repo.GetChatMember(ctx, member.userID, member.chatID)
+	if err != nil {
+		return false
+	}
+	if m.Status >= ChatMemberAdminStatus {
+		return true
+	}
+	u, err := member.app.repo.GetUser(ctx, member.userID)
+	return err == nil && u.Kind == models.UserKindIntegration
+}
+
+// send creates a message of the member without checking who's sending it,
+// the callers are responsible for the authorization
+func (member chatMember) send(ctx *Context, form forms.SendMessage) (Message, error) {
+	action := SendMessageAction{
+		ChatID: member.chatID,
+		UserID: member.userID,
+		Form:   form,
+	}
+	if err := member.app.hooks.sendMessage.run(ctx, &action); err != nil {
+		return nil, err
+	}
+	form = action.Form
 
 	if err := form.Validate(); err != nil {
 		return nil, err
 	}
 
-	mes, err := member.app.repo.CreateMessage(ctx, models.Message{
-		Payload:   form.Payload,
-		TimeStamp: time.Now(),
-		ChatID:    member.chatID,
-		UserID:    member.userID,
-	})
+	c, err := member.app.repo.GetChat(ctx, member.chatID)
 	if err != nil {
 		return nil, err
 	}
 
-	e := event.New(NewMessageEventName, NewMessageEvent{
-		MessageID: mes.ID,
-		ChatID:    mes.ChatID,
-	}, event.WithTime(time.Now()))
-
-	if err := member.app.Events().Send(ctx, e); err != nil {
-		// currently not handled
-		log.Println("failed to send event:", err)
+	if c.Kind == models.ChatKindChannel && !member.isPublisher(ctx) {
+		return nil, errors.RightsViolation
+	}
+
+	res, err := member.app.transaction(ctx, func(tx data.Tx) (interface{}, error) {
+		mes, err := tx.CreateMessage(ctx, models.Message{
+			Payload:     form.Payload,
+			Attachments: form.Attachments,
+			TimeStamp:   time.Now(),
+			ChatID:      member.chatID,
+			UserID:      member.userID,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		e := event.New(NewMessageEventName, NewMessageEvent{
+			MessageID: mes.ID,
+			ChatID:    mes.ChatID,
+		}, event.WithTime(time.Now()), event.ToChat(mes.ChatID))
+
+		if c.Kind == models.ChatKindChannel {
+			e = event.New(NewChannelPostEventName, NewChannelPostEvent{
+				MessageID:   mes.ID,
+				ChatID:      mes.ChatID,
+				SenderID:    mes.UserID,
+				Payload:     mes.Payload,
+				Attachments: mes.Attachments,
+				TimeStamp:   mes.TimeStamp,
+			}, event.WithTime(time.Now()), event.ToChat(mes.ChatID))
+		}
+
+		commands, err := botCommandEvents(ctx, tx, mes)
+		if err != nil {
+			return nil, err
+		}
+
+		return mes, member.app.publish(ctx, tx, append([]event.Event{e}, commands...)...)
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	return unsafeMessageFromModel(member.app, mes), nil
+	return unsafeMessageFromModel(member.app, res.(models.Message)), nil
 }
 
+// Delete removes the member from the chat. Members can always leave on their own,
+// while removing somebody else requires admin rights. The owner can't be removed.
 func (member chatMember) Delete(ctx *Context) error {
+	if !ctx.Allows(security.ScopeChatsWrite) {
+		return errors.NotAuthorized
+	}
 	if !member.isWritable(ctx) {
 		return errors.ResourceInaccessible
 	}
 
-	if err := member.app.repo.DeleteChatMember(ctx, member.userID, member.chatID); err != nil {
-		return err
+	c := chat{app: member.app, id: member.chatID}
+
+	if ctx.User().ID() == member.userID {
+		return c.Leave(ctx)
 	}
 
-	e := event.New(ChatMemberDeletedEventName, ChatMemberDeletedEvent{
-		ChatID: member.chatID,
-		UserID: member.userID,
-	}, event.WithTime(time.Now()))
+	if !c.isManageable(ctx) {
+		return errors.RightsViolation
+	}
 
-	if err := member.app.Events().Send(ctx, e); err != nil {
-		// currently not handled
-		log.Println("failed to send event:", err)
+	if model, err := member.app.repo.GetChat(ctx, member.chatID); err != nil {
+		return err
+	} else if model.OwnerID == member.userID {
+		return errors.RightsViolation
 	}
 
-	return nil
+	_, err := member.app.transaction(ctx, func(tx data.Tx) (interface{}, error) {
+		if err := tx.DeleteChatMember(ctx, member.userID, member.chatID); err != nil {
+			return nil, err
+		}
+
+		e := event.New(ChatMemberDeletedEventName, ChatMemberDeletedEvent{
+			ChatID: member.chatID,
+			UserID: member.userID,
+		}, event.WithTime(time.Now()), event.ToChat(member.chatID), event.ToUsers(member.userID))
+
+		return nil, member.app.publish(ctx, tx, e)
+	})
+
+	return err
 }
 
 func unsafeChatMemberFromModel(app *App, model models.ChatMember) ChatMember {
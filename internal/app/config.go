@@ -4,10 +4,32 @@ import (
 	"github.com/ischenkx/vk-test-task/internal/app/data"
 	"github.com/ischenkx/vk-test-task/internal/app/event"
 	"github.com/ischenkx/vk-test-task/internal/app/security"
+	"time"
 )
 
 type Config struct {
 	Repo       data.Repository
 	Authorizer security.Authorizer
 	Bus        event.Bus
+	// OutboxPollInterval is how often the relay checks the outbox
+	// for events committed by other instances (a second by default)
+	OutboxPollInterval time.Duration
+	// IncomingWebhookRateLimit is how many messages an incoming webhook
+	// can post per minute (20 by default)
+	IncomingWebhookRateLimit int
+	// RefreshTokenTTL is how long a refresh token stays valid if it's not used (30 days by default),
+	// the lifetime of access tokens is up to the Authorizer
+	RefreshTokenTTL time.Duration
+	// IdentityProviders are the services users can log in with besides their passwords
+	IdentityProviders []security.IdentityProvider
+	// TOTPIssuer is the name authenticator apps show next to the codes ("vk-test-task" by default)
+	TOTPIssuer string
+	// PasswordPolicy is checked when a password is set
+	PasswordPolicy PasswordPolicy
+	// PasswordHasher hashes the passwords, the outdated hashes are replaced on login
+	PasswordHasher security.PasswordHasher
+	// Notifier delivers the password reset tokens, the reset isn't available without it
+	Notifier security.Notifier
+	// LoginThrottling limits the failed logins
+	LoginThrottling LoginThrottling
 }
@@ -4,9 +4,20 @@ import (
 	"context"
 )
 
+// ClientInfo describes where a request comes from
+type ClientInfo struct {
+	IP        string
+	UserAgent string
+}
+
 type Context struct {
 	context.Context
-	user User
+	user    User
+	session string
+	client  ClientInfo
+	// scopes limit the caller if restricted is set (see SetScopes)
+	scopes     []string
+	restricted bool
 }
 
 func (ctx *Context) User() User {
@@ -17,6 +28,55 @@ func (ctx *Context) SetUser(user User) {
 	ctx.user = user
 }
 
+// Session returns the id of the session the user is authorized with,
+// it's empty for bots and anonymous users
+func (ctx *Context) Session() string {
+	return ctx.session
+}
+
+func (ctx *Context) SetSession(id string) {
+	ctx.session = id
+}
+
+// SetScopes restricts the caller to the scopes (see security.ScopeChatsRead and others),
+// it's done for API keys, users and bots using their own tokens aren't restricted
+func (ctx *Context) SetScopes(scopes []string) {
+	ctx.scopes = scopes
+	ctx.restricted = true
+}
+
+func (ctx *Context) Scopes() []string {
+	return ctx.scopes
+}
+
+// Restricted reports whether the caller can only do what its scopes allow,
+// restricted callers can't manage the credentials of the user
+func (ctx *Context) Restricted() bool {
+	return ctx.restricted
+}
+
+// Allows reports whether the caller can do what the scope covers,
+// the domain methods check it on their own besides the transport
+func (ctx *Context) Allows(scope string) bool {
+	if !ctx.restricted {
+		return true
+	}
+	for _, s := range ctx.scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+func (ctx *Context) Client() ClientInfo {
+	return ctx.client
+}
+
+func (ctx *Context) SetClient(client ClientInfo) {
+	ctx.client = client
+}
+
 func NewContext(ctx context.Context) *Context {
 	return &Context{
 		Context: ctx,
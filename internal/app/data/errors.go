@@ -0,0 +1,7 @@
+package data
+
+import "errors"
+
+// ErrNotFound is returned by the lookups that tell a missing row apart from a failure,
+// the methods doing it say so
+var ErrNotFound = errors.New("not found")
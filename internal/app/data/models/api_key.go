@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// APIKey is a credential of a user limited to Scopes. A zero ExpiresAt means the key
+// never expires, a zero LastUsedAt means it has never been used.
+type APIKey struct {
+	ID         string
+	UserID     string
+	Name       string
+	TokenHash  string
+	Scopes     []string
+	ExpiresAt  time.Time
+	LastUsedAt time.Time
+	CreatedAt  time.Time
+}
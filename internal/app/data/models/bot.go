@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// Bot holds the credentials of a bot user (UserID) owned by a real user
+type Bot struct {
+	UserID    string
+	OwnerID   string
+	TokenHash string
+	CreatedAt time.Time
+}
+
+type BotCommand struct {
+	BotID       string
+	Command     string
+	Description string
+}
+
+// BotUpdate is an event waiting to be fetched by a bot, Payload is an event.Envelope
+type BotUpdate struct {
+	ID        int64
+	BotID     string
+	EventID   string
+	Name      string
+	Payload   []byte
+	CreatedAt time.Time
+}
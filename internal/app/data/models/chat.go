@@ -1,8 +1,18 @@
 package models
 
+const ChatVisibilityPrivate = 0
+const ChatVisibilityPublic = 1
+const ChatVisibilityPublicWithApproval = 2
+
+const ChatKindGroup = 0
+const ChatKindChannel = 1
+
 type Chat struct {
 	ID          string
 	Name        string
 	Description string
 	OwnerID     string
+	Visibility  int
+	Kind        int
+	CountViews  bool
 }
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+type ChatInvite struct {
+	Code             string
+	ChatID           string
+	CreatorID        string
+	MaxUses          int
+	Uses             int
+	ExpiresAt        time.Time
+	RequiresApproval bool
+	Revoked          bool
+	CreatedAt        time.Time
+}
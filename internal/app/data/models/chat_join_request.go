@@ -0,0 +1,11 @@
+package models
+
+import "time"
+
+type ChatJoinRequest struct {
+	ID         string
+	ChatID     string
+	UserID     string
+	InviteCode string
+	Time       time.Time
+}
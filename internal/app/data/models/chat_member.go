@@ -1,7 +1,10 @@
 package models
 
+import "time"
+
 type ChatMember struct {
-	ChatID string
-	UserID string
-	Status int
+	ChatID   string
+	UserID   string
+	Status   int
+	JoinedAt time.Time
 }
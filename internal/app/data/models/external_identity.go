@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// ExternalIdentity links an account at an identity provider to a user,
+// a user has at most one identity per provider
+type ExternalIdentity struct {
+	Provider string
+	// Subject is the id of the account at the provider
+	Subject   string
+	UserID    string
+	Email     string
+	CreatedAt time.Time
+}
+
+// LoginFlow is a login through an identity provider waiting for the provider to redirect back.
+// It's looked up by the hash of its state and can be completed only once.
+type LoginFlow struct {
+	StateHash    string
+	Provider     string
+	CodeVerifier string
+	Nonce        string
+	// UserID is set if the flow links the identity to an existing user instead of logging in
+	UserID    string
+	ExpiresAt time.Time
+	CreatedAt time.Time
+}
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// IncomingWebhook lets external services post into a chat on behalf of UserID,
+// the integration identity created along with the webhook
+type IncomingWebhook struct {
+	ID        string
+	ChatID    string
+	UserID    string
+	CreatorID string
+	Name      string
+	TokenHash string
+	CreatedAt time.Time
+}
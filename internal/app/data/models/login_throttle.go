@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// LoginThrottle counts the failed logins of a username or an IP address (the Key tells which),
+// a zero LockedUntil means the logins have never been delayed
+type LoginThrottle struct {
+	Key           string
+	Failures      int
+	LockedUntil   time.Time
+	LastFailureAt time.Time
+}
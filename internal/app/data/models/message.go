@@ -3,10 +3,27 @@ package models
 import "time"
 
 type Message struct {
-	Payload    string
-	TimeStamp  time.Time
-	LastUpdate time.Time
-	ChatID     string
-	UserID     string
-	ID         string
+	Payload     string
+	TimeStamp   time.Time
+	LastUpdate  time.Time
+	ChatID      string
+	UserID      string
+	ID          string
+	Views       int
+	Attachments []MessageAttachment
+}
+
+// MessageAttachment is a rich block rendered below the message's text
+type MessageAttachment struct {
+	Title  string                   `json:"title,omitempty"`
+	Text   string                   `json:"text,omitempty"`
+	Link   string                   `json:"link,omitempty"`
+	Color  string                   `json:"color,omitempty"`
+	Fields []MessageAttachmentField `json:"fields,omitempty"`
+}
+
+type MessageAttachmentField struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+	Short bool   `json:"short,omitempty"`
 }
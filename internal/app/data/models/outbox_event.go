@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// OutboxEvent is an event stored in the same transaction as the changes
+// it describes, it's published to the event bus after the commit
+type OutboxEvent struct {
+	ID        string
+	Name      string
+	Data      []byte
+	TimeStamp int64
+	ChatID    string
+	UserIDs   []string
+	// SchemaVersion is the version of the payload's schema, see event.Registry
+	SchemaVersion int
+	CreatedAt     time.Time
+}
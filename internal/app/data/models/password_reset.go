@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// PasswordReset lets a user who has forgotten the password set a new one,
+// a user has at most one and it can be used once
+type PasswordReset struct {
+	ID        string
+	UserID    string
+	TokenHash string
+	ExpiresAt time.Time
+	CreatedAt time.Time
+}
@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// Session is a login of a user on some device. Its refresh token is replaced on every
+// refresh and TokenHash holds the hash of the latest one, the previous ones are invalid.
+type Session struct {
+	ID        string
+	UserID    string
+	TokenHash string
+	// PreviousTokenHashes are the hashes of the latest replaced tokens,
+	// presenting one of them again means the tokens have leaked
+	PreviousTokenHashes []string
+	UserAgent           string
+	IP                  string
+	ExpiresAt           time.Time
+	CreatedAt           time.Time
+	LastSeenAt          time.Time
+}
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// SigningKey is a key of the token key ring. It signs tokens from CreatedAt till RetiresAt
+// and verifies them till ExpiresAt. PrivateKey is PKCS #8 encoded and encrypted by the ring.
+type SigningKey struct {
+	ID         string
+	Algorithm  string
+	PrivateKey []byte
+	CreatedAt  time.Time
+	RetiresAt  time.Time
+	ExpiresAt  time.Time
+}
@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// TwoFactor is the TOTP secret of a user, it's checked on login once Enabled is set
+type TwoFactor struct {
+	UserID string
+	// Secret is the base32-encoded key shared with the authenticator app
+	Secret  string
+	Enabled bool
+	// LastUsedStep is the time step of the last accepted code, the codes of
+	// the steps up to it are rejected so that a code can't be used twice
+	LastUsedStep int64
+	CreatedAt    time.Time
+}
+
+// LoginChallenge is a login waiting for the second factor,
+// the password has been checked already
+type LoginChallenge struct {
+	ID        string
+	UserID    string
+	TokenHash string
+	Attempts  int
+	ExpiresAt time.Time
+	CreatedAt time.Time
+}
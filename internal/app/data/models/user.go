@@ -1,7 +1,16 @@
 package models
 
+const UserKindRegular = 0
+
+// UserKindIntegration marks the identities of incoming webhooks, they can't log in
+const UserKindIntegration = 1
+
+// UserKindBot marks the accounts driven by programs, they authenticate with bot tokens
+const UserKindBot = 2
+
 type User struct {
 	PasswordHash []byte
 	Username     string
 	ID           string
+	Kind         int
 }
@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+const (
+	WebhookDeliveryPending   = 0
+	WebhookDeliveryDelivered = 1
+	// WebhookDeliveryDead marks deliveries that ran out of attempts (the dead-letter list)
+	WebhookDeliveryDead = 2
+)
+
+// Webhook belongs either to a user (ChatID is empty) and receives the events
+// routed to the user, or to a chat and receives the events of the chat.
+// An empty Events list stands for all the events.
+type Webhook struct {
+	ID        string
+	OwnerID   string
+	ChatID    string
+	URL       string
+	Secret    string
+	Events    []string
+	CreatedAt time.Time
+}
+
+type WebhookDelivery struct {
+	ID            string
+	WebhookID     string
+	EventID       string
+	EventName     string
+	Payload       []byte
+	Status        int
+	Attempts      int
+	ResponseCode  int
+	Error         string
+	NextAttemptAt time.Time
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
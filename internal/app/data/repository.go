@@ -3,6 +3,7 @@ package data
 import (
 	"context"
 	"github.com/ischenkx/vk-test-task/internal/app/data/models"
+	"time"
 )
 
 type Tx interface {
@@ -12,6 +13,24 @@ type Tx interface {
 	CreateChatMember(ctx context.Context, member models.ChatMember) (models.ChatMember, error)
 	CreateMessage(ctx context.Context, model models.Message) (models.Message, error)
 	CreateFriendConnection(ctx context.Context, id1, id2 string) error
+	CreateChatInvite(ctx context.Context, invite models.ChatInvite) (models.ChatInvite, error)
+	CreateChatJoinRequest(ctx context.Context, request models.ChatJoinRequest) (models.ChatJoinRequest, error)
+	CreateOutboxEvent(ctx context.Context, e models.OutboxEvent) (models.OutboxEvent, error)
+	CreateWebhook(ctx context.Context, webhook models.Webhook) (models.Webhook, error)
+	CreateWebhookDelivery(ctx context.Context, delivery models.WebhookDelivery) error
+	CreateIncomingWebhook(ctx context.Context, webhook models.IncomingWebhook) (models.IncomingWebhook, error)
+	CreateBot(ctx context.Context, bot models.Bot) (models.Bot, error)
+	CreateBotCommand(ctx context.Context, command models.BotCommand) error
+	CreateBotUpdates(ctx context.Context, update models.BotUpdate, chatId string, userIds []string) error
+	CreateSession(ctx context.Context, session models.Session) (models.Session, error)
+	CreateSigningKey(ctx context.Context, key models.SigningKey) error
+	CreateAPIKey(ctx context.Context, key models.APIKey) (models.APIKey, error)
+	CreateExternalIdentity(ctx context.Context, identity models.ExternalIdentity) (models.ExternalIdentity, error)
+	CreateLoginFlow(ctx context.Context, flow models.LoginFlow) error
+	CreateTwoFactor(ctx context.Context, twoFactor models.TwoFactor) (models.TwoFactor, error)
+	CreateRecoveryCodes(ctx context.Context, userId string, codeHashes []string) error
+	CreateLoginChallenge(ctx context.Context, challenge models.LoginChallenge) (models.LoginChallenge, error)
+	CreatePasswordReset(ctx context.Context, reset models.PasswordReset) (models.PasswordReset, error)
 
 	DeleteUser(ctx context.Context, id string) error
 	DeleteFriendConnection(ctx context.Context, id1, id2 string) error
@@ -19,11 +38,56 @@ type Tx interface {
 	DeleteChat(ctx context.Context, id string) error
 	DeleteChatMember(ctx context.Context, userId, chatId string) error
 	DeleteMessage(ctx context.Context, id string) error
+	DeleteChatJoinRequest(ctx context.Context, id string) error
+	DeleteOutboxEvents(ctx context.Context, ids []string) error
+	DeleteWebhook(ctx context.Context, id string) error
+	DeleteIncomingWebhook(ctx context.Context, id string) error
+	DeleteUserBots(ctx context.Context, ownerId string) error
+	DeleteBotCommands(ctx context.Context, botId string) error
+	DeleteBotUpdates(ctx context.Context, botId string, offset int64, retention time.Duration) error
+	DeleteSession(ctx context.Context, id string) error
+	DeleteUserSessions(ctx context.Context, userId string) ([]string, error)
+	DeleteOtherUserSessions(ctx context.Context, userId string, keepId string) ([]string, error)
+	DeleteExpiredSigningKeys(ctx context.Context) error
+	DeleteAPIKey(ctx context.Context, id string) error
+	DeleteExternalIdentity(ctx context.Context, userId string, provider string) error
+	DeleteTwoFactor(ctx context.Context, userId string) error
+	DeleteRecoveryCodes(ctx context.Context, userId string) error
+	DeleteLoginChallenge(ctx context.Context, id string) error
+	DeletePasswordReset(ctx context.Context, userId string) error
+	DeleteLoginThrottle(ctx context.Context, key string) error
 
 	UpdateUser(ctx context.Context, user models.User) error
+	// UpdateUserPasswordHash replaces the password hash of the user if it's still oldHash,
+	// nothing is changed if the hash has been changed meanwhile
+	UpdateUserPasswordHash(ctx context.Context, id string, oldHash, newHash []byte) error
+	// SetUserPasswordHash replaces the password hash of the user leaving the other fields as they are
+	SetUserPasswordHash(ctx context.Context, id string, hash []byte) error
 	UpdateChat(ctx context.Context, user models.Chat) (models.Chat, error)
 	UpdateChatMember(ctx context.Context, model models.ChatMember) (models.ChatMember, error)
 	UpdateMessage(ctx context.Context, model models.Message) error
+	MarkMessagesViewed(ctx context.Context, userId string, messageIds []string) error
+	// UseChatInvite counts a use of the invite, it fails with ErrNotFound if the invite can't be used
+	UseChatInvite(ctx context.Context, code string) (models.ChatInvite, error)
+	RevokeChatInvite(ctx context.Context, code string) error
+	UpdateWebhookDelivery(ctx context.Context, delivery models.WebhookDelivery) error
+	ClaimWebhookDeliveries(ctx context.Context, count int, lease time.Duration) ([]models.WebhookDelivery, error)
+	// ClaimOutboxEvents leases the oldest pending events to the caller for the given duration,
+	// they are deleted once sent or released to be claimed again
+	ClaimOutboxEvents(ctx context.Context, count int, lease time.Duration) ([]models.OutboxEvent, error)
+	ReleaseOutboxEvents(ctx context.Context, ids []string) error
+	UpdateBotToken(ctx context.Context, userId string, tokenHash string) error
+	RotateSession(ctx context.Context, id string, tokenHash string, newTokenHash string, ip string, expiresAt time.Time) (models.Session, error)
+	TouchSession(ctx context.Context, id string) (models.Session, error)
+	TouchAPIKey(ctx context.Context, id string) error
+	TakeLoginFlow(ctx context.Context, stateHash string) (models.LoginFlow, error)
+	EnableTwoFactor(ctx context.Context, userId string) error
+	UseTwoFactorStep(ctx context.Context, userId string, step int64) (models.TwoFactor, error)
+	UseRecoveryCode(ctx context.Context, userId string, codeHash string) error
+	AttemptLoginChallenge(ctx context.Context, id string, tokenHash string) (models.LoginChallenge, error)
+	TakePasswordReset(ctx context.Context, id string, tokenHash string) (models.PasswordReset, error)
+	RecordLoginFailure(ctx context.Context, key string, window time.Duration) (models.LoginThrottle, error)
+	LockLogin(ctx context.Context, key string, until time.Time) error
 
 	GetUserChats(ctx context.Context, userId string, offset int, count int) ([]models.ChatMember, error)
 	GetChatMembers(ctx context.Context, chatId string, offset int, count int) ([]models.ChatMember, error)
@@ -37,8 +101,39 @@ type Tx interface {
 	GetFriendRequest(ctx context.Context, from, to string) (models.FriendRequest, error)
 	GetFriendRequestByID(ctx context.Context, id string) (models.FriendRequest, error)
 	GetChat(ctx context.Context, id string) (models.Chat, error)
+	SearchPublicChats(ctx context.Context, query string, offset int, count int) ([]models.Chat, error)
 	GetChatMember(ctx context.Context, userId, chatId string) (models.ChatMember, error)
+	GetChatSuccessor(ctx context.Context, chatId, userId string) (models.ChatMember, error)
+	GetUserOwnedChats(ctx context.Context, userId string) ([]models.Chat, error)
 	GetMessage(ctx context.Context, id string) (models.Message, error)
+	GetChatInvite(ctx context.Context, code string) (models.ChatInvite, error)
+	GetChatInvites(ctx context.Context, chatId string, offset int, count int) ([]models.ChatInvite, error)
+	GetChatJoinRequest(ctx context.Context, id string) (models.ChatJoinRequest, error)
+	GetChatJoinRequests(ctx context.Context, chatId string, offset int, count int) ([]models.ChatJoinRequest, error)
+	// GetUserChatJoinRequest returns the pending request of the user to join the chat or ErrNotFound
+	GetUserChatJoinRequest(ctx context.Context, chatId, userId string) (models.ChatJoinRequest, error)
+	GetWebhook(ctx context.Context, id string) (models.Webhook, error)
+	GetUserWebhooks(ctx context.Context, userId string, offset int, count int) ([]models.Webhook, error)
+	GetChatWebhooks(ctx context.Context, chatId string, offset int, count int) ([]models.Webhook, error)
+	GetMatchingWebhooks(ctx context.Context, name string, chatId string, userIds []string) ([]models.Webhook, error)
+	GetWebhookDelivery(ctx context.Context, id string) (models.WebhookDelivery, error)
+	GetWebhookDeliveries(ctx context.Context, webhookId string, status int, offset int, count int) ([]models.WebhookDelivery, error)
+	GetIncomingWebhook(ctx context.Context, id string) (models.IncomingWebhook, error)
+	GetChatIncomingWebhooks(ctx context.Context, chatId string, offset int, count int) ([]models.IncomingWebhook, error)
+	GetBot(ctx context.Context, userId string) (models.Bot, error)
+	GetUserBots(ctx context.Context, ownerId string, offset int, count int) ([]models.Bot, error)
+	GetBotCommands(ctx context.Context, botId string) ([]models.BotCommand, error)
+	GetChatBotCommands(ctx context.Context, chatId string, command string) ([]models.BotCommand, error)
+	GetBotUpdates(ctx context.Context, botId string, offset int64, count int) ([]models.BotUpdate, error)
+	GetSession(ctx context.Context, id string) (models.Session, error)
+	GetUserSessions(ctx context.Context, userId string, offset int, count int) ([]models.Session, error)
+	GetSigningKeys(ctx context.Context) ([]models.SigningKey, error)
+	GetAPIKey(ctx context.Context, id string) (models.APIKey, error)
+	GetUserAPIKeys(ctx context.Context, userId string, offset int, count int) ([]models.APIKey, error)
+	GetExternalIdentity(ctx context.Context, provider string, subject string) (models.ExternalIdentity, error)
+	GetUserExternalIdentities(ctx context.Context, userId string) ([]models.ExternalIdentity, error)
+	GetTwoFactor(ctx context.Context, userId string) (models.TwoFactor, error)
+	GetLoginThrottle(ctx context.Context, key string) (models.LoginThrottle, error)
 	FriendConnectionExists(ctx context.Context, id1, id2 string) bool
 
 	CountFriends(ctx context.Context, id string) (int, error)
@@ -47,6 +142,7 @@ type Tx interface {
 	CountChatMembers(ctx context.Context, chatId string) (int, error)
 	CountChatMessages(ctx context.Context, chatId string) (int, error)
 	CountUserChats(ctx context.Context, id string) (int, error)
+	CountRecoveryCodes(ctx context.Context, userId string) (int, error)
 }
 
 type Repository interface {
@@ -7,3 +7,5 @@ var DoesNotExist = errors.New("does not exist")
 var NotAuthorized = errors.New("not authorized")
 var AlreadyAuthorized = errors.New("already authorized")
 var RightsViolation = errors.New("not enough rights")
+var RateLimited = errors.New("rate limit exceeded")
+var InsufficientScope = errors.New("insufficient scope")
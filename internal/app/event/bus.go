@@ -5,9 +5,10 @@ import "context"
 type ChannelHandle interface {
 	Chan(ctx context.Context) (<-chan Event, error)
 	Close(ctx context.Context) error
+	Stats() ChannelStats
 }
 
 type Bus interface {
-	Channel(ctx context.Context) (ChannelHandle, error)
+	Channel(ctx context.Context, options ...ChannelOption) (ChannelHandle, error)
 	Send(ctx context.Context, event Event) error
 }
@@ -0,0 +1,95 @@
+package event
+
+import "time"
+
+// OverflowPolicy tells a bus what to do when a subscriber's buffer is full
+type OverflowPolicy int
+
+const (
+	// DropNewest discards the event that doesn't fit into the buffer
+	DropNewest OverflowPolicy = iota
+	// DropOldest discards the oldest buffered event to make room for the new one
+	DropOldest
+	// Block waits for the subscriber until the block timeout expires
+	// and drops the event afterwards
+	Block
+	// Disconnect closes the channel of a subscriber that can't keep up
+	Disconnect
+)
+
+const (
+	DefaultBufferSize   = 256
+	DefaultBlockTimeout = time.Second
+)
+
+type ChannelOptions struct {
+	BufferSize   int
+	Overflow     OverflowPolicy
+	BlockTimeout time.Duration
+	Filter       Filter
+}
+
+type ChannelOption func(*ChannelOptions)
+
+func WithBufferSize(size int) ChannelOption {
+	return func(options *ChannelOptions) {
+		options.BufferSize = size
+	}
+}
+
+func WithOverflowPolicy(policy OverflowPolicy) ChannelOption {
+	return func(options *ChannelOptions) {
+		options.Overflow = policy
+	}
+}
+
+// WithBlockTimeout sets the Block policy with the given timeout
+func WithBlockTimeout(timeout time.Duration) ChannelOption {
+	return func(options *ChannelOptions) {
+		options.Overflow = Block
+		options.BlockTimeout = timeout
+	}
+}
+
+// WithNames subscribes to the events with the given names only
+func WithNames(names ...string) ChannelOption {
+	return func(options *ChannelOptions) {
+		options.Filter.Names = append(options.Filter.Names, names...)
+	}
+}
+
+// WithChats subscribes to the events routed to the given chats
+func WithChats(ids ...string) ChannelOption {
+	return func(options *ChannelOptions) {
+		options.Filter.ChatIDs = append(options.Filter.ChatIDs, ids...)
+	}
+}
+
+// WithUsers subscribes to the events routed to the given users
+func WithUsers(ids ...string) ChannelOption {
+	return func(options *ChannelOptions) {
+		options.Filter.UserIDs = append(options.Filter.UserIDs, ids...)
+	}
+}
+
+func NewChannelOptions(options ...ChannelOption) ChannelOptions {
+	res := ChannelOptions{
+		BufferSize:   DefaultBufferSize,
+		Overflow:     DropNewest,
+		BlockTimeout: DefaultBlockTimeout,
+	}
+	for _, option := range options {
+		option(&res)
+	}
+	if res.BufferSize < 0 {
+		res.BufferSize = 0
+	}
+	return res
+}
+
+// ChannelStats are the delivery metrics of a single subscriber
+type ChannelStats struct {
+	Delivered    uint64
+	Dropped      uint64
+	Disconnected bool
+}
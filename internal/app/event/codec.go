@@ -0,0 +1,47 @@
+package event
+
+import "encoding/json"
+
+// Codec serializes events to be passed between processes.
+//
+// The default one is JSONCodec, a binary codec (e.g. protobuf) can
+// implement the same interface using the registry for the payload types.
+type Codec interface {
+	Encode(e Event) ([]byte, error)
+	Decode(data []byte) (Event, error)
+}
+
+// JSONCodec serializes events as Envelopes
+type JSONCodec struct {
+	Registry *Registry
+}
+
+func (c JSONCodec) Encode(e Event) ([]byte, error) {
+	env, err := c.Envelope(e)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(env)
+}
+
+func (c JSONCodec) Decode(data []byte) (Event, error) {
+	var env Envelope
+	if err := json.Unmarshal(data, &env); err != nil {
+		return Event{}, err
+	}
+	return env.Event(c.Registry)
+}
+
+// Envelope wraps the event stamping it with the current schema version of its payload
+func (c JSONCodec) Envelope(e Event) (Envelope, error) {
+	env, err := NewEnvelope(e)
+	if err != nil {
+		return Envelope{}, err
+	}
+	env.SchemaVersion = c.Registry.Version(e.Name)
+	return env, nil
+}
+
+func NewJSONCodec(registry *Registry) JSONCodec {
+	return JSONCodec{Registry: registry}
+}
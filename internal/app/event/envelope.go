@@ -0,0 +1,61 @@
+package event
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// EnvelopeVersion is increased on every incompatible change of the Envelope
+const EnvelopeVersion = 1
+
+// Envelope is the stable JSON representation of an event
+// used to pass it between processes.
+//
+// Version is the version of the envelope itself, SchemaVersion is
+// the version of the payload's schema (zero if it's unknown).
+type Envelope struct {
+	Version       int             `json:"version"`
+	ID            string          `json:"id,omitempty"`
+	Name          string          `json:"name"`
+	SchemaVersion int             `json:"schema_version,omitempty"`
+	Data          json.RawMessage `json:"data,omitempty"`
+	TimeStamp     int64           `json:"time_stamp"`
+	ChatID        string          `json:"chat_id,omitempty"`
+	UserIDs       []string        `json:"user_ids,omitempty"`
+}
+
+func NewEnvelope(e Event) (Envelope, error) {
+	data, err := json.Marshal(e.Data)
+	if err != nil {
+		return Envelope{}, err
+	}
+	return Envelope{
+		Version:   EnvelopeVersion,
+		ID:        e.ID,
+		Name:      e.Name,
+		Data:      data,
+		TimeStamp: e.TimeStamp,
+		ChatID:    e.ChatID,
+		UserIDs:   e.UserIDs,
+	}, nil
+}
+
+// Event restores the event, its payload is decoded with the registry
+func (env Envelope) Event(registry *Registry) (Event, error) {
+	if env.Version != EnvelopeVersion {
+		return Event{}, fmt.Errorf("unsupported envelope version: %d", env.Version)
+	}
+
+	data, err := registry.DecodeVersion(env.Name, env.SchemaVersion, env.Data)
+	if err != nil {
+		return Event{}, err
+	}
+	return Event{
+		ID:        env.ID,
+		Name:      env.Name,
+		Data:      data,
+		TimeStamp: env.TimeStamp,
+		ChatID:    env.ChatID,
+		UserIDs:   env.UserIDs,
+	}, nil
+}
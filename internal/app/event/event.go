@@ -1,9 +1,17 @@
 package event
 
 type Event struct {
+	// ID identifies the event across redeliveries, so consumers can deduplicate it.
+	// It's empty for events that aren't sent through an outbox
+	ID        string
 	Name      string
 	Data      interface{}
 	TimeStamp int64
+	// Offset is the position of the event in a Log, it's zero for non-durable buses
+	Offset int64
+	// ChatID and UserIDs route the event to the subscribers of the chat and the users
+	ChatID  string
+	UserIDs []string
 }
 
 func New(name string, data interface{}, options ...Option) Event {
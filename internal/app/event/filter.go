@@ -0,0 +1,47 @@
+package event
+
+// Filter selects the events a subscriber is interested in.
+//
+// An empty list matches everything. ChatIDs and UserIDs are alternatives:
+// an event passes if it's routed to one of the chats or to one of the users.
+type Filter struct {
+	Names   []string
+	ChatIDs []string
+	UserIDs []string
+}
+
+// Routed reports whether the filter restricts chats or users
+func (f Filter) Routed() bool {
+	return len(f.ChatIDs) > 0 || len(f.UserIDs) > 0
+}
+
+func (f Filter) Match(e Event) bool {
+	if len(f.Names) > 0 && !contains(f.Names, e.Name) {
+		return false
+	}
+
+	if !f.Routed() {
+		return true
+	}
+
+	if e.ChatID != "" && contains(f.ChatIDs, e.ChatID) {
+		return true
+	}
+
+	for _, id := range e.UserIDs {
+		if contains(f.UserIDs, id) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,20 @@
+package event
+
+import "context"
+
+// ConsumerHandle is a channel of a named consumer that remembers
+// the position it has reached in the log
+type ConsumerHandle interface {
+	ChannelHandle
+	Commit(ctx context.Context, offset int64) error
+}
+
+// Log is a durable bus: sent events are persisted, so they can be
+// replayed and consumers can continue from their committed positions.
+//
+// Events received from a log have the Offset field set.
+type Log interface {
+	Bus
+	Replay(ctx context.Context, from int64, options ...ChannelOption) (ChannelHandle, error)
+	Consumer(ctx context.Context, name string, options ...ChannelOption) (ConsumerHandle, error)
+}
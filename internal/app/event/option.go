@@ -9,3 +9,17 @@ func WithTime(time time.Time) Option {
 		event.TimeStamp = time.UnixNano()
 	}
 }
+
+// ToChat routes the event to the subscribers of the chat
+func ToChat(id string) Option {
+	return func(event *Event) {
+		event.ChatID = id
+	}
+}
+
+// ToUsers routes the event to the subscribers of the users
+func ToUsers(ids ...string) Option {
+	return func(event *Event) {
+		event.UserIDs = append(event.UserIDs, ids...)
+	}
+}
@@ -0,0 +1,135 @@
+package event
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// Upgrade converts a serialized payload from one schema version to the next one
+type Upgrade func(data json.RawMessage) (json.RawMessage, error)
+
+type schema struct {
+	payload  reflect.Type
+	version  int
+	upgrades map[int]Upgrade
+}
+
+// Registry maps event names to the types of their payloads,
+// so events can be restored after being serialized.
+//
+// Payload schemas evolve according to the following rules:
+//   - adding a field keeps the version: old payloads decode with the zero value,
+//     old decoders ignore the new field;
+//   - renaming, removing or changing the type of a field bumps the version,
+//     and an Upgrade from the previous version must be registered;
+//   - event names are never reused for a different payload.
+type Registry struct {
+	schemas map[string]*schema
+	mu      sync.RWMutex
+}
+
+// Register binds the name to the type of the prototype at version 1, e.g.
+//
+//	registry.Register(NewMessageEventName, NewMessageEvent{})
+func (r *Registry) Register(name string, prototype interface{}) {
+	r.RegisterVersion(name, 1, prototype)
+}
+
+// RegisterVersion binds the name to the type of the prototype
+// which is the given version of the payload's schema
+func (r *Registry) RegisterVersion(name string, version int, prototype interface{}) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	s, ok := r.schemas[name]
+	if !ok {
+		s = &schema{upgrades: map[int]Upgrade{}}
+		r.schemas[name] = s
+	}
+	s.payload = reflect.TypeOf(prototype)
+	s.version = version
+}
+
+// RegisterUpgrade sets the conversion of the named payload from the given version to the next one
+func (r *Registry) RegisterUpgrade(name string, from int, upgrade Upgrade) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	s, ok := r.schemas[name]
+	if !ok {
+		s = &schema{upgrades: map[int]Upgrade{}}
+		r.schemas[name] = s
+	}
+	s.upgrades[from] = upgrade
+}
+
+func (r *Registry) Type(name string) (reflect.Type, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	s, ok := r.schemas[name]
+	if !ok || s.payload == nil {
+		return nil, false
+	}
+	return s.payload, true
+}
+
+// Version returns the current schema version of the named payload, zero if it's unknown
+func (r *Registry) Version(name string) int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if s, ok := r.schemas[name]; ok {
+		return s.version
+	}
+	return 0
+}
+
+// Decode restores the payload of the named event serialized with the current schema.
+//
+// Payloads of unknown events are returned as json.RawMessage.
+func (r *Registry) Decode(name string, data []byte) (interface{}, error) {
+	return r.DecodeVersion(name, 0, data)
+}
+
+// DecodeVersion restores the payload serialized with the given schema version
+// upgrading it to the current one first. Zero stands for the current version.
+func (r *Registry) DecodeVersion(name string, version int, data []byte) (interface{}, error) {
+	r.mu.RLock()
+	s, ok := r.schemas[name]
+	r.mu.RUnlock()
+
+	if !ok || s.payload == nil {
+		return json.RawMessage(data), nil
+	}
+
+	if version > s.version {
+		// written by a newer release: the payload is decoded as is,
+		// which works as long as the rules above are followed
+		version = s.version
+	}
+
+	for v := version; v > 0 && v < s.version; v++ {
+		upgrade, ok := s.upgrades[v]
+		if !ok {
+			return nil, fmt.Errorf("no upgrade for '%s' from version %d", name, v)
+		}
+		upgraded, err := upgrade(data)
+		if err != nil {
+			return nil, err
+		}
+		data = upgraded
+	}
+
+	value := reflect.New(s.payload)
+	if err := json.Unmarshal(data, value.Interface()); err != nil {
+		return nil, err
+	}
+	return value.Elem().Interface(), nil
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		schemas: map[string]*schema{},
+	}
+}
@@ -0,0 +1,85 @@
+package event
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// Typed is an event with a payload of a known type
+type Typed[T any] struct {
+	Event
+	Payload T
+}
+
+// PayloadOf extracts the payload of type T from the event.
+//
+// Besides T itself, it accepts *T and serialized payloads (json.RawMessage or []byte),
+// which is what buses without a registry deliver.
+func PayloadOf[T any](e Event) (T, error) {
+	var res T
+
+	switch data := e.Data.(type) {
+	case T:
+		return data, nil
+	case *T:
+		if data == nil {
+			return res, fmt.Errorf("nil payload of '%s'", e.Name)
+		}
+		return *data, nil
+	case json.RawMessage:
+		err := json.Unmarshal(data, &res)
+		return res, err
+	case []byte:
+		err := json.Unmarshal(data, &res)
+		return res, err
+	default:
+		return res, fmt.Errorf("unexpected payload of '%s': %T", e.Name, e.Data)
+	}
+}
+
+// Subscribe opens a channel of the events with the given name and payloads of type T, e.g.
+//
+//	messages, handle, err := event.Subscribe[app.NewMessageEvent](ctx, bus, app.NewMessageEventName)
+//
+// Events whose payloads can't be converted to T are skipped.
+// The returned channel is closed when the context is done or the subscription is over,
+// the handle must still be closed by the caller.
+func Subscribe[T any](ctx context.Context, bus Bus, name string, options ...ChannelOption) (<-chan Typed[T], ChannelHandle, error) {
+	handle, err := bus.Channel(ctx, append(options, WithNames(name))...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	events, err := handle.Chan(ctx)
+	if err != nil {
+		handle.Close(ctx)
+		return nil, nil, err
+	}
+
+	res := make(chan Typed[T])
+	go func() {
+		defer close(res)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case e, ok := <-events:
+				if !ok {
+					return
+				}
+				payload, err := PayloadOf[T](e)
+				if err != nil {
+					continue
+				}
+				select {
+				case res <- Typed[T]{Event: e, Payload: payload}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return res, handle, nil
+}
@@ -1,68 +1,178 @@
 package app
 
+import (
+	"github.com/ischenkx/vk-test-task/internal/app/data/models"
+	"github.com/ischenkx/vk-test-task/internal/app/event"
+	"time"
+)
+
 const NewMessageEventName = "new_message"
+const NewChannelPostEventName = "new_channel_post"
 const MessageDeletedEventName = "message_deleted"
 const MessageUpdatedEventName = "message_updated"
 const ChatDeletedEventName = "chat_deleted"
+const ChatUpdatedEventName = "chat_updated"
+const ChatOwnerChangedEventName = "chat_owner_changed"
 const ChatMemberCreatedEventName = "chat_member_created"
 const ChatMemberDeletedEventName = "chat_member_deleted"
 const NewFriendRequestEventName = "friend_request"
 const FriendRequestUpdateEventName = "friend_request_update"
 const FriendAddedEventName = "friend_added"
 const FriendDeletedEventName = "friend_deleted"
+const NewChatJoinRequestEventName = "chat_join_request"
+const ChatJoinRequestUpdateEventName = "chat_join_request_update"
+const BotCommandEventName = "bot_command"
+const LoginFailedEventName = "login_failed"
+const LoginLockedEventName = "login_locked"
 
 const FriendRequestUpdateAccepted = 1
 const FriendRequestUpdateDeclined = 2
 const FriendRequestUpdateDeleted = 3
 
+const ChatJoinRequestUpdateApproved = 1
+const ChatJoinRequestUpdateRejected = 2
+
 type NewMessageEvent struct {
-	MessageID string
-	ChatID    string
+	MessageID string `json:"message_id"`
+	ChatID    string `json:"chat_id"`
+}
+
+// NewChannelPostEvent is sent instead of NewMessageEvent for channels.
+//
+// It carries the whole post, so delivering it to thousands of subscribers
+// doesn't require loading the message for every one of them.
+type NewChannelPostEvent struct {
+	MessageID   string                     `json:"message_id"`
+	ChatID      string                     `json:"chat_id"`
+	SenderID    string                     `json:"sender_id"`
+	Payload     string                     `json:"payload"`
+	Attachments []models.MessageAttachment `json:"attachments,omitempty"`
+	TimeStamp   time.Time                  `json:"time_stamp"`
 }
 
 type MessageDeletedEvent struct {
-	MessageID string
-	ChatID    string
+	MessageID string `json:"message_id"`
+	ChatID    string `json:"chat_id"`
 }
 
 type MessageUpdatedEvent struct {
-	MessageID string
-	ChatID    string
+	MessageID string `json:"message_id"`
+	ChatID    string `json:"chat_id"`
 }
 
 type NewFriendRequestEvent struct {
-	FromID string
-	ToID   string
-	ID     string
+	FromID string `json:"from_id"`
+	ToID   string `json:"to_id"`
+	ID     string `json:"id"`
 }
 
 type ChatDeletedEvent struct {
-	ChatID string
+	ChatID string `json:"chat_id"`
+}
+
+type ChatUpdatedEvent struct {
+	ChatID string `json:"chat_id"`
+}
+
+type ChatOwnerChangedEvent struct {
+	ChatID          string `json:"chat_id"`
+	PreviousOwnerID string `json:"previous_owner_id"`
+	OwnerID         string `json:"owner_id"`
 }
 
 type ChatMemberDeletedEvent struct {
-	ChatID string
-	UserID string
+	ChatID string `json:"chat_id"`
+	UserID string `json:"user_id"`
 }
 
 type ChatMemberCreatedEvent struct {
-	ChatID string
-	UserID string
+	ChatID string `json:"chat_id"`
+	UserID string `json:"user_id"`
 }
 
 type FriendRequestUpdateEvent struct {
-	FriendRequestID string
-	From            string
-	To              string
-	Code            int
+	FriendRequestID string `json:"friend_request_id"`
+	From            string `json:"from"`
+	To              string `json:"to"`
+	Code            int    `json:"code"`
 }
 
 type FriendAddedEvent struct {
-	FriendID string
-	UserID   string
+	FriendID string `json:"friend_id"`
+	UserID   string `json:"user_id"`
 }
 
 type FriendDeletedEvent struct {
-	FriendID string
-	UserID   string
+	FriendID string `json:"friend_id"`
+	UserID   string `json:"user_id"`
+}
+
+type NewChatJoinRequestEvent struct {
+	ID     string `json:"id"`
+	ChatID string `json:"chat_id"`
+	UserID string `json:"user_id"`
+}
+
+type ChatJoinRequestUpdateEvent struct {
+	JoinRequestID string `json:"join_request_id"`
+	ChatID        string `json:"chat_id"`
+	UserID        string `json:"user_id"`
+	Code          int    `json:"code"`
+}
+
+// BotCommandEvent is sent to a bot when a member of its chat uses one of its slash commands
+type BotCommandEvent struct {
+	BotID     string `json:"bot_id"`
+	MessageID string `json:"message_id"`
+	ChatID    string `json:"chat_id"`
+	SenderID  string `json:"sender_id"`
+	Command   string `json:"command"`
+	Args      string `json:"args"`
+}
+
+// LoginFailedEvent is a security event sent to the user whose password has been tried.
+//
+// It's published for the usernames nobody has as well (without a UserID and recipients),
+// so that the consumers of the bus can watch for guessing.
+type LoginFailedEvent struct {
+	UserID    string `json:"user_id,omitempty"`
+	Username  string `json:"username"`
+	IP        string `json:"ip"`
+	UserAgent string `json:"user_agent"`
+	// Failures is the number of the recent failures of the username
+	Failures int `json:"failures"`
+}
+
+// LoginLockedEvent is sent when a username or an IP address (see Target) is locked out
+type LoginLockedEvent struct {
+	UserID   string `json:"user_id,omitempty"`
+	Username string `json:"username"`
+	IP       string `json:"ip"`
+	// Target is LoginThrottleUser or LoginThrottleIP
+	Target      string    `json:"target"`
+	LockedUntil time.Time `json:"locked_until"`
+}
+
+// RegisterEvents binds the names of the application's events to their payloads.
+//
+// Changes of the payloads must follow the evolution rules of event.Registry.
+func RegisterEvents(registry *event.Registry) {
+	registry.Register(NewMessageEventName, NewMessageEvent{})
+	registry.Register(NewChannelPostEventName, NewChannelPostEvent{})
+	registry.Register(MessageDeletedEventName, MessageDeletedEvent{})
+	registry.Register(MessageUpdatedEventName, MessageUpdatedEvent{})
+	registry.Register(ChatDeletedEventName, ChatDeletedEvent{})
+	registry.Register(ChatUpdatedEventName, ChatUpdatedEvent{})
+	registry.Register(ChatOwnerChangedEventName, ChatOwnerChangedEvent{})
+	registry.Register(ChatMemberCreatedEventName, ChatMemberCreatedEvent{})
+	registry.Register(ChatMemberDeletedEventName, ChatMemberDeletedEvent{})
+	registry.Register(NewFriendRequestEventName, NewFriendRequestEvent{})
+	registry.Register(FriendRequestUpdateEventName, FriendRequestUpdateEvent{})
+	registry.Register(FriendAddedEventName, FriendAddedEvent{})
+	registry.Register(FriendDeletedEventName, FriendDeletedEvent{})
+	registry.Register(NewChatJoinRequestEventName, NewChatJoinRequestEvent{})
+	registry.Register(ChatJoinRequestUpdateEventName, ChatJoinRequestUpdateEvent{})
+	registry.Register(BotCommandEventName, BotCommandEvent{})
+	registry.Register(LoginFailedEventName, LoginFailedEvent{})
+	registry.Register(LoginLockedEventName, LoginLockedEvent{})
 }
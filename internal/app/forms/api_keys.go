@@ -0,0 +1,36 @@
+package forms
+
+import (
+	"errors"
+	"github.com/ischenkx/vk-test-task/internal/app/security"
+	"time"
+)
+
+type APIKeyCreation struct {
+	Name   string
+	Scopes []string
+	// ExpiresAt is zero for the keys that never expire
+	ExpiresAt time.Time
+}
+
+func (form APIKeyCreation) Validate() error {
+	if len(form.Name) < 1 || len(form.Name) > 40 {
+		return errors.New("invalid name length")
+	}
+
+	if len(form.Scopes) == 0 {
+		return errors.New("no scopes")
+	}
+
+	for _, scope := range form.Scopes {
+		if !security.IsScope(scope) {
+			return errors.New("unknown scope: " + scope)
+		}
+	}
+
+	if !form.ExpiresAt.IsZero() && form.ExpiresAt.Before(time.Now()) {
+		return errors.New("invalid expiration time")
+	}
+
+	return nil
+}
@@ -0,0 +1,58 @@
+package forms
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+)
+
+const maxBotCommands = 100
+
+var botCommandPattern = regexp.MustCompile(`^[a-z0-9_]{1,32}$`)
+
+type BotCreation struct {
+	// Username must end with "bot", so bots are told apart from people
+	Username string
+}
+
+type BotCommand struct {
+	// Command is the name without the leading slash
+	Command     string
+	Description string
+}
+
+type BotCommandsUpdate struct {
+	Commands []BotCommand
+}
+
+func (form BotCreation) Validate() error {
+	if len(form.Username) < 5 || len(form.Username) > 20 {
+		return errors.New("invalid username length")
+	}
+	if !strings.HasSuffix(strings.ToLower(form.Username), "bot") {
+		return errors.New("bot usernames must end with 'bot'")
+	}
+	return nil
+}
+
+func (form BotCommandsUpdate) Validate() error {
+	if len(form.Commands) > maxBotCommands {
+		return errors.New("too many commands")
+	}
+
+	seen := map[string]bool{}
+	for _, command := range form.Commands {
+		if !botCommandPattern.MatchString(command.Command) {
+			return errors.New("invalid command: " + command.Command)
+		}
+		if len(command.Description) > 256 {
+			return errors.New("the command description is too long")
+		}
+		if seen[command.Command] {
+			return errors.New("duplicate command: " + command.Command)
+		}
+		seen[command.Command] = true
+	}
+
+	return nil
+}
@@ -1,10 +1,36 @@
 package forms
 
-import "errors"
+import (
+	"errors"
+	"github.com/ischenkx/vk-test-task/internal/app/data/models"
+	"time"
+)
 
 type ChatCreationForm struct {
 	Name        string
 	Description string
+	Visibility  int
+	Kind        int
+	// CountViews enables per-post view counters, only channels support it
+	CountViews bool
+}
+
+type ChatUpdate struct {
+	Name        string
+	Description string
+}
+
+type ChatVisibilityUpdate struct {
+	Visibility int
+}
+
+func validateChatVisibility(visibility int) error {
+	switch visibility {
+	case models.ChatVisibilityPrivate, models.ChatVisibilityPublic, models.ChatVisibilityPublicWithApproval:
+		return nil
+	default:
+		return errors.New("invalid visibility")
+	}
 }
 
 func (form *ChatCreationForm) Validate() error {
@@ -16,5 +42,49 @@ func (form *ChatCreationForm) Validate() error {
 		return errors.New("invalid description length")
 	}
 
+	switch form.Kind {
+	case models.ChatKindGroup:
+		if form.CountViews {
+			return errors.New("view counters are only available in channels")
+		}
+	case models.ChatKindChannel:
+	default:
+		return errors.New("invalid chat kind")
+	}
+
+	return validateChatVisibility(form.Visibility)
+}
+
+func (form *ChatUpdate) Validate() error {
+	if len(form.Name) < 5 || len(form.Name) > 230 {
+		return errors.New("invalid name length")
+	}
+
+	if len(form.Description) > 400 {
+		return errors.New("invalid description length")
+	}
+
+	return nil
+}
+
+func (form *ChatVisibilityUpdate) Validate() error {
+	return validateChatVisibility(form.Visibility)
+}
+
+type ChatInviteCreation struct {
+	MaxUses          int
+	ExpiresAt        time.Time
+	RequiresApproval bool
+}
+
+func (form *ChatInviteCreation) Validate() error {
+	if form.MaxUses < 0 {
+		return errors.New("invalid max uses")
+	}
+
+	if !form.ExpiresAt.IsZero() && form.ExpiresAt.Before(time.Now()) {
+		return errors.New("invalid expiration time")
+	}
+
 	return nil
 }
@@ -1,13 +1,20 @@
 package forms
 
-import "errors"
+import (
+	"errors"
+	"github.com/ischenkx/vk-test-task/internal/app/data/models"
+)
+
+const maxMessageAttachments = 10
+const maxAttachmentFields = 20
 
 type MessageUpdate struct {
 	Payload string
 }
 
 type SendMessage struct {
-	Payload string
+	Payload     string
+	Attachments []models.MessageAttachment
 }
 
 func (form *MessageUpdate) Validate() error {
@@ -18,8 +25,34 @@ func (form *MessageUpdate) Validate() error {
 }
 
 func (form *SendMessage) Validate() error {
-	if len(form.Payload) == 0 {
+	if len(form.Payload) == 0 && len(form.Attachments) == 0 {
 		return errors.New("empty messages are not valid")
 	}
+
+	if len(form.Payload) > 400 {
+		return errors.New("the message is too long")
+	}
+
+	if len(form.Attachments) > maxMessageAttachments {
+		return errors.New("too many attachments")
+	}
+
+	for _, attachment := range form.Attachments {
+		if attachment.Title == "" && attachment.Text == "" && len(attachment.Fields) == 0 {
+			return errors.New("empty attachments are not valid")
+		}
+		if len(attachment.Title) > 200 || len(attachment.Text) > 2000 || len(attachment.Link) > 500 || len(attachment.Color) > 20 {
+			return errors.New("the attachment is too long")
+		}
+		if len(attachment.Fields) > maxAttachmentFields {
+			return errors.New("too many attachment fields")
+		}
+		for _, field := range attachment.Fields {
+			if field.Title == "" || len(field.Title) > 100 || len(field.Value) > 500 {
+				return errors.New("invalid attachment field")
+			}
+		}
+	}
+
 	return nil
 }
@@ -4,6 +4,10 @@ import (
 	"errors"
 )
 
+// maxPasswordSize bounds the input passed to the password hashing,
+// the length of new passwords is up to the password policy of the app
+const maxPasswordSize = 1024
+
 type UserUpdate struct {
 	Username string
 }
@@ -18,6 +22,17 @@ type UserRegistration struct {
 	Password string
 }
 
+type PasswordChange struct {
+	// OldPassword is empty if the user has no password yet
+	OldPassword string
+	NewPassword string
+}
+
+type PasswordReset struct {
+	Token       string
+	NewPassword string
+}
+
 func (form UserUpdate) Validate() error {
 	if len(form.Username) < 5 || len(form.Username) > 20 {
 		return errors.New("invalid username length")
@@ -30,7 +45,7 @@ func (form UserLogin) Validate() error {
 		return errors.New("invalid username length")
 	}
 
-	if len(form.Password) < 5 || len(form.Password) > 20 {
+	if len(form.Password) == 0 || len(form.Password) > maxPasswordSize {
 		return errors.New("invalid password length")
 	}
 
@@ -42,7 +57,26 @@ func (form UserRegistration) Validate() error {
 		return errors.New("invalid username length")
 	}
 
-	if len(form.Password) < 5 || len(form.Password) > 20 {
+	if len(form.Password) > maxPasswordSize {
+		return errors.New("invalid password length")
+	}
+
+	return nil
+}
+
+func (form PasswordChange) Validate() error {
+	if len(form.OldPassword) > maxPasswordSize || len(form.NewPassword) > maxPasswordSize {
+		return errors.New("invalid password length")
+	}
+	return nil
+}
+
+func (form PasswordReset) Validate() error {
+	if form.Token == "" {
+		return errors.New("empty token")
+	}
+
+	if len(form.NewPassword) > maxPasswordSize {
 		return errors.New("invalid password length")
 	}
 
@@ -0,0 +1,39 @@
+package forms
+
+import (
+	"errors"
+	"net/url"
+)
+
+type WebhookCreation struct {
+	URL string
+	// Events limits the webhook to the given event names, all the events are delivered if it's empty
+	Events []string
+}
+
+func (form *WebhookCreation) Validate() error {
+	u, err := url.Parse(form.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("invalid webhook url")
+	}
+
+	for _, name := range form.Events {
+		if len(name) == 0 || len(name) > 100 {
+			return errors.New("invalid event name")
+		}
+	}
+
+	return nil
+}
+
+type IncomingWebhookCreation struct {
+	// Name is shown as the author of the webhook's messages
+	Name string
+}
+
+func (form *IncomingWebhookCreation) Validate() error {
+	if len(form.Name) < 3 || len(form.Name) > 20 {
+		return errors.New("invalid webhook name length")
+	}
+	return nil
+}
@@ -1,9 +1,10 @@
 package app
 
 import (
+	"github.com/ischenkx/vk-test-task/internal/app/data"
 	"github.com/ischenkx/vk-test-task/internal/app/errors"
 	"github.com/ischenkx/vk-test-task/internal/app/event"
-	"log"
+	"github.com/ischenkx/vk-test-task/internal/app/security"
 	"time"
 )
 
@@ -40,24 +41,26 @@ func (f friendConnection) Friend(ctx *Context) (User, error) {
 }
 
 func (f friendConnection) Delete(ctx *Context) error {
+	if !ctx.Allows(security.ScopeFriendsWrite) {
+		return errors.NotAuthorized
+	}
 	if !f.isWritable(ctx) {
 		return errors.ResourceInaccessible
 	}
-	if err := f.app.repo.DeleteFriendConnection(ctx, f.user, f.friend); err != nil {
-		return err
-	}
+	_, err := f.app.transaction(ctx, func(tx data.Tx) (interface{}, error) {
+		if err := tx.DeleteFriendConnection(ctx, f.user, f.friend); err != nil {
+			return nil, err
+		}
 
-	e := event.New(FriendDeletedEventName, FriendDeletedEvent{
-		FriendID: f.friend,
-		UserID:   f.user,
-	}, event.WithTime(time.Now()))
+		e := event.New(FriendDeletedEventName, FriendDeletedEvent{
+			FriendID: f.friend,
+			UserID:   f.user,
+		}, event.WithTime(time.Now()), event.ToUsers(f.friend, f.user))
 
-	if err := f.app.Events().Send(ctx, e); err != nil {
-		// currently not handled
-		log.Println("failed to send event:", err)
-	}
+		return nil, f.app.publish(ctx, tx, e)
+	})
 
-	return nil
+	return err
 }
 
 func unsafeFriendConnection(app *App, src, friend string) friendConnection {
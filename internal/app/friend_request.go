@@ -5,7 +5,7 @@ import (
 	"github.com/ischenkx/vk-test-task/internal/app/data/models"
 	"github.com/ischenkx/vk-test-task/internal/app/errors"
 	"github.com/ischenkx/vk-test-task/internal/app/event"
-	"log"
+	"github.com/ischenkx/vk-test-task/internal/app/security"
 	"time"
 )
 
@@ -74,6 +74,9 @@ func (f friendRequest) To(ctx *Context) (User, error) {
 }
 
 func (f friendRequest) Accept(ctx *Context) error {
+	if !ctx.Allows(security.ScopeFriendsWrite) {
+		return errors.NotAuthorized
+	}
 	if ctx.User() == nil {
 		return errors.NotAuthorized
 	}
@@ -88,47 +91,37 @@ func (f friendRequest) Accept(ctx *Context) error {
 		return errors.RightsViolation
 	}
 
-	_, err = f.app.repo.Transaction(ctx, func(repo data.Tx) (interface{}, error) {
-		if err := f.app.repo.CreateFriendConnection(ctx, model.From, model.To); err != nil {
+	_, err = f.app.transaction(ctx, func(tx data.Tx) (interface{}, error) {
+		if err := tx.CreateFriendConnection(ctx, model.From, model.To); err != nil {
 			return nil, err
 		}
 
-		if err := f.app.repo.DeleteFriendRequest(ctx, model.ID); err != nil {
+		if err := tx.DeleteFriendRequest(ctx, model.ID); err != nil {
 			return nil, err
 		}
-		return nil, nil
-	})
-
-	if err != nil {
-		return err
-	}
 
-	e := event.New(FriendAddedEventName, FriendAddedEvent{
-		FriendID: model.From,
-		UserID:   model.To,
-	}, event.WithTime(time.Now()))
+		added := event.New(FriendAddedEventName, FriendAddedEvent{
+			FriendID: model.From,
+			UserID:   model.To,
+		}, event.WithTime(time.Now()), event.ToUsers(model.From, model.To))
 
-	if err := f.app.Events().Send(ctx, e); err != nil {
-		// currently not handled
-		log.Println("failed to send event:", err)
-	}
-
-	e = event.New(FriendRequestUpdateEventName, FriendRequestUpdateEvent{
-		FriendRequestID: model.ID,
-		From:            model.To,
-		To:              model.From,
-		Code:            FriendRequestUpdateAccepted,
-	}, event.WithTime(time.Now()))
+		updated := event.New(FriendRequestUpdateEventName, FriendRequestUpdateEvent{
+			FriendRequestID: model.ID,
+			From:            model.To,
+			To:              model.From,
+			Code:            FriendRequestUpdateAccepted,
+		}, event.WithTime(time.Now()), event.ToUsers(model.To, model.From))
 
-	if err := f.app.Events().Send(ctx, e); err != nil {
-		// currently not handled
-		log.Println("failed to send event:", err)
-	}
+		return nil, f.app.publish(ctx, tx, added, updated)
+	})
 
-	return nil
+	return err
 }
 
 func (f friendRequest) Decline(ctx *Context) error {
+	if !ctx.Allows(security.ScopeFriendsWrite) {
+		return errors.NotAuthorized
+	}
 	if ctx.User() == nil {
 		return errors.NotAuthorized
 	}
@@ -139,26 +132,28 @@ func (f friendRequest) Decline(ctx *Context) error {
 	if model.To != ctx.User().ID() {
 		return errors.RightsViolation
 	}
-	if err := f.app.repo.DeleteFriendRequest(ctx, model.ID); err != nil {
-		return err
-	}
+	_, err = f.app.transaction(ctx, func(tx data.Tx) (interface{}, error) {
+		if err := tx.DeleteFriendRequest(ctx, model.ID); err != nil {
+			return nil, err
+		}
 
-	e := event.New(FriendRequestUpdateEventName, FriendRequestUpdateEvent{
-		FriendRequestID: model.ID,
-		From:            model.To,
-		To:              model.From,
-		Code:            FriendRequestUpdateDeclined,
-	}, event.WithTime(time.Now()))
+		e := event.New(FriendRequestUpdateEventName, FriendRequestUpdateEvent{
+			FriendRequestID: model.ID,
+			From:            model.To,
+			To:              model.From,
+			Code:            FriendRequestUpdateDeclined,
+		}, event.WithTime(time.Now()), event.ToUsers(model.To, model.From))
 
-	if err := f.app.Events().Send(ctx, e); err != nil {
-		// currently not handled
-		log.Println("failed to send event:", err)
-	}
+		return nil, f.app.publish(ctx, tx, e)
+	})
 
-	return nil
+	return err
 }
 
 func (f friendRequest) Delete(ctx *Context) error {
+	if !ctx.Allows(security.ScopeFriendsWrite) {
+		return errors.NotAuthorized
+	}
 	if ctx.User() == nil {
 		return errors.NotAuthorized
 	}
@@ -169,23 +164,22 @@ func (f friendRequest) Delete(ctx *Context) error {
 	if model.From != ctx.User().ID() {
 		return errors.RightsViolation
 	}
-	if err := f.app.repo.DeleteFriendRequest(ctx, model.ID); err != nil {
-		return err
-	}
+	_, err = f.app.transaction(ctx, func(tx data.Tx) (interface{}, error) {
+		if err := tx.DeleteFriendRequest(ctx, model.ID); err != nil {
+			return nil, err
+		}
 
-	e := event.New(FriendRequestUpdateEventName, FriendRequestUpdateEvent{
-		FriendRequestID: model.ID,
-		From:            model.To,
-		To:              model.From,
-		Code:            FriendRequestUpdateDeleted,
-	}, event.WithTime(time.Now()))
+		e := event.New(FriendRequestUpdateEventName, FriendRequestUpdateEvent{
+			FriendRequestID: model.ID,
+			From:            model.To,
+			To:              model.From,
+			Code:            FriendRequestUpdateDeleted,
+		}, event.WithTime(time.Now()), event.ToUsers(model.To, model.From))
 
-	if err := f.app.Events().Send(ctx, e); err != nil {
-		// currently not handled
-		log.Println("failed to send event:", err)
-	}
+		return nil, f.app.publish(ctx, tx, e)
+	})
 
-	return nil
+	return err
 }
 
 func unsafeFriendRequestFromModel(app *App, request models.FriendRequest) friendRequest {
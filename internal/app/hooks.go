@@ -0,0 +1,123 @@
+package app
+
+import (
+	"github.com/ischenkx/vk-test-task/internal/app/forms"
+	"sync"
+)
+
+// The ways a member gets into a chat, see AddMemberAction
+const (
+	AddMemberDirect   = "add"
+	AddMemberInvite   = "invite"
+	AddMemberJoin     = "join"
+	AddMemberApproval = "approval"
+)
+
+// HookError is returned by hooks to reject an operation,
+// the operation fails with it as is, so callers can tell rejections apart
+type HookError struct {
+	// Code is a machine-readable reason, e.g. "spam"
+	Code   string
+	Reason string
+}
+
+func (err *HookError) Error() string {
+	return "rejected: " + err.Reason
+}
+
+// Reject builds a HookError
+func Reject(code, reason string) error {
+	return &HookError{Code: code, Reason: reason}
+}
+
+// Hook is called synchronously before an operation is performed.
+//
+// It can rewrite the action or return an error to reject the operation.
+// Hooks run in the order of registration, each one sees the changes of the previous ones.
+// The actions are validated after all the hooks have run.
+type Hook[T any] func(ctx *Context, action *T) error
+
+type SendMessageAction struct {
+	ChatID string
+	UserID string
+	Form   forms.SendMessage
+}
+
+type UpdateMessageAction struct {
+	MessageID string
+	ChatID    string
+	UserID    string
+	Form      forms.MessageUpdate
+}
+
+// AddMemberAction describes a user getting into a chat, only Status can be rewritten
+type AddMemberAction struct {
+	ChatID string
+	UserID string
+	// ActorID is the user adding the member, it's the member itself for invites and joins
+	ActorID string
+	Status  int
+	// Source is one of AddMemberDirect, AddMemberInvite, AddMemberJoin and AddMemberApproval
+	Source string
+}
+
+// FriendRequestAction can only be rejected
+type FriendRequestAction struct {
+	FromID string
+	ToID   string
+}
+
+type hookChain[T any] struct {
+	mu    sync.RWMutex
+	hooks []Hook[T]
+}
+
+func (chain *hookChain[T]) add(hook Hook[T]) {
+	chain.mu.Lock()
+	defer chain.mu.Unlock()
+	chain.hooks = append(chain.hooks, hook)
+}
+
+func (chain *hookChain[T]) run(ctx *Context, action *T) error {
+	chain.mu.RLock()
+	hooks := chain.hooks
+	chain.mu.RUnlock()
+
+	for _, hook := range hooks {
+		if err := hook(ctx, action); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Hooks holds the synchronous hooks of the domain operations.
+//
+// Unlike events, which are sent after the fact, hooks can stop or change
+// an operation: that's the place for spam filters and custom business rules.
+type Hooks struct {
+	sendMessage   hookChain[SendMessageAction]
+	updateMessage hookChain[UpdateMessageAction]
+	addMember     hookChain[AddMemberAction]
+	friendRequest hookChain[FriendRequestAction]
+}
+
+// BeforeSendMessage registers a hook called for every new message,
+// including the ones of bots and incoming webhooks
+func (h *Hooks) BeforeSendMessage(hook Hook[SendMessageAction]) {
+	h.sendMessage.add(hook)
+}
+
+func (h *Hooks) BeforeUpdateMessage(hook Hook[UpdateMessageAction]) {
+	h.updateMessage.add(hook)
+}
+
+// BeforeAddMember registers a hook called whenever a user gets into a chat.
+// Join requests are checked when they are approved.
+func (h *Hooks) BeforeAddMember(hook Hook[AddMemberAction]) {
+	h.addMember.add(hook)
+}
+
+func (h *Hooks) BeforeFriendRequest(hook Hook[FriendRequestAction]) {
+	h.friendRequest.add(hook)
+}
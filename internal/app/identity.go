@@ -0,0 +1,218 @@
+package app
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	goerrors "errors"
+	"github.com/ischenkx/vk-test-task/internal/app/data"
+	"github.com/ischenkx/vk-test-task/internal/app/data/models"
+	"github.com/ischenkx/vk-test-task/internal/app/errors"
+	"github.com/ischenkx/vk-test-task/internal/app/security"
+	"strings"
+	"time"
+)
+
+const loginFlowTTL = 10 * time.Minute
+const loginStateSize = 32
+const codeVerifierSize = 32
+const loginNonceSize = 16
+const usernameSuffixSize = 3
+const maxUsernameAttempts = 5
+
+var ErrUnknownIdentityProvider = goerrors.New("unknown identity provider")
+var ErrInvalidLoginState = goerrors.New("invalid or expired login state")
+var ErrIdentityLinked = goerrors.New("the identity is linked to another user")
+
+// startLoginFlow remembers what's needed to complete the login and returns the page of the provider.
+// The flow links the identity to the user if userID is set.
+func startLoginFlow(ctx *Context, app *App, providerName string, userID string) (string, error) {
+	provider, ok := app.identityProviders[providerName]
+	if !ok {
+		return "", ErrUnknownIdentityProvider
+	}
+
+	state, err := generateToken(loginStateSize)
+	if err != nil {
+		return "", err
+	}
+
+	verifier, err := generateToken(codeVerifierSize)
+	if err != nil {
+		return "", err
+	}
+
+	nonce, err := generateToken(loginNonceSize)
+	if err != nil {
+		return "", err
+	}
+
+	err = app.repo.CreateLoginFlow(ctx, models.LoginFlow{
+		StateHash:    hashToken(state),
+		Provider:     providerName,
+		CodeVerifier: verifier,
+		Nonce:        nonce,
+		UserID:       userID,
+		ExpiresAt:    time.Now().Add(loginFlowTTL),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return provider.AuthURL(ctx, state, nonce, codeChallenge(verifier))
+}
+
+// completeLoginFlow takes the flow of the state and exchanges the code for the identity,
+// a flow can't be completed twice even if the exchange fails
+func completeLoginFlow(ctx *Context, app *App, state string, code string) (models.LoginFlow, security.Identity, error) {
+	flow, err := app.repo.TakeLoginFlow(ctx, hashToken(state))
+	if err != nil {
+		return models.LoginFlow{}, security.Identity{}, ErrInvalidLoginState
+	}
+
+	provider, ok := app.identityProviders[flow.Provider]
+	if !ok {
+		return models.LoginFlow{}, security.Identity{}, ErrUnknownIdentityProvider
+	}
+
+	identity, err := provider.Exchange(ctx, code, flow.CodeVerifier, flow.Nonce)
+	if err != nil {
+		return models.LoginFlow{}, security.Identity{}, err
+	}
+	identity.Provider = flow.Provider
+
+	return flow, identity, nil
+}
+
+// externalLogin returns the user the identity is linked to,
+// a new user is provisioned for an identity that's seen for the first time
+func externalLogin(ctx *Context, app *App, state string, code string) (User, error) {
+	flow, identity, err := completeLoginFlow(ctx, app, state, code)
+	if err != nil {
+		return nil, err
+	}
+
+	if flow.UserID != "" {
+		return nil, goerrors.New("the flow links an identity, it can't be used to log in")
+	}
+
+	linked, err := app.repo.GetExternalIdentity(ctx, identity.Provider, identity.Subject)
+	if err == nil {
+		u, err := app.repo.GetUser(ctx, linked.UserID)
+		if err != nil || u.Kind != models.UserKindRegular {
+			return nil, goerrors.New("failed to find such a user")
+		}
+		return unsafeUserFromModel(app, u), nil
+	}
+
+	return provisionUser(ctx, app, identity)
+}
+
+// linkIdentity links the identity of the flow to the current user, who must have started the flow
+func linkIdentity(ctx *Context, app *App, state string, code string) (models.ExternalIdentity, error) {
+	flow, identity, err := completeLoginFlow(ctx, app, state, code)
+	if err != nil {
+		return models.ExternalIdentity{}, err
+	}
+
+	if flow.UserID == "" || ctx.User() == nil || ctx.User().ID() != flow.UserID || ctx.Restricted() {
+		return models.ExternalIdentity{}, errors.ResourceInaccessible
+	}
+
+	if linked, err := app.repo.GetExternalIdentity(ctx, identity.Provider, identity.Subject); err == nil {
+		if linked.UserID != flow.UserID {
+			return models.ExternalIdentity{}, ErrIdentityLinked
+		}
+		return linked, nil
+	}
+
+	return app.repo.CreateExternalIdentity(ctx, models.ExternalIdentity{
+		Provider: identity.Provider,
+		Subject:  identity.Subject,
+		UserID:   flow.UserID,
+		Email:    identity.Email,
+	})
+}
+
+// provisionUser creates a user for the identity, the user has no password
+// and logs in through the provider only
+func provisionUser(ctx *Context, app *App, identity security.Identity) (User, error) {
+	res, err := app.repo.Transaction(ctx, func(tx data.Tx) (interface{}, error) {
+		username, err := freeUsername(ctx, tx, identity)
+		if err != nil {
+			return nil, err
+		}
+
+		u, err := tx.CreateUser(ctx, models.User{
+			Username:     username,
+			PasswordHash: []byte{},
+			Kind:         models.UserKindRegular,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = tx.CreateExternalIdentity(ctx, models.ExternalIdentity{
+			Provider: identity.Provider,
+			Subject:  identity.Subject,
+			UserID:   u.ID,
+			Email:    identity.Email,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		return u, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return unsafeUserFromModel(app, res.(models.User)), nil
+}
+
+// freeUsername derives a username from the identity, a random suffix is added if it's taken
+func freeUsername(ctx *Context, tx data.Tx, identity security.Identity) (string, error) {
+	base := identity.Username
+	if base == "" {
+		base, _, _ = strings.Cut(identity.Email, "@")
+	}
+
+	base = strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == '.' || r == '-' {
+			return r
+		}
+		return -1
+	}, base)
+
+	if base == "" {
+		base = "user"
+	}
+
+	// leave room for the suffix, usernames are 5 to 20 characters long
+	if len(base) > 15 {
+		base = base[:15]
+	}
+
+	for attempt := 0; attempt < maxUsernameAttempts; attempt++ {
+		username := base
+		if attempt > 0 || len(username) < 5 {
+			suffix, err := generateToken(usernameSuffixSize)
+			if err != nil {
+				return "", err
+			}
+			username = base + "-" + suffix
+		}
+
+		if _, err := tx.GetUserByUsername(ctx, username); err != nil {
+			return username, nil
+		}
+	}
+
+	return "", goerrors.New("failed to find a free username")
+}
+
+// codeChallenge derives the S256 PKCE challenge from the verifier (RFC 7636)
+func codeChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
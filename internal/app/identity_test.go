@@ -0,0 +1,283 @@
+package app
+
+import (
+	"context"
+	goerrors "errors"
+	"github.com/ischenkx/vk-test-task/internal/app/data"
+	"github.com/ischenkx/vk-test-task/internal/app/data/models"
+	"github.com/ischenkx/vk-test-task/internal/app/security"
+	"github.com/ischenkx/vk-test-task/internal/impl/identity/oidc"
+	"github.com/ischenkx/vk-test-task/internal/impl/identity/oidc/oidctest"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+var errNotFound = goerrors.New("not found")
+
+// identityRepo keeps what the external logins touch in memory, the other methods aren't implemented
+type identityRepo struct {
+	data.Repository
+
+	mu         sync.Mutex
+	users      map[string]models.User
+	identities map[string]models.ExternalIdentity
+	flows      map[string]models.LoginFlow
+	twoFactor  map[string]models.TwoFactor
+	challenges map[string]models.LoginChallenge
+}
+
+func newIdentityRepo() *identityRepo {
+	return &identityRepo{
+		users:      map[string]models.User{},
+		identities: map[string]models.ExternalIdentity{},
+		flows:      map[string]models.LoginFlow{},
+		twoFactor:  map[string]models.TwoFactor{},
+		challenges: map[string]models.LoginChallenge{},
+	}
+}
+
+func (r *identityRepo) Transaction(ctx context.Context, f func(data.Tx) (interface{}, error)) (interface{}, error) {
+	return f(r)
+}
+
+func (r *identityRepo) CreateUser(ctx context.Context, user models.User) (models.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, u := range r.users {
+		if u.Username == user.Username {
+			return models.User{}, goerrors.New("duplicate username")
+		}
+	}
+
+	user.ID = strconv.Itoa(len(r.users) + 1)
+	r.users[user.ID] = user
+	return user, nil
+}
+
+func (r *identityRepo) GetUser(ctx context.Context, id string) (models.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	u, ok := r.users[id]
+	if !ok {
+		return models.User{}, errNotFound
+	}
+	return u, nil
+}
+
+func (r *identityRepo) GetUserByUsername(ctx context.Context, username string) (models.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, u := range r.users {
+		if u.Username == username {
+			return u, nil
+		}
+	}
+	return models.User{}, errNotFound
+}
+
+func (r *identityRepo) CreateExternalIdentity(ctx context.Context, identity models.ExternalIdentity) (models.ExternalIdentity, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := identity.Provider + ":" + identity.Subject
+	if _, ok := r.identities[key]; ok {
+		return models.ExternalIdentity{}, goerrors.New("duplicate identity")
+	}
+	r.identities[key] = identity
+	return identity, nil
+}
+
+func (r *identityRepo) GetExternalIdentity(ctx context.Context, provider string, subject string) (models.ExternalIdentity, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	identity, ok := r.identities[provider+":"+subject]
+	if !ok {
+		return models.ExternalIdentity{}, errNotFound
+	}
+	return identity, nil
+}
+
+func (r *identityRepo) CreateLoginFlow(ctx context.Context, flow models.LoginFlow) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.flows[flow.StateHash] = flow
+	return nil
+}
+
+func (r *identityRepo) TakeLoginFlow(ctx context.Context, stateHash string) (models.LoginFlow, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	flow, ok := r.flows[stateHash]
+	if !ok {
+		return models.LoginFlow{}, errNotFound
+	}
+	delete(r.flows, stateHash)
+	return flow, nil
+}
+
+func (r *identityRepo) GetTwoFactor(ctx context.Context, userID string) (models.TwoFactor, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	twoFactor, ok := r.twoFactor[userID]
+	if !ok {
+		return models.TwoFactor{}, errNotFound
+	}
+	return twoFactor, nil
+}
+
+func (r *identityRepo) CreateLoginChallenge(ctx context.Context, challenge models.LoginChallenge) (models.LoginChallenge, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	challenge.ID = strconv.Itoa(len(r.challenges) + 1)
+	r.challenges[challenge.ID] = challenge
+	return challenge, nil
+}
+
+// plainHasher keeps the passwords as they are, the external logins don't check them
+type plainHasher struct{}
+
+func (plainHasher) Hash(password string) ([]byte, error) {
+	return []byte(password), nil
+}
+
+func (plainHasher) Verify(hash []byte, password string) (bool, bool, error) {
+	return string(hash) == password, false, nil
+}
+
+// setupExternalLogin returns an app that logs users in with a mock provider
+func setupExternalLogin(t *testing.T) (*App, *identityRepo) {
+	t.Helper()
+
+	server := httptest.NewUnstartedServer(nil)
+	issuer := "http://" + server.Listener.Addr().String()
+	mock, err := oidctest.New(issuer, "client", oidctest.Identity{Subject: "alice", Username: "alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.Config.Handler = mock
+	server.Start()
+	t.Cleanup(server.Close)
+
+	repo := newIdentityRepo()
+	app := New(Config{
+		Repo:           repo,
+		PasswordHasher: plainHasher{},
+		IdentityProviders: []security.IdentityProvider{
+			oidc.New(oidc.Config{
+				Name:        "mock",
+				Issuer:      issuer,
+				ClientID:    "client",
+				RedirectURL: "http://localhost/callback",
+			}, oidc.WithHTTPClient(server.Client())),
+		},
+	})
+
+	return app, repo
+}
+
+// authorize logs in at the provider and returns the state and the code it redirects back with
+func authorize(t *testing.T, app *App) (string, string) {
+	t.Helper()
+
+	authURL, err := app.Users().StartExternalLogin(NewContext(context.Background()), "mock")
+	if err != nil {
+		t.Fatalf("failed to start the login: %s", err)
+	}
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err := client.Get(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	location, err := resp.Location()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	query, err := url.ParseQuery(location.RawQuery)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return query.Get("state"), query.Get("code")
+}
+
+func TestExternalLoginProvisionsUserOnce(t *testing.T) {
+	app, repo := setupExternalLogin(t)
+
+	var ids []string
+	for i := 0; i < 3; i++ {
+		state, code := authorize(t, app)
+
+		u, challenge, err := app.Users().CompleteExternalLogin(NewContext(context.Background()), state, code)
+		if err != nil {
+			t.Fatalf("failed to complete the login: %s", err)
+		}
+		if u == nil || challenge != "" {
+			t.Fatalf("expected the user to be logged in, got a challenge %q", challenge)
+		}
+		ids = append(ids, u.ID())
+	}
+
+	if len(repo.users) != 1 {
+		t.Fatalf("expected a single user, got %d", len(repo.users))
+	}
+	for _, id := range ids {
+		if id != ids[0] {
+			t.Fatalf("expected the logins to return the same user, got %v", ids)
+		}
+	}
+}
+
+func TestExternalLoginRejectsReusedState(t *testing.T) {
+	app, _ := setupExternalLogin(t)
+
+	state, code := authorize(t, app)
+	if _, _, err := app.Users().CompleteExternalLogin(NewContext(context.Background()), state, code); err != nil {
+		t.Fatalf("failed to complete the login: %s", err)
+	}
+
+	_, _, err := app.Users().CompleteExternalLogin(NewContext(context.Background()), state, code)
+	if err != ErrInvalidLoginState {
+		t.Fatalf("expected %v, got %v", ErrInvalidLoginState, err)
+	}
+}
+
+func TestExternalLoginRequiresSecondFactor(t *testing.T) {
+	app, repo := setupExternalLogin(t)
+
+	state, code := authorize(t, app)
+	u, _, err := app.Users().CompleteExternalLogin(NewContext(context.Background()), state, code)
+	if err != nil {
+		t.Fatalf("failed to complete the login: %s", err)
+	}
+
+	repo.twoFactor[u.ID()] = models.TwoFactor{UserID: u.ID(), Enabled: true}
+
+	state, code = authorize(t, app)
+	u, challenge, err := app.Users().CompleteExternalLogin(NewContext(context.Background()), state, code)
+	if err != nil {
+		t.Fatalf("failed to complete the login: %s", err)
+	}
+	if u != nil || challenge == "" {
+		t.Fatal("expected a login challenge instead of the user")
+	}
+	if len(repo.challenges) != 1 {
+		t.Fatalf("expected a single challenge, got %d", len(repo.challenges))
+	}
+}
@@ -0,0 +1,202 @@
+package app
+
+import (
+	"crypto/subtle"
+	"github.com/ischenkx/vk-test-task/internal/app/data"
+	"github.com/ischenkx/vk-test-task/internal/app/data/models"
+	"github.com/ischenkx/vk-test-task/internal/app/errors"
+	"github.com/ischenkx/vk-test-task/internal/app/event"
+	"github.com/ischenkx/vk-test-task/internal/app/forms"
+	"github.com/ischenkx/vk-test-task/internal/app/security"
+	"time"
+)
+
+const incomingWebhookTokenSize = 32
+const integrationUsernameSuffixSize = 4
+const defaultIncomingWebhookRateLimit = 20
+
+// IncomingWebhook posts messages into a chat on behalf of an integration identity,
+// a user that's created along with the webhook and can't log in.
+type IncomingWebhook interface {
+	ID() string
+	Chat(ctx *Context) (Chat, error)
+	// User returns the integration identity the messages are attributed to
+	User(ctx *Context) (User, error)
+	Model(ctx *Context) (models.IncomingWebhook, error)
+	// Delete revokes the token, the integration and its messages stay in the chat
+	Delete(ctx *Context) error
+}
+
+type incomingWebhook struct {
+	app *App
+	id  string
+}
+
+func (w incomingWebhook) model(ctx *Context) (models.IncomingWebhook, error) {
+	return w.app.repo.GetIncomingWebhook(ctx, w.id)
+}
+
+func (w incomingWebhook) exists(ctx *Context) bool {
+	if _, err := w.model(ctx); err != nil {
+		return false
+	}
+	return true
+}
+
+// isWritable reports whether the current user is an admin of the webhook's chat
+func (w incomingWebhook) isWritable(ctx *Context) bool {
+	model, err := w.model(ctx)
+	if err != nil {
+		return false
+	}
+	return chat{app: w.app, id: model.ChatID}.isManageable(ctx)
+}
+
+func (w incomingWebhook) ID() string {
+	return w.id
+}
+
+func (w incomingWebhook) Chat(ctx *Context) (Chat, error) {
+	model, err := w.Model(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return newChat(ctx, w.app, model.ChatID)
+}
+
+func (w incomingWebhook) User(ctx *Context) (User, error) {
+	model, err := w.Model(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return newUser(ctx, w.app, model.UserID)
+}
+
+func (w incomingWebhook) Model(ctx *Context) (models.IncomingWebhook, error) {
+	if !w.isWritable(ctx) {
+		return models.IncomingWebhook{}, errors.ResourceInaccessible
+	}
+	return w.model(ctx)
+}
+
+func (w incomingWebhook) Delete(ctx *Context) error {
+	if !ctx.Allows(security.ScopeWebhooks) {
+		return errors.NotAuthorized
+	}
+	if !w.isWritable(ctx) {
+		return errors.ResourceInaccessible
+	}
+	return w.app.repo.DeleteIncomingWebhook(ctx, w.id)
+}
+
+// post creates a message if the token belongs to the webhook.
+// The integration goes through the same path as the members sending messages.
+func (w incomingWebhook) post(ctx *Context, token string, form forms.SendMessage) (Message, error) {
+	model, err := w.model(ctx)
+	if err != nil {
+		return nil, errors.DoesNotExist
+	}
+
+	if subtle.ConstantTimeCompare([]byte(hashToken(token)), []byte(model.TokenHash)) != 1 {
+		return nil, errors.NotAuthorized
+	}
+
+	if !w.app.incomingWebhookLimiter.Allow(model.ID) {
+		return nil, errors.RateLimited
+	}
+
+	member := chatMember{
+		app:    w.app,
+		chatID: model.ChatID,
+		userID: model.UserID,
+	}
+
+	// the integration could have been removed from the chat by an admin
+	if !member.exists(ctx) {
+		return nil, errors.ResourceInaccessible
+	}
+
+	return member.send(ctx, form)
+}
+
+// createIncomingWebhook registers the integration identity as a member of the chat
+// and returns the webhook along with its token, only the hash of the token is stored
+func createIncomingWebhook(ctx *Context, app *App, chatID string, form forms.IncomingWebhookCreation) (IncomingWebhook, string, error) {
+	if err := form.Validate(); err != nil {
+		return nil, "", err
+	}
+
+	token, err := generateToken(incomingWebhookTokenSize)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// integrations share the namespace of usernames, the suffix keeps them unique
+	suffix, err := generateToken(integrationUsernameSuffixSize)
+	if err != nil {
+		return nil, "", err
+	}
+
+	res, err := app.transaction(ctx, func(tx data.Tx) (interface{}, error) {
+		u, err := tx.CreateUser(ctx, models.User{
+			Username:     form.Name + "#" + suffix,
+			PasswordHash: []byte{},
+			Kind:         models.UserKindIntegration,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = tx.CreateChatMember(ctx, models.ChatMember{
+			ChatID: chatID,
+			UserID: u.ID,
+			Status: ChatMemberRegularStatus,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		model, err := tx.CreateIncomingWebhook(ctx, models.IncomingWebhook{
+			ChatID:    chatID,
+			UserID:    u.ID,
+			CreatorID: ctx.User().ID(),
+			Name:      form.Name,
+			TokenHash: hashToken(token),
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		e := event.New(ChatMemberCreatedEventName, ChatMemberCreatedEvent{
+			UserID: u.ID,
+			ChatID: chatID,
+		}, event.WithTime(time.Now()), event.ToChat(chatID))
+
+		return model, app.publish(ctx, tx, e)
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	return unsafeIncomingWebhookFromModel(app, res.(models.IncomingWebhook)), token, nil
+}
+
+func unsafeIncomingWebhookFromModel(app *App, model models.IncomingWebhook) IncomingWebhook {
+	return incomingWebhook{
+		app: app,
+		id:  model.ID,
+	}
+}
+
+func newIncomingWebhook(ctx *Context, app *App, id string) (IncomingWebhook, error) {
+	w := incomingWebhook{
+		app: app,
+		id:  id,
+	}
+
+	if !w.exists(ctx) {
+		return nil, errors.DoesNotExist
+	}
+
+	return w, nil
+}
@@ -0,0 +1,209 @@
+package app
+
+import (
+	goerrors "errors"
+	"github.com/ischenkx/vk-test-task/internal/app/data"
+	"github.com/ischenkx/vk-test-task/internal/app/event"
+	"log"
+	"strings"
+	"time"
+)
+
+const defaultUserLockoutThreshold = 10
+const defaultIPLockoutThreshold = 100
+const defaultLoginBaseDelay = time.Second
+const defaultLoginLockoutDuration = 15 * time.Minute
+
+// The targets of the login throttling, see LoginLockedEvent
+const (
+	LoginThrottleUser = "user"
+	LoginThrottleIP   = "ip"
+)
+
+// ErrInvalidCredentials is returned whether the user doesn't exist or the password is wrong,
+// so that the usernames can't be found out by logging in
+var ErrInvalidCredentials = goerrors.New("invalid username or password")
+var ErrTooManyLogins = goerrors.New("too many failed logins, try again later")
+
+// LoginThrottling slows down guessing the passwords.
+//
+// The failed logins are counted per username (whether the user exists or not) and per IP address.
+// Once half of the threshold has failed, every next login is delayed by BaseDelay doubled with each
+// failure, and when the threshold is reached the logins are locked out for LockoutDuration.
+// The failures are forgotten after LockoutDuration without new ones, or after a successful login
+// (only the ones of the username), which includes the second factor if the user has it enabled.
+type LoginThrottling struct {
+	// UserLockoutThreshold is the failures of a username that lock it out (10 by default)
+	UserLockoutThreshold int
+	// IPLockoutThreshold is the failures from an IP address that lock it out (100 by default),
+	// it's higher since users behind a NAT share the address
+	IPLockoutThreshold int
+	// BaseDelay is the first delay (a second by default)
+	BaseDelay time.Duration
+	// LockoutDuration is 15 minutes by default, the delays don't exceed it
+	LockoutDuration time.Duration
+}
+
+type loginThrottle struct {
+	userThreshold   int
+	ipThreshold     int
+	baseDelay       time.Duration
+	lockoutDuration time.Duration
+}
+
+func newLoginThrottle(cfg LoginThrottling) loginThrottle {
+	throttle := loginThrottle{
+		userThreshold:   cfg.UserLockoutThreshold,
+		ipThreshold:     cfg.IPLockoutThreshold,
+		baseDelay:       cfg.BaseDelay,
+		lockoutDuration: cfg.LockoutDuration,
+	}
+	if throttle.userThreshold <= 0 {
+		throttle.userThreshold = defaultUserLockoutThreshold
+	}
+	if throttle.ipThreshold <= 0 {
+		throttle.ipThreshold = defaultIPLockoutThreshold
+	}
+	if throttle.baseDelay <= 0 {
+		throttle.baseDelay = defaultLoginBaseDelay
+	}
+	if throttle.lockoutDuration <= 0 {
+		throttle.lockoutDuration = defaultLoginLockoutDuration
+	}
+	return throttle
+}
+
+// delay returns how long the logins are delayed after the failures, locked reports a lockout
+func (throttle loginThrottle) delay(failures int, threshold int) (delay time.Duration, locked bool) {
+	if failures >= threshold {
+		return throttle.lockoutDuration, true
+	}
+
+	free := threshold / 2
+	if failures <= free {
+		return 0, false
+	}
+
+	delay = throttle.baseDelay
+	for i := free + 1; i < failures && delay < throttle.lockoutDuration; i++ {
+		delay *= 2
+	}
+	if delay > throttle.lockoutDuration {
+		delay = throttle.lockoutDuration
+	}
+	return delay, false
+}
+
+type loginThrottleKey struct {
+	target    string
+	key       string
+	threshold int
+}
+
+// keys returns the counters of the login, the username is case-insensitive
+// so that changing the case doesn't give more attempts
+func (throttle loginThrottle) keys(ctx *Context, username string) []loginThrottleKey {
+	keys := []loginThrottleKey{{
+		target:    LoginThrottleUser,
+		key:       loginThrottleUserKey(username),
+		threshold: throttle.userThreshold,
+	}}
+	if ip := ctx.Client().IP; ip != "" {
+		keys = append(keys, loginThrottleKey{
+			target:    LoginThrottleIP,
+			key:       "ip:" + ip,
+			threshold: throttle.ipThreshold,
+		})
+	}
+	return keys
+}
+
+func loginThrottleUserKey(username string) string {
+	return "user:" + strings.ToLower(username)
+}
+
+// checkLoginThrottle fails if the username or the IP address of the client is delayed or locked out,
+// the attempts made meanwhile aren't counted
+func checkLoginThrottle(ctx *Context, app *App, username string) error {
+	for _, key := range app.loginThrottle.keys(ctx, username) {
+		model, err := app.repo.GetLoginThrottle(ctx, key.key)
+		if err != nil {
+			continue
+		}
+		if time.Now().Before(model.LockedUntil) {
+			return ErrTooManyLogins
+		}
+	}
+	return nil
+}
+
+// loginFailed counts the failure and returns the error the login fails with.
+// The user is notified with a security event if it exists (userID isn't empty).
+func loginFailed(ctx *Context, app *App, username string, userID string) error {
+	var events []event.Event
+	now := time.Now()
+
+	var failures int
+	for _, key := range app.loginThrottle.keys(ctx, username) {
+		model, err := app.repo.RecordLoginFailure(ctx, key.key, app.loginThrottle.lockoutDuration)
+		if err != nil {
+			log.Println("failed to record a failed login:", err)
+			continue
+		}
+		if key.target == LoginThrottleUser {
+			failures = model.Failures
+		}
+
+		delay, locked := app.loginThrottle.delay(model.Failures, key.threshold)
+		if delay == 0 {
+			continue
+		}
+		if err := app.repo.LockLogin(ctx, key.key, now.Add(delay)); err != nil {
+			log.Println("failed to delay logins:", err)
+			continue
+		}
+
+		if locked {
+			events = append(events, event.New(LoginLockedEventName, LoginLockedEvent{
+				UserID:      userID,
+				Username:    username,
+				IP:          ctx.Client().IP,
+				Target:      key.target,
+				LockedUntil: now.Add(delay),
+			}, event.WithTime(now), event.ToUsers(recipients(userID)...)))
+		}
+	}
+
+	events = append([]event.Event{event.New(LoginFailedEventName, LoginFailedEvent{
+		UserID:    userID,
+		Username:  username,
+		IP:        ctx.Client().IP,
+		UserAgent: ctx.Client().UserAgent,
+		Failures:  failures,
+	}, event.WithTime(now), event.ToUsers(recipients(userID)...))}, events...)
+
+	_, err := app.transaction(ctx, func(tx data.Tx) (interface{}, error) {
+		return nil, app.publish(ctx, tx, events...)
+	})
+	if err != nil {
+		log.Println("failed to publish security events:", err)
+	}
+
+	return ErrInvalidCredentials
+}
+
+// loginSucceeded forgets the failures of the username, the ones of the IP address are kept
+// so that an attacker can't reset them by logging into an own account
+func loginSucceeded(ctx *Context, app *App, username string) {
+	if err := app.repo.DeleteLoginThrottle(ctx, loginThrottleUserKey(username)); err != nil {
+		log.Println("failed to reset the failed logins:", err)
+	}
+}
+
+// recipients returns the users an event about the user is sent to, nobody if there is no such user
+func recipients(userID string) []string {
+	if userID == "" {
+		return nil
+	}
+	return []string{userID}
+}
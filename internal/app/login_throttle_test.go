@@ -0,0 +1,58 @@
+package app
+
+import (
+	"context"
+	"github.com/ischenkx/vk-test-task/internal/app/data/models"
+	"github.com/ischenkx/vk-test-task/internal/app/forms"
+	"testing"
+)
+
+// throttleRepo records which throttling counters are reset
+type throttleRepo struct {
+	*identityRepo
+
+	reset []string
+}
+
+func (r *throttleRepo) GetLoginThrottle(ctx context.Context, key string) (models.LoginThrottle, error) {
+	return models.LoginThrottle{}, errNotFound
+}
+
+func (r *throttleRepo) DeleteLoginThrottle(ctx context.Context, key string) error {
+	r.reset = append(r.reset, key)
+	return nil
+}
+
+func TestLoginResetsThrottleAfterSecondFactor(t *testing.T) {
+	repo := &throttleRepo{identityRepo: newIdentityRepo()}
+	app := New(Config{Repo: repo, PasswordHasher: plainHasher{}})
+
+	for _, username := range []string{"alice", "bobby"} {
+		if _, err := repo.CreateUser(context.Background(), models.User{
+			Username:     username,
+			PasswordHash: []byte("password"),
+			Kind:         models.UserKindRegular,
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	bobby, _ := repo.GetUserByUsername(context.Background(), "bobby")
+	repo.twoFactor[bobby.ID] = models.TwoFactor{UserID: bobby.ID, Enabled: true}
+
+	u, _, err := app.Users().Login(NewContext(context.Background()), forms.UserLogin{Username: "alice", Password: "password"})
+	if err != nil || u == nil {
+		t.Fatalf("failed to log in: %v", err)
+	}
+	if len(repo.reset) != 1 || repo.reset[0] != loginThrottleUserKey("alice") {
+		t.Fatalf("expected the failures of alice to be reset, got %v", repo.reset)
+	}
+
+	repo.reset = nil
+	u, challenge, err := app.Users().Login(NewContext(context.Background()), forms.UserLogin{Username: "bobby", Password: "password"})
+	if err != nil || u != nil || challenge == "" {
+		t.Fatalf("expected a login challenge, got %v %q %v", u, challenge, err)
+	}
+	if len(repo.reset) != 0 {
+		t.Fatalf("expected the failures to be kept until the second factor, got %v", repo.reset)
+	}
+}
@@ -1,11 +1,12 @@
 package app
 
 import (
+	"github.com/ischenkx/vk-test-task/internal/app/data"
 	"github.com/ischenkx/vk-test-task/internal/app/data/models"
 	"github.com/ischenkx/vk-test-task/internal/app/errors"
 	"github.com/ischenkx/vk-test-task/internal/app/event"
 	"github.com/ischenkx/vk-test-task/internal/app/forms"
-	"log"
+	"github.com/ischenkx/vk-test-task/internal/app/security"
 	"time"
 )
 
@@ -16,6 +17,7 @@ type Message interface {
 	Payload(ctx *Context) (string, error)
 	TimeStamp(ctx *Context) (time.Time, error)
 	LastUpdate(ctx *Context) (time.Time, error)
+	Views(ctx *Context) (int, error)
 	Update(ctx *Context, update forms.MessageUpdate) error
 	Delete(ctx *Context) error
 	Model(ctx *Context) (models.Message, error)
@@ -107,41 +109,69 @@ func (m message) LastUpdate(ctx *Context) (time.Time, error) {
 	}
 }
 
+func (m message) Views(ctx *Context) (int, error) {
+	if !m.isAccessible(ctx) {
+		return 0, errors.ResourceInaccessible
+	}
+	if model, err := m.Model(ctx); err != nil {
+		return 0, err
+	} else {
+		return model.Views, nil
+	}
+}
+
 func (m message) Update(ctx *Context, update forms.MessageUpdate) error {
+	if !ctx.Allows(security.ScopeMessagesWrite) {
+		return errors.NotAuthorized
+	}
 
 	if !m.isAccessible(ctx) {
 		return errors.ResourceInaccessible
 	}
 
-	if err := update.Validate(); err != nil {
+	model, err := m.Model(ctx)
+	if err != nil {
 		return err
 	}
 
-	model, err := m.Model(ctx)
-	if err != nil {
+	action := UpdateMessageAction{
+		MessageID: model.ID,
+		ChatID:    model.ChatID,
+		UserID:    model.UserID,
+		Form:      update,
+	}
+	if err := m.app.hooks.updateMessage.run(ctx, &action); err != nil {
 		return err
 	}
-	model.Payload = update.Payload
-	model.LastUpdate = time.Now()
+	update = action.Form
 
-	if err := m.app.repo.UpdateMessage(ctx, model); err != nil {
+	if err := update.Validate(); err != nil {
 		return err
 	}
 
-	e := event.New(MessageUpdatedEventName, MessageUpdatedEvent{
-		MessageID: model.ID,
-		ChatID:    model.ChatID,
-	}, event.WithTime(time.Now()))
+	model.Payload = update.Payload
+	model.LastUpdate = time.Now()
 
-	if err := m.app.Events().Send(ctx, e); err != nil {
-		// currently not handled
-		log.Println("failed to send event:", err)
-	}
+	_, err = m.app.transaction(ctx, func(tx data.Tx) (interface{}, error) {
+		if err := tx.UpdateMessage(ctx, model); err != nil {
+			return nil, err
+		}
+
+		e := event.New(MessageUpdatedEventName, MessageUpdatedEvent{
+			MessageID: model.ID,
+			ChatID:    model.ChatID,
+		}, event.WithTime(time.Now()), event.ToChat(model.ChatID))
 
-	return nil
+		return nil, m.app.publish(ctx, tx, e)
+	})
+
+	return err
 }
 
 func (m message) Delete(ctx *Context) error {
+	if !ctx.Allows(security.ScopeMessagesWrite) {
+		return errors.NotAuthorized
+	}
 	if !m.isAccessible(ctx) {
 		return errors.ResourceInaccessible
 	}
@@ -151,21 +181,20 @@ func (m message) Delete(ctx *Context) error {
 		return err
 	}
 
-	if err := m.app.repo.DeleteMessage(ctx, m.id); err != nil {
-		return err
-	}
+	_, err = m.app.transaction(ctx, func(tx data.Tx) (interface{}, error) {
+		if err := tx.DeleteMessage(ctx, m.id); err != nil {
+			return nil, err
+		}
 
-	e := event.New(MessageDeletedEventName, MessageDeletedEvent{
-		MessageID: m.id,
-		ChatID:    chat.ID(),
-	}, event.WithTime(time.Now()))
+		e := event.New(MessageDeletedEventName, MessageDeletedEvent{
+			MessageID: m.id,
+			ChatID:    chat.ID(),
+		}, event.WithTime(time.Now()), event.ToChat(chat.ID()))
 
-	if err := m.app.Events().Send(ctx, e); err != nil {
-		// currently not handled
-		log.Println("failed to send event:", err)
-	}
+		return nil, m.app.publish(ctx, tx, e)
+	})
 
-	return nil
+	return err
 }
 
 func unsafeMessageFromModel(app *App, m models.Message) Message {
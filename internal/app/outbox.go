@@ -0,0 +1,166 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/ischenkx/vk-test-task/internal/app/data"
+	"github.com/ischenkx/vk-test-task/internal/app/data/models"
+	"github.com/ischenkx/vk-test-task/internal/app/event"
+	"log"
+	"time"
+)
+
+const defaultOutboxPollInterval = time.Second
+const outboxBatchSize = 100
+
+// outboxLease is how long a claimed batch is hidden from the other relays,
+// outboxSendTimeout bounds the sending of the batch and must be shorter
+const (
+	outboxLease       = time.Minute
+	outboxSendTimeout = 30 * time.Second
+)
+
+// publish stores the events in the outbox within the transaction.
+//
+// They reach the bus only if the transaction is committed, see transaction and RunRelay.
+// The bots the events are meant for get them as updates in the same transaction.
+func (app *App) publish(ctx context.Context, tx data.Tx, events ...event.Event) error {
+	for _, e := range events {
+		payload, err := json.Marshal(e.Data)
+		if err != nil {
+			return err
+		}
+
+		model, err := tx.CreateOutboxEvent(ctx, models.OutboxEvent{
+			Name:          e.Name,
+			Data:          payload,
+			TimeStamp:     e.TimeStamp,
+			ChatID:        e.ChatID,
+			UserIDs:       e.UserIDs,
+			SchemaVersion: app.registry.Version(e.Name),
+		})
+		if err != nil {
+			return err
+		}
+
+		e.ID = model.ID
+		envelope, err := event.NewJSONCodec(app.registry).Encode(e)
+		if err != nil {
+			return err
+		}
+
+		err = tx.CreateBotUpdates(ctx, models.BotUpdate{
+			EventID: e.ID,
+			Name:    e.Name,
+			Payload: envelope,
+		}, e.ChatID, e.UserIDs)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// transaction runs f in a transaction and wakes the relay up after the commit,
+// so the events published by f are delivered without waiting for the next poll
+func (app *App) transaction(ctx context.Context, f func(tx data.Tx) (interface{}, error)) (interface{}, error) {
+	res, err := app.repo.Transaction(ctx, f)
+	if err == nil {
+		select {
+		case app.outboxWake <- struct{}{}:
+		default:
+			// already woken up
+		}
+	}
+	return res, err
+}
+
+// RunRelay publishes the events from the outbox to the bus until the context is done.
+//
+// Delivery is at-least-once: an event is removed from the outbox only after
+// it has been sent, so a crash in between leads to a redelivery with the same ID
+// once the claim of the relay runs out.
+func (app *App) RunRelay(ctx context.Context) {
+	for {
+		n, err := app.relayOutbox(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			log.Println("failed to relay events:", err)
+		}
+
+		if n == outboxBatchSize {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-app.outboxWake:
+		case <-time.After(app.outboxPollInterval):
+		}
+	}
+}
+
+// relayOutbox sends a batch of pending events and returns how many of them were processed.
+//
+// The batch is claimed for outboxLease and sent outside of a transaction,
+// the sends are cut off by outboxSendTimeout so that they end before the lease does.
+func (app *App) relayOutbox(ctx context.Context) (int, error) {
+	pending, err := app.repo.ClaimOutboxEvents(ctx, outboxBatchSize, outboxLease)
+	if err != nil {
+		return 0, err
+	}
+
+	sendCtx, cancel := context.WithTimeout(ctx, outboxSendTimeout)
+	defer cancel()
+
+	var processed []string
+	var sendErr error
+	for i, model := range pending {
+		data, err := app.registry.DecodeVersion(model.Name, model.SchemaVersion, model.Data)
+		if err != nil {
+			// the event can't be restored, so retrying is pointless
+			log.Println("dropping malformed outbox event:", model.ID, err)
+			processed = append(processed, model.ID)
+			continue
+		}
+
+		e := event.Event{
+			ID:        model.ID,
+			Name:      model.Name,
+			Data:      data,
+			TimeStamp: model.TimeStamp,
+			ChatID:    model.ChatID,
+			UserIDs:   model.UserIDs,
+		}
+		if sendErr = app.events.Send(sendCtx, e); sendErr != nil {
+			// the rest is released instead of waiting for the lease,
+			// so it's retried before the newer events
+			app.releaseOutboxEvents(ctx, pending[i:])
+			break
+		}
+		processed = append(processed, model.ID)
+	}
+
+	if len(processed) > 0 {
+		if err := app.repo.DeleteOutboxEvents(ctx, processed); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(processed), sendErr
+}
+
+// releaseOutboxEvents returns the claimed events to the outbox,
+// they are claimed again when their leases run out if it fails
+func (app *App) releaseOutboxEvents(ctx context.Context, events []models.OutboxEvent) {
+	ids := make([]string, 0, len(events))
+	for _, e := range events {
+		ids = append(ids, e.ID)
+	}
+	if err := app.repo.ReleaseOutboxEvents(ctx, ids); err != nil && ctx.Err() == nil {
+		log.Println("failed to release outbox events:", err)
+	}
+}
@@ -0,0 +1,84 @@
+package app
+
+import (
+	"context"
+	goerrors "errors"
+	"github.com/ischenkx/vk-test-task/internal/app/data"
+	"github.com/ischenkx/vk-test-task/internal/app/data/models"
+	"github.com/ischenkx/vk-test-task/internal/app/event"
+	"testing"
+	"time"
+)
+
+// outboxRepo hands out its events once and records what the relay does with them
+type outboxRepo struct {
+	data.Repository
+
+	events   []models.OutboxEvent
+	deleted  []string
+	released []string
+}
+
+func (r *outboxRepo) ClaimOutboxEvents(ctx context.Context, count int, lease time.Duration) ([]models.OutboxEvent, error) {
+	events := r.events
+	r.events = nil
+	return events, nil
+}
+
+func (r *outboxRepo) DeleteOutboxEvents(ctx context.Context, ids []string) error {
+	r.deleted = append(r.deleted, ids...)
+	return nil
+}
+
+func (r *outboxRepo) ReleaseOutboxEvents(ctx context.Context, ids []string) error {
+	r.released = append(r.released, ids...)
+	return nil
+}
+
+// failingBus accepts the first events and fails to send the rest
+type failingBus struct {
+	event.Bus
+
+	accept int
+	sent   []string
+}
+
+func (b *failingBus) Send(ctx context.Context, e event.Event) error {
+	if _, ok := ctx.Deadline(); !ok {
+		return goerrors.New("the send isn't bounded")
+	}
+	if len(b.sent) == b.accept {
+		return goerrors.New("bus unavailable")
+	}
+	b.sent = append(b.sent, e.ID)
+	return nil
+}
+
+func TestRelayReleasesUnsentEvents(t *testing.T) {
+	repo := &outboxRepo{}
+	bus := &failingBus{accept: 1}
+	app := New(Config{Repo: repo, PasswordHasher: plainHasher{}, Bus: bus})
+
+	for _, id := range []string{"first", "second", "third"} {
+		repo.events = append(repo.events, models.OutboxEvent{
+			ID:            id,
+			Name:          ChatMemberCreatedEventName,
+			Data:          []byte(`{"user_id":"user","chat_id":"chat"}`),
+			SchemaVersion: app.registry.Version(ChatMemberCreatedEventName),
+		})
+	}
+
+	n, err := app.relayOutbox(context.Background())
+	if err == nil {
+		t.Fatal("expected the send error")
+	}
+	if n != 1 || len(bus.sent) != 1 {
+		t.Fatalf("expected a single event to be sent, got %d", n)
+	}
+	if len(repo.deleted) != 1 || repo.deleted[0] != "first" {
+		t.Fatalf("expected the sent event to be deleted, got %v", repo.deleted)
+	}
+	if len(repo.released) != 2 || repo.released[0] != "second" || repo.released[1] != "third" {
+		t.Fatalf("expected the unsent events to be released, got %v", repo.released)
+	}
+}
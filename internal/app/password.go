@@ -0,0 +1,282 @@
+package app
+
+import (
+	"context"
+	goerrors "errors"
+	"fmt"
+	"github.com/ischenkx/vk-test-task/internal/app/data"
+	"github.com/ischenkx/vk-test-task/internal/app/data/models"
+	"github.com/ischenkx/vk-test-task/internal/app/errors"
+	"github.com/ischenkx/vk-test-task/internal/app/forms"
+	"github.com/ischenkx/vk-test-task/internal/app/security"
+	"log"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const defaultPasswordMinLength = 8
+const defaultPasswordMaxLength = 128
+const passwordResetTTL = 30 * time.Minute
+const passwordResetSecretSize = 32
+const passwordResetNotifyTimeout = 30 * time.Second
+
+// maxPasswordResets limits the resets requested per username an hour,
+// every request sends a notification to the user
+const maxPasswordResets = 3
+
+var ErrInvalidPassword = goerrors.New("invalid password")
+var ErrInvalidPasswordReset = goerrors.New("invalid or expired password reset token")
+var ErrPasswordResetUnavailable = goerrors.New("password reset is not available")
+
+// PasswordPolicy is what new passwords must satisfy,
+// the passwords set before it has changed keep working
+type PasswordPolicy struct {
+	// MinLength is the minimum number of characters (8 by default)
+	MinLength int
+	// MaxLength is the maximum number of characters (128 by default)
+	MaxLength int
+	// Denylist holds the passwords known from breaches, they are compared case-insensitively
+	Denylist []string
+}
+
+type passwordPolicy struct {
+	minLength int
+	maxLength int
+	denylist  map[string]struct{}
+}
+
+func newPasswordPolicy(cfg PasswordPolicy) passwordPolicy {
+	policy := passwordPolicy{
+		minLength: cfg.MinLength,
+		maxLength: cfg.MaxLength,
+		denylist:  make(map[string]struct{}, len(cfg.Denylist)),
+	}
+	if policy.minLength <= 0 {
+		policy.minLength = defaultPasswordMinLength
+	}
+	if policy.maxLength <= 0 {
+		policy.maxLength = defaultPasswordMaxLength
+	}
+	for _, password := range cfg.Denylist {
+		policy.denylist[strings.ToLower(password)] = struct{}{}
+	}
+	return policy
+}
+
+func (policy passwordPolicy) check(password string) error {
+	length := utf8.RuneCountInString(password)
+	if length < policy.minLength {
+		return fmt.Errorf("the password must be at least %d characters long", policy.minLength)
+	}
+	if length > policy.maxLength {
+		return fmt.Errorf("the password must be at most %d characters long", policy.maxLength)
+	}
+	if _, ok := policy.denylist[strings.ToLower(password)]; ok {
+		return goerrors.New("the password is known from data breaches, choose another one")
+	}
+	return nil
+}
+
+func hashPassword(app *App, password string) ([]byte, error) {
+	hash, err := app.passwordHasher.Hash(password)
+	if err != nil {
+		return nil, goerrors.New("failed to hash the password")
+	}
+	return hash, nil
+}
+
+// checkDummyPassword is called when there is no password to check,
+// so that the missing users can't be told apart by the time the login takes
+func checkDummyPassword(app *App, password string) {
+	_, _, _ = app.passwordHasher.Verify(app.dummyPasswordHash, password)
+}
+
+// checkPassword fails for the users without a password (e.g. the ones provisioned by identity providers).
+// Rehash reports that the hash is outdated, see rehashPassword.
+func checkPassword(app *App, model models.User, password string) (rehash bool, err error) {
+	if len(model.PasswordHash) == 0 {
+		checkDummyPassword(app, password)
+		return false, ErrInvalidPassword
+	}
+
+	ok, rehash, err := app.passwordHasher.Verify(model.PasswordHash, password)
+	if err != nil {
+		log.Println("failed to verify a password:", err)
+		return false, ErrInvalidPassword
+	}
+	if !ok {
+		return false, ErrInvalidPassword
+	}
+	return rehash, nil
+}
+
+// rehashPassword replaces an outdated hash with the one made with the current settings,
+// it's done on login when the password is known. The login doesn't fail if it fails.
+// The hash is replaced only if it's still the verified one, so a concurrent change of
+// the password or the username isn't overwritten.
+func rehashPassword(ctx *Context, app *App, model models.User, password string) {
+	hash, err := hashPassword(app, password)
+	if err != nil {
+		log.Println("failed to rehash a password:", err)
+		return
+	}
+
+	if err := app.repo.UpdateUserPasswordHash(ctx, model.ID, model.PasswordHash, hash); err != nil {
+		log.Println("failed to rehash a password:", err)
+	}
+}
+
+// changePassword sets a new password and logs the user out of the other sessions.
+// The users without a password set their first one with an empty old password.
+func changePassword(ctx *Context, app *App, model models.User, form forms.PasswordChange) error {
+	if err := form.Validate(); err != nil {
+		return err
+	}
+
+	if len(model.PasswordHash) > 0 || form.OldPassword != "" {
+		if _, err := checkPassword(app, model, form.OldPassword); err != nil {
+			return err
+		}
+	}
+
+	if err := app.passwordPolicy.check(form.NewPassword); err != nil {
+		return err
+	}
+
+	hash, err := hashPassword(app, form.NewPassword)
+	if err != nil {
+		return err
+	}
+
+	// only the hash is written, so a concurrent change of the other fields isn't overwritten,
+	// and the sessions are revoked along with it
+	ids, err := app.repo.Transaction(ctx, func(tx data.Tx) (interface{}, error) {
+		if err := tx.SetUserPasswordHash(ctx, model.ID, hash); err != nil {
+			return nil, err
+		}
+		if err := tx.DeletePasswordReset(ctx, model.ID); err != nil {
+			return nil, err
+		}
+		if ctx.Session() == "" {
+			return tx.DeleteUserSessions(ctx, model.ID)
+		}
+		return tx.DeleteOtherUserSessions(ctx, model.ID, ctx.Session())
+	})
+	if err != nil {
+		return err
+	}
+
+	forgetSessions(app, ids.([]string))
+	return nil
+}
+
+// requestPasswordReset sends a reset token to the user.
+//
+// It succeeds whether the user exists or not, so that it can't be used to find out the usernames,
+// and the notification is sent in the background for the same reason.
+func requestPasswordReset(ctx *Context, app *App, username string) error {
+	if app.notifier == nil {
+		return ErrPasswordResetUnavailable
+	}
+
+	if !app.passwordResetLimiter.Allow(strings.ToLower(username)) {
+		return errors.RateLimited
+	}
+
+	model, err := app.repo.GetUserByUsername(ctx, username)
+	if err != nil || model.Kind != models.UserKindRegular {
+		return nil
+	}
+
+	secret, err := generateToken(passwordResetSecretSize)
+	if err != nil {
+		return err
+	}
+
+	reset, err := app.repo.CreatePasswordReset(ctx, models.PasswordReset{
+		UserID:    model.ID,
+		TokenHash: hashToken(secret),
+		ExpiresAt: time.Now().Add(passwordResetTTL),
+	})
+	if err != nil {
+		return err
+	}
+
+	notification := security.Notification{
+		UserID:   model.ID,
+		Username: model.Username,
+		Subject:  "Password reset",
+		Body: fmt.Sprintf("Someone has requested a password reset for %s.\n\n"+
+			"Reset token: %s\n\n"+
+			"The token expires in %s. If it wasn't you, ignore this message.\n",
+			model.Username, reset.ID+":"+secret, passwordResetTTL),
+	}
+	if identities, err := app.repo.GetUserExternalIdentities(ctx, model.ID); err == nil {
+		for _, identity := range identities {
+			if identity.Email != "" {
+				notification.Email = identity.Email
+				break
+			}
+		}
+	}
+
+	go func() {
+		notifyCtx, cancel := context.WithTimeout(context.Background(), passwordResetNotifyTimeout)
+		defer cancel()
+		if err := app.notifier.Notify(notifyCtx, notification); err != nil {
+			log.Println("failed to send a password reset:", err)
+		}
+	}()
+
+	return nil
+}
+
+// resetPassword sets a new password with a reset token and logs the user out everywhere,
+// the token can be used once
+func resetPassword(ctx *Context, app *App, form forms.PasswordReset) error {
+	if err := form.Validate(); err != nil {
+		return err
+	}
+
+	// checked before the token is taken, so that the token isn't lost to a weak password
+	if err := app.passwordPolicy.check(form.NewPassword); err != nil {
+		return err
+	}
+
+	id, secret, ok := strings.Cut(form.Token, ":")
+	if !ok {
+		return ErrInvalidPasswordReset
+	}
+
+	reset, err := app.repo.TakePasswordReset(ctx, id, hashToken(secret))
+	if err != nil {
+		return ErrInvalidPasswordReset
+	}
+
+	model, err := app.repo.GetUser(ctx, reset.UserID)
+	if err != nil {
+		return ErrInvalidPasswordReset
+	}
+
+	hash, err := hashPassword(app, form.NewPassword)
+	if err != nil {
+		return err
+	}
+
+	ids, err := app.repo.Transaction(ctx, func(tx data.Tx) (interface{}, error) {
+		if err := tx.SetUserPasswordHash(ctx, model.ID, hash); err != nil {
+			return nil, err
+		}
+		return tx.DeleteUserSessions(ctx, model.ID)
+	})
+	if err != nil {
+		return err
+	}
+	forgetSessions(app, ids.([]string))
+
+	// the owner has proven the access, the lockout caused by someone else is lifted
+	loginSucceeded(ctx, app, model.Username)
+
+	return nil
+}
@@ -0,0 +1,70 @@
+package app
+
+import (
+	"context"
+	"github.com/ischenkx/vk-test-task/internal/app/data"
+	"github.com/ischenkx/vk-test-task/internal/app/data/models"
+	"github.com/ischenkx/vk-test-task/internal/app/forms"
+	"testing"
+)
+
+// passwordRepo keeps a user and its sessions, UpdateUser isn't implemented
+// as the password changes must not write the whole user
+type passwordRepo struct {
+	data.Repository
+
+	user     models.User
+	sessions map[string]bool
+}
+
+func (r *passwordRepo) Transaction(ctx context.Context, f func(data.Tx) (interface{}, error)) (interface{}, error) {
+	return f(r)
+}
+
+func (r *passwordRepo) SetUserPasswordHash(ctx context.Context, id string, hash []byte) error {
+	r.user.PasswordHash = hash
+	return nil
+}
+
+func (r *passwordRepo) DeletePasswordReset(ctx context.Context, userID string) error {
+	return nil
+}
+
+func (r *passwordRepo) DeleteOtherUserSessions(ctx context.Context, userID string, keepID string) ([]string, error) {
+	var ids []string
+	for id := range r.sessions {
+		if id != keepID {
+			delete(r.sessions, id)
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+func TestChangePasswordKeepsConcurrentUpdates(t *testing.T) {
+	repo := &passwordRepo{
+		user:     models.User{ID: "user", Username: "renamed", PasswordHash: []byte("old password")},
+		sessions: map[string]bool{"current": true, "other": true},
+	}
+	app := New(Config{Repo: repo, PasswordHasher: plainHasher{}, Authorizer: stubAuthorizer{}})
+
+	ctx := NewContext(context.Background())
+	ctx.SetSession("current")
+
+	// the model has been read before the user was renamed
+	stale := models.User{ID: "user", Username: "original", PasswordHash: []byte("old password")}
+	err := changePassword(ctx, app, stale, forms.PasswordChange{OldPassword: "old password", NewPassword: "new password"})
+	if err != nil {
+		t.Fatalf("failed to change the password: %s", err)
+	}
+
+	if repo.user.Username != "renamed" {
+		t.Fatalf("expected the username to stay %q, got %q", "renamed", repo.user.Username)
+	}
+	if string(repo.user.PasswordHash) != "new password" {
+		t.Fatalf("expected the new password hash, got %q", repo.user.PasswordHash)
+	}
+	if len(repo.sessions) != 1 || !repo.sessions["current"] {
+		t.Fatalf("expected only the current session to remain, got %v", repo.sessions)
+	}
+}
@@ -0,0 +1,119 @@
+package app
+
+import (
+	"context"
+	"github.com/ischenkx/vk-test-task/internal/app/errors"
+	"github.com/ischenkx/vk-test-task/internal/app/forms"
+	"github.com/ischenkx/vk-test-task/internal/app/security"
+	"testing"
+)
+
+func TestDomainChecksScopes(t *testing.T) {
+	app := &App{}
+
+	tests := []struct {
+		name  string
+		scope string
+		call  func(ctx *Context) error
+	}{
+		{
+			name:  "send message",
+			scope: security.ScopeMessagesWrite,
+			call: func(ctx *Context) error {
+				_, err := chatMember{app: app, chatID: "chat", userID: "user"}.SendMessage(ctx, forms.SendMessage{Payload: "hi"})
+				return err
+			},
+		},
+		{
+			name:  "add member",
+			scope: security.ScopeChatsWrite,
+			call: func(ctx *Context) error {
+				_, err := chat{app: app, id: "chat"}.Add(ctx, "user", ChatMemberRegularStatus)
+				return err
+			},
+		},
+		{
+			name:  "kick member",
+			scope: security.ScopeChatsWrite,
+			call: func(ctx *Context) error {
+				return chatMember{app: app, chatID: "chat", userID: "user"}.Delete(ctx)
+			},
+		},
+		{
+			name:  "create webhook",
+			scope: security.ScopeWebhooks,
+			call: func(ctx *Context) error {
+				_, err := user{app: app, userID: "user"}.CreateWebhook(ctx, forms.WebhookCreation{})
+				return err
+			},
+		},
+		{
+			name:  "delete bot",
+			scope: security.ScopeBots,
+			call: func(ctx *Context) error {
+				return bot{app: app, id: "bot"}.Delete(ctx)
+			},
+		},
+		{
+			name:  "send friend request",
+			scope: security.ScopeFriendsWrite,
+			call: func(ctx *Context) error {
+				_, err := user{app: app, userID: "user"}.SendFriendRequest(ctx, "friend")
+				return err
+			},
+		},
+		{
+			name:  "list members",
+			scope: security.ScopeChatsRead,
+			call: func(ctx *Context) error {
+				_, err := chat{app: app, id: "chat"}.Members(ctx, 0, 10)
+				return err
+			},
+		},
+		{
+			name:  "list messages",
+			scope: security.ScopeMessagesRead,
+			call: func(ctx *Context) error {
+				_, err := chat{app: app, id: "chat"}.Messages(ctx, 0, 10)
+				return err
+			},
+		},
+		{
+			name:  "list chats",
+			scope: security.ScopeChatsRead,
+			call: func(ctx *Context) error {
+				_, err := user{app: app, userID: "user"}.Chats(ctx, 0, 10)
+				return err
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := NewContext(context.Background())
+			ctx.SetUser(user{app: app, userID: "user"})
+			ctx.SetScopes([]string{security.ScopeUsersRead})
+
+			if err := test.call(ctx); err != errors.NotAuthorized {
+				t.Fatalf("expected %v without %s, got %v", errors.NotAuthorized, test.scope, err)
+			}
+		})
+	}
+}
+
+func TestMessageWebhooksRequireMessagesScope(t *testing.T) {
+	app := &App{}
+	ctx := NewContext(context.Background())
+	ctx.SetUser(user{app: app, userID: "user"})
+	ctx.SetScopes([]string{security.ScopeWebhooks})
+
+	for _, events := range [][]string{nil, {NewMessageEventName}, {FriendAddedEventName, NewChannelPostEventName}} {
+		_, err := user{app: app, userID: "user"}.CreateWebhook(ctx, forms.WebhookCreation{
+			URL:    "https://example.com/hook",
+			Events: events,
+		})
+		if err != errors.NotAuthorized {
+			t.Fatalf("expected %v for %v, got %v", errors.NotAuthorized, events, err)
+		}
+	}
+}
@@ -4,7 +4,24 @@ import (
 	"context"
 )
 
+// Claims is what an access token tells about its holder
+type Claims struct {
+	UserID string
+	// SessionID is the session the token has been issued for (the "jti" claim)
+	SessionID string
+}
+
 type Authorizer interface {
-	Verify(ctx context.Context, token string) (userId string, err error)
-	GenerateToken(ctx context.Context, userId string) (token string, err error)
+	Verify(ctx context.Context, token string) (Claims, error)
+	GenerateToken(ctx context.Context, claims Claims) (token string, err error)
+	// Forget drops whatever the authorizer remembers about the session,
+	// it's called when the session is revoked
+	Forget(sessionId string)
+}
+
+// SessionStore lets authorizers reject the tokens of revoked sessions before they expire
+type SessionStore interface {
+	// SessionActive reports whether the session exists and hasn't expired,
+	// the session is considered seen at the moment
+	SessionActive(ctx context.Context, id string) (bool, error)
 }
@@ -0,0 +1,27 @@
+package security
+
+import "context"
+
+// Identity is an account at an external identity provider
+type Identity struct {
+	Provider string
+	// Subject is the id of the account at the provider, it never changes
+	Subject string
+	// Username is the preferred username, it can be empty or taken by another user
+	Username      string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+// IdentityProvider lets users log in with their accounts at other services
+// using the authorization code flow with PKCE (see impl/identity/oidc)
+type IdentityProvider interface {
+	// Name identifies the provider in the API and in the linked identities
+	Name() string
+	// AuthURL returns the page the user is sent to, the provider redirects
+	// the user back with the state and a code that is exchanged for the identity
+	AuthURL(ctx context.Context, state, nonce, codeChallenge string) (string, error)
+	// Exchange exchanges the code for the identity, the ID token must carry the nonce
+	Exchange(ctx context.Context, code, codeVerifier, nonce string) (Identity, error)
+}
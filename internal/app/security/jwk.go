@@ -0,0 +1,23 @@
+package security
+
+// JWK is a public key in the JSON Web Key format (RFC 7517)
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	// RSA keys
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// OKP (EdDSA) and EC keys
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// KeyPublisher is implemented by the authorizers signing tokens with asymmetric keys,
+// so that other services can verify the tokens on their own
+type KeyPublisher interface {
+	// PublicKeys returns the keys the tokens can currently be verified with
+	PublicKeys() []JWK
+}
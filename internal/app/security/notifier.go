@@ -0,0 +1,18 @@
+package security
+
+import "context"
+
+// Notification is a message sent to a user outside the application, e.g. a password reset link
+type Notification struct {
+	UserID   string
+	Username string
+	// Email is the address of the user if it's known (it's taken from the linked identities)
+	Email   string
+	Subject string
+	Body    string
+}
+
+// Notifier delivers notifications to users (see impl/notify)
+type Notifier interface {
+	Notify(ctx context.Context, notification Notification) error
+}
@@ -0,0 +1,10 @@
+package security
+
+// PasswordHasher hashes the passwords into strings that carry the algorithm and its parameters,
+// so that the hashes made with other settings can still be verified (see impl/passwords/passhash)
+type PasswordHasher interface {
+	Hash(password string) ([]byte, error)
+	// Verify reports whether the password matches the hash. Rehash tells that the hash is made
+	// with another algorithm or outdated parameters and should be replaced by a new one.
+	Verify(hash []byte, password string) (ok bool, rehash bool, err error)
+}
@@ -0,0 +1,30 @@
+package security
+
+// The scopes of API keys, a key can only do what its scopes allow
+const (
+	ScopeUsersRead     = "users:read"
+	ScopeFriendsRead   = "friends:read"
+	ScopeFriendsWrite  = "friends:write"
+	ScopeChatsRead     = "chats:read"
+	ScopeChatsWrite    = "chats:write"
+	ScopeMessagesRead  = "messages:read"
+	ScopeMessagesWrite = "messages:write"
+	ScopeWebhooks      = "webhooks:manage"
+	ScopeBots          = "bots:manage"
+)
+
+var scopes = map[string]bool{
+	ScopeUsersRead:     true,
+	ScopeFriendsRead:   true,
+	ScopeFriendsWrite:  true,
+	ScopeChatsRead:     true,
+	ScopeChatsWrite:    true,
+	ScopeMessagesRead:  true,
+	ScopeMessagesWrite: true,
+	ScopeWebhooks:      true,
+	ScopeBots:          true,
+}
+
+func IsScope(scope string) bool {
+	return scopes[scope]
+}
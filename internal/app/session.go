@@ -0,0 +1,306 @@
+package app
+
+import (
+	"context"
+	"crypto/subtle"
+	goerrors "errors"
+	"github.com/ischenkx/vk-test-task/internal/app/data"
+	"github.com/ischenkx/vk-test-task/internal/app/data/models"
+	"github.com/ischenkx/vk-test-task/internal/app/errors"
+	"github.com/ischenkx/vk-test-task/internal/app/security"
+	"strings"
+	"time"
+)
+
+const refreshTokenSecretSize = 32
+const defaultRefreshTokenTTL = 30 * 24 * time.Hour
+const maxUserAgentSize = 256
+
+// ErrRefreshTokenReused is returned when an already exchanged refresh token is presented again,
+// the session of the token is revoked as the token has probably been stolen
+var ErrRefreshTokenReused = goerrors.New("refresh token reuse detected, the session is revoked")
+
+// TokenPair is a short-lived access token (see security.Authorizer)
+// along with a long-lived refresh token used to get the next pair
+type TokenPair struct {
+	SessionID    string
+	AccessToken  string
+	RefreshToken string
+	// RefreshExpiresAt is when the refresh token expires if it's not used
+	RefreshExpiresAt time.Time
+}
+
+// Session is a login of a user on some device.
+//
+// It lives as long as its refresh token is in use, the access tokens
+// issued for it are rejected as soon as it's revoked.
+type Session interface {
+	ID() string
+	Model(ctx *Context) (models.Session, error)
+	// Current reports whether the request is authorized with the session
+	Current(ctx *Context) bool
+	Revoke(ctx *Context) error
+}
+
+type session struct {
+	app *App
+	id  string
+}
+
+func (s session) model(ctx *Context) (models.Session, error) {
+	return s.app.repo.GetSession(ctx, s.id)
+}
+
+func (s session) exists(ctx *Context) bool {
+	if _, err := s.model(ctx); err != nil {
+		return false
+	}
+	return true
+}
+
+// isWritable reports whether the session belongs to the current user
+func (s session) isWritable(ctx *Context) bool {
+	if ctx.User() == nil {
+		return false
+	}
+	model, err := s.model(ctx)
+	if err != nil {
+		return false
+	}
+	return model.UserID == ctx.User().ID()
+}
+
+func (s session) ID() string {
+	return s.id
+}
+
+func (s session) Model(ctx *Context) (models.Session, error) {
+	if !s.isWritable(ctx) {
+		return models.Session{}, errors.ResourceInaccessible
+	}
+	return s.model(ctx)
+}
+
+func (s session) Current(ctx *Context) bool {
+	return ctx.Session() == s.id
+}
+
+func (s session) Revoke(ctx *Context) error {
+	if !s.isWritable(ctx) {
+		return errors.ResourceInaccessible
+	}
+	return revokeSession(ctx, s.app, s.id)
+}
+
+// newRefreshToken returns a token of the form "<session id>:<secret>",
+// only the hash of the secret is stored
+func newRefreshToken(sessionID string) (token string, secretHash string, err error) {
+	secret, err := generateToken(refreshTokenSecretSize)
+	if err != nil {
+		return "", "", err
+	}
+	return sessionID + ":" + secret, hashToken(secret), nil
+}
+
+// createSession logs the user in on the device the request comes from
+func createSession(ctx *Context, app *App, userID string) (TokenPair, error) {
+	secret, err := generateToken(refreshTokenSecretSize)
+	if err != nil {
+		return TokenPair{}, err
+	}
+
+	client := ctx.Client()
+	if len(client.UserAgent) > maxUserAgentSize {
+		client.UserAgent = strings.ToValidUTF8(client.UserAgent[:maxUserAgentSize], "")
+	}
+
+	model, err := app.repo.CreateSession(ctx, models.Session{
+		UserID:    userID,
+		TokenHash: hashToken(secret),
+		UserAgent: client.UserAgent,
+		IP:        client.IP,
+		ExpiresAt: time.Now().Add(app.refreshTokenTTL),
+	})
+	if err != nil {
+		return TokenPair{}, err
+	}
+
+	accessToken, err := app.authorizer.GenerateToken(ctx, security.Claims{
+		UserID:    userID,
+		SessionID: model.ID,
+	})
+	if err != nil {
+		return TokenPair{}, err
+	}
+
+	return TokenPair{
+		SessionID:        model.ID,
+		AccessToken:      accessToken,
+		RefreshToken:     model.ID + ":" + secret,
+		RefreshExpiresAt: model.ExpiresAt,
+	}, nil
+}
+
+// refreshSession exchanges the refresh token for a new pair (the token is rotated).
+//
+// A session accepts only its latest token. A token the session has issued before has already
+// been exchanged: that's a replay and the session is revoked. Other tokens are just rejected,
+// as anyone who knows the id of the session can make them up.
+func refreshSession(ctx *Context, app *App, token string) (TokenPair, error) {
+	sessionID, secret, ok := strings.Cut(token, ":")
+	if !ok {
+		return TokenPair{}, errors.NotAuthorized
+	}
+
+	model, err := app.repo.GetSession(ctx, sessionID)
+	if err != nil {
+		return TokenPair{}, errors.NotAuthorized
+	}
+
+	hash := hashToken(secret)
+	if subtle.ConstantTimeCompare([]byte(hash), []byte(model.TokenHash)) != 1 {
+		if !issuedBefore(model, hash) {
+			return TokenPair{}, errors.NotAuthorized
+		}
+		if err := revokeSession(ctx, app, model.ID); err != nil {
+			return TokenPair{}, err
+		}
+		return TokenPair{}, ErrRefreshTokenReused
+	}
+
+	if time.Now().After(model.ExpiresAt) {
+		if err := revokeSession(ctx, app, model.ID); err != nil {
+			return TokenPair{}, err
+		}
+		return TokenPair{}, errors.NotAuthorized
+	}
+
+	next, nextHash, err := newRefreshToken(model.ID)
+	if err != nil {
+		return TokenPair{}, err
+	}
+
+	model, err = app.repo.RotateSession(ctx, model.ID, model.TokenHash, nextHash, ctx.Client().IP, time.Now().Add(app.refreshTokenTTL))
+	if err != nil {
+		// another request has exchanged the same token in the meantime
+		if err := revokeSession(ctx, app, sessionID); err != nil {
+			return TokenPair{}, err
+		}
+		return TokenPair{}, ErrRefreshTokenReused
+	}
+
+	accessToken, err := app.authorizer.GenerateToken(ctx, security.Claims{
+		UserID:    model.UserID,
+		SessionID: model.ID,
+	})
+	if err != nil {
+		return TokenPair{}, err
+	}
+
+	return TokenPair{
+		SessionID:        model.ID,
+		AccessToken:      accessToken,
+		RefreshToken:     next,
+		RefreshExpiresAt: model.ExpiresAt,
+	}, nil
+}
+
+// issuedBefore reports whether the hash belongs to a token the session has replaced
+func issuedBefore(model models.Session, hash string) bool {
+	for _, previous := range model.PreviousTokenHashes {
+		if subtle.ConstantTimeCompare([]byte(hash), []byte(previous)) == 1 {
+			return true
+		}
+	}
+	return false
+}
+
+// revokeRefreshToken revokes the session of the token
+func revokeRefreshToken(ctx *Context, app *App, token string) error {
+	sessionID, secret, ok := strings.Cut(token, ":")
+	if !ok {
+		return errors.NotAuthorized
+	}
+
+	model, err := app.repo.GetSession(ctx, sessionID)
+	if err != nil {
+		return errors.NotAuthorized
+	}
+
+	if subtle.ConstantTimeCompare([]byte(hashToken(secret)), []byte(model.TokenHash)) != 1 {
+		return errors.NotAuthorized
+	}
+
+	return revokeSession(ctx, app, model.ID)
+}
+
+func revokeSession(ctx *Context, app *App, id string) error {
+	if err := app.repo.DeleteSession(ctx, id); err != nil {
+		return err
+	}
+	app.authorizer.Forget(id)
+	return nil
+}
+
+// revokeUserSessions logs the user out everywhere
+func revokeUserSessions(ctx *Context, app *App, userID string) error {
+	ids, err := app.repo.DeleteUserSessions(ctx, userID)
+	if err != nil {
+		return err
+	}
+	forgetSessions(app, ids)
+	return nil
+}
+
+// forgetSessions drops the deleted sessions from the cache of the authorizer
+func forgetSessions(app *App, ids []string) {
+	for _, id := range ids {
+		app.authorizer.Forget(id)
+	}
+}
+
+type sessionStore struct {
+	repo data.Repository
+}
+
+// NewSessionStore lets authorizers check the sessions kept in the repository
+func NewSessionStore(repo data.Repository) security.SessionStore {
+	return sessionStore{repo: repo}
+}
+
+// SessionActive treats a session it fails to find as inactive,
+// so the tokens are rejected while the repository is unavailable
+func (store sessionStore) SessionActive(ctx context.Context, id string) (bool, error) {
+	if _, err := store.repo.TouchSession(ctx, id); err != nil {
+		return false, nil
+	}
+	return true, nil
+}
+
+func sessionsFromModels(app *App, rawSessions []models.Session) []Session {
+	sessions := make([]Session, 0, len(rawSessions))
+	for _, model := range rawSessions {
+		sessions = append(sessions, unsafeSessionFromModel(app, model))
+	}
+	return sessions
+}
+
+func unsafeSessionFromModel(app *App, model models.Session) Session {
+	return session{
+		app: app,
+		id:  model.ID,
+	}
+}
+
+func newSession(ctx *Context, app *App, id string) (Session, error) {
+	s := session{
+		app: app,
+		id:  id,
+	}
+
+	if !s.exists(ctx) {
+		return nil, errors.DoesNotExist
+	}
+
+	return s, nil
+}
@@ -0,0 +1,108 @@
+package app
+
+import (
+	"context"
+	"github.com/ischenkx/vk-test-task/internal/app/data"
+	"github.com/ischenkx/vk-test-task/internal/app/data/models"
+	"github.com/ischenkx/vk-test-task/internal/app/errors"
+	"github.com/ischenkx/vk-test-task/internal/app/security"
+	"testing"
+	"time"
+)
+
+// sessionRepo keeps a single session
+type sessionRepo struct {
+	data.Repository
+
+	session *models.Session
+}
+
+func (r *sessionRepo) CreateSession(ctx context.Context, session models.Session) (models.Session, error) {
+	session.ID = "session"
+	r.session = &session
+	return session, nil
+}
+
+func (r *sessionRepo) GetSession(ctx context.Context, id string) (models.Session, error) {
+	if r.session == nil || r.session.ID != id {
+		return models.Session{}, errNotFound
+	}
+	return *r.session, nil
+}
+
+func (r *sessionRepo) RotateSession(ctx context.Context, id string, tokenHash string, newTokenHash string, ip string, expiresAt time.Time) (models.Session, error) {
+	if r.session == nil || r.session.ID != id || r.session.TokenHash != tokenHash {
+		return models.Session{}, errNotFound
+	}
+	r.session.PreviousTokenHashes = append(r.session.PreviousTokenHashes, tokenHash)
+	r.session.TokenHash = newTokenHash
+	r.session.ExpiresAt = expiresAt
+	return *r.session, nil
+}
+
+func (r *sessionRepo) DeleteSession(ctx context.Context, id string) error {
+	r.session = nil
+	return nil
+}
+
+// stubAuthorizer issues the session id as the access token
+type stubAuthorizer struct{}
+
+func (stubAuthorizer) Verify(ctx context.Context, token string) (security.Claims, error) {
+	return security.Claims{SessionID: token}, nil
+}
+
+func (stubAuthorizer) GenerateToken(ctx context.Context, claims security.Claims) (string, error) {
+	return claims.SessionID, nil
+}
+
+func (stubAuthorizer) Forget(sessionId string) {}
+
+func setupSession(t *testing.T) (*App, *sessionRepo, TokenPair) {
+	t.Helper()
+
+	repo := &sessionRepo{}
+	app := &App{repo: repo, authorizer: stubAuthorizer{}, refreshTokenTTL: time.Hour}
+
+	tokens, err := createSession(NewContext(context.Background()), app, "user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return app, repo, tokens
+}
+
+func TestRefreshRejectsForgedToken(t *testing.T) {
+	app, repo, tokens := setupSession(t)
+
+	_, err := refreshSession(NewContext(context.Background()), app, tokens.SessionID+":forged")
+	if err != errors.NotAuthorized {
+		t.Fatalf("expected %v, got %v", errors.NotAuthorized, err)
+	}
+	if repo.session == nil {
+		t.Fatal("a forged token has revoked the session")
+	}
+
+	if _, err := refreshSession(NewContext(context.Background()), app, tokens.RefreshToken); err != nil {
+		t.Fatalf("failed to refresh the session: %s", err)
+	}
+}
+
+func TestRefreshRevokesOnReplay(t *testing.T) {
+	app, repo, tokens := setupSession(t)
+
+	next, err := refreshSession(NewContext(context.Background()), app, tokens.RefreshToken)
+	if err != nil {
+		t.Fatalf("failed to refresh the session: %s", err)
+	}
+	if next.RefreshToken == tokens.RefreshToken {
+		t.Fatal("the refresh token hasn't been rotated")
+	}
+
+	_, err = refreshSession(NewContext(context.Background()), app, tokens.RefreshToken)
+	if err != ErrRefreshTokenReused {
+		t.Fatalf("expected %v, got %v", ErrRefreshTokenReused, err)
+	}
+	if repo.session != nil {
+		t.Fatal("the session hasn't been revoked after the replay")
+	}
+}
@@ -0,0 +1,338 @@
+package app
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	goerrors "errors"
+	"fmt"
+	"github.com/ischenkx/vk-test-task/internal/app/data"
+	"github.com/ischenkx/vk-test-task/internal/app/data/models"
+	"github.com/ischenkx/vk-test-task/internal/app/errors"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const totpPeriod = 30 * time.Second
+const totpDigits = 6
+const totpSecretSize = 20
+
+// totpSkew is how many steps a code can be off by, it makes up for the clocks of phones
+const totpSkew = 1
+
+const defaultTOTPIssuer = "vk-test-task"
+const recoveryCodesCount = 10
+const recoveryCodeSize = 10
+const loginChallengeTTL = 5 * time.Minute
+const loginChallengeSecretSize = 32
+const maxLoginChallengeAttempts = 5
+
+// maxSecondFactorAttempts limits the codes a user can try per minute,
+// six digits are guessed quickly otherwise
+const maxSecondFactorAttempts = 5
+
+var ErrInvalidTwoFactorCode = goerrors.New("invalid two-factor code")
+var ErrInvalidLoginChallenge = goerrors.New("invalid or expired login challenge")
+var ErrTwoFactorEnabled = goerrors.New("two-factor authentication is already enabled")
+var ErrTwoFactorDisabled = goerrors.New("two-factor authentication isn't enabled")
+
+var base32NoPadding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// TwoFactorEnrollment is what an authenticator app needs, URI is usually shown as a QR code
+type TwoFactorEnrollment struct {
+	Secret string
+	URI    string
+}
+
+type TwoFactorStatus struct {
+	Enabled           bool
+	RecoveryCodesLeft int
+}
+
+// totpCode computes the code of the time step (RFC 6238, which is HOTP of RFC 4226 over time)
+func totpCode(key []byte, step int64) string {
+	var counter [8]byte
+	binary.BigEndian.PutUint64(counter[:], uint64(step))
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(counter[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	return fmt.Sprintf("%0*d", totpDigits, value%1000000)
+}
+
+// matchTOTP returns the time step the code belongs to
+func matchTOTP(secret string, code string, now time.Time) (int64, bool) {
+	key, err := base32NoPadding.DecodeString(secret)
+	if err != nil || len(code) != totpDigits {
+		return 0, false
+	}
+
+	current := now.Unix() / int64(totpPeriod.Seconds())
+	for step := current - totpSkew; step <= current+totpSkew; step++ {
+		if subtle.ConstantTimeCompare([]byte(totpCode(key, step)), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// totpURI builds the provisioning URI of authenticator apps ("Key Uri Format" of Google Authenticator)
+func totpURI(issuer, account, secret string) string {
+	query := url.Values{}
+	query.Set("secret", secret)
+	query.Set("issuer", issuer)
+	query.Set("algorithm", "SHA1")
+	query.Set("digits", fmt.Sprint(totpDigits))
+	query.Set("period", fmt.Sprint(int(totpPeriod.Seconds())))
+
+	label := url.PathEscape(issuer) + ":" + url.PathEscape(account)
+	return "otpauth://totp/" + label + "?" + query.Encode()
+}
+
+func newTOTPSecret() (string, error) {
+	buf := make([]byte, totpSecretSize)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base32NoPadding.EncodeToString(buf), nil
+}
+
+// normalizeCode drops the separators people type, recovery codes are case-insensitive
+func normalizeCode(code string) string {
+	return strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+}
+
+// newRecoveryCodes returns the codes formatted like "abcde-fghij" along with their hashes
+func newRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, 0, recoveryCodesCount)
+	hashes := make([]string, 0, recoveryCodesCount)
+
+	for i := 0; i < recoveryCodesCount; i++ {
+		buf := make([]byte, 8)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, nil, err
+		}
+
+		code := strings.ToLower(base32NoPadding.EncodeToString(buf))[:recoveryCodeSize]
+		codes = append(codes, code[:recoveryCodeSize/2]+"-"+code[recoveryCodeSize/2:])
+		hashes = append(hashes, hashToken(code))
+	}
+
+	return codes, hashes, nil
+}
+
+// verifyTOTP accepts a code of the secret once, the codes of the earlier steps are rejected afterwards
+func verifyTOTP(ctx *Context, app *App, twoFactor models.TwoFactor, code string) error {
+	if !app.secondFactorLimiter.Allow(twoFactor.UserID) {
+		return errors.RateLimited
+	}
+
+	step, ok := matchTOTP(twoFactor.Secret, normalizeCode(code), time.Now())
+	if !ok {
+		return ErrInvalidTwoFactorCode
+	}
+
+	if _, err := app.repo.UseTwoFactorStep(ctx, twoFactor.UserID, step); err != nil {
+		return ErrInvalidTwoFactorCode
+	}
+
+	return nil
+}
+
+// verifySecondFactor accepts a TOTP code or a recovery code of a user with two-factor authentication,
+// a recovery code can be used once
+func verifySecondFactor(ctx *Context, app *App, userID string, code string) error {
+	twoFactor, err := app.repo.GetTwoFactor(ctx, userID)
+	if err != nil || !twoFactor.Enabled {
+		return ErrTwoFactorDisabled
+	}
+
+	if normalized := normalizeCode(code); len(normalized) == totpDigits {
+		return verifyTOTP(ctx, app, twoFactor, normalized)
+	}
+
+	if !app.secondFactorLimiter.Allow(userID) {
+		return errors.RateLimited
+	}
+
+	if err := app.repo.UseRecoveryCode(ctx, userID, hashToken(normalizeCode(code))); err != nil {
+		return ErrInvalidTwoFactorCode
+	}
+
+	return nil
+}
+
+// enrollTwoFactor generates a new secret, it's used once confirmed with a code
+func enrollTwoFactor(ctx *Context, app *App, userID string, account string) (TwoFactorEnrollment, error) {
+	if twoFactor, err := app.repo.GetTwoFactor(ctx, userID); err == nil && twoFactor.Enabled {
+		return TwoFactorEnrollment{}, ErrTwoFactorEnabled
+	}
+
+	secret, err := newTOTPSecret()
+	if err != nil {
+		return TwoFactorEnrollment{}, err
+	}
+
+	if _, err := app.repo.CreateTwoFactor(ctx, models.TwoFactor{UserID: userID, Secret: secret}); err != nil {
+		return TwoFactorEnrollment{}, err
+	}
+
+	return TwoFactorEnrollment{
+		Secret: secret,
+		URI:    totpURI(app.totpIssuer, account, secret),
+	}, nil
+}
+
+// confirmTwoFactor enables two-factor authentication if the code matches the enrolled secret
+// and returns the recovery codes, they aren't shown again
+func confirmTwoFactor(ctx *Context, app *App, userID string, code string) ([]string, error) {
+	twoFactor, err := app.repo.GetTwoFactor(ctx, userID)
+	if err != nil {
+		return nil, goerrors.New("two-factor authentication hasn't been enrolled")
+	}
+
+	if twoFactor.Enabled {
+		return nil, ErrTwoFactorEnabled
+	}
+
+	if err := verifyTOTP(ctx, app, twoFactor, code); err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = app.repo.Transaction(ctx, func(tx data.Tx) (interface{}, error) {
+		if err := tx.EnableTwoFactor(ctx, userID); err != nil {
+			return nil, err
+		}
+		return nil, tx.CreateRecoveryCodes(ctx, userID, hashes)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+func disableTwoFactor(ctx *Context, app *App, userID string, code string) error {
+	if err := verifySecondFactor(ctx, app, userID, code); err != nil {
+		return err
+	}
+
+	_, err := app.repo.Transaction(ctx, func(tx data.Tx) (interface{}, error) {
+		if err := tx.DeleteRecoveryCodes(ctx, userID); err != nil {
+			return nil, err
+		}
+		return nil, tx.DeleteTwoFactor(ctx, userID)
+	})
+
+	return err
+}
+
+// regenerateRecoveryCodes replaces the recovery codes, the old ones stop working
+func regenerateRecoveryCodes(ctx *Context, app *App, userID string, code string) ([]string, error) {
+	if err := verifySecondFactor(ctx, app, userID, code); err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := app.repo.CreateRecoveryCodes(ctx, userID, hashes); err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+func twoFactorStatus(ctx *Context, app *App, userID string) (TwoFactorStatus, error) {
+	twoFactor, err := app.repo.GetTwoFactor(ctx, userID)
+	if err != nil || !twoFactor.Enabled {
+		return TwoFactorStatus{}, nil
+	}
+
+	left, err := app.repo.CountRecoveryCodes(ctx, userID)
+	if err != nil {
+		return TwoFactorStatus{}, err
+	}
+
+	return TwoFactorStatus{Enabled: true, RecoveryCodesLeft: left}, nil
+}
+
+// requiresSecondFactor reports whether the user must pass a login challenge after the password
+func requiresSecondFactor(ctx *Context, app *App, userID string) bool {
+	twoFactor, err := app.repo.GetTwoFactor(ctx, userID)
+	return err == nil && twoFactor.Enabled
+}
+
+// createLoginChallenge returns a token of the form "<challenge id>:<secret>",
+// only the hash of the secret is stored
+func createLoginChallenge(ctx *Context, app *App, userID string) (string, error) {
+	secret, err := generateToken(loginChallengeSecretSize)
+	if err != nil {
+		return "", err
+	}
+
+	model, err := app.repo.CreateLoginChallenge(ctx, models.LoginChallenge{
+		UserID:    userID,
+		TokenHash: hashToken(secret),
+		ExpiresAt: time.Now().Add(loginChallengeTTL),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return model.ID + ":" + secret, nil
+}
+
+// completeLoginChallenge returns the user of the challenge if the code is right,
+// the challenge is dropped after it's passed or has run out of attempts.
+// The failed logins of the username are forgotten once it's passed.
+func completeLoginChallenge(ctx *Context, app *App, token string, code string) (User, error) {
+	id, secret, ok := strings.Cut(token, ":")
+	if !ok {
+		return nil, ErrInvalidLoginChallenge
+	}
+
+	challenge, err := app.repo.AttemptLoginChallenge(ctx, id, hashToken(secret))
+	if err != nil {
+		return nil, ErrInvalidLoginChallenge
+	}
+
+	if challenge.Attempts > maxLoginChallengeAttempts {
+		if err := app.repo.DeleteLoginChallenge(ctx, challenge.ID); err != nil {
+			return nil, err
+		}
+		return nil, ErrInvalidLoginChallenge
+	}
+
+	if err := verifySecondFactor(ctx, app, challenge.UserID, code); err != nil {
+		return nil, err
+	}
+
+	if err := app.repo.DeleteLoginChallenge(ctx, challenge.ID); err != nil {
+		return nil, err
+	}
+
+	u, err := app.repo.GetUser(ctx, challenge.UserID)
+	if err != nil {
+		return nil, errors.DoesNotExist
+	}
+
+	loginSucceeded(ctx, app, u.Username)
+
+	return unsafeUserFromModel(app, u), nil
+}
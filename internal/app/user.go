@@ -7,7 +7,7 @@ import (
 	"github.com/ischenkx/vk-test-task/internal/app/errors"
 	"github.com/ischenkx/vk-test-task/internal/app/event"
 	"github.com/ischenkx/vk-test-task/internal/app/forms"
-	"log"
+	"github.com/ischenkx/vk-test-task/internal/app/security"
 	"time"
 )
 
@@ -15,6 +15,8 @@ type User interface {
 	ID() string
 	Username(ctx *Context) (string, error)
 	Update(ctx *Context, update forms.UserUpdate) error
+	// ChangePassword logs the user out of the sessions other than the current one
+	ChangePassword(ctx *Context, form forms.PasswordChange) error
 	Chats(ctx *Context, offset int, count int) ([]ChatMember, error)
 	Friends(ctx *Context, offset int, count int) ([]FriendConnection, error)
 	Friend(ctx *Context, id string) (FriendConnection, error)
@@ -30,6 +32,39 @@ type User interface {
 	CountChats(ctx *Context) (int, error)
 	CountFriends(ctx *Context) (int, error)
 
+	CreateWebhook(ctx *Context, form forms.WebhookCreation) (Webhook, error)
+	Webhooks(ctx *Context, offset int, count int) ([]Webhook, error)
+
+	CreateBot(ctx *Context, form forms.BotCreation) (Bot, string, error)
+	Bots(ctx *Context, offset int, count int) ([]Bot, error)
+
+	Sessions(ctx *Context, offset int, count int) ([]Session, error)
+	Session(ctx *Context, id string) (Session, error)
+	// RevokeSessions logs the user out everywhere, including the current session
+	RevokeSessions(ctx *Context) error
+
+	// CreateAPIKey returns the key along with its token, the token is shown only once
+	CreateAPIKey(ctx *Context, form forms.APIKeyCreation) (APIKey, string, error)
+	APIKeys(ctx *Context, offset int, count int) ([]APIKey, error)
+	APIKey(ctx *Context, id string) (APIKey, error)
+
+	Identities(ctx *Context) ([]models.ExternalIdentity, error)
+	// StartIdentityLink returns the page of the provider, the identity is linked
+	// when the user comes back with the state and the code (see CompleteIdentityLink)
+	StartIdentityLink(ctx *Context, provider string) (string, error)
+	CompleteIdentityLink(ctx *Context, state string, code string) (models.ExternalIdentity, error)
+	// UnlinkIdentity fails if the user has no password and it's the last identity
+	UnlinkIdentity(ctx *Context, provider string) error
+
+	TwoFactor(ctx *Context) (TwoFactorStatus, error)
+	// EnrollTwoFactor generates a TOTP secret, it's enabled by ConfirmTwoFactor
+	EnrollTwoFactor(ctx *Context) (TwoFactorEnrollment, error)
+	// ConfirmTwoFactor enables two-factor authentication and returns the recovery codes
+	ConfirmTwoFactor(ctx *Context, code string) ([]string, error)
+	// DisableTwoFactor and RegenerateRecoveryCodes take a TOTP code or a recovery code
+	DisableTwoFactor(ctx *Context, code string) error
+	RegenerateRecoveryCodes(ctx *Context, code string) ([]string, error)
+
 	Delete(ctx *Context) error
 
 	Model(ctx *Context) (models.User, error)
@@ -47,6 +82,12 @@ func (u user) isWritable(ctx *Context) bool {
 	return ctx.User().ID() == u.userID
 }
 
+// isManageable reports whether the current user can manage the credentials of the user,
+// it takes the user's own credentials rather than an API key
+func (u user) isManageable(ctx *Context) bool {
+	return u.isWritable(ctx) && !ctx.Restricted()
+}
+
 func (u user) Model(ctx *Context) (models.User, error) {
 	return u.app.repo.GetUser(ctx, u.userID)
 }
@@ -88,7 +129,23 @@ func (u user) Update(ctx *Context, update forms.UserUpdate) error {
 	return u.app.repo.UpdateUser(ctx, m)
 }
 
+func (u user) ChangePassword(ctx *Context, form forms.PasswordChange) error {
+	if !u.isManageable(ctx) {
+		return errors.ResourceInaccessible
+	}
+
+	m, err := u.Model(ctx)
+	if err != nil || m.Kind != models.UserKindRegular {
+		return errors.RightsViolation
+	}
+
+	return changePassword(ctx, u.app, m, form)
+}
+
 func (u user) Chats(ctx *Context, offset int, count int) ([]ChatMember, error) {
+	if !ctx.Allows(security.ScopeChatsRead) {
+		return nil, errors.NotAuthorized
+	}
 	repoChats, err := u.app.repo.GetUserChats(ctx, u.userID, offset, count)
 	if err != nil {
 		return nil, err
@@ -107,14 +164,273 @@ func (u user) CountChats(ctx *Context) (int, error) {
 	return u.app.repo.CountUserChats(ctx, u.userID)
 }
 
-func (u user) Delete(ctx *Context) error {
+// CreateWebhook registers a webhook receiving the events routed to the user
+func (u user) CreateWebhook(ctx *Context, form forms.WebhookCreation) (Webhook, error) {
+	if !ctx.Allows(security.ScopeWebhooks) {
+		return nil, errors.NotAuthorized
+	}
+	if !u.isWritable(ctx) {
+		return nil, errors.RightsViolation
+	}
+	return createWebhook(ctx, u.app, "", form)
+}
+
+func (u user) Webhooks(ctx *Context, offset int, count int) ([]Webhook, error) {
+	if !ctx.Allows(security.ScopeWebhooks) {
+		return nil, errors.NotAuthorized
+	}
+	if !u.isWritable(ctx) {
+		return nil, errors.RightsViolation
+	}
+
+	rawWebhooks, err := u.app.repo.GetUserWebhooks(ctx, u.userID, offset, count)
+	if err != nil {
+		return nil, err
+	}
+	return webhooksFromModels(u.app, rawWebhooks), nil
+}
+
+// CreateBot registers a bot owned by the user, the returned token is shown only once.
+// Bots and integrations can't own bots.
+func (u user) CreateBot(ctx *Context, form forms.BotCreation) (Bot, string, error) {
+	if !ctx.Allows(security.ScopeBots) {
+		return nil, "", errors.NotAuthorized
+	}
 	if !u.isWritable(ctx) {
+		return nil, "", errors.RightsViolation
+	}
+
+	if m, err := u.Model(ctx); err != nil || m.Kind != models.UserKindRegular {
+		return nil, "", errors.RightsViolation
+	}
+
+	return createBot(ctx, u.app, form)
+}
+
+func (u user) Bots(ctx *Context, offset int, count int) ([]Bot, error) {
+	if !ctx.Allows(security.ScopeBots) {
+		return nil, errors.NotAuthorized
+	}
+	if !u.isWritable(ctx) {
+		return nil, errors.RightsViolation
+	}
+
+	rawBots, err := u.app.repo.GetUserBots(ctx, u.userID, offset, count)
+	if err != nil {
+		return nil, err
+	}
+	return botsFromModels(u.app, rawBots), nil
+}
+
+// Sessions returns the active sessions of the user, the most recently used first
+func (u user) Sessions(ctx *Context, offset int, count int) ([]Session, error) {
+	if !u.isManageable(ctx) {
+		return nil, errors.ResourceInaccessible
+	}
+
+	rawSessions, err := u.app.repo.GetUserSessions(ctx, u.userID, offset, count)
+	if err != nil {
+		return nil, err
+	}
+
+	return sessionsFromModels(u.app, rawSessions), nil
+}
+
+func (u user) Session(ctx *Context, id string) (Session, error) {
+	s, err := newSession(ctx, u.app, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if !u.isManageable(ctx) || !s.(session).isWritable(ctx) {
+		return nil, errors.ResourceInaccessible
+	}
+
+	return s, nil
+}
+
+func (u user) RevokeSessions(ctx *Context) error {
+	if !u.isManageable(ctx) {
 		return errors.ResourceInaccessible
 	}
-	return u.app.repo.DeleteUser(ctx, u.userID)
+	return revokeUserSessions(ctx, u.app, u.userID)
+}
+
+// CreateAPIKey is available to regular users only, bots have their own tokens
+func (u user) CreateAPIKey(ctx *Context, form forms.APIKeyCreation) (APIKey, string, error) {
+	if !u.isManageable(ctx) {
+		return nil, "", errors.RightsViolation
+	}
+
+	if m, err := u.Model(ctx); err != nil || m.Kind != models.UserKindRegular {
+		return nil, "", errors.RightsViolation
+	}
+
+	return createAPIKey(ctx, u.app, u.userID, form)
+}
+
+func (u user) APIKeys(ctx *Context, offset int, count int) ([]APIKey, error) {
+	if !u.isManageable(ctx) {
+		return nil, errors.ResourceInaccessible
+	}
+
+	rawKeys, err := u.app.repo.GetUserAPIKeys(ctx, u.userID, offset, count)
+	if err != nil {
+		return nil, err
+	}
+
+	return apiKeysFromModels(u.app, rawKeys), nil
+}
+
+func (u user) APIKey(ctx *Context, id string) (APIKey, error) {
+	k, err := newAPIKey(ctx, u.app, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if !u.isManageable(ctx) || !k.(apiKey).isWritable(ctx) {
+		return nil, errors.ResourceInaccessible
+	}
+
+	return k, nil
+}
+
+func (u user) Identities(ctx *Context) ([]models.ExternalIdentity, error) {
+	if !u.isManageable(ctx) {
+		return nil, errors.ResourceInaccessible
+	}
+	return u.app.repo.GetUserExternalIdentities(ctx, u.userID)
+}
+
+func (u user) StartIdentityLink(ctx *Context, provider string) (string, error) {
+	if !u.isManageable(ctx) {
+		return "", errors.ResourceInaccessible
+	}
+
+	if m, err := u.Model(ctx); err != nil || m.Kind != models.UserKindRegular {
+		return "", errors.RightsViolation
+	}
+
+	return startLoginFlow(ctx, u.app, provider, u.userID)
+}
+
+func (u user) CompleteIdentityLink(ctx *Context, state string, code string) (models.ExternalIdentity, error) {
+	if !u.isManageable(ctx) {
+		return models.ExternalIdentity{}, errors.ResourceInaccessible
+	}
+	return linkIdentity(ctx, u.app, state, code)
+}
+
+func (u user) UnlinkIdentity(ctx *Context, provider string) error {
+	if !u.isManageable(ctx) {
+		return errors.ResourceInaccessible
+	}
+
+	m, err := u.Model(ctx)
+	if err != nil {
+		return err
+	}
+
+	identities, err := u.app.repo.GetUserExternalIdentities(ctx, u.userID)
+	if err != nil {
+		return err
+	}
+
+	linked := false
+	for _, identity := range identities {
+		if identity.Provider == provider {
+			linked = true
+		}
+	}
+	if !linked {
+		return errors.DoesNotExist
+	}
+
+	if len(m.PasswordHash) == 0 && len(identities) == 1 {
+		return goerrors.New("the user has no password, the last identity can't be unlinked")
+	}
+
+	return u.app.repo.DeleteExternalIdentity(ctx, u.userID, provider)
+}
+
+func (u user) TwoFactor(ctx *Context) (TwoFactorStatus, error) {
+	if !u.isManageable(ctx) {
+		return TwoFactorStatus{}, errors.ResourceInaccessible
+	}
+	return twoFactorStatus(ctx, u.app, u.userID)
+}
+
+func (u user) EnrollTwoFactor(ctx *Context) (TwoFactorEnrollment, error) {
+	if !u.isManageable(ctx) {
+		return TwoFactorEnrollment{}, errors.ResourceInaccessible
+	}
+
+	m, err := u.Model(ctx)
+	if err != nil || m.Kind != models.UserKindRegular {
+		return TwoFactorEnrollment{}, errors.RightsViolation
+	}
+
+	return enrollTwoFactor(ctx, u.app, u.userID, m.Username)
+}
+
+func (u user) ConfirmTwoFactor(ctx *Context, code string) ([]string, error) {
+	if !u.isManageable(ctx) {
+		return nil, errors.ResourceInaccessible
+	}
+	return confirmTwoFactor(ctx, u.app, u.userID, code)
+}
+
+func (u user) DisableTwoFactor(ctx *Context, code string) error {
+	if !u.isManageable(ctx) {
+		return errors.ResourceInaccessible
+	}
+	return disableTwoFactor(ctx, u.app, u.userID, code)
+}
+
+func (u user) RegenerateRecoveryCodes(ctx *Context, code string) ([]string, error) {
+	if !u.isManageable(ctx) {
+		return nil, errors.ResourceInaccessible
+	}
+	return regenerateRecoveryCodes(ctx, u.app, u.userID, code)
+}
+
+// Delete removes the user's account along with the user's bots. The chats the user owns
+// are handed over to their successors or deleted if nobody is left there.
+func (u user) Delete(ctx *Context) error {
+	if !u.isManageable(ctx) {
+		return errors.ResourceInaccessible
+	}
+
+	_, err := u.app.transaction(ctx, func(tx data.Tx) (interface{}, error) {
+		ownedChats, err := tx.GetUserOwnedChats(ctx, u.userID)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, c := range ownedChats {
+			departure, err := leaveChat(ctx, tx, c.ID, u.userID)
+			if err != nil {
+				return nil, err
+			}
+			if err := u.app.publish(ctx, tx, departure.events()...); err != nil {
+				return nil, err
+			}
+		}
+
+		if err := tx.DeleteUserBots(ctx, u.userID); err != nil {
+			return nil, err
+		}
+
+		return nil, tx.DeleteUser(ctx, u.userID)
+	})
+
+	return err
 }
 
 func (u user) SendFriendRequest(ctx *Context, to string) (FriendRequest, error) {
+	if !ctx.Allows(security.ScopeFriendsWrite) {
+		return nil, errors.NotAuthorized
+	}
 	if ctx.User() == nil {
 		return nil, errors.NotAuthorized
 	}
@@ -127,7 +443,14 @@ func (u user) SendFriendRequest(ctx *Context, to string) (FriendRequest, error)
 		return nil, goerrors.New("sending friend requests to yourself is wierd")
 	}
 
-	req, err := u.app.repo.Transaction(ctx, func(repo data.Tx) (interface{}, error) {
+	if err := u.app.hooks.friendRequest.run(ctx, &FriendRequestAction{
+		FromID: ctx.User().ID(),
+		ToID:   to,
+	}); err != nil {
+		return nil, err
+	}
+
+	req, err := u.app.transaction(ctx, func(repo data.Tx) (interface{}, error) {
 		_, err := repo.GetFriendRequest(ctx, ctx.User().ID(), to)
 		if err == nil {
 			return nil, goerrors.New("already exists")
@@ -142,11 +465,22 @@ func (u user) SendFriendRequest(ctx *Context, to string) (FriendRequest, error)
 			return nil, goerrors.New("already friends")
 		}
 
-		return repo.CreateFriendRequest(ctx, models.FriendRequest{
+		model, err := repo.CreateFriendRequest(ctx, models.FriendRequest{
 			From: ctx.User().ID(),
 			To:   to,
 			Time: time.Now(),
 		})
+		if err != nil {
+			return nil, err
+		}
+
+		e := event.New(NewFriendRequestEventName, NewFriendRequestEvent{
+			FromID: u.userID,
+			ToID:   to,
+			ID:     model.ID,
+		}, event.WithTime(time.Now()), event.ToUsers(u.userID, to))
+
+		return model, u.app.publish(ctx, repo, e)
 	})
 
 	if err != nil {
@@ -155,21 +489,13 @@ func (u user) SendFriendRequest(ctx *Context, to string) (FriendRequest, error)
 
 	model := req.(models.FriendRequest)
 
-	e := event.New(NewFriendRequestEventName, NewFriendRequestEvent{
-		FromID: u.userID,
-		ToID:   to,
-		ID:     model.ID,
-	}, event.WithTime(time.Now()))
-
-	if err := u.app.Events().Send(ctx, e); err != nil {
-		// currently not handled
-		log.Println("failed to send event:", err)
-	}
-
 	return unsafeFriendRequestFromModel(u.app, model), nil
 }
 
 func (u user) IncomingFriendRequests(ctx *Context, offset int, count int) ([]FriendRequest, error) {
+	if !ctx.Allows(security.ScopeFriendsRead) {
+		return nil, errors.NotAuthorized
+	}
 	rawRequests, err := u.app.repo.GetUserIncomingFriendRequests(ctx, u.userID, offset, count)
 	if err != nil {
 		return nil, err
@@ -183,6 +509,9 @@ func (u user) IncomingFriendRequests(ctx *Context, offset int, count int) ([]Fri
 }
 
 func (u user) OutgoingFriendRequests(ctx *Context, offset int, count int) ([]FriendRequest, error) {
+	if !ctx.Allows(security.ScopeFriendsRead) {
+		return nil, errors.NotAuthorized
+	}
 	rawRequests, err := u.app.repo.GetUserOutgoingFriendRequests(ctx, u.userID, offset, count)
 	if err != nil {
 		return nil, err
@@ -220,6 +549,9 @@ func (u user) CountOutgoingFriendRequests(ctx *Context) (int, error) {
 }
 
 func (u user) Friends(ctx *Context, offset int, count int) ([]FriendConnection, error) {
+	if !ctx.Allows(security.ScopeFriendsRead) {
+		return nil, errors.NotAuthorized
+	}
 	repoFriends, err := u.app.repo.GetUserFriends(ctx, u.userID, offset, count)
 	if err != nil {
 		return nil, err
@@ -1,11 +1,10 @@
 package app
 
 import (
-	goerrors "errors"
 	"github.com/ischenkx/vk-test-task/internal/app/data/models"
 	"github.com/ischenkx/vk-test-task/internal/app/errors"
 	"github.com/ischenkx/vk-test-task/internal/app/forms"
-	"golang.org/x/crypto/bcrypt"
+	"sort"
 )
 
 type UserManager struct {
@@ -21,10 +20,14 @@ func (manager UserManager) Register(ctx *Context, form forms.UserRegistration) (
 		return nil, err
 	}
 
-	passwordHash, err := bcrypt.GenerateFromPassword([]byte(form.Password), 10)
+	if err := manager.app.passwordPolicy.check(form.Password); err != nil {
+		return nil, err
+	}
+
+	passwordHash, err := hashPassword(manager.app, form.Password)
 
 	if err != nil {
-		return nil, goerrors.New("failed to hash the password")
+		return nil, err
 	}
 
 	model := models.User{
@@ -41,28 +44,162 @@ func (manager UserManager) Register(ctx *Context, form forms.UserRegistration) (
 	return unsafeUserFromModel(manager.app, res), nil
 }
 
-func (manager UserManager) Login(ctx *Context, form forms.UserLogin) (User, error) {
+// Login checks the password of the user.
+//
+// If the user has two-factor authentication enabled, the returned user is nil
+// and the login is completed with the returned challenge token (see CompleteLogin).
+// The failed logins are throttled (see LoginThrottling), the login fails with
+// ErrInvalidCredentials whether there is such a user or not.
+func (manager UserManager) Login(ctx *Context, form forms.UserLogin) (User, string, error) {
 	if ctx.User() != nil {
-		return nil, errors.AlreadyAuthorized
+		return nil, "", errors.AlreadyAuthorized
 	}
 
 	if err := form.Validate(); err != nil {
-		return nil, err
+		return nil, "", err
+	}
+
+	if err := checkLoginThrottle(ctx, manager.app, form.Username); err != nil {
+		return nil, "", err
 	}
 
 	u, err := manager.app.repo.GetUserByUsername(ctx, form.Username)
 
+	if err != nil || u.Kind != models.UserKindRegular {
+		checkDummyPassword(manager.app, form.Password)
+		return nil, "", loginFailed(ctx, manager.app, form.Username, "")
+	}
+
+	rehash, err := checkPassword(manager.app, u, form.Password)
 	if err != nil {
-		return nil, goerrors.New("failed to find such a user")
+		return nil, "", loginFailed(ctx, manager.app, form.Username, u.ID)
+	}
+
+	if rehash {
+		rehashPassword(ctx, manager.app, u, form.Password)
+	}
+
+	// the failures are forgotten once the second factor is passed as well (see completeLoginChallenge)
+	if requiresSecondFactor(ctx, manager.app, u.ID) {
+		challenge, err := createLoginChallenge(ctx, manager.app, u.ID)
+		if err != nil {
+			return nil, "", err
+		}
+		return nil, challenge, nil
 	}
 
-	if err := bcrypt.CompareHashAndPassword(u.PasswordHash, []byte(form.Password)); err != nil {
-		return nil, goerrors.New("invalid password")
+	loginSucceeded(ctx, manager.app, form.Username)
+
+	return unsafeUserFromModel(manager.app, u), "", nil
+}
+
+// CompleteLogin passes the challenge of Login with a TOTP code or a recovery code
+func (manager UserManager) CompleteLogin(ctx *Context, challenge string, code string) (User, error) {
+	if ctx.User() != nil {
+		return nil, errors.AlreadyAuthorized
+	}
+	return completeLoginChallenge(ctx, manager.app, challenge, code)
+}
+
+// RequestPasswordReset sends a reset token to the user through the notifier of the app,
+// it succeeds whether there is such a user or not
+func (manager UserManager) RequestPasswordReset(ctx *Context, username string) error {
+	if ctx.User() != nil {
+		return errors.AlreadyAuthorized
 	}
+	return requestPasswordReset(ctx, manager.app, username)
+}
 
-	return unsafeUserFromModel(manager.app, u), nil
+// ResetPassword sets a new password with the token sent by RequestPasswordReset,
+// the user is logged out everywhere and logs in with the new password
+func (manager UserManager) ResetPassword(ctx *Context, form forms.PasswordReset) error {
+	return resetPassword(ctx, manager.app, form)
 }
 
 func (manager UserManager) Get(ctx *Context, id string) (User, error) {
 	return newUser(ctx, manager.app, id)
 }
+
+// GetWebhook returns a user or chat webhook by its id
+func (manager UserManager) GetWebhook(ctx *Context, id string) (Webhook, error) {
+	return newWebhook(ctx, manager.app, id)
+}
+
+// GetBot returns a bot by its id (which is the id of its user)
+func (manager UserManager) GetBot(ctx *Context, id string) (Bot, error) {
+	return newBot(ctx, manager.app, id)
+}
+
+// AuthenticateBot returns the bot user the token belongs to
+func (manager UserManager) AuthenticateBot(ctx *Context, token string) (User, error) {
+	return authenticateBot(ctx, manager.app, token)
+}
+
+// IssueTokens starts a new session for the current user and returns its tokens
+func (manager UserManager) IssueTokens(ctx *Context) (TokenPair, error) {
+	if ctx.User() == nil {
+		return TokenPair{}, errors.NotAuthorized
+	}
+	return createSession(ctx, manager.app, ctx.User().ID())
+}
+
+// Refresh exchanges a refresh token for a new token pair, each refresh token can be used once
+func (manager UserManager) Refresh(ctx *Context, refreshToken string) (TokenPair, error) {
+	return refreshSession(ctx, manager.app, refreshToken)
+}
+
+// RevokeRefreshToken revokes the session of the refresh token, e.g. on logout
+func (manager UserManager) RevokeRefreshToken(ctx *Context, refreshToken string) error {
+	return revokeRefreshToken(ctx, manager.app, refreshToken)
+}
+
+// AuthenticateAPIKey returns the owner of the API key along with the scopes of the key,
+// they must be set to the context (see Context.SetScopes)
+func (manager UserManager) AuthenticateAPIKey(ctx *Context, token string) (User, []string, error) {
+	return authenticateAPIKey(ctx, manager.app, token)
+}
+
+// IdentityProviders returns the names of the providers users can log in with
+func (manager UserManager) IdentityProviders() []string {
+	names := make([]string, 0, len(manager.app.identityProviders))
+	for name := range manager.app.identityProviders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// StartExternalLogin returns the page of the provider the user logs in on,
+// the provider redirects the user back with the state and the code for CompleteExternalLogin
+func (manager UserManager) StartExternalLogin(ctx *Context, provider string) (string, error) {
+	if ctx.User() != nil {
+		return "", errors.AlreadyAuthorized
+	}
+	return startLoginFlow(ctx, manager.app, provider, "")
+}
+
+// CompleteExternalLogin returns the user linked to the identity at the provider,
+// the user is created if the identity is seen for the first time.
+//
+// If the user has two-factor authentication enabled, the returned user is nil
+// and the login is completed with the returned challenge token (see CompleteLogin).
+func (manager UserManager) CompleteExternalLogin(ctx *Context, state string, code string) (User, string, error) {
+	if ctx.User() != nil {
+		return nil, "", errors.AlreadyAuthorized
+	}
+
+	u, err := externalLogin(ctx, manager.app, state, code)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if requiresSecondFactor(ctx, manager.app, u.ID()) {
+		challenge, err := createLoginChallenge(ctx, manager.app, u.ID())
+		if err != nil {
+			return nil, "", err
+		}
+		return nil, challenge, nil
+	}
+
+	return u, "", nil
+}
@@ -0,0 +1,200 @@
+package app
+
+import (
+	"github.com/ischenkx/vk-test-task/internal/app/data/models"
+	"github.com/ischenkx/vk-test-task/internal/app/errors"
+	"github.com/ischenkx/vk-test-task/internal/app/forms"
+	"github.com/ischenkx/vk-test-task/internal/app/security"
+	"time"
+)
+
+const webhookSecretSize = 32
+
+type Webhook interface {
+	ID() string
+	Model(ctx *Context) (models.Webhook, error)
+	// Deliveries returns the delivery log, the latest deliveries come first
+	Deliveries(ctx *Context, offset int, count int) ([]models.WebhookDelivery, error)
+	// DeadLetters returns the deliveries that ran out of attempts
+	DeadLetters(ctx *Context, offset int, count int) ([]models.WebhookDelivery, error)
+	// Redeliver schedules the delivery to be sent again with a fresh set of attempts
+	Redeliver(ctx *Context, deliveryID string) error
+	Delete(ctx *Context) error
+}
+
+type webhook struct {
+	app *App
+	id  string
+}
+
+func (w webhook) model(ctx *Context) (models.Webhook, error) {
+	return w.app.repo.GetWebhook(ctx, w.id)
+}
+
+func (w webhook) exists(ctx *Context) bool {
+	if _, err := w.model(ctx); err != nil {
+		return false
+	}
+	return true
+}
+
+// isWritable reports whether the current user owns the webhook.
+// Chat webhooks are managed by the current owner of the chat only, their creators
+// lose access (and the webhooks stop matching events) once they leave the chat or hand it over.
+func (w webhook) isWritable(ctx *Context) bool {
+	if ctx.User() == nil {
+		return false
+	}
+
+	model, err := w.model(ctx)
+	if err != nil {
+		return false
+	}
+
+	if model.ChatID == "" {
+		return model.OwnerID == ctx.User().ID()
+	}
+
+	c, err := w.app.repo.GetChat(ctx, model.ChatID)
+	return err == nil && c.OwnerID == ctx.User().ID()
+}
+
+func (w webhook) ID() string {
+	return w.id
+}
+
+func (w webhook) Model(ctx *Context) (models.Webhook, error) {
+	if !w.isWritable(ctx) {
+		return models.Webhook{}, errors.ResourceInaccessible
+	}
+	return w.model(ctx)
+}
+
+func (w webhook) Deliveries(ctx *Context, offset int, count int) ([]models.WebhookDelivery, error) {
+	if !ctx.Allows(security.ScopeWebhooks) {
+		return nil, errors.NotAuthorized
+	}
+	if !w.isWritable(ctx) {
+		return nil, errors.ResourceInaccessible
+	}
+	return w.app.repo.GetWebhookDeliveries(ctx, w.id, -1, offset, count)
+}
+
+func (w webhook) DeadLetters(ctx *Context, offset int, count int) ([]models.WebhookDelivery, error) {
+	if !ctx.Allows(security.ScopeWebhooks) {
+		return nil, errors.NotAuthorized
+	}
+	if !w.isWritable(ctx) {
+		return nil, errors.ResourceInaccessible
+	}
+	return w.app.repo.GetWebhookDeliveries(ctx, w.id, models.WebhookDeliveryDead, offset, count)
+}
+
+func (w webhook) Redeliver(ctx *Context, deliveryID string) error {
+	if !ctx.Allows(security.ScopeWebhooks) {
+		return errors.NotAuthorized
+	}
+	if !w.isWritable(ctx) {
+		return errors.ResourceInaccessible
+	}
+
+	delivery, err := w.app.repo.GetWebhookDelivery(ctx, deliveryID)
+	if err != nil || delivery.WebhookID != w.id {
+		return errors.DoesNotExist
+	}
+
+	delivery.Status = models.WebhookDeliveryPending
+	delivery.Attempts = 0
+	delivery.NextAttemptAt = time.Now()
+
+	return w.app.repo.UpdateWebhookDelivery(ctx, delivery)
+}
+
+func (w webhook) Delete(ctx *Context) error {
+	if !ctx.Allows(security.ScopeWebhooks) {
+		return errors.NotAuthorized
+	}
+	if !w.isWritable(ctx) {
+		return errors.ResourceInaccessible
+	}
+	return w.app.repo.DeleteWebhook(ctx, w.id)
+}
+
+// messageEvents carry the contents of messages
+var messageEvents = map[string]bool{
+	NewMessageEventName:     true,
+	NewChannelPostEventName: true,
+	MessageUpdatedEventName: true,
+	BotCommandEventName:     true,
+}
+
+// receivesMessages reports whether a webhook subscribed to the events gets the contents of messages
+func receivesMessages(events []string) bool {
+	if len(events) == 0 {
+		return true
+	}
+	for _, name := range events {
+		if messageEvents[name] {
+			return true
+		}
+	}
+	return false
+}
+
+// createWebhook registers a webhook of the current user, chatID is empty for user webhooks.
+// The webhooks receiving messages also require the scope to read them.
+func createWebhook(ctx *Context, app *App, chatID string, form forms.WebhookCreation) (Webhook, error) {
+	if receivesMessages(form.Events) && !ctx.Allows(security.ScopeMessagesRead) {
+		return nil, errors.NotAuthorized
+	}
+
+	if err := form.Validate(); err != nil {
+		return nil, err
+	}
+
+	secret, err := generateToken(webhookSecretSize)
+	if err != nil {
+		return nil, err
+	}
+
+	model, err := app.repo.CreateWebhook(ctx, models.Webhook{
+		OwnerID: ctx.User().ID(),
+		ChatID:  chatID,
+		URL:     form.URL,
+		Secret:  secret,
+		Events:  form.Events,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return unsafeWebhookFromModel(app, model), nil
+}
+
+func webhooksFromModels(app *App, rawWebhooks []models.Webhook) []Webhook {
+	webhooks := make([]Webhook, 0, len(rawWebhooks))
+	for _, model := range rawWebhooks {
+		webhooks = append(webhooks, unsafeWebhookFromModel(app, model))
+	}
+	return webhooks
+}
+
+func unsafeWebhookFromModel(app *App, model models.Webhook) Webhook {
+	return webhook{
+		app: app,
+		id:  model.ID,
+	}
+}
+
+func newWebhook(ctx *Context, app *App, id string) (Webhook, error) {
+	w := webhook{
+		app: app,
+		id:  id,
+	}
+
+	if !w.exists(ctx) {
+		return nil, errors.DoesNotExist
+	}
+
+	return w, nil
+}
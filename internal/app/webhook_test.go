@@ -0,0 +1,50 @@
+package app
+
+import (
+	"context"
+	"github.com/ischenkx/vk-test-task/internal/app/data"
+	"github.com/ischenkx/vk-test-task/internal/app/data/models"
+	"github.com/ischenkx/vk-test-task/internal/app/errors"
+	"testing"
+)
+
+// webhookRepo serves a single chat webhook and its chat
+type webhookRepo struct {
+	data.Repository
+
+	webhook models.Webhook
+	chat    models.Chat
+}
+
+func (r *webhookRepo) GetWebhook(ctx context.Context, id string) (models.Webhook, error) {
+	return r.webhook, nil
+}
+
+func (r *webhookRepo) GetChat(ctx context.Context, id string) (models.Chat, error) {
+	return r.chat, nil
+}
+
+func (r *webhookRepo) GetWebhookDeliveries(ctx context.Context, webhookID string, status int, offset int, count int) ([]models.WebhookDelivery, error) {
+	return nil, nil
+}
+
+func TestChatWebhookFollowsChatOwner(t *testing.T) {
+	repo := &webhookRepo{
+		webhook: models.Webhook{ID: "hook", OwnerID: "creator", ChatID: "chat"},
+		chat:    models.Chat{ID: "chat", OwnerID: "successor"},
+	}
+	app := &App{repo: repo}
+	w := webhook{app: app, id: "hook"}
+
+	creator := NewContext(context.Background())
+	creator.SetUser(user{app: app, userID: "creator"})
+	if _, err := w.Deliveries(creator, 0, 10); err != errors.ResourceInaccessible {
+		t.Fatalf("expected the former owner to lose access, got %v", err)
+	}
+
+	successor := NewContext(context.Background())
+	successor.SetUser(user{app: app, userID: "successor"})
+	if _, err := w.Deliveries(successor, 0, 10); err != nil {
+		t.Fatalf("expected the owner of the chat to manage the webhook, got %v", err)
+	}
+}
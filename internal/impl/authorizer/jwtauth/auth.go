@@ -5,45 +5,163 @@ import (
 	"errors"
 	"github.com/golang-jwt/jwt"
 	"github.com/ischenkx/vk-test-task/internal/app/security"
+	"sync"
 	"time"
 )
 
+const defaultSessionCacheTTL = 30 * time.Second
+
+type Option func(a *Auth)
+
+// WithSessions makes the authorizer reject the tokens of the sessions
+// that aren't active in the store, see security.SessionStore
+func WithSessions(store security.SessionStore) Option {
+	return func(a *Auth) {
+		a.sessions = store
+	}
+}
+
+// WithSessionCacheTTL sets how long the authorizer trusts a session after checking it,
+// a session revoked by another instance can be used for that long (30 seconds by default)
+func WithSessionCacheTTL(ttl time.Duration) Option {
+	return func(a *Auth) {
+		a.sessionCacheTTL = ttl
+	}
+}
+
 type Auth struct {
 	expirationTime time.Duration
-	key            []byte
+	keys           KeySet
+
+	sessions        security.SessionStore
+	sessionCacheTTL time.Duration
+	// checkedSessions maps the ids of the active sessions to the time they have been checked at
+	checkedSessions map[string]time.Time
+	mu              sync.Mutex
 }
 
-func (a *Auth) Verify(ctx context.Context, tokenString string) (userId string, err error) {
+func (a *Auth) Verify(ctx context.Context, tokenString string) (security.Claims, error) {
 	var claims Claims
 	token, err := jwt.ParseWithClaims(tokenString, &claims, func(token *jwt.Token) (interface{}, error) {
-		return a.key, nil
+		kid, _ := token.Header["kid"].(string)
+		key, err := a.keys.VerificationKey(ctx, kid)
+		if err != nil {
+			return nil, err
+		}
+		// the algorithm of the header can't be trusted, it must be the one of the key
+		if token.Method.Alg() != key.Method.Alg() {
+			return nil, errors.New("unexpected signing method")
+		}
+		return key.Verify, nil
 	})
 
 	if err != nil {
-		return "", errors.New("failed to parse token: " + err.Error())
+		return security.Claims{}, errors.New("failed to parse token: " + err.Error())
 	}
 
 	if !token.Valid {
-		return "", errors.New("invalid token")
+		return security.Claims{}, errors.New("invalid token")
 	}
 
 	if time.Now().After(time.Unix(claims.ExpiresAt, 0)) {
-		return "", errors.New("expired token")
+		return security.Claims{}, errors.New("expired token")
+	}
+
+	if claims.Subject == "" || claims.Id == "" {
+		return security.Claims{}, errors.New("invalid token")
 	}
 
-	return claims.Id, nil
+	if err := a.checkSession(ctx, claims.Id); err != nil {
+		return security.Claims{}, err
+	}
+
+	return security.Claims{
+		UserID:    claims.Subject,
+		SessionID: claims.Id,
+	}, nil
 }
 
-func (a *Auth) GenerateToken(ctx context.Context, userId string) (token string, err error) {
-	var claims Claims
-	claims.Id = userId
-	claims.ExpiresAt = time.Now().Add(a.expirationTime).Unix()
-	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(a.key)
+func (a *Auth) GenerateToken(ctx context.Context, claims security.Claims) (token string, err error) {
+	var jwtClaims Claims
+	jwtClaims.Subject = claims.UserID
+	jwtClaims.Id = claims.SessionID
+	jwtClaims.IssuedAt = time.Now().Unix()
+	jwtClaims.ExpiresAt = time.Now().Add(a.expirationTime).Unix()
+
+	key, err := a.keys.SigningKey()
+	if err != nil {
+		return "", err
+	}
+
+	jwtToken := jwt.NewWithClaims(key.Method, jwtClaims)
+	if key.ID != "" {
+		jwtToken.Header["kid"] = key.ID
+	}
+	return jwtToken.SignedString(key.Sign)
+}
+
+// PublicKeys returns the keys of the JWKS, it's empty for HMAC keys
+func (a *Auth) PublicKeys() []security.JWK {
+	return a.keys.PublicKeys()
 }
 
-func New(key []byte, expirationTime time.Duration) security.Authorizer {
-	return &Auth{
-		expirationTime: expirationTime,
-		key:            key,
+func (a *Auth) Forget(sessionId string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.checkedSessions, sessionId)
+}
+
+func (a *Auth) checkSession(ctx context.Context, id string) error {
+	if a.sessions == nil {
+		return nil
+	}
+
+	a.mu.Lock()
+	checkedAt, ok := a.checkedSessions[id]
+	a.mu.Unlock()
+
+	if ok && time.Since(checkedAt) < a.sessionCacheTTL {
+		return nil
 	}
+
+	active, err := a.sessions.SessionActive(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if !active {
+		a.Forget(id)
+		return errors.New("revoked session")
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.evictSessions()
+	a.checkedSessions[id] = time.Now()
+
+	return nil
+}
+
+// evictSessions drops the outdated entries of the cache, must be called under the lock
+func (a *Auth) evictSessions() {
+	for id, checkedAt := range a.checkedSessions {
+		if time.Since(checkedAt) >= a.sessionCacheTTL {
+			delete(a.checkedSessions, id)
+		}
+	}
+}
+
+func New(keys KeySet, expirationTime time.Duration, options ...Option) security.Authorizer {
+	a := &Auth{
+		expirationTime:  expirationTime,
+		keys:            keys,
+		sessionCacheTTL: defaultSessionCacheTTL,
+		checkedSessions: map[string]time.Time{},
+	}
+
+	for _, option := range options {
+		option(a)
+	}
+
+	return a
 }
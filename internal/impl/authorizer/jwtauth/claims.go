@@ -2,6 +2,7 @@ package jwtauth
 
 import "github.com/golang-jwt/jwt"
 
+// Claims keep the user id in "sub" and the session id in "jti"
 type Claims struct {
 	jwt.StandardClaims
 }
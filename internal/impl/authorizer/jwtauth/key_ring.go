@@ -0,0 +1,372 @@
+package jwtauth
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt"
+	"github.com/ischenkx/vk-test-task/internal/app/data/models"
+	"github.com/ischenkx/vk-test-task/internal/app/security"
+	"log"
+	"math/big"
+	"sync"
+	"time"
+)
+
+const (
+	AlgorithmRS256 = "RS256"
+	AlgorithmEdDSA = "EdDSA"
+)
+
+const rsaKeySize = 2048
+const keyIDSize = 12
+
+// sealedKeyVersion is the first byte of the stored private keys, followed by the nonce and the ciphertext
+const sealedKeyVersion = 1
+
+// an unknown key could have just been created by another instance,
+// but the ring doesn't reload more often than that to look for it
+const minKeyReloadInterval = 5 * time.Second
+const maxKeyRefreshInterval = time.Minute
+
+// KeyStore keeps the keys of a ring, so that they survive restarts and are shared by the instances
+type KeyStore interface {
+	CreateSigningKey(ctx context.Context, key models.SigningKey) error
+	GetSigningKeys(ctx context.Context) ([]models.SigningKey, error)
+	DeleteExpiredSigningKeys(ctx context.Context) error
+}
+
+type ringKey struct {
+	Key
+	model  models.SigningKey
+	public security.JWK
+}
+
+// KeyRing is a set of asymmetric keys rotated on schedule.
+//
+// A key signs tokens for the rotation interval and then retires: it's not used for
+// new tokens, but still verifies the existing ones during the grace period, which must
+// be longer than the lifetime of the tokens. The keys are published (see PublicKeys)
+// until they expire, so other services can verify the tokens.
+//
+// The private keys are stored encrypted with AES-GCM, the key is derived from the secret
+// the ring is created with, which all the instances must share.
+type KeyRing struct {
+	store     KeyStore
+	aead      cipher.AEAD
+	algorithm string
+	rotation  time.Duration
+	grace     time.Duration
+	// refreshInterval is how often Run refreshes the ring
+	refreshInterval time.Duration
+
+	mu       sync.RWMutex
+	keys     []ringKey
+	loadedAt time.Time
+}
+
+func NewKeyRing(store KeyStore, secret []byte, algorithm string, rotation, grace time.Duration) (*KeyRing, error) {
+	if algorithm != AlgorithmRS256 && algorithm != AlgorithmEdDSA {
+		return nil, fmt.Errorf("unsupported signing algorithm: %s", algorithm)
+	}
+
+	if rotation <= 0 || grace <= 0 {
+		return nil, errors.New("the rotation interval and the grace period must be positive")
+	}
+
+	if len(secret) == 0 {
+		return nil, errors.New("the secret the private keys are encrypted with is required")
+	}
+
+	encryptionKey := sha256.Sum256(secret)
+	block, err := aes.NewCipher(encryptionKey[:])
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshInterval := rotation / 4
+	if refreshInterval > maxKeyRefreshInterval {
+		refreshInterval = maxKeyRefreshInterval
+	}
+
+	return &KeyRing{
+		store:           store,
+		aead:            aead,
+		algorithm:       algorithm,
+		rotation:        rotation,
+		grace:           grace,
+		refreshInterval: refreshInterval,
+	}, nil
+}
+
+func (ring *KeyRing) SigningKey() (Key, error) {
+	ring.mu.RLock()
+	defer ring.mu.RUnlock()
+
+	now := time.Now()
+	for _, key := range ring.keys {
+		if key.model.Algorithm == ring.algorithm && now.Before(key.model.RetiresAt) {
+			return key.Key, nil
+		}
+	}
+
+	return Key{}, errors.New("no signing key, the key ring must be refreshed")
+}
+
+func (ring *KeyRing) VerificationKey(ctx context.Context, kid string) (Key, error) {
+	if key, ok := ring.find(kid); ok {
+		return key, nil
+	}
+
+	ring.mu.RLock()
+	reload := time.Since(ring.loadedAt) >= minKeyReloadInterval
+	ring.mu.RUnlock()
+
+	if reload {
+		if err := ring.load(ctx); err != nil {
+			return Key{}, err
+		}
+		if key, ok := ring.find(kid); ok {
+			return key, nil
+		}
+	}
+
+	return Key{}, errors.New("unknown key")
+}
+
+func (ring *KeyRing) PublicKeys() []security.JWK {
+	ring.mu.RLock()
+	defer ring.mu.RUnlock()
+
+	now := time.Now()
+	keys := make([]security.JWK, 0, len(ring.keys))
+	for _, key := range ring.keys {
+		if now.Before(key.model.ExpiresAt) {
+			keys = append(keys, key.public)
+		}
+	}
+	return keys
+}
+
+// Refresh loads the keys and creates a new signing key if the current one has retired,
+// it must be called before the ring is used
+func (ring *KeyRing) Refresh(ctx context.Context) error {
+	if err := ring.store.DeleteExpiredSigningKeys(ctx); err != nil {
+		return err
+	}
+
+	if err := ring.load(ctx); err != nil {
+		return err
+	}
+
+	// the next key is created a refresh before the current one retires,
+	// so that there's always a key to sign with
+	if !ring.retiresBefore(time.Now().Add(ring.refreshInterval)) {
+		return nil
+	}
+
+	// several instances can rotate at once, that's fine:
+	// all the keys are valid and the newest one is used for signing
+	model, err := ring.generate()
+	if err != nil {
+		return err
+	}
+
+	if err := ring.store.CreateSigningKey(ctx, model); err != nil {
+		return err
+	}
+
+	return ring.load(ctx)
+}
+
+// Run refreshes the ring until the context is done, the failures are logged
+func (ring *KeyRing) Run(ctx context.Context) error {
+	ticker := time.NewTicker(ring.refreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			if err := ring.Refresh(ctx); err != nil {
+				log.Println("jwtauth: failed to refresh the key ring:", err)
+			}
+		}
+	}
+}
+
+// retiresBefore reports whether all the signing keys of the algorithm retire before the moment
+func (ring *KeyRing) retiresBefore(moment time.Time) bool {
+	ring.mu.RLock()
+	defer ring.mu.RUnlock()
+
+	for _, key := range ring.keys {
+		if key.model.Algorithm == ring.algorithm && key.model.RetiresAt.After(moment) {
+			return false
+		}
+	}
+	return true
+}
+
+func (ring *KeyRing) find(kid string) (Key, bool) {
+	ring.mu.RLock()
+	defer ring.mu.RUnlock()
+
+	for _, key := range ring.keys {
+		if key.ID == kid && time.Now().Before(key.model.ExpiresAt) {
+			return key.Key, true
+		}
+	}
+	return Key{}, false
+}
+
+func (ring *KeyRing) load(ctx context.Context) error {
+	rawKeys, err := ring.store.GetSigningKeys(ctx)
+	if err != nil {
+		return err
+	}
+
+	keys := make([]ringKey, 0, len(rawKeys))
+	for _, model := range rawKeys {
+		private, err := ring.open(model)
+		if err != nil {
+			return err
+		}
+		key, err := parseRingKey(model, private)
+		if err != nil {
+			return err
+		}
+		keys = append(keys, key)
+	}
+
+	ring.mu.Lock()
+	defer ring.mu.Unlock()
+	ring.keys = keys
+	ring.loadedAt = time.Now()
+
+	return nil
+}
+
+func (ring *KeyRing) generate() (models.SigningKey, error) {
+	var private interface{}
+	var err error
+
+	switch ring.algorithm {
+	case AlgorithmRS256:
+		private, err = rsa.GenerateKey(rand.Reader, rsaKeySize)
+	case AlgorithmEdDSA:
+		_, private, err = ed25519.GenerateKey(rand.Reader)
+	}
+	if err != nil {
+		return models.SigningKey{}, err
+	}
+
+	encoded, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return models.SigningKey{}, err
+	}
+
+	rawID := make([]byte, keyIDSize)
+	if _, err := rand.Read(rawID); err != nil {
+		return models.SigningKey{}, err
+	}
+	id := base64.RawURLEncoding.EncodeToString(rawID)
+
+	sealed, err := ring.seal(id, encoded)
+	if err != nil {
+		return models.SigningKey{}, err
+	}
+
+	now := time.Now()
+	return models.SigningKey{
+		ID:         id,
+		Algorithm:  ring.algorithm,
+		PrivateKey: sealed,
+		CreatedAt:  now,
+		RetiresAt:  now.Add(ring.rotation),
+		ExpiresAt:  now.Add(ring.rotation + ring.grace),
+	}, nil
+}
+
+// seal encrypts the encoded private key, the id is authenticated along with it,
+// so a key can't be swapped with another one in the store
+func (ring *KeyRing) seal(id string, encoded []byte) ([]byte, error) {
+	nonce := make([]byte, ring.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	sealed := append([]byte{sealedKeyVersion}, nonce...)
+	return ring.aead.Seal(sealed, nonce, encoded, []byte(id)), nil
+}
+
+// open decrypts the private key of the model, it fails if the key has been encrypted with another secret
+func (ring *KeyRing) open(model models.SigningKey) ([]byte, error) {
+	sealed := model.PrivateKey
+	nonceSize := ring.aead.NonceSize()
+	if len(sealed) < 1+nonceSize || sealed[0] != sealedKeyVersion {
+		return nil, fmt.Errorf("key %s: unexpected format", model.ID)
+	}
+
+	encoded, err := ring.aead.Open(nil, sealed[1:1+nonceSize], sealed[1+nonceSize:], []byte(model.ID))
+	if err != nil {
+		return nil, fmt.Errorf("key %s: failed to decrypt, the secret may differ from the one it was encrypted with", model.ID)
+	}
+	return encoded, nil
+}
+
+func parseRingKey(model models.SigningKey, encoded []byte) (ringKey, error) {
+	private, err := x509.ParsePKCS8PrivateKey(encoded)
+	if err != nil {
+		return ringKey{}, err
+	}
+
+	key := ringKey{
+		Key:   Key{ID: model.ID, Sign: private},
+		model: model,
+		public: security.JWK{
+			Kid: model.ID,
+			Use: "sig",
+			Alg: model.Algorithm,
+		},
+	}
+
+	switch private := private.(type) {
+	case *rsa.PrivateKey:
+		if model.Algorithm != AlgorithmRS256 {
+			return ringKey{}, fmt.Errorf("key %s: unexpected RSA key", model.ID)
+		}
+		key.Method = jwt.SigningMethodRS256
+		key.Verify = &private.PublicKey
+		key.public.Kty = "RSA"
+		key.public.N = base64.RawURLEncoding.EncodeToString(private.N.Bytes())
+		key.public.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(private.E)).Bytes())
+	case ed25519.PrivateKey:
+		if model.Algorithm != AlgorithmEdDSA {
+			return ringKey{}, fmt.Errorf("key %s: unexpected Ed25519 key", model.ID)
+		}
+		public := private.Public().(ed25519.PublicKey)
+		key.Method = jwt.SigningMethodEdDSA
+		key.Verify = public
+		key.public.Kty = "OKP"
+		key.public.Crv = "Ed25519"
+		key.public.X = base64.RawURLEncoding.EncodeToString(public)
+	default:
+		return ringKey{}, fmt.Errorf("key %s: unsupported key type", model.ID)
+	}
+
+	return key, nil
+}
@@ -0,0 +1,84 @@
+package jwtauth
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"github.com/ischenkx/vk-test-task/internal/app/data/models"
+	"strings"
+	"testing"
+	"time"
+)
+
+// memoryKeyStore keeps the keys as the database would
+type memoryKeyStore struct {
+	keys []models.SigningKey
+}
+
+func (s *memoryKeyStore) CreateSigningKey(ctx context.Context, key models.SigningKey) error {
+	s.keys = append(s.keys, key)
+	return nil
+}
+
+func (s *memoryKeyStore) GetSigningKeys(ctx context.Context) ([]models.SigningKey, error) {
+	return s.keys, nil
+}
+
+func (s *memoryKeyStore) DeleteExpiredSigningKeys(ctx context.Context) error {
+	return nil
+}
+
+func TestKeyRingEncryptsPrivateKeys(t *testing.T) {
+	store := &memoryKeyStore{}
+	ring, err := NewKeyRing(store, []byte("secret"), AlgorithmEdDSA, time.Hour, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ring.Refresh(context.Background()); err != nil {
+		t.Fatalf("failed to refresh the ring: %s", err)
+	}
+
+	if len(store.keys) != 1 {
+		t.Fatalf("expected a single key, got %d", len(store.keys))
+	}
+	if _, err := x509.ParsePKCS8PrivateKey(store.keys[0].PrivateKey); err == nil {
+		t.Fatal("expected the private key to be stored encrypted")
+	}
+
+	key, err := ring.SigningKey()
+	if err != nil {
+		t.Fatalf("failed to get the signing key: %s", err)
+	}
+	encoded, err := x509.MarshalPKCS8PrivateKey(key.Sign)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(store.keys[0].PrivateKey, encoded) {
+		t.Fatal("expected the stored key not to contain the private key")
+	}
+}
+
+func TestKeyRingRejectsForeignKeys(t *testing.T) {
+	store := &memoryKeyStore{}
+	ring, err := NewKeyRing(store, []byte("secret"), AlgorithmEdDSA, time.Hour, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ring.Refresh(context.Background()); err != nil {
+		t.Fatalf("failed to refresh the ring: %s", err)
+	}
+
+	other, err := NewKeyRing(store, []byte("another secret"), AlgorithmEdDSA, time.Hour, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := other.Refresh(context.Background()); err == nil || !strings.Contains(err.Error(), "failed to decrypt") {
+		t.Fatalf("expected the key to fail to decrypt, got %v", err)
+	}
+
+	// the id is authenticated, so a sealed key can't be moved to another row
+	store.keys[0].ID = "swapped"
+	if err := ring.Refresh(context.Background()); err == nil {
+		t.Fatal("expected the swapped key to be rejected")
+	}
+}
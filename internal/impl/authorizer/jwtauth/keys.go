@@ -0,0 +1,57 @@
+package jwtauth
+
+import (
+	"context"
+	"errors"
+	"github.com/golang-jwt/jwt"
+	"github.com/ischenkx/vk-test-task/internal/app/security"
+)
+
+// Key is a key tokens are signed or verified with, ID is put in the "kid" header
+type Key struct {
+	ID     string
+	Method jwt.SigningMethod
+	// Sign and Verify are the keys jwt.SigningMethod expects, the same secret for HMAC
+	Sign   interface{}
+	Verify interface{}
+}
+
+// KeySet provides the keys of an Auth
+type KeySet interface {
+	// SigningKey returns the key new tokens are signed with
+	SigningKey() (Key, error)
+	// VerificationKey returns the key with the id, it fails for unknown and expired keys
+	VerificationKey(ctx context.Context, kid string) (Key, error)
+	PublicKeys() []security.JWK
+}
+
+type hmacKey struct {
+	key Key
+}
+
+// HMACKey is a key set of a single shared secret, the tokens are signed with HS256 and have no "kid"
+func HMACKey(secret []byte) KeySet {
+	return hmacKey{
+		key: Key{
+			Method: jwt.SigningMethodHS256,
+			Sign:   secret,
+			Verify: secret,
+		},
+	}
+}
+
+func (k hmacKey) SigningKey() (Key, error) {
+	return k.key, nil
+}
+
+func (k hmacKey) VerificationKey(ctx context.Context, kid string) (Key, error) {
+	if kid != "" {
+		return Key{}, errors.New("unknown key")
+	}
+	return k.key, nil
+}
+
+// PublicKeys returns nothing as the secret can't be published
+func (k hmacKey) PublicKeys() []security.JWK {
+	return nil
+}
@@ -1,13 +1,18 @@
 package postgres
 
 import (
+	"encoding/json"
+	"errors"
+	"github.com/ischenkx/vk-test-task/internal/app/data"
 	"github.com/ischenkx/vk-test-task/internal/app/data/models"
 	"github.com/jackc/pgx/v4"
+	"strings"
+	"time"
 )
 
 func parseUser(row pgx.Row) (models.User, error) {
 	var user models.User
-	err := row.Scan(&user.ID, &user.Username, &user.PasswordHash)
+	err := row.Scan(&user.ID, &user.Username, &user.PasswordHash, &user.Kind)
 	return user, err
 }
 
@@ -31,18 +36,198 @@ func parseFriendRequest(row pgx.Row) (models.FriendRequest, error) {
 
 func parseChat(row pgx.Row) (models.Chat, error) {
 	var res models.Chat
-	err := row.Scan(&res.ID, &res.Name, &res.Description, &res.OwnerID)
+	err := row.Scan(&res.ID, &res.Name, &res.Description, &res.OwnerID, &res.Visibility, &res.Kind, &res.CountViews)
 	return res, err
 }
 
 func parseChatMember(row pgx.Row) (models.ChatMember, error) {
 	var res models.ChatMember
-	err := row.Scan(&res.UserID, &res.ChatID, &res.Status)
+	err := row.Scan(&res.UserID, &res.ChatID, &res.Status, &res.JoinedAt)
 	return res, err
 }
 
 func parseMessage(row pgx.Row) (models.Message, error) {
 	var res models.Message
-	err := row.Scan(&res.ID, &res.UserID, &res.ChatID, &res.Payload, &res.TimeStamp, &res.LastUpdate)
+	var attachments []byte
+	err := row.Scan(&res.ID, &res.UserID, &res.ChatID, &res.Payload, &res.TimeStamp, &res.LastUpdate, &res.Views, &attachments)
+	if err != nil {
+		return res, err
+	}
+	if err := json.Unmarshal(attachments, &res.Attachments); err != nil {
+		return res, err
+	}
+	return res, nil
+}
+
+func parseChatInvite(row pgx.Row) (models.ChatInvite, error) {
+	var res models.ChatInvite
+	var expiresAt *time.Time
+	err := row.Scan(&res.Code, &res.ChatID, &res.CreatorID, &res.MaxUses, &res.Uses,
+		&expiresAt, &res.RequiresApproval, &res.Revoked, &res.CreatedAt)
+	if expiresAt != nil {
+		res.ExpiresAt = *expiresAt
+	}
+	return res, err
+}
+
+func parseChatJoinRequest(row pgx.Row) (models.ChatJoinRequest, error) {
+	var res models.ChatJoinRequest
+	err := row.Scan(&res.ID, &res.ChatID, &res.UserID, &res.InviteCode, &res.Time)
+	return res, err
+}
+
+func parseOutboxEvent(row pgx.Row) (models.OutboxEvent, error) {
+	var res models.OutboxEvent
+	err := row.Scan(&res.ID, &res.Name, &res.Data, &res.TimeStamp, &res.ChatID, &res.UserIDs, &res.SchemaVersion, &res.CreatedAt)
+	return res, err
+}
+
+func parseWebhook(row pgx.Row) (models.Webhook, error) {
+	var res models.Webhook
+	var chatID *string
+	err := row.Scan(&res.ID, &res.OwnerID, &chatID, &res.URL, &res.Secret, &res.Events, &res.CreatedAt)
+	if chatID != nil {
+		res.ChatID = *chatID
+	}
+	return res, err
+}
+
+func parseIncomingWebhook(row pgx.Row) (models.IncomingWebhook, error) {
+	var res models.IncomingWebhook
+	err := row.Scan(&res.ID, &res.ChatID, &res.UserID, &res.CreatorID, &res.Name, &res.TokenHash, &res.CreatedAt)
+	return res, err
+}
+
+func parseBot(row pgx.Row) (models.Bot, error) {
+	var res models.Bot
+	err := row.Scan(&res.UserID, &res.OwnerID, &res.TokenHash, &res.CreatedAt)
+	return res, err
+}
+
+func parseBotCommand(row pgx.Row) (models.BotCommand, error) {
+	var res models.BotCommand
+	err := row.Scan(&res.BotID, &res.Command, &res.Description)
+	return res, err
+}
+
+func parseBotUpdate(row pgx.Row) (models.BotUpdate, error) {
+	var res models.BotUpdate
+	err := row.Scan(&res.ID, &res.BotID, &res.EventID, &res.Name, &res.Payload, &res.CreatedAt)
+	return res, err
+}
+
+func parseWebhookDelivery(row pgx.Row) (models.WebhookDelivery, error) {
+	var res models.WebhookDelivery
+	err := row.Scan(&res.ID, &res.WebhookID, &res.EventID, &res.EventName, &res.Payload, &res.Status, &res.Attempts,
+		&res.ResponseCode, &res.Error, &res.NextAttemptAt, &res.CreatedAt, &res.UpdatedAt)
 	return res, err
 }
+
+// nullableString turns the empty string into NULL
+func nullableString(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// nullableTime turns the zero time into NULL
+func nullableTime(t time.Time) interface{} {
+	if t.IsZero() {
+		return nil
+	}
+	return t
+}
+
+// likePattern builds a pattern matching any string that contains the query
+func likePattern(query string) string {
+	query = strings.ReplaceAll(query, `\`, `\\`)
+	query = strings.ReplaceAll(query, "%", `\%`)
+	query = strings.ReplaceAll(query, "_", `\_`)
+	return "%" + query + "%"
+}
+
+// marshalAttachments encodes the attachments of a message, nil is stored as an empty list
+func marshalAttachments(attachments []models.MessageAttachment) ([]byte, error) {
+	if attachments == nil {
+		attachments = []models.MessageAttachment{}
+	}
+	return json.Marshal(attachments)
+}
+
+func parseSession(row pgx.Row) (models.Session, error) {
+	var res models.Session
+	err := row.Scan(&res.ID, &res.UserID, &res.TokenHash, &res.UserAgent, &res.IP, &res.ExpiresAt, &res.CreatedAt, &res.LastSeenAt, &res.PreviousTokenHashes)
+	return res, err
+}
+
+func parseSigningKey(row pgx.Row) (models.SigningKey, error) {
+	var res models.SigningKey
+	err := row.Scan(&res.ID, &res.Algorithm, &res.PrivateKey, &res.CreatedAt, &res.RetiresAt, &res.ExpiresAt)
+	return res, err
+}
+
+func parseAPIKey(row pgx.Row) (models.APIKey, error) {
+	var res models.APIKey
+	var expiresAt, lastUsedAt *time.Time
+	err := row.Scan(&res.ID, &res.UserID, &res.Name, &res.TokenHash, &res.Scopes, &expiresAt, &lastUsedAt, &res.CreatedAt)
+	if expiresAt != nil {
+		res.ExpiresAt = *expiresAt
+	}
+	if lastUsedAt != nil {
+		res.LastUsedAt = *lastUsedAt
+	}
+	return res, err
+}
+
+func parseExternalIdentity(row pgx.Row) (models.ExternalIdentity, error) {
+	var res models.ExternalIdentity
+	err := row.Scan(&res.Provider, &res.Subject, &res.UserID, &res.Email, &res.CreatedAt)
+	return res, err
+}
+
+func parseLoginFlow(row pgx.Row) (models.LoginFlow, error) {
+	var res models.LoginFlow
+	var userID *string
+	err := row.Scan(&res.StateHash, &res.Provider, &res.CodeVerifier, &res.Nonce, &userID, &res.ExpiresAt, &res.CreatedAt)
+	if userID != nil {
+		res.UserID = *userID
+	}
+	return res, err
+}
+
+func parseTwoFactor(row pgx.Row) (models.TwoFactor, error) {
+	var res models.TwoFactor
+	err := row.Scan(&res.UserID, &res.Secret, &res.Enabled, &res.LastUsedStep, &res.CreatedAt)
+	return res, err
+}
+
+func parseLoginChallenge(row pgx.Row) (models.LoginChallenge, error) {
+	var res models.LoginChallenge
+	err := row.Scan(&res.ID, &res.UserID, &res.TokenHash, &res.Attempts, &res.ExpiresAt, &res.CreatedAt)
+	return res, err
+}
+
+func parsePasswordReset(row pgx.Row) (models.PasswordReset, error) {
+	var res models.PasswordReset
+	err := row.Scan(&res.ID, &res.UserID, &res.TokenHash, &res.ExpiresAt, &res.CreatedAt)
+	return res, err
+}
+
+func parseLoginThrottle(row pgx.Row) (models.LoginThrottle, error) {
+	var res models.LoginThrottle
+	var lockedUntil *time.Time
+	err := row.Scan(&res.Key, &res.Failures, &lockedUntil, &res.LastFailureAt)
+	if lockedUntil != nil {
+		res.LockedUntil = *lockedUntil
+	}
+	return res, err
+}
+
+// notFound replaces pgx.ErrNoRows with data.ErrNotFound for the lookups that report missing rows
+func notFound(err error) error {
+	if errors.Is(err, pgx.ErrNoRows) {
+		return data.ErrNotFound
+	}
+	return err
+}
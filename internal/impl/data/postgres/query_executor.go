@@ -4,6 +4,7 @@ import (
 	"context"
 	"github.com/ischenkx/vk-test-task/internal/app/data"
 	"github.com/ischenkx/vk-test-task/internal/app/data/models"
+	"time"
 )
 
 type QueryExecutor struct {
@@ -11,7 +12,7 @@ type QueryExecutor struct {
 }
 
 func (r QueryExecutor) CreateUser(ctx context.Context, user models.User) (models.User, error) {
-	row := r.pg.QueryRow(ctx, createUserSql, user.Username, user.PasswordHash)
+	row := r.pg.QueryRow(ctx, createUserSql, user.Username, user.PasswordHash, user.Kind)
 	return parseUser(row)
 }
 
@@ -35,6 +36,16 @@ func (r QueryExecutor) UpdateUser(ctx context.Context, user models.User) error {
 	return err
 }
 
+func (r QueryExecutor) UpdateUserPasswordHash(ctx context.Context, id string, oldHash, newHash []byte) error {
+	_, err := r.pg.Exec(ctx, updateUserPasswordHashSql, id, newHash, oldHash)
+	return err
+}
+
+func (r QueryExecutor) SetUserPasswordHash(ctx context.Context, id string, hash []byte) error {
+	_, err := r.pg.Exec(ctx, setUserPasswordHashSql, id, hash)
+	return err
+}
+
 func (r QueryExecutor) CreateFriendConnection(ctx context.Context, id1, id2 string) error {
 	_, err := r.pg.Exec(ctx, createFriendConnectionSql, id1, id2)
 	return err
@@ -160,7 +171,7 @@ func (r QueryExecutor) GetFriendRequestByID(ctx context.Context, id string) (mod
 }
 
 func (r QueryExecutor) CreateChat(ctx context.Context, chat models.Chat) (models.Chat, error) {
-	row := r.pg.QueryRow(ctx, createChatSql, chat.Name, chat.Description, chat.OwnerID)
+	row := r.pg.QueryRow(ctx, createChatSql, chat.Name, chat.Description, chat.OwnerID, chat.Visibility, chat.Kind, chat.CountViews)
 	return parseChat(row)
 }
 
@@ -175,10 +186,31 @@ func (r QueryExecutor) GetChat(ctx context.Context, id string) (models.Chat, err
 }
 
 func (r QueryExecutor) UpdateChat(ctx context.Context, chat models.Chat) (models.Chat, error) {
-	row := r.pg.QueryRow(ctx, updateChatSql, chat.ID, chat.Name, chat.Description)
+	row := r.pg.QueryRow(ctx, updateChatSql, chat.ID, chat.Name, chat.Description, chat.Visibility, chat.OwnerID, chat.CountViews)
 	return parseChat(row)
 }
 
+func (r QueryExecutor) SearchPublicChats(ctx context.Context, query string, offset int, count int) ([]models.Chat, error) {
+	rows, err := r.pg.Query(ctx, searchPublicChatsSql, likePattern(query), offset, count)
+	if err != nil {
+		return nil, err
+	}
+
+	var res []models.Chat
+	for rows.Next() {
+		if rows.Err() != nil {
+			return nil, rows.Err()
+		}
+		model, err := parseChat(rows)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, model)
+	}
+
+	return res, err
+}
+
 func (r QueryExecutor) CreateChatMember(ctx context.Context, member models.ChatMember) (models.ChatMember, error) {
 	row := r.pg.QueryRow(ctx, createChatMemberSql, member.UserID, member.ChatID, member.Status)
 	return parseChatMember(row)
@@ -199,8 +231,38 @@ func (r QueryExecutor) GetChatMember(ctx context.Context, userId, chatId string)
 	return parseChatMember(row)
 }
 
+func (r QueryExecutor) GetChatSuccessor(ctx context.Context, chatId, userId string) (models.ChatMember, error) {
+	row := r.pg.QueryRow(ctx, getChatSuccessorSql, chatId, userId)
+	return parseChatMember(row)
+}
+
+func (r QueryExecutor) GetUserOwnedChats(ctx context.Context, userId string) ([]models.Chat, error) {
+	query, err := r.pg.Query(ctx, getUserOwnedChatsSql, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	var res []models.Chat
+	for query.Next() {
+		if query.Err() != nil {
+			return nil, query.Err()
+		}
+		model, err := parseChat(query)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, model)
+	}
+
+	return res, err
+}
+
 func (r QueryExecutor) CreateMessage(ctx context.Context, model models.Message) (models.Message, error) {
-	row := r.pg.QueryRow(ctx, createMessageSql, model.UserID, model.ChatID, model.Payload, model.TimeStamp, model.TimeStamp)
+	attachments, err := marshalAttachments(model.Attachments)
+	if err != nil {
+		return models.Message{}, err
+	}
+	row := r.pg.QueryRow(ctx, createMessageSql, model.UserID, model.ChatID, model.Payload, model.TimeStamp, model.TimeStamp, attachments)
 	return parseMessage(row)
 }
 
@@ -215,6 +277,11 @@ func (r QueryExecutor) UpdateMessage(ctx context.Context, model models.Message)
 	return err
 }
 
+func (r QueryExecutor) MarkMessagesViewed(ctx context.Context, userId string, messageIds []string) error {
+	_, err := r.pg.Exec(ctx, markMessagesViewedSql, messageIds, userId)
+	return err
+}
+
 func (r QueryExecutor) GetMessage(ctx context.Context, id string) (models.Message, error) {
 	row := r.pg.QueryRow(ctx, getMessageSql, id)
 	return parseMessage(row)
@@ -298,8 +365,749 @@ func (r QueryExecutor) CountUserChats(ctx context.Context, id string) (int, erro
 	return parseInt(row)
 }
 
+func (r QueryExecutor) CreateChatInvite(ctx context.Context, invite models.ChatInvite) (models.ChatInvite, error) {
+	row := r.pg.QueryRow(ctx, createChatInviteSql, invite.Code, invite.ChatID, invite.CreatorID,
+		invite.MaxUses, nullableTime(invite.ExpiresAt), invite.RequiresApproval)
+	return parseChatInvite(row)
+}
+
+func (r QueryExecutor) GetChatInvite(ctx context.Context, code string) (models.ChatInvite, error) {
+	row := r.pg.QueryRow(ctx, getChatInviteSql, code)
+	invite, err := parseChatInvite(row)
+	return invite, notFound(err)
+}
+
+func (r QueryExecutor) GetChatInvites(ctx context.Context, chatId string, offset int, count int) ([]models.ChatInvite, error) {
+	query, err := r.pg.Query(ctx, getChatInvitesSql, chatId, offset, count)
+	if err != nil {
+		return nil, err
+	}
+
+	var res []models.ChatInvite
+	for query.Next() {
+		if query.Err() != nil {
+			return nil, query.Err()
+		}
+		model, err := parseChatInvite(query)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, model)
+	}
+
+	return res, err
+}
+
+func (r QueryExecutor) UseChatInvite(ctx context.Context, code string) (models.ChatInvite, error) {
+	row := r.pg.QueryRow(ctx, useChatInviteSql, code)
+	invite, err := parseChatInvite(row)
+	return invite, notFound(err)
+}
+
+func (r QueryExecutor) RevokeChatInvite(ctx context.Context, code string) error {
+	_, err := r.pg.Exec(ctx, revokeChatInviteSql, code)
+	return err
+}
+
+func (r QueryExecutor) CreateChatJoinRequest(ctx context.Context, request models.ChatJoinRequest) (models.ChatJoinRequest, error) {
+	row := r.pg.QueryRow(ctx, createChatJoinRequestSql, request.ChatID, request.UserID, request.InviteCode, request.Time)
+	return parseChatJoinRequest(row)
+}
+
+func (r QueryExecutor) DeleteChatJoinRequest(ctx context.Context, id string) error {
+	_, err := r.pg.Exec(ctx, deleteChatJoinRequestSql, id)
+	return err
+}
+
+func (r QueryExecutor) GetChatJoinRequest(ctx context.Context, id string) (models.ChatJoinRequest, error) {
+	row := r.pg.QueryRow(ctx, getChatJoinRequestSql, id)
+	return parseChatJoinRequest(row)
+}
+
+func (r QueryExecutor) GetUserChatJoinRequest(ctx context.Context, chatId, userId string) (models.ChatJoinRequest, error) {
+	row := r.pg.QueryRow(ctx, getUserChatJoinRequestSql, chatId, userId)
+	request, err := parseChatJoinRequest(row)
+	return request, notFound(err)
+}
+
+func (r QueryExecutor) GetChatJoinRequests(ctx context.Context, chatId string, offset int, count int) ([]models.ChatJoinRequest, error) {
+	query, err := r.pg.Query(ctx, getChatJoinRequestsSql, chatId, offset, count)
+	if err != nil {
+		return nil, err
+	}
+
+	var res []models.ChatJoinRequest
+	for query.Next() {
+		if query.Err() != nil {
+			return nil, query.Err()
+		}
+		model, err := parseChatJoinRequest(query)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, model)
+	}
+
+	return res, err
+}
+
+func (r QueryExecutor) CreateOutboxEvent(ctx context.Context, e models.OutboxEvent) (models.OutboxEvent, error) {
+	userIds := e.UserIDs
+	if userIds == nil {
+		userIds = []string{}
+	}
+	row := r.pg.QueryRow(ctx, createOutboxEventSql, e.Name, e.Data, e.TimeStamp, e.ChatID, userIds, e.SchemaVersion)
+	return parseOutboxEvent(row)
+}
+
+func (r QueryExecutor) DeleteOutboxEvents(ctx context.Context, ids []string) error {
+	_, err := r.pg.Exec(ctx, deleteOutboxEventsSql, ids)
+	return err
+}
+
+func (r QueryExecutor) ReleaseOutboxEvents(ctx context.Context, ids []string) error {
+	_, err := r.pg.Exec(ctx, releaseOutboxEventsSql, ids)
+	return err
+}
+
+func (r QueryExecutor) ClaimOutboxEvents(ctx context.Context, count int, lease time.Duration) ([]models.OutboxEvent, error) {
+	query, err := r.pg.Query(ctx, claimOutboxEventsSql, count, lease)
+	if err != nil {
+		return nil, err
+	}
+
+	var res []models.OutboxEvent
+	for query.Next() {
+		if query.Err() != nil {
+			return nil, query.Err()
+		}
+		model, err := parseOutboxEvent(query)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, model)
+	}
+
+	return res, err
+}
+
+func (r QueryExecutor) CreateWebhook(ctx context.Context, webhook models.Webhook) (models.Webhook, error) {
+	events := webhook.Events
+	if events == nil {
+		events = []string{}
+	}
+	row := r.pg.QueryRow(ctx, createWebhookSql, webhook.OwnerID, nullableString(webhook.ChatID),
+		webhook.URL, webhook.Secret, events)
+	return parseWebhook(row)
+}
+
+func (r QueryExecutor) DeleteWebhook(ctx context.Context, id string) error {
+	_, err := r.pg.Exec(ctx, deleteWebhookSql, id)
+	return err
+}
+
+func (r QueryExecutor) GetWebhook(ctx context.Context, id string) (models.Webhook, error) {
+	row := r.pg.QueryRow(ctx, getWebhookSql, id)
+	return parseWebhook(row)
+}
+
+func (r QueryExecutor) GetUserWebhooks(ctx context.Context, userId string, offset int, count int) ([]models.Webhook, error) {
+	query, err := r.pg.Query(ctx, getUserWebhooksSql, userId, offset, count)
+	if err != nil {
+		return nil, err
+	}
+
+	var res []models.Webhook
+	for query.Next() {
+		if query.Err() != nil {
+			return nil, query.Err()
+		}
+		model, err := parseWebhook(query)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, model)
+	}
+
+	return res, err
+}
+
+func (r QueryExecutor) GetChatWebhooks(ctx context.Context, chatId string, offset int, count int) ([]models.Webhook, error) {
+	query, err := r.pg.Query(ctx, getChatWebhooksSql, chatId, offset, count)
+	if err != nil {
+		return nil, err
+	}
+
+	var res []models.Webhook
+	for query.Next() {
+		if query.Err() != nil {
+			return nil, query.Err()
+		}
+		model, err := parseWebhook(query)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, model)
+	}
+
+	return res, err
+}
+
+func (r QueryExecutor) GetMatchingWebhooks(ctx context.Context, name string, chatId string, userIds []string) ([]models.Webhook, error) {
+	if userIds == nil {
+		userIds = []string{}
+	}
+	query, err := r.pg.Query(ctx, getMatchingWebhooksSql, name, nullableString(chatId), userIds)
+	if err != nil {
+		return nil, err
+	}
+
+	var res []models.Webhook
+	for query.Next() {
+		if query.Err() != nil {
+			return nil, query.Err()
+		}
+		model, err := parseWebhook(query)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, model)
+	}
+
+	return res, err
+}
+
+func (r QueryExecutor) CreateWebhookDelivery(ctx context.Context, delivery models.WebhookDelivery) error {
+	_, err := r.pg.Exec(ctx, createWebhookDeliverySql, delivery.WebhookID, delivery.EventID,
+		delivery.EventName, delivery.Payload)
+	return err
+}
+
+func (r QueryExecutor) UpdateWebhookDelivery(ctx context.Context, delivery models.WebhookDelivery) error {
+	_, err := r.pg.Exec(ctx, updateWebhookDeliverySql, delivery.ID, delivery.Status, delivery.Attempts,
+		delivery.ResponseCode, delivery.Error, delivery.NextAttemptAt)
+	return err
+}
+
+func (r QueryExecutor) ClaimWebhookDeliveries(ctx context.Context, count int, lease time.Duration) ([]models.WebhookDelivery, error) {
+	query, err := r.pg.Query(ctx, claimWebhookDeliveriesSql, count, lease)
+	if err != nil {
+		return nil, err
+	}
+
+	var res []models.WebhookDelivery
+	for query.Next() {
+		if query.Err() != nil {
+			return nil, query.Err()
+		}
+		model, err := parseWebhookDelivery(query)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, model)
+	}
+
+	return res, err
+}
+
+func (r QueryExecutor) GetWebhookDelivery(ctx context.Context, id string) (models.WebhookDelivery, error) {
+	row := r.pg.QueryRow(ctx, getWebhookDeliverySql, id)
+	return parseWebhookDelivery(row)
+}
+
+func (r QueryExecutor) GetWebhookDeliveries(ctx context.Context, webhookId string, status int, offset int, count int) ([]models.WebhookDelivery, error) {
+	query, err := r.pg.Query(ctx, getWebhookDeliveriesSql, webhookId, status, offset, count)
+	if err != nil {
+		return nil, err
+	}
+
+	var res []models.WebhookDelivery
+	for query.Next() {
+		if query.Err() != nil {
+			return nil, query.Err()
+		}
+		model, err := parseWebhookDelivery(query)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, model)
+	}
+
+	return res, err
+}
+
 func queryExecutor(pg PostgresInterface) data.Tx {
 	return QueryExecutor{
 		pg: pg,
 	}
 }
+
+func (r QueryExecutor) CreateIncomingWebhook(ctx context.Context, webhook models.IncomingWebhook) (models.IncomingWebhook, error) {
+	row := r.pg.QueryRow(ctx, createIncomingWebhookSql, webhook.ChatID, webhook.UserID, webhook.CreatorID,
+		webhook.Name, webhook.TokenHash)
+	return parseIncomingWebhook(row)
+}
+
+func (r QueryExecutor) DeleteIncomingWebhook(ctx context.Context, id string) error {
+	_, err := r.pg.Exec(ctx, deleteIncomingWebhookSql, id)
+	return err
+}
+
+func (r QueryExecutor) GetIncomingWebhook(ctx context.Context, id string) (models.IncomingWebhook, error) {
+	row := r.pg.QueryRow(ctx, getIncomingWebhookSql, id)
+	return parseIncomingWebhook(row)
+}
+
+func (r QueryExecutor) GetChatIncomingWebhooks(ctx context.Context, chatId string, offset int, count int) ([]models.IncomingWebhook, error) {
+	query, err := r.pg.Query(ctx, getChatIncomingWebhooksSql, chatId, offset, count)
+	if err != nil {
+		return nil, err
+	}
+
+	var res []models.IncomingWebhook
+	for query.Next() {
+		if query.Err() != nil {
+			return nil, query.Err()
+		}
+		model, err := parseIncomingWebhook(query)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, model)
+	}
+
+	return res, err
+}
+
+func (r QueryExecutor) CreateBot(ctx context.Context, bot models.Bot) (models.Bot, error) {
+	row := r.pg.QueryRow(ctx, createBotSql, bot.UserID, bot.OwnerID, bot.TokenHash)
+	return parseBot(row)
+}
+
+func (r QueryExecutor) UpdateBotToken(ctx context.Context, userId string, tokenHash string) error {
+	_, err := r.pg.Exec(ctx, updateBotTokenSql, userId, tokenHash)
+	return err
+}
+
+func (r QueryExecutor) GetBot(ctx context.Context, userId string) (models.Bot, error) {
+	row := r.pg.QueryRow(ctx, getBotSql, userId)
+	return parseBot(row)
+}
+
+func (r QueryExecutor) GetUserBots(ctx context.Context, ownerId string, offset int, count int) ([]models.Bot, error) {
+	query, err := r.pg.Query(ctx, getUserBotsSql, ownerId, offset, count)
+	if err != nil {
+		return nil, err
+	}
+
+	var res []models.Bot
+	for query.Next() {
+		if query.Err() != nil {
+			return nil, query.Err()
+		}
+		model, err := parseBot(query)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, model)
+	}
+
+	return res, err
+}
+
+func (r QueryExecutor) DeleteUserBots(ctx context.Context, ownerId string) error {
+	_, err := r.pg.Exec(ctx, deleteUserBotsSql, ownerId)
+	return err
+}
+
+func (r QueryExecutor) CreateBotCommand(ctx context.Context, command models.BotCommand) error {
+	_, err := r.pg.Exec(ctx, createBotCommandSql, command.BotID, command.Command, command.Description)
+	return err
+}
+
+func (r QueryExecutor) DeleteBotCommands(ctx context.Context, botId string) error {
+	_, err := r.pg.Exec(ctx, deleteBotCommandsSql, botId)
+	return err
+}
+
+func (r QueryExecutor) GetBotCommands(ctx context.Context, botId string) ([]models.BotCommand, error) {
+	query, err := r.pg.Query(ctx, getBotCommandsSql, botId)
+	if err != nil {
+		return nil, err
+	}
+
+	var res []models.BotCommand
+	for query.Next() {
+		if query.Err() != nil {
+			return nil, query.Err()
+		}
+		model, err := parseBotCommand(query)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, model)
+	}
+
+	return res, err
+}
+
+func (r QueryExecutor) GetChatBotCommands(ctx context.Context, chatId string, command string) ([]models.BotCommand, error) {
+	query, err := r.pg.Query(ctx, getChatBotCommandsSql, chatId, command)
+	if err != nil {
+		return nil, err
+	}
+
+	var res []models.BotCommand
+	for query.Next() {
+		if query.Err() != nil {
+			return nil, query.Err()
+		}
+		model, err := parseBotCommand(query)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, model)
+	}
+
+	return res, err
+}
+
+func (r QueryExecutor) CreateBotUpdates(ctx context.Context, update models.BotUpdate, chatId string, userIds []string) error {
+	if userIds == nil {
+		userIds = []string{}
+	}
+	_, err := r.pg.Exec(ctx, createBotUpdatesSql, update.EventID, update.Name, update.Payload, chatId, userIds)
+	return err
+}
+
+func (r QueryExecutor) GetBotUpdates(ctx context.Context, botId string, offset int64, count int) ([]models.BotUpdate, error) {
+	query, err := r.pg.Query(ctx, getBotUpdatesSql, botId, offset, count)
+	if err != nil {
+		return nil, err
+	}
+
+	var res []models.BotUpdate
+	for query.Next() {
+		if query.Err() != nil {
+			return nil, query.Err()
+		}
+		model, err := parseBotUpdate(query)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, model)
+	}
+
+	return res, err
+}
+
+func (r QueryExecutor) DeleteBotUpdates(ctx context.Context, botId string, offset int64, retention time.Duration) error {
+	_, err := r.pg.Exec(ctx, deleteBotUpdatesSql, botId, offset, retention)
+	return err
+}
+
+func (r QueryExecutor) CreateSession(ctx context.Context, session models.Session) (models.Session, error) {
+	row := r.pg.QueryRow(ctx, createSessionSql, session.UserID, session.TokenHash, session.UserAgent, session.IP, session.ExpiresAt)
+	return parseSession(row)
+}
+
+func (r QueryExecutor) GetSession(ctx context.Context, id string) (models.Session, error) {
+	row := r.pg.QueryRow(ctx, getSessionSql, id)
+	return parseSession(row)
+}
+
+func (r QueryExecutor) GetUserSessions(ctx context.Context, userId string, offset int, count int) ([]models.Session, error) {
+	query, err := r.pg.Query(ctx, getUserSessionsSql, userId, offset, count)
+	if err != nil {
+		return nil, err
+	}
+
+	var res []models.Session
+	for query.Next() {
+		if query.Err() != nil {
+			return nil, query.Err()
+		}
+		model, err := parseSession(query)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, model)
+	}
+
+	return res, err
+}
+
+func (r QueryExecutor) RotateSession(ctx context.Context, id string, tokenHash string, newTokenHash string, ip string, expiresAt time.Time) (models.Session, error) {
+	row := r.pg.QueryRow(ctx, rotateSessionSql, id, tokenHash, newTokenHash, ip, expiresAt)
+	return parseSession(row)
+}
+
+func (r QueryExecutor) TouchSession(ctx context.Context, id string) (models.Session, error) {
+	row := r.pg.QueryRow(ctx, touchSessionSql, id)
+	return parseSession(row)
+}
+
+func (r QueryExecutor) DeleteSession(ctx context.Context, id string) error {
+	_, err := r.pg.Exec(ctx, deleteSessionSql, id)
+	return err
+}
+
+func (r QueryExecutor) DeleteUserSessions(ctx context.Context, userId string) ([]string, error) {
+	query, err := r.pg.Query(ctx, deleteUserSessionsSql, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	var res []string
+	for query.Next() {
+		if query.Err() != nil {
+			return nil, query.Err()
+		}
+		var id string
+		if err := query.Scan(&id); err != nil {
+			return nil, err
+		}
+		res = append(res, id)
+	}
+
+	return res, err
+}
+
+func (r QueryExecutor) DeleteOtherUserSessions(ctx context.Context, userId string, keepId string) ([]string, error) {
+	query, err := r.pg.Query(ctx, deleteOtherUserSessionsSql, userId, keepId)
+	if err != nil {
+		return nil, err
+	}
+
+	var res []string
+	for query.Next() {
+		if query.Err() != nil {
+			return nil, query.Err()
+		}
+		var id string
+		if err := query.Scan(&id); err != nil {
+			return nil, err
+		}
+		res = append(res, id)
+	}
+
+	return res, err
+}
+
+func (r QueryExecutor) CreateSigningKey(ctx context.Context, key models.SigningKey) error {
+	_, err := r.pg.Exec(ctx, createSigningKeySql, key.ID, key.Algorithm, key.PrivateKey, key.CreatedAt, key.RetiresAt, key.ExpiresAt)
+	return err
+}
+
+func (r QueryExecutor) GetSigningKeys(ctx context.Context) ([]models.SigningKey, error) {
+	query, err := r.pg.Query(ctx, getSigningKeysSql)
+	if err != nil {
+		return nil, err
+	}
+
+	var res []models.SigningKey
+	for query.Next() {
+		if query.Err() != nil {
+			return nil, query.Err()
+		}
+		model, err := parseSigningKey(query)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, model)
+	}
+
+	return res, err
+}
+
+func (r QueryExecutor) DeleteExpiredSigningKeys(ctx context.Context) error {
+	_, err := r.pg.Exec(ctx, deleteExpiredSigningKeysSql)
+	return err
+}
+
+func (r QueryExecutor) CreateAPIKey(ctx context.Context, key models.APIKey) (models.APIKey, error) {
+	row := r.pg.QueryRow(ctx, createAPIKeySql, key.UserID, key.Name, key.TokenHash, key.Scopes, nullableTime(key.ExpiresAt))
+	return parseAPIKey(row)
+}
+
+func (r QueryExecutor) GetAPIKey(ctx context.Context, id string) (models.APIKey, error) {
+	row := r.pg.QueryRow(ctx, getAPIKeySql, id)
+	return parseAPIKey(row)
+}
+
+func (r QueryExecutor) GetUserAPIKeys(ctx context.Context, userId string, offset int, count int) ([]models.APIKey, error) {
+	query, err := r.pg.Query(ctx, getUserAPIKeysSql, userId, offset, count)
+	if err != nil {
+		return nil, err
+	}
+
+	var res []models.APIKey
+	for query.Next() {
+		if query.Err() != nil {
+			return nil, query.Err()
+		}
+		model, err := parseAPIKey(query)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, model)
+	}
+
+	return res, err
+}
+
+func (r QueryExecutor) TouchAPIKey(ctx context.Context, id string) error {
+	_, err := r.pg.Exec(ctx, touchAPIKeySql, id)
+	return err
+}
+
+func (r QueryExecutor) DeleteAPIKey(ctx context.Context, id string) error {
+	_, err := r.pg.Exec(ctx, deleteAPIKeySql, id)
+	return err
+}
+
+func (r QueryExecutor) CreateExternalIdentity(ctx context.Context, identity models.ExternalIdentity) (models.ExternalIdentity, error) {
+	row := r.pg.QueryRow(ctx, createExternalIdentitySql, identity.Provider, identity.Subject, identity.UserID, identity.Email)
+	return parseExternalIdentity(row)
+}
+
+func (r QueryExecutor) GetExternalIdentity(ctx context.Context, provider string, subject string) (models.ExternalIdentity, error) {
+	row := r.pg.QueryRow(ctx, getExternalIdentitySql, provider, subject)
+	return parseExternalIdentity(row)
+}
+
+func (r QueryExecutor) GetUserExternalIdentities(ctx context.Context, userId string) ([]models.ExternalIdentity, error) {
+	query, err := r.pg.Query(ctx, getUserExternalIdentitiesSql, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	var res []models.ExternalIdentity
+	for query.Next() {
+		if query.Err() != nil {
+			return nil, query.Err()
+		}
+		model, err := parseExternalIdentity(query)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, model)
+	}
+
+	return res, err
+}
+
+func (r QueryExecutor) DeleteExternalIdentity(ctx context.Context, userId string, provider string) error {
+	_, err := r.pg.Exec(ctx, deleteExternalIdentitySql, userId, provider)
+	return err
+}
+
+func (r QueryExecutor) CreateLoginFlow(ctx context.Context, flow models.LoginFlow) error {
+	var userID *string
+	if flow.UserID != "" {
+		userID = &flow.UserID
+	}
+	_, err := r.pg.Exec(ctx, createLoginFlowSql, flow.StateHash, flow.Provider, flow.CodeVerifier, flow.Nonce, userID, flow.ExpiresAt)
+	return err
+}
+
+func (r QueryExecutor) TakeLoginFlow(ctx context.Context, stateHash string) (models.LoginFlow, error) {
+	row := r.pg.QueryRow(ctx, takeLoginFlowSql, stateHash)
+	return parseLoginFlow(row)
+}
+
+func (r QueryExecutor) CreateTwoFactor(ctx context.Context, twoFactor models.TwoFactor) (models.TwoFactor, error) {
+	row := r.pg.QueryRow(ctx, createTwoFactorSql, twoFactor.UserID, twoFactor.Secret)
+	return parseTwoFactor(row)
+}
+
+func (r QueryExecutor) GetTwoFactor(ctx context.Context, userId string) (models.TwoFactor, error) {
+	row := r.pg.QueryRow(ctx, getTwoFactorSql, userId)
+	return parseTwoFactor(row)
+}
+
+func (r QueryExecutor) EnableTwoFactor(ctx context.Context, userId string) error {
+	_, err := r.pg.Exec(ctx, enableTwoFactorSql, userId)
+	return err
+}
+
+func (r QueryExecutor) UseTwoFactorStep(ctx context.Context, userId string, step int64) (models.TwoFactor, error) {
+	row := r.pg.QueryRow(ctx, useTwoFactorStepSql, userId, step)
+	return parseTwoFactor(row)
+}
+
+func (r QueryExecutor) DeleteTwoFactor(ctx context.Context, userId string) error {
+	_, err := r.pg.Exec(ctx, deleteTwoFactorSql, userId)
+	return err
+}
+
+func (r QueryExecutor) CreateRecoveryCodes(ctx context.Context, userId string, codeHashes []string) error {
+	_, err := r.pg.Exec(ctx, createRecoveryCodesSql, userId, codeHashes)
+	return err
+}
+
+func (r QueryExecutor) UseRecoveryCode(ctx context.Context, userId string, codeHash string) error {
+	var hash string
+	return r.pg.QueryRow(ctx, useRecoveryCodeSql, userId, codeHash).Scan(&hash)
+}
+
+func (r QueryExecutor) CountRecoveryCodes(ctx context.Context, userId string) (int, error) {
+	var count int
+	err := r.pg.QueryRow(ctx, countRecoveryCodesSql, userId).Scan(&count)
+	return count, err
+}
+
+func (r QueryExecutor) DeleteRecoveryCodes(ctx context.Context, userId string) error {
+	_, err := r.pg.Exec(ctx, deleteRecoveryCodesSql, userId)
+	return err
+}
+
+func (r QueryExecutor) CreateLoginChallenge(ctx context.Context, challenge models.LoginChallenge) (models.LoginChallenge, error) {
+	row := r.pg.QueryRow(ctx, createLoginChallengeSql, challenge.UserID, challenge.TokenHash, challenge.ExpiresAt)
+	return parseLoginChallenge(row)
+}
+
+func (r QueryExecutor) AttemptLoginChallenge(ctx context.Context, id string, tokenHash string) (models.LoginChallenge, error) {
+	row := r.pg.QueryRow(ctx, attemptLoginChallengeSql, id, tokenHash)
+	return parseLoginChallenge(row)
+}
+
+func (r QueryExecutor) DeleteLoginChallenge(ctx context.Context, id string) error {
+	_, err := r.pg.Exec(ctx, deleteLoginChallengeSql, id)
+	return err
+}
+
+func (r QueryExecutor) CreatePasswordReset(ctx context.Context, reset models.PasswordReset) (models.PasswordReset, error) {
+	row := r.pg.QueryRow(ctx, createPasswordResetSql, reset.UserID, reset.TokenHash, reset.ExpiresAt)
+	return parsePasswordReset(row)
+}
+
+func (r QueryExecutor) TakePasswordReset(ctx context.Context, id string, tokenHash string) (models.PasswordReset, error) {
+	row := r.pg.QueryRow(ctx, takePasswordResetSql, id, tokenHash)
+	return parsePasswordReset(row)
+}
+
+func (r QueryExecutor) DeletePasswordReset(ctx context.Context, userId string) error {
+	_, err := r.pg.Exec(ctx, deletePasswordResetSql, userId)
+	return err
+}
+
+func (r QueryExecutor) GetLoginThrottle(ctx context.Context, key string) (models.LoginThrottle, error) {
+	row := r.pg.QueryRow(ctx, getLoginThrottleSql, key)
+	return parseLoginThrottle(row)
+}
+
+func (r QueryExecutor) RecordLoginFailure(ctx context.Context, key string, window time.Duration) (models.LoginThrottle, error) {
+	row := r.pg.QueryRow(ctx, recordLoginFailureSql, key, window)
+	return parseLoginThrottle(row)
+}
+
+func (r QueryExecutor) LockLogin(ctx context.Context, key string, until time.Time) error {
+	_, err := r.pg.Exec(ctx, lockLoginSql, key, until)
+	return err
+}
+
+func (r QueryExecutor) DeleteLoginThrottle(ctx context.Context, key string) error {
+	_, err := r.pg.Exec(ctx, deleteLoginThrottleSql, key)
+	return err
+}
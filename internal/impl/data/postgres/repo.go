@@ -6,6 +6,7 @@ import (
 	"github.com/ischenkx/vk-test-task/internal/app/data/models"
 	"github.com/jackc/pgx/v4"
 	"github.com/jackc/pgx/v4/pgxpool"
+	"time"
 )
 
 type Repo struct {
@@ -206,3 +207,355 @@ func (r *Repo) CountChatMessages(ctx context.Context, chatId string) (int, error
 func (r *Repo) CountUserChats(ctx context.Context, id string) (int, error) {
 	return queryExecutor(r.pg).CountUserChats(ctx, id)
 }
+
+func (r *Repo) CreateChatInvite(ctx context.Context, invite models.ChatInvite) (models.ChatInvite, error) {
+	return queryExecutor(r.pg).CreateChatInvite(ctx, invite)
+}
+
+func (r *Repo) GetChatInvite(ctx context.Context, code string) (models.ChatInvite, error) {
+	return queryExecutor(r.pg).GetChatInvite(ctx, code)
+}
+
+func (r *Repo) GetChatInvites(ctx context.Context, chatId string, offset int, count int) ([]models.ChatInvite, error) {
+	return queryExecutor(r.pg).GetChatInvites(ctx, chatId, offset, count)
+}
+
+func (r *Repo) UseChatInvite(ctx context.Context, code string) (models.ChatInvite, error) {
+	return queryExecutor(r.pg).UseChatInvite(ctx, code)
+}
+
+func (r *Repo) RevokeChatInvite(ctx context.Context, code string) error {
+	return queryExecutor(r.pg).RevokeChatInvite(ctx, code)
+}
+
+func (r *Repo) CreateChatJoinRequest(ctx context.Context, request models.ChatJoinRequest) (models.ChatJoinRequest, error) {
+	return queryExecutor(r.pg).CreateChatJoinRequest(ctx, request)
+}
+
+func (r *Repo) DeleteChatJoinRequest(ctx context.Context, id string) error {
+	return queryExecutor(r.pg).DeleteChatJoinRequest(ctx, id)
+}
+
+func (r *Repo) GetChatJoinRequest(ctx context.Context, id string) (models.ChatJoinRequest, error) {
+	return queryExecutor(r.pg).GetChatJoinRequest(ctx, id)
+}
+
+func (r *Repo) GetChatJoinRequests(ctx context.Context, chatId string, offset int, count int) ([]models.ChatJoinRequest, error) {
+	return queryExecutor(r.pg).GetChatJoinRequests(ctx, chatId, offset, count)
+}
+
+func (r *Repo) SearchPublicChats(ctx context.Context, query string, offset int, count int) ([]models.Chat, error) {
+	return queryExecutor(r.pg).SearchPublicChats(ctx, query, offset, count)
+}
+
+func (r *Repo) GetChatSuccessor(ctx context.Context, chatId, userId string) (models.ChatMember, error) {
+	return queryExecutor(r.pg).GetChatSuccessor(ctx, chatId, userId)
+}
+
+func (r *Repo) GetUserOwnedChats(ctx context.Context, userId string) ([]models.Chat, error) {
+	return queryExecutor(r.pg).GetUserOwnedChats(ctx, userId)
+}
+
+func (r *Repo) MarkMessagesViewed(ctx context.Context, userId string, messageIds []string) error {
+	return queryExecutor(r.pg).MarkMessagesViewed(ctx, userId, messageIds)
+}
+
+func (r *Repo) CreateOutboxEvent(ctx context.Context, e models.OutboxEvent) (models.OutboxEvent, error) {
+	return queryExecutor(r.pg).CreateOutboxEvent(ctx, e)
+}
+
+func (r *Repo) DeleteOutboxEvents(ctx context.Context, ids []string) error {
+	return queryExecutor(r.pg).DeleteOutboxEvents(ctx, ids)
+}
+
+func (r *Repo) ReleaseOutboxEvents(ctx context.Context, ids []string) error {
+	return queryExecutor(r.pg).ReleaseOutboxEvents(ctx, ids)
+}
+
+func (r *Repo) ClaimOutboxEvents(ctx context.Context, count int, lease time.Duration) ([]models.OutboxEvent, error) {
+	return queryExecutor(r.pg).ClaimOutboxEvents(ctx, count, lease)
+}
+
+func (r *Repo) CreateWebhook(ctx context.Context, webhook models.Webhook) (models.Webhook, error) {
+	return queryExecutor(r.pg).CreateWebhook(ctx, webhook)
+}
+
+func (r *Repo) DeleteWebhook(ctx context.Context, id string) error {
+	return queryExecutor(r.pg).DeleteWebhook(ctx, id)
+}
+
+func (r *Repo) GetWebhook(ctx context.Context, id string) (models.Webhook, error) {
+	return queryExecutor(r.pg).GetWebhook(ctx, id)
+}
+
+func (r *Repo) GetUserWebhooks(ctx context.Context, userId string, offset int, count int) ([]models.Webhook, error) {
+	return queryExecutor(r.pg).GetUserWebhooks(ctx, userId, offset, count)
+}
+
+func (r *Repo) GetChatWebhooks(ctx context.Context, chatId string, offset int, count int) ([]models.Webhook, error) {
+	return queryExecutor(r.pg).GetChatWebhooks(ctx, chatId, offset, count)
+}
+
+func (r *Repo) GetMatchingWebhooks(ctx context.Context, name string, chatId string, userIds []string) ([]models.Webhook, error) {
+	return queryExecutor(r.pg).GetMatchingWebhooks(ctx, name, chatId, userIds)
+}
+
+func (r *Repo) CreateWebhookDelivery(ctx context.Context, delivery models.WebhookDelivery) error {
+	return queryExecutor(r.pg).CreateWebhookDelivery(ctx, delivery)
+}
+
+func (r *Repo) UpdateWebhookDelivery(ctx context.Context, delivery models.WebhookDelivery) error {
+	return queryExecutor(r.pg).UpdateWebhookDelivery(ctx, delivery)
+}
+
+func (r *Repo) ClaimWebhookDeliveries(ctx context.Context, count int, lease time.Duration) ([]models.WebhookDelivery, error) {
+	return queryExecutor(r.pg).ClaimWebhookDeliveries(ctx, count, lease)
+}
+
+func (r *Repo) GetWebhookDelivery(ctx context.Context, id string) (models.WebhookDelivery, error) {
+	return queryExecutor(r.pg).GetWebhookDelivery(ctx, id)
+}
+
+func (r *Repo) GetWebhookDeliveries(ctx context.Context, webhookId string, status int, offset int, count int) ([]models.WebhookDelivery, error) {
+	return queryExecutor(r.pg).GetWebhookDeliveries(ctx, webhookId, status, offset, count)
+}
+
+func (r *Repo) CreateIncomingWebhook(ctx context.Context, webhook models.IncomingWebhook) (models.IncomingWebhook, error) {
+	return queryExecutor(r.pg).CreateIncomingWebhook(ctx, webhook)
+}
+
+func (r *Repo) DeleteIncomingWebhook(ctx context.Context, id string) error {
+	return queryExecutor(r.pg).DeleteIncomingWebhook(ctx, id)
+}
+
+func (r *Repo) GetIncomingWebhook(ctx context.Context, id string) (models.IncomingWebhook, error) {
+	return queryExecutor(r.pg).GetIncomingWebhook(ctx, id)
+}
+
+func (r *Repo) GetChatIncomingWebhooks(ctx context.Context, chatId string, offset int, count int) ([]models.IncomingWebhook, error) {
+	return queryExecutor(r.pg).GetChatIncomingWebhooks(ctx, chatId, offset, count)
+}
+
+func (r *Repo) CreateBot(ctx context.Context, bot models.Bot) (models.Bot, error) {
+	return queryExecutor(r.pg).CreateBot(ctx, bot)
+}
+
+func (r *Repo) UpdateBotToken(ctx context.Context, userId string, tokenHash string) error {
+	return queryExecutor(r.pg).UpdateBotToken(ctx, userId, tokenHash)
+}
+
+func (r *Repo) GetBot(ctx context.Context, userId string) (models.Bot, error) {
+	return queryExecutor(r.pg).GetBot(ctx, userId)
+}
+
+func (r *Repo) GetUserBots(ctx context.Context, ownerId string, offset int, count int) ([]models.Bot, error) {
+	return queryExecutor(r.pg).GetUserBots(ctx, ownerId, offset, count)
+}
+
+func (r *Repo) DeleteUserBots(ctx context.Context, ownerId string) error {
+	return queryExecutor(r.pg).DeleteUserBots(ctx, ownerId)
+}
+
+func (r *Repo) CreateBotCommand(ctx context.Context, command models.BotCommand) error {
+	return queryExecutor(r.pg).CreateBotCommand(ctx, command)
+}
+
+func (r *Repo) DeleteBotCommands(ctx context.Context, botId string) error {
+	return queryExecutor(r.pg).DeleteBotCommands(ctx, botId)
+}
+
+func (r *Repo) GetBotCommands(ctx context.Context, botId string) ([]models.BotCommand, error) {
+	return queryExecutor(r.pg).GetBotCommands(ctx, botId)
+}
+
+func (r *Repo) GetChatBotCommands(ctx context.Context, chatId string, command string) ([]models.BotCommand, error) {
+	return queryExecutor(r.pg).GetChatBotCommands(ctx, chatId, command)
+}
+
+func (r *Repo) CreateBotUpdates(ctx context.Context, update models.BotUpdate, chatId string, userIds []string) error {
+	return queryExecutor(r.pg).CreateBotUpdates(ctx, update, chatId, userIds)
+}
+
+func (r *Repo) GetBotUpdates(ctx context.Context, botId string, offset int64, count int) ([]models.BotUpdate, error) {
+	return queryExecutor(r.pg).GetBotUpdates(ctx, botId, offset, count)
+}
+
+func (r *Repo) DeleteBotUpdates(ctx context.Context, botId string, offset int64, retention time.Duration) error {
+	return queryExecutor(r.pg).DeleteBotUpdates(ctx, botId, offset, retention)
+}
+
+func (r *Repo) CreateSession(ctx context.Context, session models.Session) (models.Session, error) {
+	return queryExecutor(r.pg).CreateSession(ctx, session)
+}
+
+func (r *Repo) GetSession(ctx context.Context, id string) (models.Session, error) {
+	return queryExecutor(r.pg).GetSession(ctx, id)
+}
+
+func (r *Repo) GetUserSessions(ctx context.Context, userId string, offset int, count int) ([]models.Session, error) {
+	return queryExecutor(r.pg).GetUserSessions(ctx, userId, offset, count)
+}
+
+func (r *Repo) RotateSession(ctx context.Context, id string, tokenHash string, newTokenHash string, ip string, expiresAt time.Time) (models.Session, error) {
+	return queryExecutor(r.pg).RotateSession(ctx, id, tokenHash, newTokenHash, ip, expiresAt)
+}
+
+func (r *Repo) TouchSession(ctx context.Context, id string) (models.Session, error) {
+	return queryExecutor(r.pg).TouchSession(ctx, id)
+}
+
+func (r *Repo) DeleteSession(ctx context.Context, id string) error {
+	return queryExecutor(r.pg).DeleteSession(ctx, id)
+}
+
+func (r *Repo) DeleteUserSessions(ctx context.Context, userId string) ([]string, error) {
+	return queryExecutor(r.pg).DeleteUserSessions(ctx, userId)
+}
+
+func (r *Repo) CreateSigningKey(ctx context.Context, key models.SigningKey) error {
+	return queryExecutor(r.pg).CreateSigningKey(ctx, key)
+}
+
+func (r *Repo) GetSigningKeys(ctx context.Context) ([]models.SigningKey, error) {
+	return queryExecutor(r.pg).GetSigningKeys(ctx)
+}
+
+func (r *Repo) DeleteExpiredSigningKeys(ctx context.Context) error {
+	return queryExecutor(r.pg).DeleteExpiredSigningKeys(ctx)
+}
+
+func (r *Repo) CreateAPIKey(ctx context.Context, key models.APIKey) (models.APIKey, error) {
+	return queryExecutor(r.pg).CreateAPIKey(ctx, key)
+}
+
+func (r *Repo) GetAPIKey(ctx context.Context, id string) (models.APIKey, error) {
+	return queryExecutor(r.pg).GetAPIKey(ctx, id)
+}
+
+func (r *Repo) GetUserAPIKeys(ctx context.Context, userId string, offset int, count int) ([]models.APIKey, error) {
+	return queryExecutor(r.pg).GetUserAPIKeys(ctx, userId, offset, count)
+}
+
+func (r *Repo) TouchAPIKey(ctx context.Context, id string) error {
+	return queryExecutor(r.pg).TouchAPIKey(ctx, id)
+}
+
+func (r *Repo) DeleteAPIKey(ctx context.Context, id string) error {
+	return queryExecutor(r.pg).DeleteAPIKey(ctx, id)
+}
+
+func (r *Repo) CreateExternalIdentity(ctx context.Context, identity models.ExternalIdentity) (models.ExternalIdentity, error) {
+	return queryExecutor(r.pg).CreateExternalIdentity(ctx, identity)
+}
+
+func (r *Repo) GetExternalIdentity(ctx context.Context, provider string, subject string) (models.ExternalIdentity, error) {
+	return queryExecutor(r.pg).GetExternalIdentity(ctx, provider, subject)
+}
+
+func (r *Repo) GetUserExternalIdentities(ctx context.Context, userId string) ([]models.ExternalIdentity, error) {
+	return queryExecutor(r.pg).GetUserExternalIdentities(ctx, userId)
+}
+
+func (r *Repo) DeleteExternalIdentity(ctx context.Context, userId string, provider string) error {
+	return queryExecutor(r.pg).DeleteExternalIdentity(ctx, userId, provider)
+}
+
+func (r *Repo) CreateLoginFlow(ctx context.Context, flow models.LoginFlow) error {
+	return queryExecutor(r.pg).CreateLoginFlow(ctx, flow)
+}
+
+func (r *Repo) TakeLoginFlow(ctx context.Context, stateHash string) (models.LoginFlow, error) {
+	return queryExecutor(r.pg).TakeLoginFlow(ctx, stateHash)
+}
+
+func (r *Repo) CreateTwoFactor(ctx context.Context, twoFactor models.TwoFactor) (models.TwoFactor, error) {
+	return queryExecutor(r.pg).CreateTwoFactor(ctx, twoFactor)
+}
+
+func (r *Repo) GetTwoFactor(ctx context.Context, userId string) (models.TwoFactor, error) {
+	return queryExecutor(r.pg).GetTwoFactor(ctx, userId)
+}
+
+func (r *Repo) EnableTwoFactor(ctx context.Context, userId string) error {
+	return queryExecutor(r.pg).EnableTwoFactor(ctx, userId)
+}
+
+func (r *Repo) UseTwoFactorStep(ctx context.Context, userId string, step int64) (models.TwoFactor, error) {
+	return queryExecutor(r.pg).UseTwoFactorStep(ctx, userId, step)
+}
+
+func (r *Repo) DeleteTwoFactor(ctx context.Context, userId string) error {
+	return queryExecutor(r.pg).DeleteTwoFactor(ctx, userId)
+}
+
+func (r *Repo) CreateRecoveryCodes(ctx context.Context, userId string, codeHashes []string) error {
+	return queryExecutor(r.pg).CreateRecoveryCodes(ctx, userId, codeHashes)
+}
+
+func (r *Repo) UseRecoveryCode(ctx context.Context, userId string, codeHash string) error {
+	return queryExecutor(r.pg).UseRecoveryCode(ctx, userId, codeHash)
+}
+
+func (r *Repo) CountRecoveryCodes(ctx context.Context, userId string) (int, error) {
+	return queryExecutor(r.pg).CountRecoveryCodes(ctx, userId)
+}
+
+func (r *Repo) DeleteRecoveryCodes(ctx context.Context, userId string) error {
+	return queryExecutor(r.pg).DeleteRecoveryCodes(ctx, userId)
+}
+
+func (r *Repo) CreateLoginChallenge(ctx context.Context, challenge models.LoginChallenge) (models.LoginChallenge, error) {
+	return queryExecutor(r.pg).CreateLoginChallenge(ctx, challenge)
+}
+
+func (r *Repo) AttemptLoginChallenge(ctx context.Context, id string, tokenHash string) (models.LoginChallenge, error) {
+	return queryExecutor(r.pg).AttemptLoginChallenge(ctx, id, tokenHash)
+}
+
+func (r *Repo) DeleteLoginChallenge(ctx context.Context, id string) error {
+	return queryExecutor(r.pg).DeleteLoginChallenge(ctx, id)
+}
+
+func (r *Repo) DeleteOtherUserSessions(ctx context.Context, userId string, keepId string) ([]string, error) {
+	return queryExecutor(r.pg).DeleteOtherUserSessions(ctx, userId, keepId)
+}
+
+func (r *Repo) CreatePasswordReset(ctx context.Context, reset models.PasswordReset) (models.PasswordReset, error) {
+	return queryExecutor(r.pg).CreatePasswordReset(ctx, reset)
+}
+
+func (r *Repo) TakePasswordReset(ctx context.Context, id string, tokenHash string) (models.PasswordReset, error) {
+	return queryExecutor(r.pg).TakePasswordReset(ctx, id, tokenHash)
+}
+
+func (r *Repo) DeletePasswordReset(ctx context.Context, userId string) error {
+	return queryExecutor(r.pg).DeletePasswordReset(ctx, userId)
+}
+
+func (r *Repo) GetLoginThrottle(ctx context.Context, key string) (models.LoginThrottle, error) {
+	return queryExecutor(r.pg).GetLoginThrottle(ctx, key)
+}
+
+func (r *Repo) RecordLoginFailure(ctx context.Context, key string, window time.Duration) (models.LoginThrottle, error) {
+	return queryExecutor(r.pg).RecordLoginFailure(ctx, key, window)
+}
+
+func (r *Repo) LockLogin(ctx context.Context, key string, until time.Time) error {
+	return queryExecutor(r.pg).LockLogin(ctx, key, until)
+}
+
+func (r *Repo) DeleteLoginThrottle(ctx context.Context, key string) error {
+	return queryExecutor(r.pg).DeleteLoginThrottle(ctx, key)
+}
+
+func (r *Repo) GetUserChatJoinRequest(ctx context.Context, chatId, userId string) (models.ChatJoinRequest, error) {
+	return queryExecutor(r.pg).GetUserChatJoinRequest(ctx, chatId, userId)
+}
+
+func (r *Repo) UpdateUserPasswordHash(ctx context.Context, id string, oldHash, newHash []byte) error {
+	return queryExecutor(r.pg).UpdateUserPasswordHash(ctx, id, oldHash, newHash)
+}
+
+func (r *Repo) SetUserPasswordHash(ctx context.Context, id string, hash []byte) error {
+	return queryExecutor(r.pg).SetUserPasswordHash(ctx, id, hash)
+}
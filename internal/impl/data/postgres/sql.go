@@ -1,13 +1,13 @@
 package postgres
 
-// INPUT: username, password_hash
+// INPUT: username, password_hash, kind
 //
-// OUTPUT: id, username, password_hash
+// OUTPUT: id, username, password_hash, kind
 const createUserSql = `
 	insert into Users
-		(username, password_hash)
-		values ($1, $2)
-	returning Users.id, Users.username, Users.password_hash
+		(username, password_hash, kind)
+		values ($1, $2, $3)
+	returning Users.id, Users.username, Users.password_hash, Users.kind
 `
 
 // INPUT: id
@@ -20,29 +20,47 @@ const deleteUserSql = `
 
 // INPUT: id
 //
-// OUTPUT: id, username, password_hash
+// OUTPUT: id, username, password_hash, kind
 const getUserSql = `
-	select id, username, password_hash from Users
+	select id, username, password_hash, kind from Users
 		where id = $1
 `
 
 // INPUT: username
 //
-// OUTPUT: id, username, password_hash
+// OUTPUT: id, username, password_hash, kind
 const getUserByUsernameSql = `
-	select id, username, password_hash from Users
+	select id, username, password_hash, kind from Users
 		where username = $1
 `
 
 // INPUT: id, new_username, new_password_hash
 //
-// OUTPUT: id, username, password_hash
+// OUTPUT: id, username, password_hash, kind
 const updateUserSql = `
 	update Users
 	set username = $2,
 		password_hash = $3
 	where id = $1
-	returning Users.id, Users.username, Users.password_hash
+	returning Users.id, Users.username, Users.password_hash, Users.kind
+`
+
+// INPUT: id, new_password_hash, old_password_hash
+//
+// OUTPUT: nil
+const updateUserPasswordHashSql = `
+	update Users
+	set password_hash = $2
+	where id = $1 and password_hash = $3
+`
+
+// INPUT: id, password_hash
+//
+// OUTPUT: nil
+const setUserPasswordHashSql = `
+	update Users
+	set password_hash = $2
+	where id = $1
 `
 
 // INPUT: user1_id, user2_id
@@ -64,9 +82,9 @@ const deleteFriendConnectionSql = `
 
 // INPUT: id, offset, limit
 //
-// OUTPUT: id, username, password_hash
+// OUTPUT: id, username, password_hash, kind
 const getUserFriendsSql = `
-	select u.id, u.username, u.password_hash from FriendConnections
+	select u.id, u.username, u.password_hash, u.kind from FriendConnections
 	join Users u on ((u.id = user1_id or u.id = user2_id) and u.id != $1)
 	where user1_id = $1 or user2_id = $1
 	order by u.id
@@ -181,14 +199,14 @@ const getFriendRequestByIDSql = `
 		where id = $1
 `
 
-// INPUT: name, description, owner_id
+// INPUT: name, description, owner_id, visibility, kind, count_views
 //
-// OUTPUT: id, name, description, owner_id
+// OUTPUT: id, name, description, owner_id, visibility, kind, count_views
 const createChatSql = `
 	insert into Chats as chat
-	(chat_name, description, owner_id)
-	values ($1, $2, $3)
-	returning chat.id, chat.chat_name, chat.description, chat.owner_id
+	(chat_name, description, owner_id, visibility, kind, count_views)
+	values ($1, $2, $3, $4, $5, $6)
+	returning chat.id, chat.chat_name, chat.description, chat.owner_id, chat.visibility, chat.kind, chat.count_views
 `
 
 // INPUT: id
@@ -201,31 +219,46 @@ const deleteChatSql = `
 
 // INPUT: id
 //
-// OUTPUT: id, name, description, owner_id
+// OUTPUT: id, name, description, owner_id, visibility, kind, count_views
 const getChatSql = `
-	select id, chat_name, description, owner_id from Chats
+	select id, chat_name, description, owner_id, visibility, kind, count_views from Chats
 		where id = $1
 `
 
-// INPUT: id, name, description
+// INPUT: id, name, description, visibility, owner_id, count_views
 //
-// OUTPUT: id, name, description, owner_id
+// OUTPUT: id, name, description, owner_id, visibility, kind, count_views
 const updateChatSql = `
 	update Chats
 	set chat_name = $2,
-		description = $3
+		description = $3,
+		visibility = $4,
+		owner_id = $5,
+		count_views = $6
 	where id = $1
-	returning Chats.id, Chats.chat_name, Chats.owner_id
+	returning Chats.id, Chats.chat_name, Chats.description, Chats.owner_id, Chats.visibility, Chats.kind, Chats.count_views
+`
+
+// INPUT: name_pattern, offset, count
+//
+// OUTPUT: id, name, description, owner_id, visibility, kind, count_views
+const searchPublicChatsSql = `
+	select c.id, c.chat_name, c.description, c.owner_id, c.visibility, c.kind, c.count_views from Chats c
+		where c.visibility != 0
+			and (c.chat_name ilike $1 or c.description ilike $1)
+		order by (select count(*) from ChatMembers m where m.chat_id = c.id) desc, c.id
+		offset $2
+		limit $3
 `
 
 // INPUT: user_id, chat_id, status
 //
-// OUTPUT: user_id, chat_id, status
+// OUTPUT: user_id, chat_id, status, joined_at
 const createChatMemberSql = `
 	insert into ChatMembers as mem
 		(user_id, chat_id, status)
 		values ($1, $2, $3)
-		returning mem.user_id, mem.chat_id, mem.status
+		returning mem.user_id, mem.chat_id, mem.status, mem.joined_at
 `
 
 // INPUT: user_id, chat_id
@@ -238,30 +271,53 @@ const deleteChatMemberSql = `
 
 // INPUT: user_id, chat_id, status
 //
-// OUTPUT: user_id, chat_id, status
+// OUTPUT: user_id, chat_id, status, joined_at
 const updateChatMemberSql = `
 	update ChatMembers
-	set status = $2
+	set status = $3
 	where user_id = $1 and chat_id = $2
-	returning ChatMembers.user_id, ChatMembers.chat_id, ChatMembers.status
+	returning ChatMembers.user_id, ChatMembers.chat_id, ChatMembers.status, ChatMembers.joined_at
 `
 
 // INPUT: userId, chatId
 //
-// OUTPUT: user_id, chat_id, status
+// OUTPUT: user_id, chat_id, status, joined_at
 const getChatMemberSql = `
-	select user_id, chat_id, status from ChatMembers
+	select user_id, chat_id, status, joined_at from ChatMembers
 		where user_id = $1 and chat_id = $2
 `
 
-// INPUT: user_id, chat_id, payload, timestamp, last_update
+// INPUT: chat_id, user_id
+//
+// OUTPUT: user_id, chat_id, status, joined_at
+//
+// Picks the member who should own the chat after the given user:
+// the oldest admin or, if there are none, the oldest member.
+// Integrations never inherit chats.
+const getChatSuccessorSql = `
+	select user_id, chat_id, status, joined_at from ChatMembers
+		where chat_id = $1 and user_id != $2
+			and user_id in (select id from Users where kind = 0)
+		order by status desc, joined_at, user_id
+		limit 1
+`
+
+// INPUT: owner_id
+//
+// OUTPUT: id, name, description, owner_id, visibility, kind, count_views
+const getUserOwnedChatsSql = `
+	select id, chat_name, description, owner_id, visibility, kind, count_views from Chats
+		where owner_id = $1
+`
+
+// INPUT: user_id, chat_id, payload, timestamp, last_update, attachments
 //
-// Output: id, user_id, chat_id, payload, timestamp, last_update
+// Output: id, user_id, chat_id, payload, timestamp, last_update, views, attachments
 const createMessageSql = `
 	insert into Messages as mes
-	(user_id, chat_id, payload, time, last_update)
-	values ($1, $2, $3, $4, $5)
-	returning mes.id, mes.user_id, mes.chat_id, mes.payload, mes.time, mes.last_update
+	(user_id, chat_id, payload, time, last_update, attachments)
+	values ($1, $2, $3, $4, $5, $6)
+	returning mes.id, mes.user_id, mes.chat_id, mes.payload, mes.time, mes.last_update, mes.views, mes.attachments
 `
 
 // INPUT: id
@@ -280,22 +336,39 @@ const updateMessageSql = `
 	set payload = $2,
 		last_update = $3
 	where id  = $1
-	returning mes.id, mes.user_id, mes.chat_id, mes.payload, mes.time, mes.last_update
+	returning mes.id, mes.user_id, mes.chat_id, mes.payload, mes.time, mes.last_update, mes.views, mes.attachments
 `
 
 // INPUT: id
 //
-// OUTPUT: id, user_id, chat_id, payload, time, last_update
+// OUTPUT: id, user_id, chat_id, payload, time, last_update, views, attachments
 const getMessageSql = `
-	select id, user_id, chat_id, payload, time, last_update from Messages
+	select id, user_id, chat_id, payload, time, last_update, views, attachments from Messages
 		where id = $1
 `
 
+// INPUT: message_ids, user_id
+//
+// OUTPUT: nil
+//
+// Every user is counted once per message, so repeated reads don't inflate the counters.
+const markMessagesViewedSql = `
+	with viewed as (
+		insert into MessageViews (message_id, user_id)
+			select unnest($1::text[])::uuid, $2
+			on conflict do nothing
+			returning message_id
+	)
+	update Messages
+	set views = views + 1
+	where id in (select message_id from viewed)
+`
+
 // INPUT: user_id, offset, count
 //
-// OUTPUT: user_id, chat_id, status
+// OUTPUT: user_id, chat_id, status, joined_at
 const getUserChatsSql = `
-	select user_id, chat_id, status from ChatMembers
+	select user_id, chat_id, status, joined_at from ChatMembers
 		where user_id = $1
 		order by user_id
 		offset $2
@@ -304,9 +377,9 @@ const getUserChatsSql = `
 
 // INPUT: chat_id, offset, count
 //
-// OUTPUT: user_id, chat_id, status
+// OUTPUT: user_id, chat_id, status, joined_at
 const getChatMembersSql = `
-	select user_id, chat_id, status from ChatMembers
+	select user_id, chat_id, status, joined_at from ChatMembers
 		where chat_id = $1
 		order by user_id
 		offset $2
@@ -315,9 +388,9 @@ const getChatMembersSql = `
 
 // INPUT: chat_id, offset, count
 //
-// OUTPUT: id, user_id, chat_id, payload, time, last_update
+// OUTPUT: id, user_id, chat_id, payload, time, last_update, views, attachments
 const getChatMessagesSql = `
-	select id, user_id, chat_id, payload, time, last_update from Messages
+	select id, user_id, chat_id, payload, time, last_update, views, attachments from Messages
 		where chat_id = $1
 		order by time desc
 		offset $2
@@ -348,80 +421,1330 @@ const countUserChatsSql = `
 		where user_id = $1
 `
 
+// INPUT: code, chat_id, creator_id, max_uses, expires_at, requires_approval
 //
+// OUTPUT: code, chat_id, creator_id, max_uses, uses, expires_at, requires_approval, revoked, created_at
+const createChatInviteSql = `
+	insert into ChatInvites as inv
+	(code, chat_id, creator_id, max_uses, expires_at, requires_approval)
+	values ($1, $2, $3, $4, $5, $6)
+	returning inv.code, inv.chat_id, inv.creator_id, inv.max_uses, inv.uses,
+		inv.expires_at, inv.requires_approval, inv.revoked, inv.created_at
+`
 
-const initializeTablesSql = `
--- Extensions
-create extension if not exists "uuid-ossp";
+// INPUT: code
+//
+// OUTPUT: code, chat_id, creator_id, max_uses, uses, expires_at, requires_approval, revoked, created_at
+const getChatInviteSql = `
+	select code, chat_id, creator_id, max_uses, uses,
+		expires_at, requires_approval, revoked, created_at from ChatInvites
+		where code = $1
+`
 
--- Tables
-create table if not exists Users (
-	id uuid default uuid_generate_v1() primary key,
-	username varchar (40) unique not null,
-	password_hash varchar (200) not null
-);
+// INPUT: chat_id, offset, count
+//
+// OUTPUT: code, chat_id, creator_id, max_uses, uses, expires_at, requires_approval, revoked, created_at
+const getChatInvitesSql = `
+	select code, chat_id, creator_id, max_uses, uses,
+		expires_at, requires_approval, revoked, created_at from ChatInvites
+		where chat_id = $1
+			and not revoked
+			and (expires_at is null or expires_at > now())
+			and (max_uses = 0 or uses < max_uses)
+		order by created_at desc
+		offset $2
+		limit $3
+`
 
-create table if not exists Chats (
-	id uuid default uuid_generate_v1() primary key,
-	chat_name varchar (40) not null,
-	description varchar (500),
-	owner_id uuid not null,
-	
-	foreign key (owner_id)
-		references Users (id)
-);
+// INPUT: code
+//
+// OUTPUT: code, chat_id, creator_id, max_uses, uses, expires_at, requires_approval, revoked, created_at
+//
+// Only active invites are used, so an empty result means that the invite
+// does not exist, was revoked, has expired or has run out of uses.
+const useChatInviteSql = `
+	update ChatInvites as inv
+	set uses = inv.uses + 1
+	where inv.code = $1
+		and not inv.revoked
+		and (inv.expires_at is null or inv.expires_at > now())
+		and (inv.max_uses = 0 or inv.uses < inv.max_uses)
+	returning inv.code, inv.chat_id, inv.creator_id, inv.max_uses, inv.uses,
+		inv.expires_at, inv.requires_approval, inv.revoked, inv.created_at
+`
 
-create table if not exists ChatMembers (
-	user_id uuid not null,
-	chat_id uuid not null,
-	status int not null,
-	
-	foreign key (user_id)
-		references Users (id)
-			on delete cascade,
-	foreign key (chat_id)
-		references Chats (id)
-			on delete cascade,
-	primary key (user_id, chat_id)
-);
+// INPUT: code
+//
+// OUTPUT: nil
+const revokeChatInviteSql = `
+	update ChatInvites
+	set revoked = true
+	where code = $1
+`
 
-create table if not exists FriendConnections (
-	user1_id uuid not null,
-	user2_id uuid not null,
-	
-	foreign key (user1_id)
-		references Users (id),
-	foreign key (user2_id)
-		references Users (id),
-	primary key (user1_id, user2_id)
-);
+// INPUT: chat_id, user_id, invite_code, time
+//
+// OUTPUT: id, chat_id, user_id, invite_code, time
+const createChatJoinRequestSql = `
+	insert into ChatJoinRequests as req
+	(chat_id, user_id, invite_code, time)
+	values ($1, $2, $3, $4)
+	returning req.id, req.chat_id, req.user_id, req.invite_code, req.time
+`
 
-create table if not exists FriendRequests (
-	id uuid default uuid_generate_v1() primary key,
-	from_id uuid not null,
-	time timestamp default now(),
-	to_id uuid not null,
-	
-	foreign key (from_id)
-		references Users (id),
-	foreign key (to_id)
-		references Users (id)
-);
+// INPUT: id
+//
+// OUTPUT: nil
+const deleteChatJoinRequestSql = `
+	delete from ChatJoinRequests
+		where id = $1
+`
 
-create table if not exists Messages (
-	id uuid  default uuid_generate_v1() primary key,
-	user_id uuid not null,
-	chat_id uuid not null,
-	payload varchar (400) not null,
-	time timestamp default now(),
-	last_update timestamp,
-	
-	foreign key (user_id, chat_id)
-		references ChatMembers (user_id, chat_id) on delete cascade
-);
+// INPUT: id
+//
+// OUTPUT: id, chat_id, user_id, invite_code, time
+const getChatJoinRequestSql = `
+	select id, chat_id, user_id, invite_code, time from ChatJoinRequests
+		where id = $1
+`
 
--- Indices
+// INPUT: chat_id, offset, count
+//
+// OUTPUT: id, chat_id, user_id, invite_code, time
+const getChatJoinRequestsSql = `
+	select id, chat_id, user_id, invite_code, time from ChatJoinRequests
+		where chat_id = $1
+		order by time
+		offset $2
+		limit $3
+`
 
-create index if not exists "index_message_time"
-on Messages using btree (time);
+// INPUT: chat_id, user_id
+//
+// OUTPUT: id, chat_id, user_id, invite_code, time
+const getUserChatJoinRequestSql = `
+	select id, chat_id, user_id, invite_code, time from ChatJoinRequests
+		where chat_id = $1 and user_id = $2
+`
+
+// INPUT: name, data, time_stamp, chat_id, user_ids, schema_version
+//
+// OUTPUT: id, name, data, time_stamp, chat_id, user_ids, schema_version, created_at
+const createOutboxEventSql = `
+	insert into Outbox as o
+	(name, data, time_stamp, chat_id, user_ids, schema_version)
+	values ($1, $2, $3, $4, $5, $6)
+	returning o.id, o.name, o.data, o.time_stamp, o.chat_id, o.user_ids, o.schema_version, o.created_at
+`
+
+// INPUT: ids
+//
+// OUTPUT: nil
+const deleteOutboxEventsSql = `
+	delete from Outbox
+		where id = any($1::uuid[])
+`
+
+// Claimed events are leased until claimed_until, so other relays don't pick them up
+// while they are being sent, and no transaction is held open meanwhile.
+//
+// INPUT: count, lease
+//
+// OUTPUT: [](id, name, data, time_stamp, chat_id, user_ids, schema_version, created_at)
+const claimOutboxEventsSql = `
+	update Outbox as o
+	set claimed_until = now() + $2::interval
+	where o.id in (
+		select id from Outbox
+			where claimed_until <= now()
+			order by seq
+			limit $1
+			for update skip locked
+	)
+	returning o.id, o.name, o.data, o.time_stamp, o.chat_id, o.user_ids, o.schema_version, o.created_at
+`
+
+// INPUT: ids
+//
+// OUTPUT: nil
+const releaseOutboxEventsSql = `
+	update Outbox
+	set claimed_until = now()
+	where id = any($1::uuid[])
+`
+
+// INPUT: owner_id, chat_id, url, secret, events
+//
+// OUTPUT: id, owner_id, chat_id, url, secret, events, created_at
+const createWebhookSql = `
+	insert into Webhooks as w
+	(owner_id, chat_id, url, secret, events)
+	values ($1, $2, $3, $4, $5)
+	returning w.id, w.owner_id, w.chat_id, w.url, w.secret, w.events, w.created_at
+`
+
+// INPUT: id
+//
+// OUTPUT: nil
+const deleteWebhookSql = `
+	delete from Webhooks
+		where id = $1
+`
+
+// INPUT: id
+//
+// OUTPUT: id, owner_id, chat_id, url, secret, events, created_at
+const getWebhookSql = `
+	select id, owner_id, chat_id, url, secret, events, created_at from Webhooks
+		where id = $1
+`
+
+// INPUT: user_id, offset, count
+//
+// OUTPUT: [](id, owner_id, chat_id, url, secret, events, created_at)
+const getUserWebhooksSql = `
+	select id, owner_id, chat_id, url, secret, events, created_at from Webhooks
+		where owner_id = $1 and chat_id is null
+		order by created_at
+		offset $2
+		limit $3
+`
+
+// INPUT: chat_id, offset, count
+//
+// OUTPUT: [](id, owner_id, chat_id, url, secret, events, created_at)
+const getChatWebhooksSql = `
+	select id, owner_id, chat_id, url, secret, events, created_at from Webhooks
+		where chat_id = $1
+		order by created_at
+		offset $2
+		limit $3
+`
+
+// Chat webhooks match the events of their chats as long as their owners own the chats,
+// user webhooks match the events routed to their owners,
+// webhooks of bots also match the events of the bots' chats.
+//
+// INPUT: name, chat_id (nullable), user_ids
+//
+// OUTPUT: [](id, owner_id, chat_id, url, secret, events, created_at)
+const getMatchingWebhooksSql = `
+	select id, owner_id, chat_id, url, secret, events, created_at from Webhooks
+		where (cardinality(events) = 0 or $1 = any(events))
+			and (
+				(chat_id = $2 and owner_id = (select c.owner_id from Chats c where c.id = $2))
+				or (chat_id is null and owner_id::text = any($3::text[]))
+				or (chat_id is null and owner_id in (
+					select m.user_id from ChatMembers m
+					join Users u on u.id = m.user_id and u.kind = 2
+					where m.chat_id = $2
+				))
+			)
+`
+
+// Redelivered events (with the same id) are ignored.
+//
+// INPUT: webhook_id, event_id, event_name, payload
+//
+// OUTPUT: nil
+const createWebhookDeliverySql = `
+	insert into WebhookDeliveries
+	(webhook_id, event_id, event_name, payload)
+	values ($1, $2, $3, $4)
+	on conflict do nothing
+`
+
+// INPUT: id, status, attempts, response_code, error, next_attempt_at
+//
+// OUTPUT: nil
+const updateWebhookDeliverySql = `
+	update WebhookDeliveries
+	set status = $2,
+		attempts = $3,
+		response_code = $4,
+		error = $5,
+		next_attempt_at = $6,
+		updated_at = now()
+	where id = $1
+`
+
+// Due deliveries are leased by moving their next attempt forward,
+// so other workers don't pick them up while they are being sent.
+//
+// INPUT: count, lease
+//
+// OUTPUT: [](id, webhook_id, event_id, event_name, payload, status, attempts, response_code, error, next_attempt_at, created_at, updated_at)
+const claimWebhookDeliveriesSql = `
+	update WebhookDeliveries as d
+	set next_attempt_at = now() + $2::interval
+	where d.id in (
+		select id from WebhookDeliveries
+			where status = 0 and next_attempt_at <= now()
+			order by next_attempt_at
+			limit $1
+			for update skip locked
+	)
+	returning d.id, d.webhook_id, d.event_id, d.event_name, d.payload, d.status, d.attempts,
+		d.response_code, d.error, d.next_attempt_at, d.created_at, d.updated_at
+`
+
+// INPUT: id
+//
+// OUTPUT: id, webhook_id, event_id, event_name, payload, status, attempts, response_code, error, next_attempt_at, created_at, updated_at
+const getWebhookDeliverySql = `
+	select id, webhook_id, event_id, event_name, payload, status, attempts,
+		response_code, error, next_attempt_at, created_at, updated_at from WebhookDeliveries
+		where id = $1
+`
+
+// A negative status selects deliveries in any status.
+//
+// INPUT: webhook_id, status, offset, count
+//
+// OUTPUT: [](id, webhook_id, event_id, event_name, payload, status, attempts, response_code, error, next_attempt_at, created_at, updated_at)
+const getWebhookDeliveriesSql = `
+	select id, webhook_id, event_id, event_name, payload, status, attempts,
+		response_code, error, next_attempt_at, created_at, updated_at from WebhookDeliveries
+		where webhook_id = $1 and ($2 < 0 or status = $2)
+		order by created_at desc
+		offset $3
+		limit $4
+`
+
+//
+
+// INPUT: chat_id, user_id, creator_id, name, token_hash
+//
+// OUTPUT: id, chat_id, user_id, creator_id, name, token_hash, created_at
+const createIncomingWebhookSql = `
+	insert into IncomingWebhooks as w
+	(chat_id, user_id, creator_id, name, token_hash)
+	values ($1, $2, $3, $4, $5)
+	returning w.id, w.chat_id, w.user_id, w.creator_id, w.name, w.token_hash, w.created_at
+`
+
+// INPUT: id
+//
+// OUTPUT: nil
+const deleteIncomingWebhookSql = `
+	delete from IncomingWebhooks
+		where id = $1
+`
+
+// INPUT: id
+//
+// OUTPUT: id, chat_id, user_id, creator_id, name, token_hash, created_at
+const getIncomingWebhookSql = `
+	select id, chat_id, user_id, creator_id, name, token_hash, created_at from IncomingWebhooks
+		where id = $1
+`
+
+// INPUT: chat_id, offset, count
+//
+// OUTPUT: [](id, chat_id, user_id, creator_id, name, token_hash, created_at)
+const getChatIncomingWebhooksSql = `
+	select id, chat_id, user_id, creator_id, name, token_hash, created_at from IncomingWebhooks
+		where chat_id = $1
+		order by created_at
+		offset $2
+		limit $3
+`
+
+// INPUT: user_id, owner_id, token_hash
+//
+// OUTPUT: user_id, owner_id, token_hash, created_at
+const createBotSql = `
+	insert into Bots as b
+	(user_id, owner_id, token_hash)
+	values ($1, $2, $3)
+	returning b.user_id, b.owner_id, b.token_hash, b.created_at
+`
+
+// INPUT: user_id, token_hash
+//
+// OUTPUT: nil
+const updateBotTokenSql = `
+	update Bots
+	set token_hash = $2
+	where user_id = $1
+`
+
+// INPUT: user_id
+//
+// OUTPUT: user_id, owner_id, token_hash, created_at
+const getBotSql = `
+	select user_id, owner_id, token_hash, created_at from Bots
+		where user_id = $1
+`
+
+// INPUT: owner_id, offset, count
+//
+// OUTPUT: [](user_id, owner_id, token_hash, created_at)
+const getUserBotsSql = `
+	select user_id, owner_id, token_hash, created_at from Bots
+		where owner_id = $1
+		order by created_at
+		offset $2
+		limit $3
+`
+
+// Deletes the users of the bots, their credentials are removed by the cascade.
+//
+// INPUT: owner_id
+//
+// OUTPUT: nil
+const deleteUserBotsSql = `
+	delete from Users
+		where id in (select user_id from Bots where owner_id = $1)
+`
+
+// INPUT: bot_id, command, description
+//
+// OUTPUT: nil
+const createBotCommandSql = `
+	insert into BotCommands
+	(bot_id, command, description)
+	values ($1, $2, $3)
+`
+
+// INPUT: bot_id
+//
+// OUTPUT: nil
+const deleteBotCommandsSql = `
+	delete from BotCommands
+		where bot_id = $1
+`
+
+// INPUT: bot_id
+//
+// OUTPUT: [](bot_id, command, description)
+const getBotCommandsSql = `
+	select bot_id, command, description from BotCommands
+		where bot_id = $1
+		order by command
+`
+
+// Finds the bots of the chat that have registered the command.
+//
+// INPUT: chat_id, command
+//
+// OUTPUT: [](bot_id, command, description)
+const getChatBotCommandsSql = `
+	select c.bot_id, c.command, c.description from BotCommands c
+	join ChatMembers m on m.user_id = c.bot_id
+	where m.chat_id = $1 and c.command = $2
+`
+
+// Fans the event out to the bots that are members of the chat or are among
+// the recipients. Bots with webhooks receive their updates there instead.
+//
+// INPUT: event_id, name, payload, chat_id, user_ids
+//
+// OUTPUT: nil
+const createBotUpdatesSql = `
+	insert into BotUpdates
+	(bot_id, event_id, name, payload)
+	select u.id, $1, $2, $3 from Users u
+		where u.kind = 2
+			and (
+				u.id in (select user_id from ChatMembers where chat_id::text = $4)
+				or u.id::text = any($5::text[])
+			)
+			and not exists (select 1 from Webhooks w where w.owner_id = u.id)
+	on conflict do nothing
+`
+
+// INPUT: bot_id, offset, count
+//
+// OUTPUT: [](id, bot_id, event_id, name, payload, created_at)
+const getBotUpdatesSql = `
+	select id, bot_id, event_id, name, payload, created_at from BotUpdates
+		where bot_id = $1 and id >= $2
+		order by id
+		limit $3
+`
+
+// Removes the updates confirmed by the bot and the ones that
+// have been waiting for longer than the retention period.
+//
+// INPUT: bot_id, offset, retention
+//
+// OUTPUT: nil
+const deleteBotUpdatesSql = `
+	delete from BotUpdates
+		where (bot_id = $1 and id < $2)
+			or created_at < now() - $3::interval
+`
+
+// Removes the expired sessions of the user along the way.
+//
+// INPUT: user_id, token_hash, user_agent, ip, expires_at
+//
+// OUTPUT: id, user_id, token_hash, user_agent, ip, expires_at, created_at, last_seen_at, previous_token_hashes
+const createSessionSql = `
+	with expired as (
+		delete from Sessions
+			where user_id = $1 and expires_at < now()
+	)
+	insert into Sessions as s
+	(user_id, token_hash, user_agent, ip, expires_at)
+	values ($1, $2, $3, $4, $5)
+	returning s.id, s.user_id, s.token_hash, s.user_agent, s.ip, s.expires_at, s.created_at, s.last_seen_at, s.previous_token_hashes
+`
+
+// INPUT: id
+//
+// OUTPUT: id, user_id, token_hash, user_agent, ip, expires_at, created_at, last_seen_at, previous_token_hashes
+const getSessionSql = `
+	select id, user_id, token_hash, user_agent, ip, expires_at, created_at, last_seen_at, previous_token_hashes from Sessions
+		where id = $1
+`
+
+// INPUT: user_id, offset, count
+//
+// OUTPUT: [](id, user_id, token_hash, user_agent, ip, expires_at, created_at, last_seen_at, previous_token_hashes)
+const getUserSessionsSql = `
+	select id, user_id, token_hash, user_agent, ip, expires_at, created_at, last_seen_at, previous_token_hashes from Sessions
+		where user_id = $1 and expires_at > now()
+		order by last_seen_at desc
+		offset $2
+		limit $3
+`
+
+// Replaces the refresh token only if it's still the one the caller has seen,
+// so a token can't be exchanged twice by concurrent requests. The replaced hash
+// is kept among the last 32 previous ones to tell a replay from a forged token.
+//
+// INPUT: id, token_hash, new_token_hash, ip, expires_at
+//
+// OUTPUT: id, user_id, token_hash, user_agent, ip, expires_at, created_at, last_seen_at, previous_token_hashes
+const rotateSessionSql = `
+	update Sessions as s
+	set token_hash = $3, ip = $4, expires_at = $5, last_seen_at = now(),
+		previous_token_hashes = (array_append(s.previous_token_hashes, $2))[greatest(cardinality(s.previous_token_hashes) - 30, 1):]
+	where s.id = $1 and s.token_hash = $2
+	returning s.id, s.user_id, s.token_hash, s.user_agent, s.ip, s.expires_at, s.created_at, s.last_seen_at, s.previous_token_hashes
+`
+
+// Fails (no rows) if the session doesn't exist or has expired.
+//
+// INPUT: id
+//
+// OUTPUT: id, user_id, token_hash, user_agent, ip, expires_at, created_at, last_seen_at, previous_token_hashes
+const touchSessionSql = `
+	update Sessions as s
+	set last_seen_at = now()
+	where s.id = $1 and s.expires_at > now()
+	returning s.id, s.user_id, s.token_hash, s.user_agent, s.ip, s.expires_at, s.created_at, s.last_seen_at, s.previous_token_hashes
+`
+
+// INPUT: id
+//
+// OUTPUT: nil
+const deleteSessionSql = `
+	delete from Sessions
+		where id = $1
+`
+
+// INPUT: user_id
+//
+// OUTPUT: [](id)
+const deleteUserSessionsSql = `
+	delete from Sessions
+		where user_id = $1
+	returning id
+`
+
+// INPUT: id, algorithm, private_key, created_at, retires_at, expires_at
+//
+// OUTPUT: nil
+const createSigningKeySql = `
+	insert into SigningKeys
+	(id, algorithm, private_key, created_at, retires_at, expires_at)
+	values ($1, $2, $3, $4, $5, $6)
+`
+
+// INPUT: nil
+//
+// OUTPUT: [](id, algorithm, private_key, created_at, retires_at, expires_at)
+const getSigningKeysSql = `
+	select id, algorithm, private_key, created_at, retires_at, expires_at from SigningKeys
+		where expires_at > now()
+		order by created_at desc
+`
+
+// INPUT: nil
+//
+// OUTPUT: nil
+const deleteExpiredSigningKeysSql = `
+	delete from SigningKeys
+		where expires_at <= now()
+`
+
+// INPUT: user_id, name, token_hash, scopes, expires_at
+//
+// OUTPUT: id, user_id, name, token_hash, scopes, expires_at, last_used_at, created_at
+const createAPIKeySql = `
+	insert into APIKeys as k
+	(user_id, name, token_hash, scopes, expires_at)
+	values ($1, $2, $3, $4, $5)
+	returning k.id, k.user_id, k.name, k.token_hash, k.scopes, k.expires_at, k.last_used_at, k.created_at
+`
+
+// INPUT: id
+//
+// OUTPUT: id, user_id, name, token_hash, scopes, expires_at, last_used_at, created_at
+const getAPIKeySql = `
+	select id, user_id, name, token_hash, scopes, expires_at, last_used_at, created_at from APIKeys
+		where id = $1
+`
+
+// INPUT: user_id, offset, count
+//
+// OUTPUT: [](id, user_id, name, token_hash, scopes, expires_at, last_used_at, created_at)
+const getUserAPIKeysSql = `
+	select id, user_id, name, token_hash, scopes, expires_at, last_used_at, created_at from APIKeys
+		where user_id = $1
+		order by created_at
+		offset $2
+		limit $3
+`
+
+// Records the use of a key, at most once a minute to spare the writes.
+//
+// INPUT: id
+//
+// OUTPUT: nil
+const touchAPIKeySql = `
+	update APIKeys
+	set last_used_at = now()
+	where id = $1 and (last_used_at is null or last_used_at < now() - interval '1 minute')
+`
+
+// INPUT: id
+//
+// OUTPUT: nil
+const deleteAPIKeySql = `
+	delete from APIKeys
+		where id = $1
+`
+
+// INPUT: provider, subject, user_id, email
+//
+// OUTPUT: provider, subject, user_id, email, created_at
+const createExternalIdentitySql = `
+	insert into ExternalIdentities as i
+	(provider, subject, user_id, email)
+	values ($1, $2, $3, $4)
+	returning i.provider, i.subject, i.user_id, i.email, i.created_at
+`
+
+// INPUT: provider, subject
+//
+// OUTPUT: provider, subject, user_id, email, created_at
+const getExternalIdentitySql = `
+	select provider, subject, user_id, email, created_at from ExternalIdentities
+		where provider = $1 and subject = $2
+`
+
+// INPUT: user_id
+//
+// OUTPUT: [](provider, subject, user_id, email, created_at)
+const getUserExternalIdentitiesSql = `
+	select provider, subject, user_id, email, created_at from ExternalIdentities
+		where user_id = $1
+		order by created_at
+`
+
+// INPUT: user_id, provider
+//
+// OUTPUT: nil
+const deleteExternalIdentitySql = `
+	delete from ExternalIdentities
+		where user_id = $1 and provider = $2
+`
+
+// Removes the expired flows along the way.
+//
+// INPUT: state_hash, provider, code_verifier, nonce, user_id, expires_at
+//
+// OUTPUT: nil
+const createLoginFlowSql = `
+	with expired as (
+		delete from LoginFlows
+			where expires_at < now()
+	)
+	insert into LoginFlows
+	(state_hash, provider, code_verifier, nonce, user_id, expires_at)
+	values ($1, $2, $3, $4, $5, $6)
+`
+
+// Deletes the flow, so it can't be completed twice.
+// Fails (no rows) if the flow doesn't exist or has expired.
+//
+// INPUT: state_hash
+//
+// OUTPUT: state_hash, provider, code_verifier, nonce, user_id, expires_at, created_at
+const takeLoginFlowSql = `
+	with taken as (
+		delete from LoginFlows
+			where state_hash = $1
+		returning state_hash, provider, code_verifier, nonce, user_id, expires_at, created_at
+	)
+	select state_hash, provider, code_verifier, nonce, user_id, expires_at, created_at from taken
+		where expires_at > now()
+`
+
+// Replaces the secret of a user who hasn't enabled two-factor authentication yet.
+//
+// INPUT: user_id, secret
+//
+// OUTPUT: user_id, secret, enabled, last_used_step, created_at
+const createTwoFactorSql = `
+	insert into TwoFactor as t
+	(user_id, secret)
+	values ($1, $2)
+	on conflict (user_id) do update
+		set secret = excluded.secret, last_used_step = 0, created_at = now()
+		where t.enabled = false
+	returning t.user_id, t.secret, t.enabled, t.last_used_step, t.created_at
+`
+
+// INPUT: user_id
+//
+// OUTPUT: user_id, secret, enabled, last_used_step, created_at
+const getTwoFactorSql = `
+	select user_id, secret, enabled, last_used_step, created_at from TwoFactor
+		where user_id = $1
+`
+
+// INPUT: user_id
+//
+// OUTPUT: nil
+const enableTwoFactorSql = `
+	update TwoFactor
+		set enabled = true
+		where user_id = $1
+`
+
+// Accepts the time step only if it's later than the last accepted one.
+// Fails (no rows) otherwise.
+//
+// INPUT: user_id, step
+//
+// OUTPUT: user_id, secret, enabled, last_used_step, created_at
+const useTwoFactorStepSql = `
+	update TwoFactor as t
+		set last_used_step = $2
+		where t.user_id = $1 and t.last_used_step < $2
+	returning t.user_id, t.secret, t.enabled, t.last_used_step, t.created_at
+`
+
+// INPUT: user_id
+//
+// OUTPUT: nil
+const deleteTwoFactorSql = `
+	delete from TwoFactor
+		where user_id = $1
+`
+
+// Replaces the recovery codes of the user.
+//
+// INPUT: user_id, code_hashes
+//
+// OUTPUT: nil
+const createRecoveryCodesSql = `
+	with deleted as (
+		delete from RecoveryCodes
+			where user_id = $1
+	)
+	insert into RecoveryCodes
+	(user_id, code_hash)
+	select $1, unnest($2::text[])
+`
+
+// Fails (no rows) if there is no such code.
+//
+// INPUT: user_id, code_hash
+//
+// OUTPUT: code_hash
+const useRecoveryCodeSql = `
+	delete from RecoveryCodes
+		where user_id = $1 and code_hash = $2
+	returning code_hash
+`
+
+// INPUT: user_id
+//
+// OUTPUT: count
+const countRecoveryCodesSql = `
+	select count(*) from RecoveryCodes
+		where user_id = $1
+`
+
+// INPUT: user_id
+//
+// OUTPUT: nil
+const deleteRecoveryCodesSql = `
+	delete from RecoveryCodes
+		where user_id = $1
+`
+
+// Removes the expired challenges of the user along the way.
+//
+// INPUT: user_id, token_hash, expires_at
+//
+// OUTPUT: id, user_id, token_hash, attempts, expires_at, created_at
+const createLoginChallengeSql = `
+	with expired as (
+		delete from LoginChallenges
+			where user_id = $1 and expires_at < now()
+	)
+	insert into LoginChallenges as c
+	(user_id, token_hash, expires_at)
+	values ($1, $2, $3)
+	returning c.id, c.user_id, c.token_hash, c.attempts, c.expires_at, c.created_at
+`
+
+// Counts an attempt to complete the challenge, only the holder of the token can make one.
+// Fails (no rows) if the challenge doesn't exist, has expired or the token doesn't match.
+//
+// INPUT: id, token_hash
+//
+// OUTPUT: id, user_id, token_hash, attempts, expires_at, created_at
+const attemptLoginChallengeSql = `
+	update LoginChallenges as c
+		set attempts = c.attempts + 1
+		where c.id = $1 and c.token_hash = $2 and c.expires_at > now()
+	returning c.id, c.user_id, c.token_hash, c.attempts, c.expires_at, c.created_at
+`
+
+// INPUT: id
+//
+// OUTPUT: nil
+const deleteLoginChallengeSql = `
+	delete from LoginChallenges
+		where id = $1
+`
+
+// Deletes the sessions of the user except the one given.
+//
+// INPUT: user_id, keep_id
+//
+// OUTPUT: id
+const deleteOtherUserSessionsSql = `
+	delete from Sessions
+		where user_id = $1 and id <> $2
+	returning id
+`
+
+// Replaces the password reset of the user, the expired resets of the others are cleaned up
+// (the user's own one is updated by the upsert rather than deleted in the same statement).
+//
+// INPUT: user_id, token_hash, expires_at
+//
+// OUTPUT: id, user_id, token_hash, expires_at, created_at
+const createPasswordResetSql = `
+	with expired as (
+		delete from PasswordResets
+			where expires_at < now() and user_id <> $1
+	)
+	insert into PasswordResets as p
+	(user_id, token_hash, expires_at)
+	values ($1, $2, $3)
+	on conflict (user_id) do update
+		set id = uuid_generate_v1(), token_hash = excluded.token_hash,
+			expires_at = excluded.expires_at, created_at = now()
+	returning p.id, p.user_id, p.token_hash, p.expires_at, p.created_at
+`
+
+// Deletes the password reset and returns it if the token matches and it hasn't expired.
+// Fails (no rows) otherwise.
+//
+// INPUT: id, token_hash
+//
+// OUTPUT: id, user_id, token_hash, expires_at, created_at
+const takePasswordResetSql = `
+	delete from PasswordResets as p
+		where p.id = $1 and p.token_hash = $2 and p.expires_at > now()
+	returning p.id, p.user_id, p.token_hash, p.expires_at, p.created_at
+`
+
+// INPUT: user_id
+//
+// OUTPUT: nil
+const deletePasswordResetSql = `
+	delete from PasswordResets
+		where user_id = $1
+`
+
+// INPUT: key
+//
+// OUTPUT: key, failures, locked_until, last_failure_at
+const getLoginThrottleSql = `
+	select key, failures, locked_until, last_failure_at from LoginThrottles
+		where key = $1
+`
+
+// Counts a failed login, the count starts over if the previous failure is older than the window.
+// The throttles that have been quiet for the window are cleaned up along the way.
+//
+// INPUT: key, window
+//
+// OUTPUT: key, failures, locked_until, last_failure_at
+const recordLoginFailureSql = `
+	with expired as (
+		delete from LoginThrottles
+			where key <> $1 and last_failure_at < now() - $2::interval
+				and (locked_until is null or locked_until < now())
+	)
+	insert into LoginThrottles as t
+	(key, failures)
+	values ($1, 1)
+	on conflict (key) do update
+		set failures = case
+				when t.last_failure_at < now() - $2::interval then 1
+				else t.failures + 1
+			end,
+			last_failure_at = now()
+	returning t.key, t.failures, t.locked_until, t.last_failure_at
+`
+
+// INPUT: key, locked_until
+//
+// OUTPUT: nil
+const lockLoginSql = `
+	update LoginThrottles
+		set locked_until = $2
+		where key = $1
+`
+
+// INPUT: key
+//
+// OUTPUT: nil
+const deleteLoginThrottleSql = `
+	delete from LoginThrottles
+		where key = $1
+`
+
+const initializeTablesSql = `
+-- Extensions
+create extension if not exists "uuid-ossp";
+
+-- Tables
+create table if not exists Users (
+	id uuid default uuid_generate_v1() primary key,
+	username varchar (40) unique not null,
+	password_hash varchar (200) not null,
+	kind int not null default 0
+);
+
+alter table Users add column if not exists kind int not null default 0;
+
+create table if not exists Chats (
+	id uuid default uuid_generate_v1() primary key,
+	chat_name varchar (40) not null,
+	description varchar (500),
+	owner_id uuid not null,
+	visibility int not null default 0,
+	kind int not null default 0,
+	count_views boolean not null default false,
+	
+	foreign key (owner_id)
+		references Users (id)
+);
+
+alter table Chats add column if not exists visibility int not null default 0;
+alter table Chats add column if not exists kind int not null default 0;
+alter table Chats add column if not exists count_views boolean not null default false;
+
+create table if not exists ChatMembers (
+	user_id uuid not null,
+	chat_id uuid not null,
+	status int not null,
+	joined_at timestamp default now(),
+	
+	foreign key (user_id)
+		references Users (id)
+			on delete cascade,
+	foreign key (chat_id)
+		references Chats (id)
+			on delete cascade,
+	primary key (user_id, chat_id)
+);
+
+alter table ChatMembers add column if not exists joined_at timestamp default now();
+
+create table if not exists FriendConnections (
+	user1_id uuid not null,
+	user2_id uuid not null,
+	
+	foreign key (user1_id)
+		references Users (id),
+	foreign key (user2_id)
+		references Users (id),
+	primary key (user1_id, user2_id)
+);
+
+create table if not exists FriendRequests (
+	id uuid default uuid_generate_v1() primary key,
+	from_id uuid not null,
+	time timestamp default now(),
+	to_id uuid not null,
+	
+	foreign key (from_id)
+		references Users (id),
+	foreign key (to_id)
+		references Users (id)
+);
+
+create table if not exists Messages (
+	id uuid  default uuid_generate_v1() primary key,
+	user_id uuid not null,
+	chat_id uuid not null,
+	payload varchar (400) not null,
+	time timestamp default now(),
+	last_update timestamp,
+	views int not null default 0,
+	
+	foreign key (user_id, chat_id)
+		references ChatMembers (user_id, chat_id) on delete cascade
+);
+
+alter table Messages add column if not exists views int not null default 0;
+alter table Messages add column if not exists attachments jsonb not null default '[]';
+
+create table if not exists MessageViews (
+	message_id uuid not null,
+	user_id uuid not null,
+	
+	foreign key (message_id)
+		references Messages (id)
+			on delete cascade,
+	foreign key (user_id)
+		references Users (id)
+			on delete cascade,
+	primary key (message_id, user_id)
+);
+
+create table if not exists ChatInvites (
+	code varchar (40) primary key,
+	chat_id uuid not null,
+	creator_id uuid not null,
+	max_uses int not null default 0,
+	uses int not null default 0,
+	expires_at timestamp,
+	requires_approval boolean not null default false,
+	revoked boolean not null default false,
+	created_at timestamp default now(),
+	
+	foreign key (chat_id)
+		references Chats (id)
+			on delete cascade,
+	foreign key (creator_id)
+		references Users (id)
+			on delete cascade
+);
+
+create table if not exists ChatJoinRequests (
+	id uuid default uuid_generate_v1() primary key,
+	chat_id uuid not null,
+	user_id uuid not null,
+	invite_code varchar (40) not null default '',
+	time timestamp default now(),
+	
+	foreign key (chat_id)
+		references Chats (id)
+			on delete cascade,
+	foreign key (user_id)
+		references Users (id)
+			on delete cascade,
+	unique (chat_id, user_id)
+);
+
+create table if not exists Outbox (
+	id uuid default uuid_generate_v1() primary key,
+	seq bigserial not null,
+	name varchar (100) not null,
+	data jsonb,
+	time_stamp bigint not null,
+	chat_id text not null default '',
+	user_ids text[] not null default '{}',
+	created_at timestamp default now()
+);
+
+alter table Outbox add column if not exists schema_version int not null default 0;
+alter table Outbox add column if not exists claimed_until timestamp not null default now();
+
+create table if not exists Webhooks (
+	id uuid default uuid_generate_v1() primary key,
+	owner_id uuid not null,
+	chat_id uuid,
+	url text not null,
+	secret text not null,
+	events text[] not null default '{}',
+	created_at timestamp default now(),
+
+	foreign key (owner_id)
+		references Users (id)
+			on delete cascade,
+	foreign key (chat_id)
+		references Chats (id)
+			on delete cascade
+);
+
+create table if not exists WebhookDeliveries (
+	id uuid default uuid_generate_v1() primary key,
+	webhook_id uuid not null,
+	event_id text not null default '',
+	event_name varchar (100) not null,
+	payload jsonb not null,
+	status int not null default 0,
+	attempts int not null default 0,
+	response_code int not null default 0,
+	error text not null default '',
+	next_attempt_at timestamp not null default now(),
+	created_at timestamp default now(),
+	updated_at timestamp default now(),
+
+	foreign key (webhook_id)
+		references Webhooks (id)
+			on delete cascade
+);
+
+create table if not exists Bots (
+	user_id uuid primary key,
+	owner_id uuid not null,
+	token_hash text not null,
+	created_at timestamp default now(),
+
+	foreign key (user_id)
+		references Users (id)
+			on delete cascade,
+	foreign key (owner_id)
+		references Users (id)
+			on delete cascade
+);
+
+create table if not exists BotCommands (
+	bot_id uuid not null,
+	command varchar (32) not null,
+	description varchar (256) not null default '',
+
+	foreign key (bot_id)
+		references Users (id)
+			on delete cascade,
+	primary key (bot_id, command)
+);
+
+create table if not exists BotUpdates (
+	id bigserial primary key,
+	bot_id uuid not null,
+	event_id text not null,
+	name varchar (100) not null,
+	payload jsonb not null,
+	created_at timestamp default now(),
+
+	foreign key (bot_id)
+		references Users (id)
+			on delete cascade,
+	unique (bot_id, event_id)
+);
+
+create table if not exists IncomingWebhooks (
+	id uuid default uuid_generate_v1() primary key,
+	chat_id uuid not null,
+	user_id uuid not null,
+	creator_id uuid not null,
+	name varchar (40) not null,
+	token_hash text not null,
+	created_at timestamp default now(),
+
+	foreign key (chat_id)
+		references Chats (id)
+			on delete cascade,
+	foreign key (user_id)
+		references Users (id)
+			on delete cascade,
+	foreign key (creator_id)
+		references Users (id)
+			on delete cascade
+);
+
+create table if not exists Sessions (
+	id uuid default uuid_generate_v4() primary key,
+	user_id uuid not null,
+	token_hash text not null,
+	previous_token_hashes text[] not null default '{}',
+	user_agent varchar (256) not null default '',
+	ip varchar (64) not null default '',
+	expires_at timestamp not null,
+	created_at timestamp default now(),
+	last_seen_at timestamp default now(),
+
+	foreign key (user_id)
+		references Users (id)
+			on delete cascade
+);
+
+-- the ids get into the access tokens, so they are random rather than time-based
+alter table Sessions alter column id set default uuid_generate_v4();
+alter table Sessions add column if not exists previous_token_hashes text[] not null default '{}';
+
+create table if not exists SigningKeys (
+	id text primary key,
+	algorithm varchar (16) not null,
+	private_key bytea not null,
+	created_at timestamp not null,
+	retires_at timestamp not null,
+	expires_at timestamp not null
+);
+
+-- the private keys used to be stored unencrypted (PKCS #8 starts with 0x30),
+-- they are dropped and the ring creates a new key in their place
+delete from SigningKeys where get_byte(private_key, 0) = 48;
+
+create table if not exists APIKeys (
+	id uuid default uuid_generate_v1() primary key,
+	user_id uuid not null,
+	name varchar (40) not null,
+	token_hash text not null,
+	scopes text[] not null default '{}',
+	expires_at timestamp,
+	last_used_at timestamp,
+	created_at timestamp default now(),
+
+	foreign key (user_id)
+		references Users (id)
+			on delete cascade
+);
+
+create table if not exists ExternalIdentities (
+	provider varchar (64) not null,
+	subject text not null,
+	user_id uuid not null,
+	email text not null default '',
+	created_at timestamp default now(),
+
+	primary key (provider, subject),
+	unique (user_id, provider),
+	foreign key (user_id)
+		references Users (id)
+			on delete cascade
+);
+
+create table if not exists LoginFlows (
+	state_hash text primary key,
+	provider varchar (64) not null,
+	code_verifier text not null,
+	nonce text not null,
+	user_id uuid,
+	expires_at timestamp not null,
+	created_at timestamp default now(),
+
+	foreign key (user_id)
+		references Users (id)
+			on delete cascade
+);
+
+create table if not exists TwoFactor (
+	user_id uuid primary key,
+	secret text not null,
+	enabled boolean not null default false,
+	last_used_step bigint not null default 0,
+	created_at timestamp default now(),
+
+	foreign key (user_id)
+		references Users (id)
+			on delete cascade
+);
+
+create table if not exists RecoveryCodes (
+	user_id uuid not null,
+	code_hash text not null,
+
+	primary key (user_id, code_hash),
+	foreign key (user_id)
+		references Users (id)
+			on delete cascade
+);
+
+create table if not exists LoginChallenges (
+	id uuid default uuid_generate_v1() primary key,
+	user_id uuid not null,
+	token_hash text not null,
+	attempts int not null default 0,
+	expires_at timestamp not null,
+	created_at timestamp default now(),
+
+	foreign key (user_id)
+		references Users (id)
+			on delete cascade
+);
+
+create table if not exists PasswordResets (
+	id uuid default uuid_generate_v1() primary key,
+	user_id uuid unique not null,
+	token_hash text not null,
+	expires_at timestamp not null,
+	created_at timestamp default now(),
+
+	foreign key (user_id)
+		references Users (id)
+			on delete cascade
+);
+
+create table if not exists LoginThrottles (
+	key text primary key,
+	failures int not null default 0,
+	locked_until timestamp,
+	last_failure_at timestamp not null default now()
+);
+
+-- Indices
+
+create index if not exists "index_message_time"
+on Messages using btree (time);
+
+create index if not exists "index_chat_visibility"
+on Chats using btree (visibility);
+
+create index if not exists "index_chat_invite_chat"
+on ChatInvites using btree (chat_id);
+
+create index if not exists "index_outbox_seq"
+on Outbox using btree (seq);
+
+create index if not exists "index_webhook_chat"
+on Webhooks using btree (chat_id);
+
+create index if not exists "index_webhook_owner"
+on Webhooks using btree (owner_id);
+
+create index if not exists "index_webhook_delivery_due"
+on WebhookDeliveries using btree (next_attempt_at) where status = 0;
+
+create unique index if not exists "index_webhook_delivery_event"
+on WebhookDeliveries (webhook_id, event_id) where event_id <> '';
+
+create index if not exists "index_incoming_webhook_chat"
+on IncomingWebhooks using btree (chat_id);
+
+create index if not exists "index_bot_owner"
+on Bots using btree (owner_id);
+
+create index if not exists "index_bot_update_bot"
+on BotUpdates using btree (bot_id, id);
+
+create index if not exists "index_bot_update_created_at"
+on BotUpdates using btree (created_at);
+
+create index if not exists "index_session_user"
+on Sessions using btree (user_id);
+
+create index if not exists "index_api_key_user"
+on APIKeys using btree (user_id);
+
+create index if not exists "index_login_challenge_user"
+on LoginChallenges using btree (user_id);
 `
@@ -4,6 +4,7 @@ import (
 	"context"
 	"github.com/ischenkx/vk-test-task/internal/app/data/models"
 	"github.com/jackc/pgx/v4"
+	"time"
 )
 
 type Tx struct {
@@ -186,3 +187,355 @@ func (t Tx) CountUserChats(ctx context.Context, id string) (int, error) {
 	return queryExecutor(t.pg).CountUserChats(ctx, id)
 
 }
+
+func (t Tx) CreateChatInvite(ctx context.Context, invite models.ChatInvite) (models.ChatInvite, error) {
+	return queryExecutor(t.pg).CreateChatInvite(ctx, invite)
+}
+
+func (t Tx) GetChatInvite(ctx context.Context, code string) (models.ChatInvite, error) {
+	return queryExecutor(t.pg).GetChatInvite(ctx, code)
+}
+
+func (t Tx) GetChatInvites(ctx context.Context, chatId string, offset int, count int) ([]models.ChatInvite, error) {
+	return queryExecutor(t.pg).GetChatInvites(ctx, chatId, offset, count)
+}
+
+func (t Tx) UseChatInvite(ctx context.Context, code string) (models.ChatInvite, error) {
+	return queryExecutor(t.pg).UseChatInvite(ctx, code)
+}
+
+func (t Tx) RevokeChatInvite(ctx context.Context, code string) error {
+	return queryExecutor(t.pg).RevokeChatInvite(ctx, code)
+}
+
+func (t Tx) CreateChatJoinRequest(ctx context.Context, request models.ChatJoinRequest) (models.ChatJoinRequest, error) {
+	return queryExecutor(t.pg).CreateChatJoinRequest(ctx, request)
+}
+
+func (t Tx) DeleteChatJoinRequest(ctx context.Context, id string) error {
+	return queryExecutor(t.pg).DeleteChatJoinRequest(ctx, id)
+}
+
+func (t Tx) GetChatJoinRequest(ctx context.Context, id string) (models.ChatJoinRequest, error) {
+	return queryExecutor(t.pg).GetChatJoinRequest(ctx, id)
+}
+
+func (t Tx) GetChatJoinRequests(ctx context.Context, chatId string, offset int, count int) ([]models.ChatJoinRequest, error) {
+	return queryExecutor(t.pg).GetChatJoinRequests(ctx, chatId, offset, count)
+}
+
+func (t Tx) SearchPublicChats(ctx context.Context, query string, offset int, count int) ([]models.Chat, error) {
+	return queryExecutor(t.pg).SearchPublicChats(ctx, query, offset, count)
+}
+
+func (t Tx) GetChatSuccessor(ctx context.Context, chatId, userId string) (models.ChatMember, error) {
+	return queryExecutor(t.pg).GetChatSuccessor(ctx, chatId, userId)
+}
+
+func (t Tx) GetUserOwnedChats(ctx context.Context, userId string) ([]models.Chat, error) {
+	return queryExecutor(t.pg).GetUserOwnedChats(ctx, userId)
+}
+
+func (t Tx) MarkMessagesViewed(ctx context.Context, userId string, messageIds []string) error {
+	return queryExecutor(t.pg).MarkMessagesViewed(ctx, userId, messageIds)
+}
+
+func (t Tx) CreateOutboxEvent(ctx context.Context, e models.OutboxEvent) (models.OutboxEvent, error) {
+	return queryExecutor(t.pg).CreateOutboxEvent(ctx, e)
+}
+
+func (t Tx) DeleteOutboxEvents(ctx context.Context, ids []string) error {
+	return queryExecutor(t.pg).DeleteOutboxEvents(ctx, ids)
+}
+
+func (t Tx) ReleaseOutboxEvents(ctx context.Context, ids []string) error {
+	return queryExecutor(t.pg).ReleaseOutboxEvents(ctx, ids)
+}
+
+func (t Tx) ClaimOutboxEvents(ctx context.Context, count int, lease time.Duration) ([]models.OutboxEvent, error) {
+	return queryExecutor(t.pg).ClaimOutboxEvents(ctx, count, lease)
+}
+
+func (t Tx) CreateWebhook(ctx context.Context, webhook models.Webhook) (models.Webhook, error) {
+	return queryExecutor(t.pg).CreateWebhook(ctx, webhook)
+}
+
+func (t Tx) DeleteWebhook(ctx context.Context, id string) error {
+	return queryExecutor(t.pg).DeleteWebhook(ctx, id)
+}
+
+func (t Tx) GetWebhook(ctx context.Context, id string) (models.Webhook, error) {
+	return queryExecutor(t.pg).GetWebhook(ctx, id)
+}
+
+func (t Tx) GetUserWebhooks(ctx context.Context, userId string, offset int, count int) ([]models.Webhook, error) {
+	return queryExecutor(t.pg).GetUserWebhooks(ctx, userId, offset, count)
+}
+
+func (t Tx) GetChatWebhooks(ctx context.Context, chatId string, offset int, count int) ([]models.Webhook, error) {
+	return queryExecutor(t.pg).GetChatWebhooks(ctx, chatId, offset, count)
+}
+
+func (t Tx) GetMatchingWebhooks(ctx context.Context, name string, chatId string, userIds []string) ([]models.Webhook, error) {
+	return queryExecutor(t.pg).GetMatchingWebhooks(ctx, name, chatId, userIds)
+}
+
+func (t Tx) CreateWebhookDelivery(ctx context.Context, delivery models.WebhookDelivery) error {
+	return queryExecutor(t.pg).CreateWebhookDelivery(ctx, delivery)
+}
+
+func (t Tx) UpdateWebhookDelivery(ctx context.Context, delivery models.WebhookDelivery) error {
+	return queryExecutor(t.pg).UpdateWebhookDelivery(ctx, delivery)
+}
+
+func (t Tx) ClaimWebhookDeliveries(ctx context.Context, count int, lease time.Duration) ([]models.WebhookDelivery, error) {
+	return queryExecutor(t.pg).ClaimWebhookDeliveries(ctx, count, lease)
+}
+
+func (t Tx) GetWebhookDelivery(ctx context.Context, id string) (models.WebhookDelivery, error) {
+	return queryExecutor(t.pg).GetWebhookDelivery(ctx, id)
+}
+
+func (t Tx) GetWebhookDeliveries(ctx context.Context, webhookId string, status int, offset int, count int) ([]models.WebhookDelivery, error) {
+	return queryExecutor(t.pg).GetWebhookDeliveries(ctx, webhookId, status, offset, count)
+}
+
+func (t Tx) CreateIncomingWebhook(ctx context.Context, webhook models.IncomingWebhook) (models.IncomingWebhook, error) {
+	return queryExecutor(t.pg).CreateIncomingWebhook(ctx, webhook)
+}
+
+func (t Tx) DeleteIncomingWebhook(ctx context.Context, id string) error {
+	return queryExecutor(t.pg).DeleteIncomingWebhook(ctx, id)
+}
+
+func (t Tx) GetIncomingWebhook(ctx context.Context, id string) (models.IncomingWebhook, error) {
+	return queryExecutor(t.pg).GetIncomingWebhook(ctx, id)
+}
+
+func (t Tx) GetChatIncomingWebhooks(ctx context.Context, chatId string, offset int, count int) ([]models.IncomingWebhook, error) {
+	return queryExecutor(t.pg).GetChatIncomingWebhooks(ctx, chatId, offset, count)
+}
+
+func (t Tx) CreateBot(ctx context.Context, bot models.Bot) (models.Bot, error) {
+	return queryExecutor(t.pg).CreateBot(ctx, bot)
+}
+
+func (t Tx) UpdateBotToken(ctx context.Context, userId string, tokenHash string) error {
+	return queryExecutor(t.pg).UpdateBotToken(ctx, userId, tokenHash)
+}
+
+func (t Tx) GetBot(ctx context.Context, userId string) (models.Bot, error) {
+	return queryExecutor(t.pg).GetBot(ctx, userId)
+}
+
+func (t Tx) GetUserBots(ctx context.Context, ownerId string, offset int, count int) ([]models.Bot, error) {
+	return queryExecutor(t.pg).GetUserBots(ctx, ownerId, offset, count)
+}
+
+func (t Tx) DeleteUserBots(ctx context.Context, ownerId string) error {
+	return queryExecutor(t.pg).DeleteUserBots(ctx, ownerId)
+}
+
+func (t Tx) CreateBotCommand(ctx context.Context, command models.BotCommand) error {
+	return queryExecutor(t.pg).CreateBotCommand(ctx, command)
+}
+
+func (t Tx) DeleteBotCommands(ctx context.Context, botId string) error {
+	return queryExecutor(t.pg).DeleteBotCommands(ctx, botId)
+}
+
+func (t Tx) GetBotCommands(ctx context.Context, botId string) ([]models.BotCommand, error) {
+	return queryExecutor(t.pg).GetBotCommands(ctx, botId)
+}
+
+func (t Tx) GetChatBotCommands(ctx context.Context, chatId string, command string) ([]models.BotCommand, error) {
+	return queryExecutor(t.pg).GetChatBotCommands(ctx, chatId, command)
+}
+
+func (t Tx) CreateBotUpdates(ctx context.Context, update models.BotUpdate, chatId string, userIds []string) error {
+	return queryExecutor(t.pg).CreateBotUpdates(ctx, update, chatId, userIds)
+}
+
+func (t Tx) GetBotUpdates(ctx context.Context, botId string, offset int64, count int) ([]models.BotUpdate, error) {
+	return queryExecutor(t.pg).GetBotUpdates(ctx, botId, offset, count)
+}
+
+func (t Tx) DeleteBotUpdates(ctx context.Context, botId string, offset int64, retention time.Duration) error {
+	return queryExecutor(t.pg).DeleteBotUpdates(ctx, botId, offset, retention)
+}
+
+func (t Tx) CreateSession(ctx context.Context, session models.Session) (models.Session, error) {
+	return queryExecutor(t.pg).CreateSession(ctx, session)
+}
+
+func (t Tx) GetSession(ctx context.Context, id string) (models.Session, error) {
+	return queryExecutor(t.pg).GetSession(ctx, id)
+}
+
+func (t Tx) GetUserSessions(ctx context.Context, userId string, offset int, count int) ([]models.Session, error) {
+	return queryExecutor(t.pg).GetUserSessions(ctx, userId, offset, count)
+}
+
+func (t Tx) RotateSession(ctx context.Context, id string, tokenHash string, newTokenHash string, ip string, expiresAt time.Time) (models.Session, error) {
+	return queryExecutor(t.pg).RotateSession(ctx, id, tokenHash, newTokenHash, ip, expiresAt)
+}
+
+func (t Tx) TouchSession(ctx context.Context, id string) (models.Session, error) {
+	return queryExecutor(t.pg).TouchSession(ctx, id)
+}
+
+func (t Tx) DeleteSession(ctx context.Context, id string) error {
+	return queryExecutor(t.pg).DeleteSession(ctx, id)
+}
+
+func (t Tx) DeleteUserSessions(ctx context.Context, userId string) ([]string, error) {
+	return queryExecutor(t.pg).DeleteUserSessions(ctx, userId)
+}
+
+func (t Tx) CreateSigningKey(ctx context.Context, key models.SigningKey) error {
+	return queryExecutor(t.pg).CreateSigningKey(ctx, key)
+}
+
+func (t Tx) GetSigningKeys(ctx context.Context) ([]models.SigningKey, error) {
+	return queryExecutor(t.pg).GetSigningKeys(ctx)
+}
+
+func (t Tx) DeleteExpiredSigningKeys(ctx context.Context) error {
+	return queryExecutor(t.pg).DeleteExpiredSigningKeys(ctx)
+}
+
+func (t Tx) CreateAPIKey(ctx context.Context, key models.APIKey) (models.APIKey, error) {
+	return queryExecutor(t.pg).CreateAPIKey(ctx, key)
+}
+
+func (t Tx) GetAPIKey(ctx context.Context, id string) (models.APIKey, error) {
+	return queryExecutor(t.pg).GetAPIKey(ctx, id)
+}
+
+func (t Tx) GetUserAPIKeys(ctx context.Context, userId string, offset int, count int) ([]models.APIKey, error) {
+	return queryExecutor(t.pg).GetUserAPIKeys(ctx, userId, offset, count)
+}
+
+func (t Tx) TouchAPIKey(ctx context.Context, id string) error {
+	return queryExecutor(t.pg).TouchAPIKey(ctx, id)
+}
+
+func (t Tx) DeleteAPIKey(ctx context.Context, id string) error {
+	return queryExecutor(t.pg).DeleteAPIKey(ctx, id)
+}
+
+func (t Tx) CreateExternalIdentity(ctx context.Context, identity models.ExternalIdentity) (models.ExternalIdentity, error) {
+	return queryExecutor(t.pg).CreateExternalIdentity(ctx, identity)
+}
+
+func (t Tx) GetExternalIdentity(ctx context.Context, provider string, subject string) (models.ExternalIdentity, error) {
+	return queryExecutor(t.pg).GetExternalIdentity(ctx, provider, subject)
+}
+
+func (t Tx) GetUserExternalIdentities(ctx context.Context, userId string) ([]models.ExternalIdentity, error) {
+	return queryExecutor(t.pg).GetUserExternalIdentities(ctx, userId)
+}
+
+func (t Tx) DeleteExternalIdentity(ctx context.Context, userId string, provider string) error {
+	return queryExecutor(t.pg).DeleteExternalIdentity(ctx, userId, provider)
+}
+
+func (t Tx) CreateLoginFlow(ctx context.Context, flow models.LoginFlow) error {
+	return queryExecutor(t.pg).CreateLoginFlow(ctx, flow)
+}
+
+func (t Tx) TakeLoginFlow(ctx context.Context, stateHash string) (models.LoginFlow, error) {
+	return queryExecutor(t.pg).TakeLoginFlow(ctx, stateHash)
+}
+
+func (t Tx) CreateTwoFactor(ctx context.Context, twoFactor models.TwoFactor) (models.TwoFactor, error) {
+	return queryExecutor(t.pg).CreateTwoFactor(ctx, twoFactor)
+}
+
+func (t Tx) GetTwoFactor(ctx context.Context, userId string) (models.TwoFactor, error) {
+	return queryExecutor(t.pg).GetTwoFactor(ctx, userId)
+}
+
+func (t Tx) EnableTwoFactor(ctx context.Context, userId string) error {
+	return queryExecutor(t.pg).EnableTwoFactor(ctx, userId)
+}
+
+func (t Tx) UseTwoFactorStep(ctx context.Context, userId string, step int64) (models.TwoFactor, error) {
+	return queryExecutor(t.pg).UseTwoFactorStep(ctx, userId, step)
+}
+
+func (t Tx) DeleteTwoFactor(ctx context.Context, userId string) error {
+	return queryExecutor(t.pg).DeleteTwoFactor(ctx, userId)
+}
+
+func (t Tx) CreateRecoveryCodes(ctx context.Context, userId string, codeHashes []string) error {
+	return queryExecutor(t.pg).CreateRecoveryCodes(ctx, userId, codeHashes)
+}
+
+func (t Tx) UseRecoveryCode(ctx context.Context, userId string, codeHash string) error {
+	return queryExecutor(t.pg).UseRecoveryCode(ctx, userId, codeHash)
+}
+
+func (t Tx) CountRecoveryCodes(ctx context.Context, userId string) (int, error) {
+	return queryExecutor(t.pg).CountRecoveryCodes(ctx, userId)
+}
+
+func (t Tx) DeleteRecoveryCodes(ctx context.Context, userId string) error {
+	return queryExecutor(t.pg).DeleteRecoveryCodes(ctx, userId)
+}
+
+func (t Tx) CreateLoginChallenge(ctx context.Context, challenge models.LoginChallenge) (models.LoginChallenge, error) {
+	return queryExecutor(t.pg).CreateLoginChallenge(ctx, challenge)
+}
+
+func (t Tx) AttemptLoginChallenge(ctx context.Context, id string, tokenHash string) (models.LoginChallenge, error) {
+	return queryExecutor(t.pg).AttemptLoginChallenge(ctx, id, tokenHash)
+}
+
+func (t Tx) DeleteLoginChallenge(ctx context.Context, id string) error {
+	return queryExecutor(t.pg).DeleteLoginChallenge(ctx, id)
+}
+
+func (t Tx) DeleteOtherUserSessions(ctx context.Context, userId string, keepId string) ([]string, error) {
+	return queryExecutor(t.pg).DeleteOtherUserSessions(ctx, userId, keepId)
+}
+
+func (t Tx) CreatePasswordReset(ctx context.Context, reset models.PasswordReset) (models.PasswordReset, error) {
+	return queryExecutor(t.pg).CreatePasswordReset(ctx, reset)
+}
+
+func (t Tx) TakePasswordReset(ctx context.Context, id string, tokenHash string) (models.PasswordReset, error) {
+	return queryExecutor(t.pg).TakePasswordReset(ctx, id, tokenHash)
+}
+
+func (t Tx) DeletePasswordReset(ctx context.Context, userId string) error {
+	return queryExecutor(t.pg).DeletePasswordReset(ctx, userId)
+}
+
+func (t Tx) GetLoginThrottle(ctx context.Context, key string) (models.LoginThrottle, error) {
+	return queryExecutor(t.pg).GetLoginThrottle(ctx, key)
+}
+
+func (t Tx) RecordLoginFailure(ctx context.Context, key string, window time.Duration) (models.LoginThrottle, error) {
+	return queryExecutor(t.pg).RecordLoginFailure(ctx, key, window)
+}
+
+func (t Tx) LockLogin(ctx context.Context, key string, until time.Time) error {
+	return queryExecutor(t.pg).LockLogin(ctx, key, until)
+}
+
+func (t Tx) DeleteLoginThrottle(ctx context.Context, key string) error {
+	return queryExecutor(t.pg).DeleteLoginThrottle(ctx, key)
+}
+
+func (t Tx) GetUserChatJoinRequest(ctx context.Context, chatId, userId string) (models.ChatJoinRequest, error) {
+	return queryExecutor(t.pg).GetUserChatJoinRequest(ctx, chatId, userId)
+}
+
+func (t Tx) UpdateUserPasswordHash(ctx context.Context, id string, oldHash, newHash []byte) error {
+	return queryExecutor(t.pg).UpdateUserPasswordHash(ctx, id, oldHash, newHash)
+}
+
+func (t Tx) SetUserPasswordHash(ctx context.Context, id string, hash []byte) error {
+	return queryExecutor(t.pg).SetUserPasswordHash(ctx, id, hash)
+}
@@ -5,20 +5,112 @@ import (
 	"errors"
 	"github.com/ischenkx/vk-test-task/internal/app/event"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
-type handle struct {
-	channel <-chan event.Event
-	bus     *Bus
-	closed  bool
+type reader struct {
+	// accessed atomically, kept first for 64-bit alignment
+	delivered    uint64
+	dropped      uint64
+	disconnected uint32
+
 	id      int64
+	channel chan event.Event
+	options event.ChannelOptions
+	// done is closed along with the handle to stop a blocked delivery
+	done chan struct{}
+
+	mu     sync.Mutex
+	closed bool
+}
+
+// deliver puts the event into the reader's buffer according to its overflow policy.
+//
+// It returns false if the reader has been disconnected or closed and must be removed from the bus.
+func (r *reader) deliver(e event.Event) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed || atomic.LoadUint32(&r.disconnected) == 1 {
+		return false
+	}
+
+	select {
+	case r.channel <- e:
+		atomic.AddUint64(&r.delivered, 1)
+		return true
+	default:
+	}
+
+	switch r.options.Overflow {
+	case event.DropOldest:
+		select {
+		case <-r.channel:
+			atomic.AddUint64(&r.dropped, 1)
+		default:
+		}
+		// nobody else sends to the channel while we hold the lock,
+		// so there is room for the event unless the channel is unbuffered
+		select {
+		case r.channel <- e:
+			atomic.AddUint64(&r.delivered, 1)
+		default:
+			atomic.AddUint64(&r.dropped, 1)
+		}
+	case event.Block:
+		timer := time.NewTimer(r.options.BlockTimeout)
+		defer timer.Stop()
+		select {
+		case r.channel <- e:
+			atomic.AddUint64(&r.delivered, 1)
+		case <-timer.C:
+			atomic.AddUint64(&r.dropped, 1)
+		case <-r.done:
+			atomic.AddUint64(&r.dropped, 1)
+			return false
+		}
+	case event.Disconnect:
+		atomic.AddUint64(&r.dropped, 1)
+		atomic.StoreUint32(&r.disconnected, 1)
+		close(r.channel)
+		return false
+	default:
+		atomic.AddUint64(&r.dropped, 1)
+	}
+
+	return true
+}
+
+// close stops the deliveries to the reader, the ones in progress are given up
+func (r *reader) close() {
+	close(r.done)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.closed = true
+}
+
+func (r *reader) stats() event.ChannelStats {
+	return event.ChannelStats{
+		Delivered:    atomic.LoadUint64(&r.delivered),
+		Dropped:      atomic.LoadUint64(&r.dropped),
+		Disconnected: atomic.LoadUint32(&r.disconnected) == 1,
+	}
+}
+
+type handle struct {
+	reader *reader
+	bus    *Bus
+	closed bool
+	id     int64
 }
 
 func (h *handle) Chan(ctx context.Context) (<-chan event.Event, error) {
 	if h.closed {
 		return nil, errors.New("already closed")
 	}
-	return h.channel, nil
+	return h.reader.channel, nil
 }
 
 func (h *handle) Close(ctx context.Context) error {
@@ -27,56 +119,159 @@ func (h *handle) Close(ctx context.Context) error {
 	}
 	h.closed = true
 	h.bus.deleteReader(h.id)
+	h.reader.close()
 	return nil
 }
 
+func (h *handle) Stats() event.ChannelStats {
+	return h.reader.stats()
+}
+
+// Bus is an in-memory event.Bus.
+//
+// Subscribers are indexed by the chats and users of their filters,
+// so Send only touches the subscribers an event is routed to.
 type Bus struct {
-	readers map[int64]chan event.Event
-	seq     int64
-	mu      sync.RWMutex
+	readers  map[int64]*reader
+	unrouted map[int64]*reader
+	chats    map[string]map[int64]*reader
+	users    map[string]map[int64]*reader
+	seq      int64
+	mu       sync.RWMutex
+}
+
+func (b *Bus) addReader(r *reader) {
+	b.readers[r.id] = r
+
+	filter := r.options.Filter
+	if !filter.Routed() {
+		b.unrouted[r.id] = r
+		return
+	}
+	for _, id := range filter.ChatIDs {
+		addToIndex(b.chats, id, r)
+	}
+	for _, id := range filter.UserIDs {
+		addToIndex(b.users, id, r)
+	}
 }
 
 func (b *Bus) deleteReader(id int64) {
 	b.mu.Lock()
 	defer b.mu.Unlock()
+
+	r, ok := b.readers[id]
+	if !ok {
+		return
+	}
 	delete(b.readers, id)
+	delete(b.unrouted, id)
+	for _, chatID := range r.options.Filter.ChatIDs {
+		deleteFromIndex(b.chats, chatID, id)
+	}
+	for _, userID := range r.options.Filter.UserIDs {
+		deleteFromIndex(b.users, userID, id)
+	}
+}
+
+// match collects the subscribers the event must be delivered to
+func (b *Bus) match(e event.Event) map[int64]*reader {
+	res := map[int64]*reader{}
+	collect := func(readers map[int64]*reader) {
+		for id, r := range readers {
+			if r.options.Filter.Match(e) {
+				res[id] = r
+			}
+		}
+	}
+
+	collect(b.unrouted)
+	if e.ChatID != "" {
+		collect(b.chats[e.ChatID])
+	}
+	for _, id := range e.UserIDs {
+		collect(b.users[id])
+	}
+
+	return res
 }
 
-func (b *Bus) Channel(ctx context.Context) (event.ChannelHandle, error) {
+// Channel subscribes to the bus.
+//
+// By default a subscriber has a buffer of event.DefaultBufferSize events,
+// new events are dropped when it's full and no events are filtered out.
+func (b *Bus) Channel(ctx context.Context, options ...event.ChannelOption) (event.ChannelHandle, error) {
 	b.mu.Lock()
 	defer b.mu.Unlock()
-	ch := make(chan event.Event, 256)
-	b.readers[b.seq] = ch
-	h := &handle{
-		channel: ch,
-		bus:     b,
-		closed:  false,
+
+	opts := event.NewChannelOptions(options...)
+	r := &reader{
 		id:      b.seq,
+		channel: make(chan event.Event, opts.BufferSize),
+		options: opts,
+		done:    make(chan struct{}),
+	}
+	b.addReader(r)
+	h := &handle{
+		reader: r,
+		bus:    b,
+		closed: false,
+		id:     b.seq,
 	}
 
 	b.seq += 1
 	return h, nil
 }
 
+// Send delivers the event to the matching subscribers. The lock of the bus is released
+// before the delivery, so a subscriber blocking Send doesn't stall the subscriptions.
 func (b *Bus) Send(ctx context.Context, event event.Event) error {
+	var disconnected []int64
+
 	b.mu.RLock()
-	defer b.mu.RUnlock()
+	readers := b.match(event)
+	b.mu.RUnlock()
 
-	for _, reader := range b.readers {
-		select {
-		case reader <- event:
-		default:
-			// no deadlock, but events are lost...
+	for id, reader := range readers {
+		if !reader.deliver(event) {
+			disconnected = append(disconnected, id)
 		}
 	}
 
+	for _, id := range disconnected {
+		b.deleteReader(id)
+	}
+
 	return nil
 }
 
+func addToIndex(index map[string]map[int64]*reader, key string, r *reader) {
+	readers, ok := index[key]
+	if !ok {
+		readers = map[int64]*reader{}
+		index[key] = readers
+	}
+	readers[r.id] = r
+}
+
+func deleteFromIndex(index map[string]map[int64]*reader, key string, id int64) {
+	readers, ok := index[key]
+	if !ok {
+		return
+	}
+	delete(readers, id)
+	if len(readers) == 0 {
+		delete(index, key)
+	}
+}
+
 func NewBus() event.Bus {
 	return &Bus{
-		readers: map[int64]chan event.Event{},
-		seq:     0,
-		mu:      sync.RWMutex{},
+		readers:  map[int64]*reader{},
+		unrouted: map[int64]*reader{},
+		chats:    map[string]map[int64]*reader{},
+		users:    map[string]map[int64]*reader{},
+		seq:      0,
+		mu:       sync.RWMutex{},
 	}
 }
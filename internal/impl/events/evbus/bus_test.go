@@ -0,0 +1,74 @@
+package evbus
+
+import (
+	"context"
+	"github.com/ischenkx/vk-test-task/internal/app/event"
+	"testing"
+	"time"
+)
+
+// blockedSend starts a Send that blocks on a full subscriber and returns when it's done
+func blockedSend(t *testing.T, bus event.Bus) (event.ChannelHandle, <-chan struct{}) {
+	t.Helper()
+	ctx := context.Background()
+
+	h, err := bus.Channel(ctx, event.WithBufferSize(1), event.WithBlockTimeout(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := bus.Send(ctx, event.New("first", nil)); err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = bus.Send(ctx, event.New("second", nil))
+	}()
+
+	// let the second event block on the full buffer
+	time.Sleep(50 * time.Millisecond)
+	return h, done
+}
+
+func TestBlockedSendDoesNotHoldTheBus(t *testing.T) {
+	bus := NewBus()
+	h, done := blockedSend(t, bus)
+	defer h.Close(context.Background())
+
+	subscribed := make(chan struct{})
+	go func() {
+		defer close(subscribed)
+		other, err := bus.Channel(context.Background())
+		if err == nil {
+			_ = other.Close(context.Background())
+		}
+	}()
+
+	select {
+	case <-subscribed:
+	case <-done:
+		t.Fatal("the blocked send has finished early")
+	case <-time.After(time.Second):
+		t.Fatal("subscribing has waited for the blocked send")
+	}
+}
+
+func TestCloseStopsBlockedSend(t *testing.T) {
+	bus := NewBus()
+	h, done := blockedSend(t, bus)
+
+	if err := h.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("the send has kept blocking on a closed subscriber")
+	}
+
+	if stats := h.Stats(); stats.Delivered != 1 || stats.Dropped != 1 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+}
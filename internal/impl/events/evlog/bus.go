@@ -0,0 +1,199 @@
+package evlog
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/ischenkx/vk-test-task/internal/app/event"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"sync"
+	"time"
+)
+
+// appendLockKey serializes appends, so offsets become visible to readers
+// in the same order they are allocated and no reader skips an event
+const appendLockKey = 7_341_129
+
+const defaultPollInterval = time.Second
+const defaultBatchSize = 256
+
+type Option func(*Bus)
+
+// WithRegistry sets the registry used to stamp payloads with their schema versions
+// and to decode them, unknown payloads are delivered as json.RawMessage
+func WithRegistry(registry *event.Registry) Option {
+	return func(bus *Bus) {
+		bus.registry = registry
+	}
+}
+
+func WithPollInterval(interval time.Duration) Option {
+	return func(bus *Bus) {
+		bus.pollInterval = interval
+	}
+}
+
+// WithBatchSize sets how many events a reader loads at once, non-positive sizes keep the default
+func WithBatchSize(size int) Option {
+	return func(bus *Bus) {
+		bus.batchSize = size
+	}
+}
+
+// Bus is a durable event.Bus backed by an append-only table in Postgres.
+//
+// Readers of the same process are woken up right after Send, readers of
+// other processes pick new events up by polling.
+type Bus struct {
+	pg           *pgxpool.Pool
+	registry     *event.Registry
+	pollInterval time.Duration
+	batchSize    int
+
+	readers map[int64]*handle
+	seq     int64
+	mu      sync.RWMutex
+}
+
+func (b *Bus) InitializeTables(ctx context.Context) error {
+	_, err := b.pg.Exec(ctx, initializeTablesSql)
+	return err
+}
+
+func (b *Bus) Send(ctx context.Context, e event.Event) error {
+	data, err := json.Marshal(e.Data)
+	if err != nil {
+		return err
+	}
+
+	err = b.pg.BeginFunc(ctx, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, lockAppendSql, appendLockKey); err != nil {
+			return err
+		}
+		userIDs := e.UserIDs
+		if userIDs == nil {
+			userIDs = []string{}
+		}
+		_, err := tx.Exec(ctx, appendEventSql, e.Name, data, e.TimeStamp, e.ChatID, userIDs, b.registry.Version(e.Name))
+		return err
+	})
+
+	if err != nil {
+		return err
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for _, reader := range b.readers {
+		reader.notify()
+	}
+
+	return nil
+}
+
+// Channel starts reading the events that are sent after the call.
+//
+// The overflow policy is ignored: a slow reader never loses events,
+// it stays behind and catches up from the table.
+func (b *Bus) Channel(ctx context.Context, options ...event.ChannelOption) (event.ChannelHandle, error) {
+	var last int64
+	if err := b.pg.QueryRow(ctx, lastOffsetSql).Scan(&last); err != nil {
+		return nil, err
+	}
+	return b.open(last, "", event.NewChannelOptions(options...)), nil
+}
+
+// Replay starts reading the log from the given offset (inclusive)
+func (b *Bus) Replay(ctx context.Context, from int64, options ...event.ChannelOption) (event.ChannelHandle, error) {
+	if from < 1 {
+		from = 1
+	}
+	return b.open(from-1, "", event.NewChannelOptions(options...)), nil
+}
+
+// Consumer continues reading right after the offset committed by the named consumer
+func (b *Bus) Consumer(ctx context.Context, name string, options ...event.ChannelOption) (event.ConsumerHandle, error) {
+	var committed int64
+	if err := b.pg.QueryRow(ctx, getCommittedOffsetSql, name).Scan(&committed); err != nil {
+		return nil, err
+	}
+	return b.open(committed, name, event.NewChannelOptions(options...)), nil
+}
+
+func (b *Bus) open(after int64, consumer string, options event.ChannelOptions) *handle {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	h := &handle{
+		bus:      b,
+		id:       b.seq,
+		consumer: consumer,
+		filter:   options.Filter,
+		channel:  make(chan event.Event, options.BufferSize),
+		wake:     make(chan struct{}, 1),
+		cancel:   cancel,
+	}
+	b.readers[b.seq] = h
+	b.seq += 1
+
+	go h.run(ctx, after)
+
+	return h
+}
+
+func (b *Bus) deleteReader(id int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.readers, id)
+}
+
+func (b *Bus) read(ctx context.Context, after int64, limit int) ([]event.Event, error) {
+	rows, err := b.pg.Query(ctx, readEventsSql, after, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var res []event.Event
+	for rows.Next() {
+		var e event.Event
+		var data []byte
+		var version int
+		if err := rows.Scan(&e.Offset, &e.Name, &data, &e.TimeStamp, &e.ChatID, &e.UserIDs, &version); err != nil {
+			return nil, err
+		}
+		if e.Data, err = b.registry.DecodeVersion(e.Name, version, data); err != nil {
+			return nil, err
+		}
+		res = append(res, e)
+	}
+
+	return res, rows.Err()
+}
+
+func New(pg *pgxpool.Pool, options ...Option) *Bus {
+	bus := &Bus{
+		pg:           pg,
+		registry:     event.NewRegistry(),
+		pollInterval: defaultPollInterval,
+		batchSize:    defaultBatchSize,
+		readers:      map[int64]*handle{},
+	}
+	for _, option := range options {
+		option(bus)
+	}
+
+	// readers load the next batch right away after a full one,
+	// they would never wait for new events with an empty batch size
+	if bus.batchSize <= 0 {
+		bus.batchSize = defaultBatchSize
+	}
+	if bus.pollInterval <= 0 {
+		bus.pollInterval = defaultPollInterval
+	}
+
+	return bus
+}
+
+var _ event.Log = (*Bus)(nil)
@@ -0,0 +1,112 @@
+package evlog
+
+import (
+	"context"
+	"errors"
+	"github.com/ischenkx/vk-test-task/internal/app/event"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type handle struct {
+	// accessed atomically, kept first for 64-bit alignment
+	delivered uint64
+
+	bus      *Bus
+	id       int64
+	consumer string
+	filter   event.Filter
+	channel  chan event.Event
+	wake     chan struct{}
+	cancel   context.CancelFunc
+	closed   bool
+	mu       sync.Mutex
+}
+
+func (h *handle) Chan(ctx context.Context) (<-chan event.Event, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return nil, errors.New("already closed")
+	}
+	return h.channel, nil
+}
+
+func (h *handle) Close(ctx context.Context) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return errors.New("already closed")
+	}
+	h.closed = true
+	h.cancel()
+	h.bus.deleteReader(h.id)
+	return nil
+}
+
+func (h *handle) Commit(ctx context.Context, offset int64) error {
+	if h.consumer == "" {
+		return errors.New("not a consumer")
+	}
+	_, err := h.bus.pg.Exec(ctx, commitOffsetSql, h.consumer, offset)
+	return err
+}
+
+func (h *handle) Stats() event.ChannelStats {
+	return event.ChannelStats{
+		Delivered: atomic.LoadUint64(&h.delivered),
+	}
+}
+
+func (h *handle) notify() {
+	select {
+	case h.wake <- struct{}{}:
+	default:
+		// already notified
+	}
+}
+
+// run tails the log starting right after the given offset.
+//
+// Nothing is lost if the reader is slow: it just stays behind
+// and catches up from the table later.
+func (h *handle) run(ctx context.Context, after int64) {
+	defer close(h.channel)
+
+	for {
+		events, err := h.bus.read(ctx, after, h.bus.batchSize)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			log.Println("evlog: failed to read events:", err)
+		}
+
+		for _, e := range events {
+			if !h.filter.Match(e) {
+				after = e.Offset
+				continue
+			}
+			select {
+			case h.channel <- e:
+				atomic.AddUint64(&h.delivered, 1)
+				after = e.Offset
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		if len(events) == h.bus.batchSize {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-h.wake:
+		case <-time.After(h.bus.pollInterval):
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package evlog
+
+// INPUT: lock_key
+//
+// OUTPUT: nil
+const lockAppendSql = `
+	select pg_advisory_xact_lock($1)
+`
+
+// INPUT: name, data, time_stamp, chat_id, user_ids, schema_version
+//
+// OUTPUT: offset
+const appendEventSql = `
+	insert into EventLog as ev
+	(name, data, time_stamp, chat_id, user_ids, schema_version)
+	values ($1, $2, $3, $4, $5, $6)
+	returning ev.event_offset
+`
+
+// INPUT: after, limit
+//
+// OUTPUT: offset, name, data, time_stamp, chat_id, user_ids, schema_version
+const readEventsSql = `
+	select event_offset, name, data, time_stamp, chat_id, user_ids, schema_version from EventLog
+		where event_offset > $1
+		order by event_offset
+		limit $2
+`
+
+// INPUT: nil
+//
+// OUTPUT: last_offset
+const lastOffsetSql = `
+	select coalesce(max(event_offset), 0) from EventLog
+`
+
+// INPUT: consumer
+//
+// OUTPUT: committed_offset
+const getCommittedOffsetSql = `
+	select coalesce(
+		(select committed_offset from EventConsumers where name = $1),
+		0
+	)
+`
+
+// INPUT: consumer, offset
+//
+// OUTPUT: nil
+const commitOffsetSql = `
+	insert into EventConsumers as c
+	(name, committed_offset)
+	values ($1, $2)
+	on conflict (name) do update
+		set committed_offset = greatest(c.committed_offset, excluded.committed_offset)
+`
+
+const initializeTablesSql = `
+create table if not exists EventLog (
+	event_offset bigserial primary key,
+	name varchar (100) not null,
+	data jsonb,
+	time_stamp bigint not null,
+	created_at timestamp default now()
+);
+
+create table if not exists EventConsumers (
+	name varchar (100) primary key,
+	committed_offset bigint not null default 0
+);
+
+alter table EventLog add column if not exists chat_id text not null default '';
+alter table EventLog add column if not exists user_ids text[] not null default '{}';
+alter table EventLog add column if not exists schema_version int not null default 0;
+`
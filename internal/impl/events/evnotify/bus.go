@@ -0,0 +1,241 @@
+package evnotify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/ischenkx/vk-test-task/internal/app/event"
+	"github.com/ischenkx/vk-test-task/internal/impl/events/evbus"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"log"
+	"sync"
+	"time"
+)
+
+// MaxNotifyPayload is a bit less than the 8000 bytes Postgres
+// allows by default for a NOTIFY payload
+const MaxNotifyPayload = 7900
+
+// notification is the payload of a NOTIFY: either the envelope itself
+// or a reference to the outbox row that holds it
+type notification struct {
+	Envelope *event.Envelope `json:"envelope,omitempty"`
+	OutboxID int64           `json:"outbox_id,omitempty"`
+}
+
+type Option func(*Bus)
+
+// WithChannel sets the name of the Postgres channel ("events" by default)
+func WithChannel(channel string) Option {
+	return func(bus *Bus) {
+		bus.channel = channel
+	}
+}
+
+// WithRegistry sets the registry used to decode payloads,
+// unknown payloads are delivered as json.RawMessage
+func WithRegistry(registry *event.Registry) Option {
+	return func(bus *Bus) {
+		bus.registry = registry
+	}
+}
+
+// WithOutboxRetention sets how long large payloads are kept in the outbox
+func WithOutboxRetention(retention time.Duration) Option {
+	return func(bus *Bus) {
+		bus.retention = retention
+	}
+}
+
+func WithReconnectDelay(delay time.Duration) Option {
+	return func(bus *Bus) {
+		bus.reconnectDelay = delay
+	}
+}
+
+// Bus is an event.Bus shared by all the instances connected to the same database.
+//
+// Send publishes events with NOTIFY, every instance (including the sender)
+// receives them with LISTEN and delivers them to its local subscribers.
+// Events sent while an instance isn't listening are lost for it.
+type Bus struct {
+	pg             *pgxpool.Pool
+	channel        string
+	registry       *event.Registry
+	retention      time.Duration
+	reconnectDelay time.Duration
+
+	local  event.Bus
+	cancel context.CancelFunc
+	mu     sync.Mutex
+}
+
+func (b *Bus) InitializeTables(ctx context.Context) error {
+	_, err := b.pg.Exec(ctx, initializeTablesSql)
+	return err
+}
+
+func (b *Bus) Channel(ctx context.Context, options ...event.ChannelOption) (event.ChannelHandle, error) {
+	return b.local.Channel(ctx, options...)
+}
+
+func (b *Bus) Send(ctx context.Context, e event.Event) error {
+	env, err := event.NewJSONCodec(b.registry).Envelope(e)
+	if err != nil {
+		return err
+	}
+
+	payload, err := json.Marshal(notification{Envelope: &env})
+	if err != nil {
+		return err
+	}
+
+	if len(payload) > MaxNotifyPayload {
+		data, err := json.Marshal(env)
+		if err != nil {
+			return err
+		}
+
+		var id int64
+		retention := fmt.Sprintf("%d milliseconds", b.retention.Milliseconds())
+		if err := b.pg.QueryRow(ctx, putOutboxSql, data, retention).Scan(&id); err != nil {
+			return err
+		}
+
+		if payload, err = json.Marshal(notification{OutboxID: id}); err != nil {
+			return err
+		}
+	}
+
+	_, err = b.pg.Exec(ctx, notifySql, b.channel, string(payload))
+	return err
+}
+
+// Start subscribes to the Postgres channel and keeps listening
+// (reconnecting if needed) until Close is called
+func (b *Bus) Start(ctx context.Context) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.cancel != nil {
+		return errors.New("already started")
+	}
+
+	conn, err := b.listen(ctx)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	b.cancel = cancel
+	go b.run(ctx, conn)
+
+	return nil
+}
+
+func (b *Bus) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.cancel != nil {
+		b.cancel()
+		b.cancel = nil
+	}
+}
+
+func (b *Bus) listen(ctx context.Context) (*pgxpool.Conn, error) {
+	conn, err := b.pg.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := conn.Exec(ctx, "listen "+pgx.Identifier{b.channel}.Sanitize()); err != nil {
+		conn.Release()
+		return nil, err
+	}
+
+	return conn, nil
+}
+
+func (b *Bus) run(ctx context.Context, conn *pgxpool.Conn) {
+	for {
+		if conn == nil {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(b.reconnectDelay):
+			}
+
+			var err error
+			if conn, err = b.listen(ctx); err != nil {
+				log.Println("evnotify: failed to listen:", err)
+				continue
+			}
+		}
+
+		n, err := conn.Conn().WaitForNotification(ctx)
+		if err != nil {
+			// the connection can't be reused: it's either broken
+			// or interrupted in the middle of waiting
+			conn.Conn().Close(context.Background())
+			conn.Release()
+			conn = nil
+
+			if ctx.Err() != nil {
+				return
+			}
+			log.Println("evnotify: failed to wait for a notification:", err)
+			continue
+		}
+
+		e, err := b.decode(ctx, n.Payload)
+		if err != nil {
+			log.Println("evnotify: failed to decode a notification:", err)
+			continue
+		}
+
+		if err := b.local.Send(ctx, e); err != nil {
+			log.Println("evnotify: failed to deliver an event:", err)
+		}
+	}
+}
+
+func (b *Bus) decode(ctx context.Context, payload string) (event.Event, error) {
+	var n notification
+	if err := json.Unmarshal([]byte(payload), &n); err != nil {
+		return event.Event{}, err
+	}
+
+	if n.Envelope == nil {
+		var data []byte
+		if err := b.pg.QueryRow(ctx, getOutboxSql, n.OutboxID).Scan(&data); err != nil {
+			return event.Event{}, err
+		}
+
+		n.Envelope = &event.Envelope{}
+		if err := json.Unmarshal(data, n.Envelope); err != nil {
+			return event.Event{}, err
+		}
+	}
+
+	return n.Envelope.Event(b.registry)
+}
+
+func New(pg *pgxpool.Pool, options ...Option) *Bus {
+	bus := &Bus{
+		pg:             pg,
+		channel:        "events",
+		registry:       event.NewRegistry(),
+		retention:      time.Hour,
+		reconnectDelay: time.Second,
+		local:          evbus.NewBus(),
+	}
+	for _, option := range options {
+		option(bus)
+	}
+	return bus
+}
+
+var _ event.Bus = (*Bus)(nil)
@@ -0,0 +1,38 @@
+package evnotify
+
+// INPUT: channel, payload
+//
+// OUTPUT: nil
+const notifySql = `
+	select pg_notify($1, $2)
+`
+
+// INPUT: envelope, retention
+//
+// OUTPUT: id
+const putOutboxSql = `
+	with expired as (
+		delete from EventOutbox where created_at < now() - $2::interval
+	)
+	insert into EventOutbox as o
+	(envelope)
+	values ($1)
+	returning o.id
+`
+
+// INPUT: id
+//
+// OUTPUT: envelope
+const getOutboxSql = `
+	select envelope from EventOutbox where id = $1
+`
+
+const initializeTablesSql = `
+create table if not exists EventOutbox (
+	id bigserial primary key,
+	envelope jsonb not null,
+	created_at timestamp default now()
+);
+
+create index if not exists event_outbox_created_at_idx on EventOutbox (created_at);
+`
@@ -0,0 +1,132 @@
+package oidc
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"github.com/ischenkx/vk-test-task/internal/app/security"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// minReloadInterval limits how often the keys are reloaded because of unknown key ids
+const minReloadInterval = 5 * time.Second
+
+type publicKey struct {
+	alg string
+	key interface{}
+}
+
+// keySet caches the public keys of an issuer
+type keySet struct {
+	mu       sync.Mutex
+	keys     map[string]publicKey
+	loadedAt time.Time
+}
+
+// key returns the key with the id, the keys are reloaded if there is no such key.
+// An empty id is accepted if the issuer has a single key.
+func (s *keySet) key(ctx context.Context, client *http.Client, jwksURI string, kid string) (publicKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if key, ok := s.lookup(kid); ok {
+		return key, nil
+	}
+
+	if time.Since(s.loadedAt) < minReloadInterval {
+		return publicKey{}, errors.New("unknown key")
+	}
+
+	var set struct {
+		Keys []security.JWK `json:"keys"`
+	}
+	if err := getJSON(ctx, client, jwksURI, &set); err != nil {
+		return publicKey{}, errors.New("failed to load the keys: " + err.Error())
+	}
+
+	s.keys = map[string]publicKey{}
+	s.loadedAt = time.Now()
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		// the keys of unsupported types are skipped, the issuer can still use the others
+		if key, err := parseJWK(jwk); err == nil {
+			s.keys[jwk.Kid] = key
+		}
+	}
+
+	if key, ok := s.lookup(kid); ok {
+		return key, nil
+	}
+	return publicKey{}, errors.New("unknown key")
+}
+
+func (s *keySet) lookup(kid string) (publicKey, bool) {
+	if kid == "" && len(s.keys) == 1 {
+		for _, key := range s.keys {
+			return key, true
+		}
+	}
+	key, ok := s.keys[kid]
+	return key, ok
+}
+
+func parseJWK(jwk security.JWK) (publicKey, error) {
+	switch jwk.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+		if err != nil {
+			return publicKey{}, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(jwk.E)
+		if err != nil {
+			return publicKey{}, err
+		}
+		return publicKey{
+			alg: jwk.Alg,
+			key: &rsa.PublicKey{
+				N: new(big.Int).SetBytes(n),
+				E: int(new(big.Int).SetBytes(e).Int64()),
+			},
+		}, nil
+	case "EC":
+		if jwk.Crv != "P-256" {
+			return publicKey{}, errors.New("unsupported curve")
+		}
+		x, err := base64.RawURLEncoding.DecodeString(jwk.X)
+		if err != nil {
+			return publicKey{}, err
+		}
+		y, err := base64.RawURLEncoding.DecodeString(jwk.Y)
+		if err != nil {
+			return publicKey{}, err
+		}
+		return publicKey{
+			alg: jwk.Alg,
+			key: &ecdsa.PublicKey{
+				Curve: elliptic.P256(),
+				X:     new(big.Int).SetBytes(x),
+				Y:     new(big.Int).SetBytes(y),
+			},
+		}, nil
+	case "OKP":
+		if jwk.Crv != "Ed25519" {
+			return publicKey{}, errors.New("unsupported curve")
+		}
+		x, err := base64.RawURLEncoding.DecodeString(jwk.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return publicKey{}, errors.New("invalid key")
+		}
+		return publicKey{alg: jwk.Alg, key: ed25519.PublicKey(x)}, nil
+	default:
+		return publicKey{}, errors.New("unsupported key type")
+	}
+}
@@ -0,0 +1,276 @@
+package oidc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt"
+	"github.com/ischenkx/vk-test-task/internal/app/security"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxClockSkew is how far the clocks of the provider and the server can diverge
+const maxClockSkew = time.Minute
+
+var defaultScopes = []string{"openid", "profile", "email"}
+
+// Config describes a provider registered as a client at an OpenID Connect issuer
+type Config struct {
+	Name     string
+	Issuer   string
+	ClientID string
+	// ClientSecret is empty for public clients, which rely on PKCE only
+	ClientSecret string
+	// RedirectURL is the page the provider sends the user back to, it passes
+	// the state and the code from the query to the API to complete the login
+	RedirectURL string
+	// Scopes are "openid profile email" by default
+	Scopes []string
+}
+
+type Option func(p *Provider)
+
+func WithHTTPClient(client *http.Client) Option {
+	return func(p *Provider) {
+		p.client = client
+	}
+}
+
+// discovery is the part of the provider metadata the login flow needs (OpenID Connect Discovery 1.0)
+type discovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// Provider logs users in with an OpenID Connect issuer using the authorization code flow with PKCE.
+//
+// The endpoints are discovered from "<issuer>/.well-known/openid-configuration" on the first use,
+// the keys of the issuer are reloaded when an ID token is signed with an unknown one.
+type Provider struct {
+	cfg    Config
+	client *http.Client
+
+	mu        sync.Mutex
+	discovery *discovery
+	keys      *keySet
+}
+
+func New(cfg Config, opts ...Option) *Provider {
+	cfg.Issuer = strings.TrimSuffix(cfg.Issuer, "/")
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = defaultScopes
+	}
+
+	p := &Provider{
+		cfg:    cfg,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	return p
+}
+
+func (p *Provider) Name() string {
+	return p.cfg.Name
+}
+
+func (p *Provider) AuthURL(ctx context.Context, state, nonce, codeChallenge string) (string, error) {
+	d, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	query := url.Values{}
+	query.Set("response_type", "code")
+	query.Set("client_id", p.cfg.ClientID)
+	query.Set("redirect_uri", p.cfg.RedirectURL)
+	query.Set("scope", strings.Join(p.cfg.Scopes, " "))
+	query.Set("state", state)
+	query.Set("nonce", nonce)
+	query.Set("code_challenge", codeChallenge)
+	query.Set("code_challenge_method", "S256")
+
+	separator := "?"
+	if strings.Contains(d.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+
+	return d.AuthorizationEndpoint + separator + query.Encode(), nil
+}
+
+// tokenResponse is the response of the token endpoint, only the ID token is used
+type tokenResponse struct {
+	IDToken          string `json:"id_token"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+func (p *Provider) Exchange(ctx context.Context, code, codeVerifier, nonce string) (security.Identity, error) {
+	d, err := p.discover(ctx)
+	if err != nil {
+		return security.Identity{}, err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.cfg.RedirectURL)
+	form.Set("client_id", p.cfg.ClientID)
+	form.Set("code_verifier", codeVerifier)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return security.Identity{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if p.cfg.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(p.cfg.ClientID), url.QueryEscape(p.cfg.ClientSecret))
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return security.Identity{}, err
+	}
+	defer resp.Body.Close()
+
+	var tokens tokenResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&tokens); err != nil {
+		return security.Identity{}, fmt.Errorf("failed to decode the token response (%d): %s", resp.StatusCode, err)
+	}
+
+	if tokens.Error != "" {
+		return security.Identity{}, fmt.Errorf("the provider has rejected the code: %s %s", tokens.Error, tokens.ErrorDescription)
+	}
+
+	if resp.StatusCode != http.StatusOK || tokens.IDToken == "" {
+		return security.Identity{}, fmt.Errorf("the provider hasn't returned an id token (%d)", resp.StatusCode)
+	}
+
+	return p.verify(ctx, d, tokens.IDToken, nonce)
+}
+
+// verify checks the signature and the claims of the ID token (OpenID Connect Core 1.0, 3.1.3.7)
+func (p *Provider) verify(ctx context.Context, d *discovery, idToken string, nonce string) (security.Identity, error) {
+	claims := jwt.MapClaims{}
+	parser := jwt.Parser{
+		ValidMethods: []string{"RS256", "ES256", "EdDSA"},
+		// the time claims are checked below allowing for the clock skew
+		SkipClaimsValidation: true,
+	}
+
+	_, err := parser.ParseWithClaims(idToken, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		key, err := p.keySet().key(ctx, p.client, d.JWKSURI, kid)
+		if err != nil {
+			return nil, err
+		}
+		if key.alg != "" && key.alg != token.Method.Alg() {
+			return nil, errors.New("unexpected signing method")
+		}
+		return key.key, nil
+	})
+	if err != nil {
+		return security.Identity{}, errors.New("invalid id token: " + err.Error())
+	}
+
+	now := time.Now()
+	if !claims.VerifyIssuer(d.Issuer, true) {
+		return security.Identity{}, errors.New("invalid id token: unexpected issuer")
+	}
+	if !claims.VerifyAudience(p.cfg.ClientID, true) {
+		return security.Identity{}, errors.New("invalid id token: unexpected audience")
+	}
+	if !claims.VerifyExpiresAt(now.Add(-maxClockSkew).Unix(), true) {
+		return security.Identity{}, errors.New("invalid id token: expired")
+	}
+	if !claims.VerifyIssuedAt(now.Add(maxClockSkew).Unix(), false) {
+		return security.Identity{}, errors.New("invalid id token: issued in the future")
+	}
+	if tokenNonce, _ := claims["nonce"].(string); tokenNonce != nonce {
+		return security.Identity{}, errors.New("invalid id token: unexpected nonce")
+	}
+
+	subject, _ := claims["sub"].(string)
+	if subject == "" {
+		return security.Identity{}, errors.New("invalid id token: no subject")
+	}
+
+	identity := security.Identity{
+		Provider: p.cfg.Name,
+		Subject:  subject,
+	}
+	identity.Username, _ = claims["preferred_username"].(string)
+	identity.Email, _ = claims["email"].(string)
+	identity.EmailVerified, _ = claims["email_verified"].(bool)
+	identity.Name, _ = claims["name"].(string)
+
+	return identity, nil
+}
+
+// discover loads the metadata of the issuer, it's kept once loaded
+func (p *Provider) discover(ctx context.Context) (*discovery, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.discovery != nil {
+		return p.discovery, nil
+	}
+
+	var d discovery
+	if err := getJSON(ctx, p.client, p.cfg.Issuer+"/.well-known/openid-configuration", &d); err != nil {
+		return nil, fmt.Errorf("failed to discover the provider '%s': %s", p.cfg.Name, err)
+	}
+
+	if strings.TrimSuffix(d.Issuer, "/") != p.cfg.Issuer {
+		return nil, fmt.Errorf("the provider '%s' has reported another issuer: '%s'", p.cfg.Name, d.Issuer)
+	}
+
+	if d.AuthorizationEndpoint == "" || d.TokenEndpoint == "" || d.JWKSURI == "" {
+		return nil, fmt.Errorf("the provider '%s' lacks the endpoints of the code flow", p.cfg.Name)
+	}
+
+	p.discovery = &d
+	return p.discovery, nil
+}
+
+func (p *Provider) keySet() *keySet {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.keys == nil {
+		p.keys = &keySet{}
+	}
+	return p.keys
+}
+
+func getJSON(ctx context.Context, client *http.Client, url string, output interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status: %d", resp.StatusCode)
+	}
+
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(output)
+}
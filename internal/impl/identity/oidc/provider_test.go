@@ -0,0 +1,291 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/golang-jwt/jwt"
+	"github.com/ischenkx/vk-test-task/internal/app/security"
+	"github.com/ischenkx/vk-test-task/internal/impl/identity/oidc/oidctest"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	testClientID = "client"
+	testKeyID    = "test"
+	testCode     = "code"
+	testVerifier = "verifier"
+	testNonce    = "nonce"
+)
+
+// testIssuer serves the discovery document, the keys and the token endpoint of an issuer,
+// the token endpoint returns the ID token the test builds
+type testIssuer struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+	// token builds the ID token from the default claims
+	token func(claims jwt.MapClaims) (string, error)
+}
+
+func newTestIssuer(t *testing.T) *testIssuer {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	issuer := &testIssuer{key: key}
+	issuer.token = issuer.sign(jwt.SigningMethodRS256, key)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		writeTestJSON(w, http.StatusOK, map[string]string{
+			"issuer":                 issuer.server.URL,
+			"authorization_endpoint": issuer.server.URL + "/authorize",
+			"token_endpoint":         issuer.server.URL + "/token",
+			"jwks_uri":               issuer.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		writeTestJSON(w, http.StatusOK, map[string]interface{}{
+			"keys": []security.JWK{{
+				Kty: "RSA",
+				Kid: testKeyID,
+				Use: "sig",
+				N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil || r.PostForm.Get("code") != testCode ||
+			r.PostForm.Get("code_verifier") != testVerifier {
+			writeTestJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+			return
+		}
+
+		now := time.Now()
+		idToken, err := issuer.token(jwt.MapClaims{
+			"iss":   issuer.server.URL,
+			"aud":   testClientID,
+			"sub":   "alice",
+			"iat":   now.Unix(),
+			"exp":   now.Add(5 * time.Minute).Unix(),
+			"nonce": testNonce,
+			"email": "alice@example.com",
+		})
+		if err != nil {
+			writeTestJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+			return
+		}
+
+		writeTestJSON(w, http.StatusOK, map[string]string{"id_token": idToken})
+	})
+
+	issuer.server = httptest.NewServer(mux)
+	t.Cleanup(issuer.server.Close)
+
+	return issuer
+}
+
+// sign returns a token builder signing the claims with the method and the key
+func (issuer *testIssuer) sign(method jwt.SigningMethod, key interface{}) func(claims jwt.MapClaims) (string, error) {
+	return func(claims jwt.MapClaims) (string, error) {
+		token := jwt.NewWithClaims(method, claims)
+		token.Header["kid"] = testKeyID
+		return token.SignedString(key)
+	}
+}
+
+// override returns a token builder that changes the claims before signing them with the key of the issuer
+func (issuer *testIssuer) override(change func(claims jwt.MapClaims)) func(claims jwt.MapClaims) (string, error) {
+	sign := issuer.sign(jwt.SigningMethodRS256, issuer.key)
+	return func(claims jwt.MapClaims) (string, error) {
+		change(claims)
+		return sign(claims)
+	}
+}
+
+func (issuer *testIssuer) provider() *Provider {
+	return New(Config{
+		Name:        "test",
+		Issuer:      issuer.server.URL,
+		ClientID:    testClientID,
+		RedirectURL: "http://localhost/callback",
+	}, WithHTTPClient(issuer.server.Client()))
+}
+
+func writeTestJSON(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(data)
+}
+
+func TestExchange(t *testing.T) {
+	issuer := newTestIssuer(t)
+
+	identity, err := issuer.provider().Exchange(context.Background(), testCode, testVerifier, testNonce)
+	if err != nil {
+		t.Fatalf("failed to exchange the code: %s", err)
+	}
+
+	if identity.Provider != "test" || identity.Subject != "alice" || identity.Email != "alice@example.com" {
+		t.Fatalf("unexpected identity: %+v", identity)
+	}
+}
+
+func TestExchangeRejectsVerifierMismatch(t *testing.T) {
+	server := httptest.NewUnstartedServer(nil)
+	issuer := "http://" + server.Listener.Addr().String()
+	mock, err := oidctest.New(issuer, testClientID, oidctest.Identity{Subject: "alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.Config.Handler = mock
+	server.Start()
+	defer server.Close()
+
+	provider := New(Config{
+		Name:        "test",
+		Issuer:      issuer,
+		ClientID:    testClientID,
+		RedirectURL: "http://localhost/callback",
+	}, WithHTTPClient(server.Client()))
+
+	// authorize returns a code bound to the challenge of the verifier
+	authorize := func() string {
+		sum := sha256.Sum256([]byte(testVerifier))
+		authURL, err := provider.AuthURL(context.Background(), "state", testNonce, base64.RawURLEncoding.EncodeToString(sum[:]))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		}}
+		resp, err := client.Get(authURL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+
+		location, err := resp.Location()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return location.Query().Get("code")
+	}
+
+	_, err = provider.Exchange(context.Background(), authorize(), "another verifier", testNonce)
+	if err == nil || !strings.Contains(err.Error(), "rejected the code") {
+		t.Fatalf("expected the code to be rejected, got %v", err)
+	}
+
+	identity, err := provider.Exchange(context.Background(), authorize(), testVerifier, testNonce)
+	if err != nil {
+		t.Fatalf("failed to exchange the code: %s", err)
+	}
+	if identity.Subject != "alice" {
+		t.Fatalf("unexpected identity: %+v", identity)
+	}
+}
+
+func TestExchangeRejectsInvalidTokens(t *testing.T) {
+	hmacKey := make([]byte, 32)
+	if _, err := rand.Read(hmacKey); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		nonce string
+		token func(issuer *testIssuer) func(claims jwt.MapClaims) (string, error)
+		err   string
+	}{
+		{
+			name: "wrong issuer",
+			token: func(issuer *testIssuer) func(claims jwt.MapClaims) (string, error) {
+				return issuer.override(func(claims jwt.MapClaims) { claims["iss"] = "https://attacker.example.com" })
+			},
+			err: "unexpected issuer",
+		},
+		{
+			name: "wrong audience",
+			token: func(issuer *testIssuer) func(claims jwt.MapClaims) (string, error) {
+				return issuer.override(func(claims jwt.MapClaims) { claims["aud"] = "another client" })
+			},
+			err: "unexpected audience",
+		},
+		{
+			name: "expired",
+			token: func(issuer *testIssuer) func(claims jwt.MapClaims) (string, error) {
+				return issuer.override(func(claims jwt.MapClaims) {
+					claims["iat"] = time.Now().Add(-time.Hour).Unix()
+					claims["exp"] = time.Now().Add(-maxClockSkew - time.Minute).Unix()
+				})
+			},
+			err: "expired",
+		},
+		{
+			name:  "nonce mismatch",
+			nonce: "another nonce",
+			err:   "unexpected nonce",
+		},
+		{
+			name: "hmac signature",
+			token: func(issuer *testIssuer) func(claims jwt.MapClaims) (string, error) {
+				return issuer.sign(jwt.SigningMethodHS256, hmacKey)
+			},
+			err: "signing method",
+		},
+		{
+			name: "no signature",
+			token: func(issuer *testIssuer) func(claims jwt.MapClaims) (string, error) {
+				return issuer.sign(jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType)
+			},
+			err: "signing method",
+		},
+		{
+			name: "foreign key",
+			token: func(issuer *testIssuer) func(claims jwt.MapClaims) (string, error) {
+				key, err := rsa.GenerateKey(rand.Reader, 2048)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return issuer.sign(jwt.SigningMethodRS256, key)
+			},
+			err: "verification error",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			issuer := newTestIssuer(t)
+			if test.token != nil {
+				issuer.token = test.token(issuer)
+			}
+
+			nonce := testNonce
+			if test.nonce != "" {
+				nonce = test.nonce
+			}
+
+			_, err := issuer.provider().Exchange(context.Background(), testCode, testVerifier, nonce)
+			if err == nil {
+				t.Fatal("expected the token to be rejected")
+			}
+			if !strings.Contains(err.Error(), test.err) {
+				t.Fatalf("expected an error containing %q, got %q", test.err, err)
+			}
+		})
+	}
+}
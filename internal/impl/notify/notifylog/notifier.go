@@ -0,0 +1,27 @@
+package notifylog
+
+import (
+	"context"
+	"github.com/ischenkx/vk-test-task/internal/app/security"
+	"log"
+)
+
+// Notifier writes the notifications to the log instead of delivering them,
+// it's meant for development: the reset tokens are read from the output of the server
+type Notifier struct {
+	logger *log.Logger
+}
+
+// New writes to the standard logger if logger is nil
+func New(logger *log.Logger) *Notifier {
+	if logger == nil {
+		logger = log.Default()
+	}
+	return &Notifier{logger: logger}
+}
+
+func (n *Notifier) Notify(ctx context.Context, notification security.Notification) error {
+	n.logger.Printf("notification for %s (%s): %s\n%s",
+		notification.Username, notification.UserID, notification.Subject, notification.Body)
+	return nil
+}
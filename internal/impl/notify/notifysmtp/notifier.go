@@ -0,0 +1,128 @@
+package notifysmtp
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"github.com/ischenkx/vk-test-task/internal/app/security"
+	"mime"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+var ErrNoRecipient = errors.New("the user has no email address")
+
+type Config struct {
+	// Addr is the "host:port" of the SMTP server
+	Addr string
+	From string
+	// Username and Password are used for PLAIN authentication if Username is set
+	Username string
+	Password string
+	// Domain makes up the addresses of the users without an email, "<username>@<domain>".
+	// Such users aren't notified if it's empty.
+	Domain string
+}
+
+// Notifier sends the notifications as plain text emails.
+//
+// STARTTLS is used if the server supports it, authentication is only done over TLS
+// or to a local server (see smtp.PlainAuth).
+type Notifier struct {
+	cfg Config
+}
+
+func New(cfg Config) *Notifier {
+	return &Notifier{cfg: cfg}
+}
+
+func (n *Notifier) recipient(notification security.Notification) (string, error) {
+	if notification.Email != "" {
+		return notification.Email, nil
+	}
+	if n.cfg.Domain != "" {
+		return notification.Username + "@" + n.cfg.Domain, nil
+	}
+	return "", ErrNoRecipient
+}
+
+func (n *Notifier) Notify(ctx context.Context, notification security.Notification) error {
+	to, err := n.recipient(notification)
+	if err != nil {
+		return err
+	}
+
+	if strings.ContainsAny(to, "\r\n") {
+		return fmt.Errorf("invalid recipient: %q", to)
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", n.cfg.Addr)
+	if err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	host, _, err := net.SplitHostPort(n.cfg.Addr)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return err
+		}
+	}
+
+	if n.cfg.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", n.cfg.Username, n.cfg.Password, host)); err != nil {
+			return err
+		}
+	}
+
+	if err := client.Mail(n.cfg.From); err != nil {
+		return err
+	}
+	if err := client.Rcpt(to); err != nil {
+		return err
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(message(n.cfg.From, to, notification)); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	return client.Quit()
+}
+
+func message(from, to string, notification security.Notification) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", to)
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", notification.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(notification.Body, "\n", "\r\n"))
+	return []byte(b.String())
+}
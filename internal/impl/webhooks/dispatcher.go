@@ -0,0 +1,294 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/ischenkx/vk-test-task/internal/app/data"
+	"github.com/ischenkx/vk-test-task/internal/app/data/models"
+	"github.com/ischenkx/vk-test-task/internal/app/event"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// maxErrorLength limits the errors kept in the delivery log
+const maxErrorLength = 500
+
+type Option func(*Dispatcher)
+
+func WithRegistry(registry *event.Registry) Option {
+	return func(d *Dispatcher) {
+		d.codec = event.NewJSONCodec(registry)
+	}
+}
+
+// WithHTTPClient replaces the client the deliveries are sent with,
+// the address check (see WithAddressCheck) is up to the client then
+func WithHTTPClient(client *http.Client) Option {
+	return func(d *Dispatcher) {
+		d.client = client
+	}
+}
+
+// WithAddressCheck replaces PublicAddressesOnly, the addresses the webhooks can be delivered to.
+// A nil check allows any address, e.g. for tests delivering to a local httptest server.
+func WithAddressCheck(check func(ip net.IP) error) Option {
+	return func(d *Dispatcher) {
+		d.addressCheck = check
+	}
+}
+
+// WithMaxAttempts sets how many times a delivery is tried before it's moved to the dead-letter list
+func WithMaxAttempts(attempts int) Option {
+	return func(d *Dispatcher) {
+		d.maxAttempts = attempts
+	}
+}
+
+// WithBackoff sets the delay before the first retry, it doubles after every
+// failed attempt up to the given maximum
+func WithBackoff(base, max time.Duration) Option {
+	return func(d *Dispatcher) {
+		d.baseBackoff = base
+		d.maxBackoff = max
+	}
+}
+
+func WithPollInterval(interval time.Duration) Option {
+	return func(d *Dispatcher) {
+		d.pollInterval = interval
+	}
+}
+
+// Dispatcher delivers events to the registered webhooks.
+//
+// Events are taken from the bus and turned into deliveries stored in the repository,
+// which are then POSTed to the webhooks' URLs (the body is an event.Envelope)
+// and retried with an exponential backoff until they succeed or run out of attempts.
+type Dispatcher struct {
+	repo   data.Repository
+	bus    event.Bus
+	codec  event.JSONCodec
+	client *http.Client
+	// addressCheck is applied by the default client to the addresses it connects to
+	addressCheck func(ip net.IP) error
+
+	maxAttempts  int
+	baseBackoff  time.Duration
+	maxBackoff   time.Duration
+	pollInterval time.Duration
+	batchSize    int
+	lease        time.Duration
+
+	wake chan struct{}
+}
+
+// Run subscribes to the bus and delivers the events until the context is done
+func (d *Dispatcher) Run(ctx context.Context) error {
+	handle, err := d.bus.Channel(ctx, event.WithBlockTimeout(5*time.Second))
+	if err != nil {
+		return err
+	}
+	defer handle.Close(context.Background())
+
+	events, err := handle.Chan(ctx)
+	if err != nil {
+		return err
+	}
+
+	go d.deliverLoop(ctx)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case e, ok := <-events:
+			if !ok {
+				return fmt.Errorf("the event channel is closed")
+			}
+			if err := d.enqueue(ctx, e); err != nil {
+				log.Println("webhooks: failed to enqueue an event:", err)
+			}
+		}
+	}
+}
+
+// enqueue creates a delivery of the event for each matching webhook
+func (d *Dispatcher) enqueue(ctx context.Context, e event.Event) error {
+	webhooks, err := d.repo.GetMatchingWebhooks(ctx, e.Name, e.ChatID, e.UserIDs)
+	if err != nil {
+		return err
+	}
+
+	if len(webhooks) == 0 {
+		return nil
+	}
+
+	payload, err := d.codec.Encode(e)
+	if err != nil {
+		return err
+	}
+
+	for _, webhook := range webhooks {
+		err := d.repo.CreateWebhookDelivery(ctx, models.WebhookDelivery{
+			WebhookID: webhook.ID,
+			EventID:   e.ID,
+			EventName: e.Name,
+			Payload:   payload,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	select {
+	case d.wake <- struct{}{}:
+	default:
+		// already woken up
+	}
+
+	return nil
+}
+
+func (d *Dispatcher) deliverLoop(ctx context.Context) {
+	for {
+		n, err := d.deliverDue(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			log.Println("webhooks: failed to deliver:", err)
+		}
+
+		// a full batch means there can be more due deliveries,
+		// after an error the loop waits so that it doesn't spin against a failing repository
+		if err == nil && n == d.batchSize {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-d.wake:
+		case <-time.After(d.pollInterval):
+		}
+	}
+}
+
+// deliverDue sends a batch of due deliveries and returns its size
+func (d *Dispatcher) deliverDue(ctx context.Context) (int, error) {
+	deliveries, err := d.repo.ClaimWebhookDeliveries(ctx, d.batchSize, d.lease)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, delivery := range deliveries {
+		webhook, err := d.repo.GetWebhook(ctx, delivery.WebhookID)
+		if err != nil {
+			// the webhook has been deleted together with its deliveries
+			continue
+		}
+
+		delivery = d.attempt(ctx, webhook, delivery)
+		if err := d.repo.UpdateWebhookDelivery(ctx, delivery); err != nil {
+			return len(deliveries), err
+		}
+	}
+
+	return len(deliveries), nil
+}
+
+// attempt sends the delivery once and records the outcome
+func (d *Dispatcher) attempt(ctx context.Context, webhook models.Webhook, delivery models.WebhookDelivery) models.WebhookDelivery {
+	delivery.Attempts++
+
+	code, err := d.send(ctx, webhook, delivery)
+	delivery.ResponseCode = code
+
+	if err == nil {
+		delivery.Status = models.WebhookDeliveryDelivered
+		delivery.Error = ""
+		return delivery
+	}
+
+	delivery.Error = err.Error()
+	if len(delivery.Error) > maxErrorLength {
+		delivery.Error = delivery.Error[:maxErrorLength]
+	}
+
+	if delivery.Attempts >= d.maxAttempts {
+		delivery.Status = models.WebhookDeliveryDead
+	} else {
+		delivery.NextAttemptAt = time.Now().Add(d.backoff(delivery.Attempts))
+	}
+
+	return delivery
+}
+
+func (d *Dispatcher) send(ctx context.Context, webhook models.Webhook, delivery models.WebhookDelivery) (int, error) {
+	timestamp := time.Now().Unix()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, delivery.EventName)
+	req.Header.Set(DeliveryHeader, delivery.ID)
+	req.Header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(SignatureHeader, Sign(webhook.Secret, timestamp, delivery.Payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	// the body isn't kept, the receiver can be anything and its responses aren't shown to the users
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxErrorLength))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	return resp.StatusCode, nil
+}
+
+// backoff returns the delay after the given number of failed attempts
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	delay := d.baseBackoff
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= d.maxBackoff {
+			return d.maxBackoff
+		}
+	}
+	return delay
+}
+
+func New(repo data.Repository, bus event.Bus, options ...Option) *Dispatcher {
+	d := &Dispatcher{
+		repo:         repo,
+		bus:          bus,
+		codec:        event.NewJSONCodec(event.NewRegistry()),
+		addressCheck: PublicAddressesOnly,
+		maxAttempts:  8,
+		baseBackoff:  5 * time.Second,
+		maxBackoff:   time.Hour,
+		pollInterval: time.Second,
+		batchSize:    10,
+		lease:        5 * time.Minute,
+		wake:         make(chan struct{}, 1),
+	}
+	for _, option := range options {
+		option(d)
+	}
+	if d.client == nil {
+		d.client = newHTTPClient(d.addressCheck)
+	}
+	return d
+}
@@ -0,0 +1,281 @@
+package webhooks
+
+import (
+	"context"
+	"github.com/ischenkx/vk-test-task/internal/app/data"
+	"github.com/ischenkx/vk-test-task/internal/app/data/models"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memoryRepo keeps the webhooks and the deliveries the dispatcher works with,
+// the rest of the repository isn't used by it
+type memoryRepo struct {
+	data.Repository
+
+	mu         sync.Mutex
+	webhooks   map[string]models.Webhook
+	deliveries map[string]models.WebhookDelivery
+	nextID     int
+}
+
+func newMemoryRepo() *memoryRepo {
+	return &memoryRepo{
+		webhooks:   map[string]models.Webhook{},
+		deliveries: map[string]models.WebhookDelivery{},
+	}
+}
+
+func (r *memoryRepo) GetWebhook(ctx context.Context, id string) (models.Webhook, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	webhook, ok := r.webhooks[id]
+	if !ok {
+		return models.Webhook{}, io.EOF
+	}
+	return webhook, nil
+}
+
+func (r *memoryRepo) CreateWebhookDelivery(ctx context.Context, delivery models.WebhookDelivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.nextID++
+	delivery.ID = strconv.Itoa(r.nextID)
+	delivery.CreatedAt = time.Now()
+	delivery.NextAttemptAt = time.Now()
+	r.deliveries[delivery.ID] = delivery
+	return nil
+}
+
+func (r *memoryRepo) ClaimWebhookDeliveries(ctx context.Context, count int, lease time.Duration) ([]models.WebhookDelivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var res []models.WebhookDelivery
+	now := time.Now()
+	for id, delivery := range r.deliveries {
+		if len(res) == count {
+			break
+		}
+		if delivery.Status != models.WebhookDeliveryPending || delivery.NextAttemptAt.After(now) {
+			continue
+		}
+		delivery.NextAttemptAt = now.Add(lease)
+		r.deliveries[id] = delivery
+		res = append(res, delivery)
+	}
+	return res, nil
+}
+
+func (r *memoryRepo) UpdateWebhookDelivery(ctx context.Context, delivery models.WebhookDelivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.deliveries[delivery.ID] = delivery
+	return nil
+}
+
+func (r *memoryRepo) delivery(id string) models.WebhookDelivery {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.deliveries[id]
+}
+
+// makeDue lets the delivery be claimed right away instead of waiting for the backoff
+func (r *memoryRepo) makeDue(id string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delivery := r.deliveries[id]
+	delivery.NextAttemptAt = time.Now()
+	r.deliveries[id] = delivery
+}
+
+// setup registers a webhook pointing to the server and a delivery of an event to it
+func setup(t *testing.T, server *httptest.Server) (*memoryRepo, string) {
+	t.Helper()
+
+	repo := newMemoryRepo()
+	repo.webhooks["hook"] = models.Webhook{ID: "hook", URL: server.URL, Secret: "secret"}
+	err := repo.CreateWebhookDelivery(context.Background(), models.WebhookDelivery{
+		WebhookID: "hook",
+		EventID:   "event",
+		EventName: "new_message",
+		Payload:   []byte(`{"name":"new_message"}`),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return repo, "1"
+}
+
+func TestDeliverySignature(t *testing.T) {
+	type received struct {
+		header http.Header
+		body   []byte
+	}
+	requests := make(chan received, 1)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests <- received{header: r.Header, body: body}
+	}))
+	defer server.Close()
+
+	repo, id := setup(t, server)
+	d := New(repo, nil, WithAddressCheck(nil))
+
+	if _, err := d.deliverDue(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	req := <-requests
+	if string(req.body) != `{"name":"new_message"}` {
+		t.Fatalf("unexpected body: %s", req.body)
+	}
+	if req.header.Get(EventHeader) != "new_message" || req.header.Get(DeliveryHeader) != id {
+		t.Fatalf("unexpected headers: %v", req.header)
+	}
+
+	timestamp, err := strconv.ParseInt(req.header.Get(TimestampHeader), 10, 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signature := req.header.Get(SignatureHeader)
+	if !Verify("secret", timestamp, req.body, signature) {
+		t.Fatal("the signature doesn't verify")
+	}
+	if signature != Sign("secret", timestamp, req.body) {
+		t.Fatal("the signature differs from Sign")
+	}
+
+	if delivery := repo.delivery(id); delivery.Status != models.WebhookDeliveryDelivered || delivery.ResponseCode != http.StatusOK {
+		t.Fatalf("unexpected delivery: %+v", delivery)
+	}
+}
+
+func TestDeliveryRetryBackoff(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal details"))
+	}))
+	defer server.Close()
+
+	repo, id := setup(t, server)
+	base, max := time.Minute, 3*time.Minute
+	d := New(repo, nil, WithAddressCheck(nil), WithBackoff(base, max), WithMaxAttempts(10))
+
+	// base, doubled after every failure, up to max
+	expected := []time.Duration{base, 2 * base, max, max}
+	for i, delay := range expected {
+		before := time.Now()
+		if _, err := d.deliverDue(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		after := time.Now()
+
+		delivery := repo.delivery(id)
+		if delivery.Status != models.WebhookDeliveryPending || delivery.Attempts != i+1 {
+			t.Fatalf("attempt %d: unexpected delivery: %+v", i+1, delivery)
+		}
+		if delivery.ResponseCode != http.StatusInternalServerError {
+			t.Fatalf("attempt %d: unexpected response code: %d", i+1, delivery.ResponseCode)
+		}
+		if strings.Contains(delivery.Error, "internal details") {
+			t.Fatalf("attempt %d: the response body is kept: %s", i+1, delivery.Error)
+		}
+		if delivery.NextAttemptAt.Before(before.Add(delay)) || delivery.NextAttemptAt.After(after.Add(delay)) {
+			t.Fatalf("attempt %d: the next attempt is in %s, expected %s",
+				i+1, delivery.NextAttemptAt.Sub(after), delay)
+		}
+
+		// not due before the backoff has passed
+		if n, _ := d.deliverDue(context.Background()); n != 0 {
+			t.Fatalf("attempt %d: the delivery is retried before the backoff", i+1)
+		}
+		repo.makeDue(id)
+	}
+}
+
+func TestDeliveryDeadLetter(t *testing.T) {
+	var mu sync.Mutex
+	hits := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		hits++
+		mu.Unlock()
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	repo, id := setup(t, server)
+	d := New(repo, nil, WithAddressCheck(nil), WithMaxAttempts(3), WithBackoff(time.Millisecond, time.Millisecond))
+
+	for i := 0; i < 5; i++ {
+		if _, err := d.deliverDue(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		if repo.delivery(id).Status == models.WebhookDeliveryPending {
+			repo.makeDue(id)
+		}
+	}
+
+	delivery := repo.delivery(id)
+	if delivery.Status != models.WebhookDeliveryDead || delivery.Attempts != 3 {
+		t.Fatalf("unexpected delivery: %+v", delivery)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if hits != 3 {
+		t.Fatalf("the webhook is called %d times, expected 3", hits)
+	}
+}
+
+func TestDeliveryRejectsLocalAddresses(t *testing.T) {
+	hit := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hit = true
+	}))
+	defer server.Close()
+
+	repo, id := setup(t, server)
+	d := New(repo, nil)
+
+	if _, err := d.deliverDue(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	delivery := repo.delivery(id)
+	if hit || delivery.Status == models.WebhookDeliveryDelivered {
+		t.Fatal("the webhook is delivered to a loopback address")
+	}
+	if !strings.Contains(delivery.Error, ErrForbiddenAddress.Error()) {
+		t.Fatalf("unexpected error: %s", delivery.Error)
+	}
+}
+
+func TestDeliveryDoesNotFollowRedirects(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("the redirect is followed")
+	}))
+	defer target.Close()
+
+	server := httptest.NewServer(http.RedirectHandler(target.URL, http.StatusTemporaryRedirect))
+	defer server.Close()
+
+	repo, id := setup(t, server)
+	d := New(repo, nil, WithAddressCheck(nil))
+
+	if _, err := d.deliverDue(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if delivery := repo.delivery(id); delivery.Status == models.WebhookDeliveryDelivered ||
+		delivery.ResponseCode != http.StatusTemporaryRedirect {
+		t.Fatalf("unexpected delivery: %+v", delivery)
+	}
+}
@@ -0,0 +1,28 @@
+package webhooks
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSignatureRoundTrip(t *testing.T) {
+	body := []byte(`{"name":"new_message","data":{"chat_id":"1"}}`)
+	signature := Sign("secret", 1700000000, body)
+
+	if !strings.HasPrefix(signature, "sha256=") {
+		t.Fatalf("unexpected signature format: %s", signature)
+	}
+	if !Verify("secret", 1700000000, body, signature) {
+		t.Fatal("the signature doesn't verify")
+	}
+
+	if Verify("other secret", 1700000000, body, signature) {
+		t.Fatal("the signature verifies with another secret")
+	}
+	if Verify("secret", 1700000001, body, signature) {
+		t.Fatal("the signature verifies with another timestamp")
+	}
+	if Verify("secret", 1700000000, append(body, ' '), signature) {
+		t.Fatal("the signature verifies with another body")
+	}
+}
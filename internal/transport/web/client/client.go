@@ -4,19 +4,26 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	chatForms "github.com/ischenkx/vk-test-task/internal/transport/web/controllers/chats/forms"
+	"github.com/ischenkx/vk-test-task/internal/transport/web/controllers/common"
 	"github.com/ischenkx/vk-test-task/internal/transport/web/controllers/common/result"
 	"github.com/ischenkx/vk-test-task/internal/transport/web/controllers/dto"
+	hookForms "github.com/ischenkx/vk-test-task/internal/transport/web/controllers/hooks/forms"
 	userForms "github.com/ischenkx/vk-test-task/internal/transport/web/controllers/users/forms"
 	"io"
 	"net/http"
 	"strings"
 )
 
+const refreshPath = "/users/refresh"
+
 type Client struct {
-	http    *http.Client
-	cookies map[string]*http.Cookie
-	baseUrl string
+	http     *http.Client
+	cookies  map[string]*http.Cookie
+	botToken string
+	apiKey   string
+	baseUrl  string
 }
 
 func (c *Client) url(suffix string) string {
@@ -51,6 +58,11 @@ func (c *Client) prepareRawRequest(req *http.Request) {
 	for _, cookie := range c.cookies {
 		req.AddCookie(cookie)
 	}
+	if c.botToken != "" {
+		req.Header.Set("Authorization", "Bot "+c.botToken)
+	} else if c.apiKey != "" {
+		req.Header.Set("Authorization", "ApiKey "+c.apiKey)
+	}
 }
 
 func (c *Client) rawRequest(method, path string, data interface{}) ([]byte, error) {
@@ -83,6 +95,17 @@ func (c *Client) rawRequest(method, path string, data interface{}) ([]byte, erro
 }
 
 func (c *Client) request(path, method string, input, output interface{}) error {
+	err := c.doRequest(path, method, input, output)
+
+	// the access token has probably expired: get a new one and try again
+	if c.canRefresh(path, err) && c.Refresh() == nil {
+		return c.doRequest(path, method, input, output)
+	}
+
+	return err
+}
+
+func (c *Client) doRequest(path, method string, input, output interface{}) error {
 	bts, err := c.rawRequest(method, path, input)
 
 	if err != nil {
@@ -92,6 +115,22 @@ func (c *Client) request(path, method string, input, output interface{}) error {
 	return c.decode(bts, output)
 }
 
+// canRefresh reports whether the request failed for the lack of authorization
+// and there is a refresh token to fix it
+func (c *Client) canRefresh(path string, err error) bool {
+	var resErr *result.Error
+	if !errors.As(err, &resErr) || resErr.Code != common.UnauthorizedErr.Code {
+		return false
+	}
+
+	if path == refreshPath || c.botToken != "" || c.apiKey != "" {
+		return false
+	}
+
+	cookie, ok := c.cookies["refresh_token"]
+	return ok && cookie.Value != ""
+}
+
 func (c *Client) post(path string, input, output interface{}) error {
 	return c.request(path, "POST", input, output)
 }
@@ -101,35 +140,188 @@ func (c *Client) get(path string, input, output interface{}) error {
 }
 
 func (c *Client) Register(form userForms.Register) (dto.User, string, error) {
-	var res dto.User
+	var res dto.AuthorizedUser
 
 	if err := c.post("/users/register", form, &res); err != nil {
-		return res, "", err
+		return res.User, "", err
 	}
 
-	token := ""
+	return res.User, res.AccessToken, nil
+}
 
-	if cookie, ok := c.cookies["auth_token"]; ok {
-		token = cookie.Value
-	}
+// TwoFactorRequiredError is returned by Login for the users with two-factor authentication,
+// the login is completed by CompleteLogin with the challenge
+type TwoFactorRequiredError struct {
+	Challenge string
+}
 
-	return res, token, nil
+func (err *TwoFactorRequiredError) Error() string {
+	return "two-factor authentication required"
 }
 
 func (c *Client) Login(form userForms.Login) (dto.User, string, error) {
-	var res dto.User
+	var res dto.LoginResult
 
 	if err := c.post("/users/login", form, &res); err != nil {
-		return res, "", err
+		return dto.User{}, "", err
 	}
 
-	token := ""
+	if res.TwoFactorRequired {
+		return dto.User{}, "", &TwoFactorRequiredError{Challenge: res.Challenge}
+	}
 
-	if cookie, ok := c.cookies["auth_token"]; ok {
-		token = cookie.Value
+	if res.AuthorizedUser == nil {
+		return dto.User{}, "", errors.New("unexpected login response")
 	}
 
-	return res, token, nil
+	return res.User, res.AccessToken, nil
+}
+
+func (c *Client) CompleteLogin(form userForms.CompleteLogin) (dto.User, string, error) {
+	var res dto.AuthorizedUser
+
+	if err := c.post("/users/completeLogin", form, &res); err != nil {
+		return res.User, "", err
+	}
+
+	return res.User, res.AccessToken, nil
+}
+
+func (c *Client) TwoFactor() (dto.TwoFactorStatus, error) {
+	var res dto.TwoFactorStatus
+	if err := c.get("/users/getTwoFactor", nil, &res); err != nil {
+		return res, err
+	}
+	return res, nil
+}
+
+func (c *Client) EnrollTwoFactor() (dto.TwoFactorEnrollment, error) {
+	var res dto.TwoFactorEnrollment
+	if err := c.post("/users/enrollTwoFactor", nil, &res); err != nil {
+		return res, err
+	}
+	return res, nil
+}
+
+func (c *Client) ConfirmTwoFactor(form userForms.ConfirmTwoFactor) (dto.RecoveryCodes, error) {
+	var res dto.RecoveryCodes
+	if err := c.post("/users/confirmTwoFactor", form, &res); err != nil {
+		return res, err
+	}
+	return res, nil
+}
+
+func (c *Client) DisableTwoFactor(form userForms.DisableTwoFactor) error {
+	if err := c.post("/users/disableTwoFactor", form, nil); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (c *Client) RegenerateRecoveryCodes(form userForms.RegenerateRecoveryCodes) (dto.RecoveryCodes, error) {
+	var res dto.RecoveryCodes
+	if err := c.post("/users/regenerateRecoveryCodes", form, &res); err != nil {
+		return res, err
+	}
+	return res, nil
+}
+
+// ChangePassword keeps the session of the client, the other sessions are revoked
+func (c *Client) ChangePassword(form userForms.ChangePassword) error {
+	if err := c.post("/users/changePassword", form, nil); err != nil {
+		return err
+	}
+	return nil
+}
+
+// RequestPasswordReset succeeds whether there is such a user or not,
+// the token is delivered to the user out of band
+func (c *Client) RequestPasswordReset(form userForms.RequestPasswordReset) error {
+	if err := c.post("/users/requestPasswordReset", form, nil); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (c *Client) ResetPassword(form userForms.ResetPassword) error {
+	if err := c.post("/users/resetPassword", form, nil); err != nil {
+		return err
+	}
+	return nil
+}
+
+// IdentityProviders returns the names of the providers users can log in with
+func (c *Client) IdentityProviders() ([]string, error) {
+	var res []string
+	if err := c.get("/users/getIdentityProviders", nil, &res); err != nil {
+		return res, err
+	}
+	return res, nil
+}
+
+// StartOIDCLogin returns the page of the provider, the provider redirects the user
+// back with the state and the code for CompleteOIDCLogin
+func (c *Client) StartOIDCLogin(form userForms.StartOIDCLogin) (dto.AuthURL, error) {
+	var res dto.AuthURL
+	if err := c.post("/users/startOIDCLogin", form, &res); err != nil {
+		return res, err
+	}
+	return res, nil
+}
+
+func (c *Client) CompleteOIDCLogin(form userForms.CompleteOIDCLogin) (dto.User, string, error) {
+	var res dto.LoginResult
+
+	if err := c.post("/users/completeOIDCLogin", form, &res); err != nil {
+		return dto.User{}, "", err
+	}
+
+	if res.TwoFactorRequired {
+		return dto.User{}, "", &TwoFactorRequiredError{Challenge: res.Challenge}
+	}
+
+	if res.AuthorizedUser == nil {
+		return dto.User{}, "", errors.New("unexpected login response")
+	}
+
+	return res.User, res.AccessToken, nil
+}
+
+func (c *Client) Identities() ([]dto.Identity, error) {
+	var res []dto.Identity
+	if err := c.get("/users/getIdentities", nil, &res); err != nil {
+		return res, err
+	}
+	return res, nil
+}
+
+func (c *Client) LinkIdentity(form userForms.LinkIdentity) (dto.AuthURL, error) {
+	var res dto.AuthURL
+	if err := c.post("/users/linkIdentity", form, &res); err != nil {
+		return res, err
+	}
+	return res, nil
+}
+
+func (c *Client) CompleteIdentityLink(form userForms.CompleteIdentityLink) (dto.Identity, error) {
+	var res dto.Identity
+	if err := c.post("/users/completeIdentityLink", form, &res); err != nil {
+		return res, err
+	}
+	return res, nil
+}
+
+func (c *Client) UnlinkIdentity(form userForms.UnlinkIdentity) error {
+	if err := c.post("/users/unlinkIdentity", form, nil); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Refresh exchanges the refresh token for a new pair of tokens,
+// the requests failing because of an expired access token call it on their own
+func (c *Client) Refresh() error {
+	return c.doRequest(refreshPath, "POST", nil, nil)
 }
 
 func (c *Client) Logout(ctx context.Context) error {
@@ -139,6 +331,53 @@ func (c *Client) Logout(ctx context.Context) error {
 	return nil
 }
 
+// LogoutEverywhere revokes all the sessions of the user, including the current one
+func (c *Client) LogoutEverywhere() error {
+	if err := c.post("/users/logoutEverywhere", nil, nil); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (c *Client) Sessions(form userForms.GetSessions) ([]dto.Session, error) {
+	var res []dto.Session
+	if err := c.post("/users/getSessions", form, &res); err != nil {
+		return res, err
+	}
+	return res, nil
+}
+
+func (c *Client) RevokeSession(form userForms.RevokeSession) error {
+	if err := c.post("/users/revokeSession", form, nil); err != nil {
+		return err
+	}
+	return nil
+}
+
+// CreateAPIKey returns the key along with its token in Key, the token isn't shown again
+func (c *Client) CreateAPIKey(form userForms.CreateAPIKey) (dto.APIKey, error) {
+	var res dto.APIKey
+	if err := c.post("/users/createAPIKey", form, &res); err != nil {
+		return res, err
+	}
+	return res, nil
+}
+
+func (c *Client) APIKeys(form userForms.GetAPIKeys) ([]dto.APIKey, error) {
+	var res []dto.APIKey
+	if err := c.post("/users/getAPIKeys", form, &res); err != nil {
+		return res, err
+	}
+	return res, nil
+}
+
+func (c *Client) DeleteAPIKey(form userForms.DeleteAPIKey) error {
+	if err := c.post("/users/deleteAPIKey", form, nil); err != nil {
+		return err
+	}
+	return nil
+}
+
 func (c *Client) Info(ctx context.Context) (dto.User, error) {
 	var res dto.User
 
@@ -236,6 +475,13 @@ func (c *Client) DeleteChat(form chatForms.DeleteChat) error {
 	return nil
 }
 
+func (c *Client) LeaveChat(form chatForms.LeaveChat) error {
+	if err := c.post("/chats/leaveChat", form, nil); err != nil {
+		return err
+	}
+	return nil
+}
+
 func (c *Client) CreateChat(form chatForms.CreateChat) (dto.Chat, error) {
 	var res dto.Chat
 	if err := c.post("/chats/createChat", form, &res); err != nil {
@@ -244,6 +490,21 @@ func (c *Client) CreateChat(form chatForms.CreateChat) (dto.Chat, error) {
 	return res, nil
 }
 
+func (c *Client) UpdateChat(form chatForms.UpdateChat) (dto.Chat, error) {
+	var res dto.Chat
+	if err := c.post("/chats/updateChat", form, &res); err != nil {
+		return res, err
+	}
+	return res, nil
+}
+
+func (c *Client) TransferOwnership(form chatForms.TransferOwnership) error {
+	if err := c.post("/chats/transferOwnership", form, nil); err != nil {
+		return err
+	}
+	return nil
+}
+
 func (c *Client) SendMessage(form chatForms.SendMessage) (dto.Message, error) {
 	var res dto.Message
 	if err := c.post("/chats/sendMessage", form, &res); err != nil {
@@ -283,6 +544,213 @@ func (c *Client) GetChatMembers(form chatForms.GetChatMembers) ([]dto.User, erro
 	return res, nil
 }
 
+func (c *Client) CreateInvite(form chatForms.CreateInvite) (dto.ChatInvite, error) {
+	var res dto.ChatInvite
+	if err := c.post("/chats/createInvite", form, &res); err != nil {
+		return res, err
+	}
+	return res, nil
+}
+
+func (c *Client) RevokeInvite(form chatForms.RevokeInvite) error {
+	if err := c.post("/chats/revokeInvite", form, nil); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (c *Client) GetInvites(form chatForms.GetInvites) ([]dto.ChatInvite, error) {
+	var res []dto.ChatInvite
+	if err := c.post("/chats/getInvites", form, &res); err != nil {
+		return res, err
+	}
+	return res, nil
+}
+
+func (c *Client) Join(form chatForms.JoinChat) (dto.JoinResult, error) {
+	var res dto.JoinResult
+	if err := c.post("/chats/join", form, &res); err != nil {
+		return res, err
+	}
+	return res, nil
+}
+
+func (c *Client) GetJoinRequests(form chatForms.GetJoinRequests) ([]dto.ChatJoinRequest, error) {
+	var res []dto.ChatJoinRequest
+	if err := c.post("/chats/getJoinRequests", form, &res); err != nil {
+		return res, err
+	}
+	return res, nil
+}
+
+func (c *Client) ApproveJoinRequest(form chatForms.ApproveJoinRequest) error {
+	if err := c.post("/chats/approveJoinRequest", form, nil); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (c *Client) RejectJoinRequest(form chatForms.RejectJoinRequest) error {
+	if err := c.post("/chats/rejectJoinRequest", form, nil); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (c *Client) SetChatVisibility(form chatForms.SetChatVisibility) error {
+	if err := c.post("/chats/setVisibility", form, nil); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (c *Client) SearchChats(form chatForms.SearchChats) ([]dto.PublicChat, error) {
+	var res []dto.PublicChat
+	if err := c.post("/chats/searchChats", form, &res); err != nil {
+		return res, err
+	}
+	return res, nil
+}
+
+func (c *Client) CreateWebhook(form userForms.CreateWebhook) (dto.Webhook, error) {
+	var res dto.Webhook
+	if err := c.post("/users/createWebhook", form, &res); err != nil {
+		return res, err
+	}
+	return res, nil
+}
+
+func (c *Client) Webhooks(form userForms.GetWebhooks) ([]dto.Webhook, error) {
+	var res []dto.Webhook
+	if err := c.post("/users/getWebhooks", form, &res); err != nil {
+		return res, err
+	}
+	return res, nil
+}
+
+func (c *Client) DeleteWebhook(form userForms.DeleteWebhook) error {
+	if err := c.post("/users/deleteWebhook", form, nil); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (c *Client) WebhookDeliveries(form userForms.GetWebhookDeliveries) ([]dto.WebhookDelivery, error) {
+	var res []dto.WebhookDelivery
+	if err := c.post("/users/getWebhookDeliveries", form, &res); err != nil {
+		return res, err
+	}
+	return res, nil
+}
+
+func (c *Client) RedeliverWebhook(form userForms.RedeliverWebhook) error {
+	if err := c.post("/users/redeliverWebhook", form, nil); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (c *Client) CreateChatWebhook(form chatForms.CreateWebhook) (dto.Webhook, error) {
+	var res dto.Webhook
+	if err := c.post("/chats/createWebhook", form, &res); err != nil {
+		return res, err
+	}
+	return res, nil
+}
+
+func (c *Client) ChatWebhooks(form chatForms.GetWebhooks) ([]dto.Webhook, error) {
+	var res []dto.Webhook
+	if err := c.post("/chats/getWebhooks", form, &res); err != nil {
+		return res, err
+	}
+	return res, nil
+}
+
+func (c *Client) CreateIncomingWebhook(form chatForms.CreateIncomingWebhook) (dto.IncomingWebhook, error) {
+	var res dto.IncomingWebhook
+	if err := c.post("/chats/createIncomingWebhook", form, &res); err != nil {
+		return res, err
+	}
+	return res, nil
+}
+
+func (c *Client) IncomingWebhooks(form chatForms.GetIncomingWebhooks) ([]dto.IncomingWebhook, error) {
+	var res []dto.IncomingWebhook
+	if err := c.post("/chats/getIncomingWebhooks", form, &res); err != nil {
+		return res, err
+	}
+	return res, nil
+}
+
+func (c *Client) DeleteIncomingWebhook(form chatForms.DeleteIncomingWebhook) error {
+	if err := c.post("/chats/deleteIncomingWebhook", form, nil); err != nil {
+		return err
+	}
+	return nil
+}
+
+// PostIncomingWebhook posts a message the way external services do, it doesn't need the auth token
+func (c *Client) PostIncomingWebhook(id, token string, form hookForms.Post) error {
+	if err := c.post("/hooks/incoming/"+id+"/"+token, form, nil); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (c *Client) CreateBot(form userForms.CreateBot) (dto.Bot, error) {
+	var res dto.Bot
+	if err := c.post("/users/createBot", form, &res); err != nil {
+		return res, err
+	}
+	return res, nil
+}
+
+func (c *Client) Bots(form userForms.GetBots) ([]dto.Bot, error) {
+	var res []dto.Bot
+	if err := c.post("/users/getBots", form, &res); err != nil {
+		return res, err
+	}
+	return res, nil
+}
+
+func (c *Client) DeleteBot(form userForms.DeleteBot) error {
+	if err := c.post("/users/deleteBot", form, nil); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (c *Client) RegenerateBotToken(form userForms.RegenerateBotToken) (dto.Bot, error) {
+	var res dto.Bot
+	if err := c.post("/users/regenerateBotToken", form, &res); err != nil {
+		return res, err
+	}
+	return res, nil
+}
+
+func (c *Client) SetBotCommands(form userForms.SetBotCommands) error {
+	if err := c.post("/users/setBotCommands", form, nil); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (c *Client) BotCommands(form userForms.GetBotCommands) ([]dto.BotCommand, error) {
+	var res []dto.BotCommand
+	if err := c.post("/users/getBotCommands", form, &res); err != nil {
+		return res, err
+	}
+	return res, nil
+}
+
+func (c *Client) Updates(form userForms.GetUpdates) ([]dto.BotUpdate, error) {
+	var res []dto.BotUpdate
+	if err := c.post("/users/getUpdates", form, &res); err != nil {
+		return res, err
+	}
+	return res, nil
+}
+
 func (c *Client) SetToken(token string) {
 	c.cookies["auth_token"] = &http.Cookie{
 		Name:     "auth_token",
@@ -293,6 +761,17 @@ func (c *Client) SetToken(token string) {
 	}
 }
 
+// SetBotToken makes the client act as a bot, an empty token switches it back
+func (c *Client) SetBotToken(token string) {
+	c.botToken = token
+}
+
+// SetAPIKey makes the client use an API key, an empty key switches it back.
+// A bot token takes precedence over it.
+func (c *Client) SetAPIKey(key string) {
+	c.apiKey = key
+}
+
 func (c *Client) SetBaseUrl(url string) {
 	c.baseUrl = url
 }
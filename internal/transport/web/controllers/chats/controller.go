@@ -4,8 +4,10 @@ import (
 	"encoding/json"
 	"github.com/ischenkx/vk-test-task/internal/app"
 	appForms "github.com/ischenkx/vk-test-task/internal/app/forms"
+	"github.com/ischenkx/vk-test-task/internal/app/security"
 	"github.com/ischenkx/vk-test-task/internal/transport/web/controllers/chats/forms"
 	"github.com/ischenkx/vk-test-task/internal/transport/web/controllers/common"
+	"github.com/ischenkx/vk-test-task/internal/transport/web/controllers/common/middlewares"
 	"github.com/ischenkx/vk-test-task/internal/transport/web/controllers/common/result"
 	"github.com/ischenkx/vk-test-task/internal/transport/web/controllers/dto"
 	"github.com/ischenkx/vk-test-task/internal/transport/web/util"
@@ -79,6 +81,34 @@ func (c *Controller) DeleteChat(w http.ResponseWriter, r *http.Request) {
 	result.WriteSilent(w, result.Ok(nil))
 }
 
+func (c *Controller) LeaveChat(w http.ResponseWriter, r *http.Request) {
+	ctx, ok := util.AppContext(r.Context())
+	if !ok {
+		result.WriteSilent(w, result.New(nil, common.InternalServerErr))
+		return
+	}
+
+	var form forms.LeaveChat
+	if err := json.NewDecoder(r.Body).Decode(&form); err != nil {
+		result.WriteSilent(w, result.New(nil, common.IncorrectInputErr))
+		return
+	}
+
+	chat, err := c.app.Chats().Get(ctx, form.ID)
+
+	if err != nil {
+		result.WriteSilent(w, result.Err(common.CustomErrorCode, err.Error()))
+		return
+	}
+
+	if err := chat.Leave(ctx); err != nil {
+		result.WriteSilent(w, result.Err(common.CustomErrorCode, err.Error()))
+		return
+	}
+
+	result.WriteSilent(w, result.Ok(nil))
+}
+
 func (c *Controller) CreateChat(w http.ResponseWriter, r *http.Request) {
 	ctx, ok := util.AppContext(r.Context())
 	if !ok {
@@ -95,6 +125,49 @@ func (c *Controller) CreateChat(w http.ResponseWriter, r *http.Request) {
 	chat, err := c.app.Chats().Create(ctx, appForms.ChatCreationForm{
 		Name:        form.Name,
 		Description: form.Description,
+		Visibility:  form.Visibility,
+		Kind:        form.Kind,
+		CountViews:  form.CountViews,
+	})
+
+	if err != nil {
+		result.WriteSilent(w, result.Err(common.CustomErrorCode, err.Error()))
+		return
+	}
+
+	var chatDto dto.Chat
+
+	if err := chatDto.Load(ctx, chat); err != nil {
+		result.WriteSilent(w, result.New(nil, common.FailedToLoadErr))
+		return
+	}
+
+	result.WriteSilent(w, result.Ok(chatDto))
+}
+
+func (c *Controller) UpdateChat(w http.ResponseWriter, r *http.Request) {
+	ctx, ok := util.AppContext(r.Context())
+	if !ok {
+		result.WriteSilent(w, result.New(nil, common.InternalServerErr))
+		return
+	}
+
+	var form forms.UpdateChat
+	if err := json.NewDecoder(r.Body).Decode(&form); err != nil {
+		result.WriteSilent(w, result.New(nil, common.IncorrectInputErr))
+		return
+	}
+
+	chat, err := c.app.Chats().Get(ctx, form.ID)
+
+	if err != nil {
+		result.WriteSilent(w, result.Err(common.CustomErrorCode, err.Error()))
+		return
+	}
+
+	err = chat.Update(ctx, appForms.ChatUpdate{
+		Name:        form.Name,
+		Description: form.Description,
 	})
 
 	if err != nil {
@@ -112,6 +185,34 @@ func (c *Controller) CreateChat(w http.ResponseWriter, r *http.Request) {
 	result.WriteSilent(w, result.Ok(chatDto))
 }
 
+func (c *Controller) TransferOwnership(w http.ResponseWriter, r *http.Request) {
+	ctx, ok := util.AppContext(r.Context())
+	if !ok {
+		result.WriteSilent(w, result.New(nil, common.InternalServerErr))
+		return
+	}
+
+	var form forms.TransferOwnership
+	if err := json.NewDecoder(r.Body).Decode(&form); err != nil {
+		result.WriteSilent(w, result.New(nil, common.IncorrectInputErr))
+		return
+	}
+
+	chat, err := c.app.Chats().Get(ctx, form.ChatID)
+
+	if err != nil {
+		result.WriteSilent(w, result.Err(common.CustomErrorCode, err.Error()))
+		return
+	}
+
+	if err := chat.TransferOwnership(ctx, form.UserID); err != nil {
+		result.WriteSilent(w, result.Err(common.CustomErrorCode, err.Error()))
+		return
+	}
+
+	result.WriteSilent(w, result.Ok(nil))
+}
+
 func (c *Controller) GetChatMembers(w http.ResponseWriter, r *http.Request) {
 	ctx, ok := util.AppContext(r.Context())
 	if !ok {
@@ -213,7 +314,7 @@ func (c *Controller) CreateChatMember(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	_, err = chat.Add(ctx, form.UserID, 0)
+	_, err = chat.Add(ctx, form.UserID, app.ChatMemberRegularStatus)
 
 	if err != nil {
 		result.WriteSilent(w, result.Err(common.CustomErrorCode, err.Error()))
@@ -285,7 +386,10 @@ func (c *Controller) SendMessage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	mes, err := member.SendMessage(ctx, appForms.SendMessage{Payload: form.Payload})
+	mes, err := member.SendMessage(ctx, appForms.SendMessage{
+		Payload:     form.Payload,
+		Attachments: dto.MessageAttachmentModels(form.Attachments),
+	})
 
 	if err != nil {
 		result.WriteSilent(w, result.Err(common.CustomErrorCode, err.Error()))
@@ -372,16 +476,33 @@ func (c *Controller) GetMessages(w http.ResponseWriter, r *http.Request) {
 }
 
 func (c *Controller) init() {
-	c.mux.HandleFunc("/createChatMember", c.CreateChatMember)
-	c.mux.HandleFunc("/deleteChatMember", c.DeleteChatMember)
-	c.mux.HandleFunc("/getChatMembers", c.GetChatMembers)
-	c.mux.HandleFunc("/getChat", c.GetChat)
-	c.mux.HandleFunc("/deleteChat", c.DeleteChat)
-	c.mux.HandleFunc("/createChat", c.CreateChat)
-	c.mux.HandleFunc("/sendMessage", c.SendMessage)
-	c.mux.HandleFunc("/updateMessage", c.UpdateMessage)
-	c.mux.HandleFunc("/deleteMessage", c.DeleteMessage)
-	c.mux.HandleFunc("/getMessages", c.GetMessages)
+	c.mux.HandleFunc("/createChatMember", middlewares.RequireScope(security.ScopeChatsWrite, c.CreateChatMember))
+	c.mux.HandleFunc("/deleteChatMember", middlewares.RequireScope(security.ScopeChatsWrite, c.DeleteChatMember))
+	c.mux.HandleFunc("/getChatMembers", middlewares.RequireScope(security.ScopeChatsRead, c.GetChatMembers))
+	c.mux.HandleFunc("/getChat", middlewares.RequireScope(security.ScopeChatsRead, c.GetChat))
+	c.mux.HandleFunc("/deleteChat", middlewares.RequireScope(security.ScopeChatsWrite, c.DeleteChat))
+	c.mux.HandleFunc("/leaveChat", middlewares.RequireScope(security.ScopeChatsWrite, c.LeaveChat))
+	c.mux.HandleFunc("/createChat", middlewares.RequireScope(security.ScopeChatsWrite, c.CreateChat))
+	c.mux.HandleFunc("/updateChat", middlewares.RequireScope(security.ScopeChatsWrite, c.UpdateChat))
+	c.mux.HandleFunc("/transferOwnership", middlewares.RequireScope(security.ScopeChatsWrite, c.TransferOwnership))
+	c.mux.HandleFunc("/sendMessage", middlewares.RequireScope(security.ScopeMessagesWrite, c.SendMessage))
+	c.mux.HandleFunc("/updateMessage", middlewares.RequireScope(security.ScopeMessagesWrite, c.UpdateMessage))
+	c.mux.HandleFunc("/deleteMessage", middlewares.RequireScope(security.ScopeMessagesWrite, c.DeleteMessage))
+	c.mux.HandleFunc("/getMessages", middlewares.RequireScope(security.ScopeMessagesRead, c.GetMessages))
+	c.mux.HandleFunc("/createInvite", middlewares.RequireScope(security.ScopeChatsWrite, c.CreateInvite))
+	c.mux.HandleFunc("/revokeInvite", middlewares.RequireScope(security.ScopeChatsWrite, c.RevokeInvite))
+	c.mux.HandleFunc("/getInvites", middlewares.RequireScope(security.ScopeChatsRead, c.GetInvites))
+	c.mux.HandleFunc("/join", middlewares.RequireScope(security.ScopeChatsWrite, c.Join))
+	c.mux.HandleFunc("/setVisibility", middlewares.RequireScope(security.ScopeChatsWrite, c.SetVisibility))
+	c.mux.HandleFunc("/searchChats", middlewares.RequireScope(security.ScopeChatsRead, c.SearchChats))
+	c.mux.HandleFunc("/getJoinRequests", middlewares.RequireScope(security.ScopeChatsRead, c.GetJoinRequests))
+	c.mux.HandleFunc("/approveJoinRequest", middlewares.RequireScope(security.ScopeChatsWrite, c.ApproveJoinRequest))
+	c.mux.HandleFunc("/rejectJoinRequest", middlewares.RequireScope(security.ScopeChatsWrite, c.RejectJoinRequest))
+	c.mux.HandleFunc("/createWebhook", middlewares.RequireScope(security.ScopeWebhooks, c.CreateWebhook))
+	c.mux.HandleFunc("/getWebhooks", middlewares.RequireScope(security.ScopeWebhooks, c.GetWebhooks))
+	c.mux.HandleFunc("/createIncomingWebhook", middlewares.RequireScope(security.ScopeWebhooks, c.CreateIncomingWebhook))
+	c.mux.HandleFunc("/getIncomingWebhooks", middlewares.RequireScope(security.ScopeWebhooks, c.GetIncomingWebhooks))
+	c.mux.HandleFunc("/deleteIncomingWebhook", middlewares.RequireScope(security.ScopeWebhooks, c.DeleteIncomingWebhook))
 }
 
 func NewController(app *app.App) *Controller {
@@ -0,0 +1,74 @@
+package chats
+
+import (
+	"encoding/json"
+	appForms "github.com/ischenkx/vk-test-task/internal/app/forms"
+	"github.com/ischenkx/vk-test-task/internal/transport/web/controllers/chats/forms"
+	"github.com/ischenkx/vk-test-task/internal/transport/web/controllers/common"
+	"github.com/ischenkx/vk-test-task/internal/transport/web/controllers/common/result"
+	"github.com/ischenkx/vk-test-task/internal/transport/web/controllers/dto"
+	"github.com/ischenkx/vk-test-task/internal/transport/web/util"
+	"net/http"
+)
+
+func (c *Controller) SetVisibility(w http.ResponseWriter, r *http.Request) {
+	ctx, ok := util.AppContext(r.Context())
+	if !ok {
+		result.WriteSilent(w, result.New(nil, common.InternalServerErr))
+		return
+	}
+
+	var form forms.SetChatVisibility
+	if err := json.NewDecoder(r.Body).Decode(&form); err != nil {
+		result.WriteSilent(w, result.New(nil, common.IncorrectInputErr))
+		return
+	}
+
+	chat, err := c.app.Chats().Get(ctx, form.ChatID)
+
+	if err != nil {
+		result.WriteSilent(w, result.Err(common.CustomErrorCode, err.Error()))
+		return
+	}
+
+	if err := chat.SetVisibility(ctx, appForms.ChatVisibilityUpdate{Visibility: form.Visibility}); err != nil {
+		result.WriteSilent(w, result.Err(common.CustomErrorCode, err.Error()))
+		return
+	}
+
+	result.WriteSilent(w, result.Ok(nil))
+}
+
+func (c *Controller) SearchChats(w http.ResponseWriter, r *http.Request) {
+	ctx, ok := util.AppContext(r.Context())
+	if !ok {
+		result.WriteSilent(w, result.New(nil, common.InternalServerErr))
+		return
+	}
+
+	var form forms.SearchChats
+	if err := json.NewDecoder(r.Body).Decode(&form); err != nil {
+		result.WriteSilent(w, result.New(nil, common.IncorrectInputErr))
+		return
+	}
+
+	chats, err := c.app.Chats().SearchPublic(ctx, form.Query, form.Offset, form.Count)
+
+	if err != nil {
+		result.WriteSilent(w, result.Err(common.CustomErrorCode, err.Error()))
+		return
+	}
+
+	var chatDtos []dto.PublicChat
+
+	for _, chat := range chats {
+		var chatDto dto.PublicChat
+		if err := chatDto.Load(ctx, chat); err != nil {
+			result.WriteSilent(w, result.New(nil, common.FailedToLoadErr))
+			return
+		}
+		chatDtos = append(chatDtos, chatDto)
+	}
+
+	result.WriteSilent(w, result.Ok(chatDtos))
+}
@@ -1,5 +1,7 @@
 package forms
 
+import "github.com/ischenkx/vk-test-task/internal/transport/web/controllers/dto"
+
 type GetChat struct {
 	ID string `json:"id"`
 }
@@ -8,9 +10,38 @@ type DeleteChat struct {
 	ID string `json:"id"`
 }
 
+type LeaveChat struct {
+	ID string `json:"id"`
+}
+
 type CreateChat struct {
 	Name        string `json:"name"`
 	Description string `json:"description"`
+	Visibility  int    `json:"visibility"`
+	Kind        int    `json:"kind"`
+	CountViews  bool   `json:"count_views"`
+}
+
+type UpdateChat struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+type TransferOwnership struct {
+	ChatID string `json:"chat_id"`
+	UserID string `json:"user_id"`
+}
+
+type SetChatVisibility struct {
+	ChatID     string `json:"chat_id"`
+	Visibility int    `json:"visibility"`
+}
+
+type SearchChats struct {
+	Query  string `json:"query"`
+	Offset int    `json:"offset"`
+	Count  int    `json:"count"`
 }
 
 type CreateChatMember struct {
@@ -29,8 +60,9 @@ type UpdateMessage struct {
 }
 
 type SendMessage struct {
-	ChatID  string `json:"chat_id"`
-	Payload string `json:"payload"`
+	ChatID      string                  `json:"chat_id"`
+	Payload     string                  `json:"payload"`
+	Attachments []dto.MessageAttachment `json:"attachments"`
 }
 
 type DeleteMessage struct {
@@ -48,3 +80,72 @@ type GetChatMembers struct {
 	Offset int    `json:"offset"`
 	Count  int    `json:"count"`
 }
+
+type CreateInvite struct {
+	ChatID           string `json:"chat_id"`
+	MaxUses          int    `json:"max_uses"`
+	ExpiresIn        int64  `json:"expires_in"`
+	RequiresApproval bool   `json:"requires_approval"`
+}
+
+type RevokeInvite struct {
+	ChatID string `json:"chat_id"`
+	Code   string `json:"code"`
+}
+
+type GetInvites struct {
+	ChatID string `json:"chat_id"`
+	Offset int    `json:"offset"`
+	Count  int    `json:"count"`
+}
+
+// JoinChat redeems an invite code if it is set,
+// otherwise the public chat with the given id is joined
+type JoinChat struct {
+	Code   string `json:"code"`
+	ChatID string `json:"chat_id"`
+}
+
+type GetJoinRequests struct {
+	ChatID string `json:"chat_id"`
+	Offset int    `json:"offset"`
+	Count  int    `json:"count"`
+}
+
+type ApproveJoinRequest struct {
+	ChatID string `json:"chat_id"`
+	ID     string `json:"id"`
+}
+
+type RejectJoinRequest struct {
+	ChatID string `json:"chat_id"`
+	ID     string `json:"id"`
+}
+
+type CreateWebhook struct {
+	ChatID string   `json:"chat_id"`
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+}
+
+type GetWebhooks struct {
+	ChatID string `json:"chat_id"`
+	Offset int    `json:"offset"`
+	Count  int    `json:"count"`
+}
+
+type CreateIncomingWebhook struct {
+	ChatID string `json:"chat_id"`
+	Name   string `json:"name"`
+}
+
+type GetIncomingWebhooks struct {
+	ChatID string `json:"chat_id"`
+	Offset int    `json:"offset"`
+	Count  int    `json:"count"`
+}
+
+type DeleteIncomingWebhook struct {
+	ChatID string `json:"chat_id"`
+	ID     string `json:"id"`
+}
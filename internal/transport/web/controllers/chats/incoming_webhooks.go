@@ -0,0 +1,128 @@
+package chats
+
+import (
+	"encoding/json"
+	appForms "github.com/ischenkx/vk-test-task/internal/app/forms"
+	"github.com/ischenkx/vk-test-task/internal/transport/web/controllers/chats/forms"
+	"github.com/ischenkx/vk-test-task/internal/transport/web/controllers/common"
+	"github.com/ischenkx/vk-test-task/internal/transport/web/controllers/common/result"
+	"github.com/ischenkx/vk-test-task/internal/transport/web/controllers/dto"
+	"github.com/ischenkx/vk-test-task/internal/transport/web/util"
+	"net/http"
+)
+
+func (c *Controller) CreateIncomingWebhook(w http.ResponseWriter, r *http.Request) {
+	ctx, ok := util.AppContext(r.Context())
+	if !ok {
+		result.WriteSilent(w, result.New(nil, common.InternalServerErr))
+		return
+	}
+
+	var form forms.CreateIncomingWebhook
+	if err := json.NewDecoder(r.Body).Decode(&form); err != nil {
+		result.WriteSilent(w, result.New(nil, common.IncorrectInputErr))
+		return
+	}
+
+	chat, err := c.app.Chats().Get(ctx, form.ChatID)
+
+	if err != nil {
+		result.WriteSilent(w, result.Err(common.CustomErrorCode, err.Error()))
+		return
+	}
+
+	webhook, token, err := chat.CreateIncomingWebhook(ctx, appForms.IncomingWebhookCreation{
+		Name: form.Name,
+	})
+
+	if err != nil {
+		result.WriteSilent(w, result.Err(common.CustomErrorCode, err.Error()))
+		return
+	}
+
+	var webhookDto dto.IncomingWebhook
+
+	if err := webhookDto.Load(ctx, webhook); err != nil {
+		result.WriteSilent(w, result.New(nil, common.FailedToLoadErr))
+		return
+	}
+	webhookDto.Token = token
+
+	result.WriteSilent(w, result.Ok(webhookDto))
+}
+
+func (c *Controller) GetIncomingWebhooks(w http.ResponseWriter, r *http.Request) {
+	ctx, ok := util.AppContext(r.Context())
+	if !ok {
+		result.WriteSilent(w, result.New(nil, common.InternalServerErr))
+		return
+	}
+
+	var form forms.GetIncomingWebhooks
+	if err := json.NewDecoder(r.Body).Decode(&form); err != nil {
+		result.WriteSilent(w, result.New(nil, common.IncorrectInputErr))
+		return
+	}
+
+	chat, err := c.app.Chats().Get(ctx, form.ChatID)
+
+	if err != nil {
+		result.WriteSilent(w, result.Err(common.CustomErrorCode, err.Error()))
+		return
+	}
+
+	webhooks, err := chat.IncomingWebhooks(ctx, form.Offset, form.Count)
+
+	if err != nil {
+		result.WriteSilent(w, result.Err(common.CustomErrorCode, err.Error()))
+		return
+	}
+
+	var webhooksDto []dto.IncomingWebhook
+
+	for _, webhook := range webhooks {
+		var webhookDto dto.IncomingWebhook
+		if err := webhookDto.Load(ctx, webhook); err != nil {
+			result.WriteSilent(w, result.New(nil, common.FailedToLoadErr))
+			return
+		}
+		webhooksDto = append(webhooksDto, webhookDto)
+	}
+
+	result.WriteSilent(w, result.Ok(webhooksDto))
+}
+
+func (c *Controller) DeleteIncomingWebhook(w http.ResponseWriter, r *http.Request) {
+	ctx, ok := util.AppContext(r.Context())
+	if !ok {
+		result.WriteSilent(w, result.New(nil, common.InternalServerErr))
+		return
+	}
+
+	var form forms.DeleteIncomingWebhook
+	if err := json.NewDecoder(r.Body).Decode(&form); err != nil {
+		result.WriteSilent(w, result.New(nil, common.IncorrectInputErr))
+		return
+	}
+
+	chat, err := c.app.Chats().Get(ctx, form.ChatID)
+
+	if err != nil {
+		result.WriteSilent(w, result.Err(common.CustomErrorCode, err.Error()))
+		return
+	}
+
+	webhook, err := chat.IncomingWebhook(ctx, form.ID)
+
+	if err != nil {
+		result.WriteSilent(w, result.Err(common.CustomErrorCode, err.Error()))
+		return
+	}
+
+	if err := webhook.Delete(ctx); err != nil {
+		result.WriteSilent(w, result.Err(common.CustomErrorCode, err.Error()))
+		return
+	}
+
+	result.WriteSilent(w, result.Ok(nil))
+}
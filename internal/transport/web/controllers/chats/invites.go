@@ -0,0 +1,298 @@
+package chats
+
+import (
+	"encoding/json"
+	"github.com/ischenkx/vk-test-task/internal/app"
+	appForms "github.com/ischenkx/vk-test-task/internal/app/forms"
+	"github.com/ischenkx/vk-test-task/internal/transport/web/controllers/chats/forms"
+	"github.com/ischenkx/vk-test-task/internal/transport/web/controllers/common"
+	"github.com/ischenkx/vk-test-task/internal/transport/web/controllers/common/result"
+	"github.com/ischenkx/vk-test-task/internal/transport/web/controllers/dto"
+	"github.com/ischenkx/vk-test-task/internal/transport/web/util"
+	"net/http"
+	"time"
+)
+
+func (c *Controller) CreateInvite(w http.ResponseWriter, r *http.Request) {
+	ctx, ok := util.AppContext(r.Context())
+	if !ok {
+		result.WriteSilent(w, result.New(nil, common.InternalServerErr))
+		return
+	}
+
+	var form forms.CreateInvite
+	if err := json.NewDecoder(r.Body).Decode(&form); err != nil {
+		result.WriteSilent(w, result.New(nil, common.IncorrectInputErr))
+		return
+	}
+
+	chat, err := c.app.Chats().Get(ctx, form.ChatID)
+
+	if err != nil {
+		result.WriteSilent(w, result.Err(common.CustomErrorCode, err.Error()))
+		return
+	}
+
+	var expiresAt time.Time
+	if form.ExpiresIn > 0 {
+		expiresAt = time.Now().Add(time.Duration(form.ExpiresIn) * time.Second)
+	}
+
+	invite, err := chat.CreateInvite(ctx, appForms.ChatInviteCreation{
+		MaxUses:          form.MaxUses,
+		ExpiresAt:        expiresAt,
+		RequiresApproval: form.RequiresApproval,
+	})
+
+	if err != nil {
+		result.WriteSilent(w, result.Err(common.CustomErrorCode, err.Error()))
+		return
+	}
+
+	var inviteDto dto.ChatInvite
+
+	if err := inviteDto.Load(ctx, invite); err != nil {
+		result.WriteSilent(w, result.New(nil, common.FailedToLoadErr))
+		return
+	}
+
+	result.WriteSilent(w, result.Ok(inviteDto))
+}
+
+func (c *Controller) RevokeInvite(w http.ResponseWriter, r *http.Request) {
+	ctx, ok := util.AppContext(r.Context())
+	if !ok {
+		result.WriteSilent(w, result.New(nil, common.InternalServerErr))
+		return
+	}
+
+	var form forms.RevokeInvite
+	if err := json.NewDecoder(r.Body).Decode(&form); err != nil {
+		result.WriteSilent(w, result.New(nil, common.IncorrectInputErr))
+		return
+	}
+
+	chat, err := c.app.Chats().Get(ctx, form.ChatID)
+
+	if err != nil {
+		result.WriteSilent(w, result.Err(common.CustomErrorCode, err.Error()))
+		return
+	}
+
+	invite, err := chat.Invite(ctx, form.Code)
+
+	if err != nil {
+		result.WriteSilent(w, result.Err(common.CustomErrorCode, err.Error()))
+		return
+	}
+
+	if err := invite.Revoke(ctx); err != nil {
+		result.WriteSilent(w, result.Err(common.CustomErrorCode, err.Error()))
+		return
+	}
+
+	result.WriteSilent(w, result.Ok(nil))
+}
+
+func (c *Controller) GetInvites(w http.ResponseWriter, r *http.Request) {
+	ctx, ok := util.AppContext(r.Context())
+	if !ok {
+		result.WriteSilent(w, result.New(nil, common.InternalServerErr))
+		return
+	}
+
+	var form forms.GetInvites
+	if err := json.NewDecoder(r.Body).Decode(&form); err != nil {
+		result.WriteSilent(w, result.New(nil, common.IncorrectInputErr))
+		return
+	}
+
+	chat, err := c.app.Chats().Get(ctx, form.ChatID)
+
+	if err != nil {
+		result.WriteSilent(w, result.Err(common.CustomErrorCode, err.Error()))
+		return
+	}
+
+	invites, err := chat.Invites(ctx, form.Offset, form.Count)
+
+	if err != nil {
+		result.WriteSilent(w, result.Err(common.CustomErrorCode, err.Error()))
+		return
+	}
+
+	var inviteDtos []dto.ChatInvite
+
+	for _, invite := range invites {
+		var inviteDto dto.ChatInvite
+		if err := inviteDto.Load(ctx, invite); err != nil {
+			result.WriteSilent(w, result.New(nil, common.FailedToLoadErr))
+			return
+		}
+		inviteDtos = append(inviteDtos, inviteDto)
+	}
+
+	result.WriteSilent(w, result.Ok(inviteDtos))
+}
+
+func (c *Controller) Join(w http.ResponseWriter, r *http.Request) {
+	ctx, ok := util.AppContext(r.Context())
+	if !ok {
+		result.WriteSilent(w, result.New(nil, common.InternalServerErr))
+		return
+	}
+
+	var form forms.JoinChat
+	if err := json.NewDecoder(r.Body).Decode(&form); err != nil {
+		result.WriteSilent(w, result.New(nil, common.IncorrectInputErr))
+		return
+	}
+
+	var member app.ChatMember
+	var request app.ChatJoinRequest
+	var err error
+
+	if form.Code != "" {
+		member, request, err = c.app.Chats().JoinByInvite(ctx, form.Code)
+	} else {
+		member, request, err = c.app.Chats().Join(ctx, form.ChatID)
+	}
+
+	if err != nil {
+		result.WriteSilent(w, result.Err(common.CustomErrorCode, err.Error()))
+		return
+	}
+
+	var joinResult dto.JoinResult
+
+	if request != nil {
+		var requestDto dto.ChatJoinRequest
+		if err := requestDto.Load(ctx, request); err != nil {
+			result.WriteSilent(w, result.New(nil, common.FailedToLoadErr))
+			return
+		}
+		joinResult.ChatID = requestDto.ChatID
+		joinResult.RequestID = requestDto.ID
+		joinResult.Pending = true
+	} else {
+		chat, err := member.Chat(ctx)
+		if err != nil {
+			result.WriteSilent(w, result.New(nil, common.FailedToLoadErr))
+			return
+		}
+		joinResult.ChatID = chat.ID()
+	}
+
+	result.WriteSilent(w, result.Ok(joinResult))
+}
+
+func (c *Controller) GetJoinRequests(w http.ResponseWriter, r *http.Request) {
+	ctx, ok := util.AppContext(r.Context())
+	if !ok {
+		result.WriteSilent(w, result.New(nil, common.InternalServerErr))
+		return
+	}
+
+	var form forms.GetJoinRequests
+	if err := json.NewDecoder(r.Body).Decode(&form); err != nil {
+		result.WriteSilent(w, result.New(nil, common.IncorrectInputErr))
+		return
+	}
+
+	chat, err := c.app.Chats().Get(ctx, form.ChatID)
+
+	if err != nil {
+		result.WriteSilent(w, result.Err(common.CustomErrorCode, err.Error()))
+		return
+	}
+
+	requests, err := chat.JoinRequests(ctx, form.Offset, form.Count)
+
+	if err != nil {
+		result.WriteSilent(w, result.Err(common.CustomErrorCode, err.Error()))
+		return
+	}
+
+	var requestDtos []dto.ChatJoinRequest
+
+	for _, req := range requests {
+		var requestDto dto.ChatJoinRequest
+		if err := requestDto.Load(ctx, req); err != nil {
+			result.WriteSilent(w, result.New(nil, common.FailedToLoadErr))
+			return
+		}
+		requestDtos = append(requestDtos, requestDto)
+	}
+
+	result.WriteSilent(w, result.Ok(requestDtos))
+}
+
+func (c *Controller) ApproveJoinRequest(w http.ResponseWriter, r *http.Request) {
+	ctx, ok := util.AppContext(r.Context())
+	if !ok {
+		result.WriteSilent(w, result.New(nil, common.InternalServerErr))
+		return
+	}
+
+	var form forms.ApproveJoinRequest
+	if err := json.NewDecoder(r.Body).Decode(&form); err != nil {
+		result.WriteSilent(w, result.New(nil, common.IncorrectInputErr))
+		return
+	}
+
+	chat, err := c.app.Chats().Get(ctx, form.ChatID)
+
+	if err != nil {
+		result.WriteSilent(w, result.Err(common.CustomErrorCode, err.Error()))
+		return
+	}
+
+	request, err := chat.JoinRequest(ctx, form.ID)
+
+	if err != nil {
+		result.WriteSilent(w, result.Err(common.CustomErrorCode, err.Error()))
+		return
+	}
+
+	if err := request.Approve(ctx); err != nil {
+		result.WriteSilent(w, result.Err(common.CustomErrorCode, err.Error()))
+		return
+	}
+
+	result.WriteSilent(w, result.Ok(nil))
+}
+
+func (c *Controller) RejectJoinRequest(w http.ResponseWriter, r *http.Request) {
+	ctx, ok := util.AppContext(r.Context())
+	if !ok {
+		result.WriteSilent(w, result.New(nil, common.InternalServerErr))
+		return
+	}
+
+	var form forms.RejectJoinRequest
+	if err := json.NewDecoder(r.Body).Decode(&form); err != nil {
+		result.WriteSilent(w, result.New(nil, common.IncorrectInputErr))
+		return
+	}
+
+	chat, err := c.app.Chats().Get(ctx, form.ChatID)
+
+	if err != nil {
+		result.WriteSilent(w, result.Err(common.CustomErrorCode, err.Error()))
+		return
+	}
+
+	request, err := chat.JoinRequest(ctx, form.ID)
+
+	if err != nil {
+		result.WriteSilent(w, result.Err(common.CustomErrorCode, err.Error()))
+		return
+	}
+
+	if err := request.Reject(ctx); err != nil {
+		result.WriteSilent(w, result.Err(common.CustomErrorCode, err.Error()))
+		return
+	}
+
+	result.WriteSilent(w, result.Ok(nil))
+}
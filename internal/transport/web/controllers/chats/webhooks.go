@@ -0,0 +1,100 @@
+package chats
+
+import (
+	"encoding/json"
+	appForms "github.com/ischenkx/vk-test-task/internal/app/forms"
+	"github.com/ischenkx/vk-test-task/internal/transport/web/controllers/chats/forms"
+	"github.com/ischenkx/vk-test-task/internal/transport/web/controllers/common"
+	"github.com/ischenkx/vk-test-task/internal/transport/web/controllers/common/result"
+	"github.com/ischenkx/vk-test-task/internal/transport/web/controllers/dto"
+	"github.com/ischenkx/vk-test-task/internal/transport/web/util"
+	"net/http"
+)
+
+func (c *Controller) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	ctx, ok := util.AppContext(r.Context())
+	if !ok {
+		result.WriteSilent(w, result.New(nil, common.InternalServerErr))
+		return
+	}
+
+	var form forms.CreateWebhook
+	if err := json.NewDecoder(r.Body).Decode(&form); err != nil {
+		result.WriteSilent(w, result.New(nil, common.IncorrectInputErr))
+		return
+	}
+
+	chat, err := c.app.Chats().Get(ctx, form.ChatID)
+
+	if err != nil {
+		result.WriteSilent(w, result.Err(common.CustomErrorCode, err.Error()))
+		return
+	}
+
+	webhook, err := chat.CreateWebhook(ctx, appForms.WebhookCreation{
+		URL:    form.URL,
+		Events: form.Events,
+	})
+
+	if err != nil {
+		result.WriteSilent(w, result.Err(common.CustomErrorCode, err.Error()))
+		return
+	}
+
+	model, err := webhook.Model(ctx)
+	if err != nil {
+		result.WriteSilent(w, result.New(nil, common.FailedToLoadErr))
+		return
+	}
+
+	var webhookDto dto.Webhook
+
+	if err := webhookDto.Load(ctx, webhook); err != nil {
+		result.WriteSilent(w, result.New(nil, common.FailedToLoadErr))
+		return
+	}
+	webhookDto.Secret = model.Secret
+
+	result.WriteSilent(w, result.Ok(webhookDto))
+}
+
+func (c *Controller) GetWebhooks(w http.ResponseWriter, r *http.Request) {
+	ctx, ok := util.AppContext(r.Context())
+	if !ok {
+		result.WriteSilent(w, result.New(nil, common.InternalServerErr))
+		return
+	}
+
+	var form forms.GetWebhooks
+	if err := json.NewDecoder(r.Body).Decode(&form); err != nil {
+		result.WriteSilent(w, result.New(nil, common.IncorrectInputErr))
+		return
+	}
+
+	chat, err := c.app.Chats().Get(ctx, form.ChatID)
+
+	if err != nil {
+		result.WriteSilent(w, result.Err(common.CustomErrorCode, err.Error()))
+		return
+	}
+
+	webhooks, err := chat.Webhooks(ctx, form.Offset, form.Count)
+
+	if err != nil {
+		result.WriteSilent(w, result.Err(common.CustomErrorCode, err.Error()))
+		return
+	}
+
+	var webhooksDto []dto.Webhook
+
+	for _, webhook := range webhooks {
+		var webhookDto dto.Webhook
+		if err := webhookDto.Load(ctx, webhook); err != nil {
+			result.WriteSilent(w, result.New(nil, common.FailedToLoadErr))
+			return
+		}
+		webhooksDto = append(webhooksDto, webhookDto)
+	}
+
+	result.WriteSilent(w, result.Ok(webhooksDto))
+}
@@ -2,8 +2,41 @@ package auth
 
 import (
 	"net/http"
+	"strings"
+	"time"
 )
 
+const botAuthScheme = "Bot "
+const bearerAuthScheme = "Bearer "
+const apiKeyAuthScheme = "ApiKey "
+
+// LoadBotToken returns the token from the "Authorization: Bot <token>" header
+func LoadBotToken(r *http.Request) (string, bool) {
+	header := r.Header.Get("Authorization")
+	if !strings.HasPrefix(header, botAuthScheme) {
+		return "", false
+	}
+	return strings.TrimPrefix(header, botAuthScheme), true
+}
+
+// LoadBearerToken returns the access token from the "Authorization: Bearer <token>" header
+func LoadBearerToken(r *http.Request) (string, bool) {
+	header := r.Header.Get("Authorization")
+	if !strings.HasPrefix(header, bearerAuthScheme) {
+		return "", false
+	}
+	return strings.TrimPrefix(header, bearerAuthScheme), true
+}
+
+// LoadAPIKey returns the key from the "Authorization: ApiKey <key>" header
+func LoadAPIKey(r *http.Request) (string, bool) {
+	header := r.Header.Get("Authorization")
+	if !strings.HasPrefix(header, apiKeyAuthScheme) {
+		return "", false
+	}
+	return strings.TrimPrefix(header, apiKeyAuthScheme), true
+}
+
 func LoadVerificationToken(w http.ResponseWriter, r *http.Request) (string, error) {
 	cookie, err := r.Cookie("auth_token")
 	if err != nil {
@@ -19,6 +52,9 @@ func DeleteVerificationToken(w http.ResponseWriter, r *http.Request) {
 		Value:    "",
 		Secure:   true,
 		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+		Path:     "/",
+		MaxAge:   -1,
 	})
 }
 
@@ -28,6 +64,43 @@ func StoreVerificationToken(w http.ResponseWriter, r *http.Request, token string
 		Value:    token,
 		Secure:   true,
 		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
 		Path:     "/",
 	})
 }
+
+// the refresh token is only sent to the endpoints of users (refresh and logout)
+const refreshTokenCookiePath = "/users/"
+
+func LoadRefreshToken(r *http.Request) (string, error) {
+	cookie, err := r.Cookie("refresh_token")
+	if err != nil {
+		return "", err
+	}
+
+	return cookie.Value, nil
+}
+
+func DeleteRefreshToken(w http.ResponseWriter, r *http.Request) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     "refresh_token",
+		Value:    "",
+		Secure:   true,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+		Path:     refreshTokenCookiePath,
+		MaxAge:   -1,
+	})
+}
+
+func StoreRefreshToken(w http.ResponseWriter, r *http.Request, token string, expiresAt time.Time) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     "refresh_token",
+		Value:    token,
+		Secure:   true,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+		Path:     refreshTokenCookiePath,
+		Expires:  expiresAt,
+	})
+}
@@ -8,3 +8,5 @@ var InternalServerErr = result.NewError(1, "server failure")
 var IncorrectInputErr = result.NewError(2, "incorrect data")
 var UnauthorizedErr = result.NewError(3, "unauthorized")
 var FailedToLoadErr = result.NewError(4, "failed to load data")
+var CrossSiteRequestErr = result.NewError(5, "cross-site request")
+var InsufficientScopeErr = result.NewError(6, "insufficient scope")
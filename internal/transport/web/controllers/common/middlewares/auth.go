@@ -19,15 +19,36 @@ func Auth(application *app.App, next http.Handler) http.Handler {
 			return
 		}
 
-		if token, err := auth.LoadVerificationToken(w, req); err == nil {
-			userID, err := application.Auth().Verify(req.Context(), token)
-			if err == nil {
-				if user, err := application.Users().Get(appCtx, userID); err == nil {
-					appCtx.SetUser(user)
-				}
+		// the Authorization header takes precedence over the cookie,
+		// the cookie isn't looked at even if the header is invalid
+		if token, ok := auth.LoadBotToken(req); ok {
+			if user, err := application.Users().AuthenticateBot(appCtx, token); err == nil {
+				appCtx.SetUser(user)
 			}
+		} else if key, ok := auth.LoadAPIKey(req); ok {
+			if user, scopes, err := application.Users().AuthenticateAPIKey(appCtx, key); err == nil {
+				appCtx.SetUser(user)
+				appCtx.SetScopes(scopes)
+			}
+		} else if token, ok := auth.LoadBearerToken(req); ok {
+			authorize(application, appCtx, token)
+		} else if token, err := auth.LoadVerificationToken(w, req); err == nil {
+			authorize(application, appCtx, token)
 		}
 
 		next.ServeHTTP(w, req)
 	})
 }
+
+// authorize sets the user and the session of the access token if it's valid
+func authorize(application *app.App, appCtx *app.Context, token string) {
+	claims, err := application.Auth().Verify(appCtx, token)
+	if err != nil {
+		return
+	}
+
+	if user, err := application.Users().Get(appCtx, claims.UserID); err == nil {
+		appCtx.SetUser(user)
+		appCtx.SetSession(claims.SessionID)
+	}
+}
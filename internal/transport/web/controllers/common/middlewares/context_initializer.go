@@ -2,12 +2,28 @@ package middlewares
 
 import (
 	"github.com/ischenkx/vk-test-task/internal/app"
+	"net"
 	"net/http"
 )
 
 func ContextInitializer(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
-		req = req.WithContext(app.NewContext(req.Context()))
+		appCtx := app.NewContext(req.Context())
+		appCtx.SetClient(clientInfo(req))
+		req = req.WithContext(appCtx)
 		next.ServeHTTP(w, req)
 	})
 }
+
+// clientInfo describes the peer the request comes from, proxies' headers are not trusted
+func clientInfo(req *http.Request) app.ClientInfo {
+	ip, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		ip = req.RemoteAddr
+	}
+
+	return app.ClientInfo{
+		IP:        ip,
+		UserAgent: req.UserAgent(),
+	}
+}
@@ -0,0 +1,68 @@
+package middlewares
+
+import (
+	"github.com/ischenkx/vk-test-task/internal/transport/web/controllers/common"
+	"github.com/ischenkx/vk-test-task/internal/transport/web/controllers/common/auth"
+	"github.com/ischenkx/vk-test-task/internal/transport/web/controllers/common/result"
+	"net/http"
+	"net/url"
+)
+
+// CSRF rejects the cross-site requests authorized with cookies.
+//
+// Browsers attach cookies on their own, so a page of another site could act on behalf
+// of the user (the cookies are SameSite=Lax, this covers older browsers and same-site
+// subdomains). The API doesn't tell reads from writes by the method, so all the requests are checked.
+// The requests authorized with the Authorization header and the ones of non-browser clients,
+// which send neither Origin nor Referer, are let through.
+func CSRF(allowedOrigins []string, next http.Handler) http.Handler {
+	allowed := map[string]bool{}
+	for _, origin := range allowedOrigins {
+		allowed[origin] = true
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if usesCookies(req) && isCrossSite(req, allowed) {
+			result.WriteSilent(w, result.New(nil, common.CrossSiteRequestErr))
+			return
+		}
+
+		next.ServeHTTP(w, req)
+	})
+}
+
+func usesCookies(req *http.Request) bool {
+	if _, ok := auth.LoadBotToken(req); ok {
+		return false
+	}
+	if _, ok := auth.LoadBearerToken(req); ok {
+		return false
+	}
+	if _, ok := auth.LoadAPIKey(req); ok {
+		return false
+	}
+
+	_, accessErr := auth.LoadVerificationToken(nil, req)
+	_, refreshErr := auth.LoadRefreshToken(req)
+	return accessErr == nil || refreshErr == nil
+}
+
+func isCrossSite(req *http.Request, allowed map[string]bool) bool {
+	origin := req.Header.Get("Origin")
+	if origin == "" || origin == "null" {
+		if referer, err := url.Parse(req.Referer()); err == nil && referer.Host != "" {
+			origin = referer.Scheme + "://" + referer.Host
+		}
+	}
+
+	if origin == "" {
+		return req.Header.Get("Sec-Fetch-Site") == "cross-site"
+	}
+
+	if allowed[origin] {
+		return false
+	}
+
+	u, err := url.Parse(origin)
+	return err != nil || u.Host != req.Host
+}
@@ -0,0 +1,28 @@
+package middlewares
+
+import (
+	"github.com/ischenkx/vk-test-task/internal/transport/web/controllers/common"
+	"github.com/ischenkx/vk-test-task/internal/transport/web/controllers/common/result"
+	"github.com/ischenkx/vk-test-task/internal/transport/web/util"
+	"net/http"
+)
+
+// RequireScope rejects the callers restricted to scopes other than the given one (see app.Context.Allows),
+// the callers authorized with their own tokens are let through
+func RequireScope(scope string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		appCtx, ok := util.AppContext(req.Context())
+
+		if !ok {
+			result.WriteSilent(w, result.New(nil, common.InternalServerErr))
+			return
+		}
+
+		if !appCtx.Allows(scope) {
+			result.WriteSilent(w, result.New(nil, common.InsufficientScopeErr))
+			return
+		}
+
+		next(w, req)
+	}
+}
@@ -0,0 +1,39 @@
+package dto
+
+import (
+	"github.com/ischenkx/vk-test-task/internal/app"
+	"time"
+)
+
+// APIKey is loaded without the key, it's only shown on creation.
+// ExpiresAt and LastUsedAt are omitted if the key never expires or has never been used.
+type APIKey struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Key        string     `json:"key,omitempty"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+func (dto *APIKey) Load(ctx *app.Context, key app.APIKey) error {
+	model, err := key.Model(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	dto.ID = model.ID
+	dto.Name = model.Name
+	dto.Scopes = model.Scopes
+	dto.CreatedAt = model.CreatedAt
+	if !model.ExpiresAt.IsZero() {
+		dto.ExpiresAt = &model.ExpiresAt
+	}
+	if !model.LastUsedAt.IsZero() {
+		dto.LastUsedAt = &model.LastUsedAt
+	}
+
+	return nil
+}
@@ -0,0 +1,65 @@
+package dto
+
+import (
+	"encoding/json"
+	"github.com/ischenkx/vk-test-task/internal/app"
+	"github.com/ischenkx/vk-test-task/internal/app/data/models"
+	"time"
+)
+
+// Bot is loaded without the token, it's only shown on creation and regeneration
+type Bot struct {
+	ID        string    `json:"id"`
+	Username  string    `json:"username"`
+	OwnerID   string    `json:"owner_id"`
+	Token     string    `json:"token,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (dto *Bot) Load(ctx *app.Context, bot app.Bot) error {
+	model, err := bot.Model(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	u, err := bot.User(ctx)
+	if err != nil {
+		return err
+	}
+
+	username, err := u.Username(ctx)
+	if err != nil {
+		return err
+	}
+
+	dto.ID = model.UserID
+	dto.Username = username
+	dto.OwnerID = model.OwnerID
+	dto.CreatedAt = model.CreatedAt
+
+	return nil
+}
+
+type BotCommand struct {
+	Command     string `json:"command"`
+	Description string `json:"description"`
+}
+
+func (dto *BotCommand) Load(model models.BotCommand) {
+	dto.Command = model.Command
+	dto.Description = model.Description
+}
+
+// BotUpdate carries an event in the same envelope the webhooks receive
+type BotUpdate struct {
+	ID    int64           `json:"id"`
+	Name  string          `json:"name"`
+	Event json.RawMessage `json:"event"`
+}
+
+func (dto *BotUpdate) Load(model models.BotUpdate) {
+	dto.ID = model.ID
+	dto.Name = model.Name
+	dto.Event = model.Payload
+}
@@ -2,13 +2,23 @@ package dto
 
 import (
 	"github.com/ischenkx/vk-test-task/internal/app"
+	"github.com/ischenkx/vk-test-task/internal/app/data/models"
 )
 
 type Chat struct {
-	Name        string `json:"name"`
-	Description string `json:"description"`
-	OwnerID     string `json:"owner_id"`
-	ID          string `json:"id"`
+	Name             string `json:"name"`
+	Description      string `json:"description"`
+	OwnerID          string `json:"owner_id"`
+	Visibility       int    `json:"visibility"`
+	Kind             int    `json:"kind"`
+	CountViews       bool   `json:"count_views"`
+	SubscribersCount int    `json:"subscribers_count,omitempty"`
+	ID               string `json:"id"`
+}
+
+type PublicChat struct {
+	Chat
+	MembersCount int `json:"members_count"`
 }
 
 func (dto *Chat) Load(ctx *app.Context, chat app.Chat) error {
@@ -22,6 +32,33 @@ func (dto *Chat) Load(ctx *app.Context, chat app.Chat) error {
 	dto.ID = model.ID
 	dto.Description = model.Description
 	dto.OwnerID = model.OwnerID
+	dto.Visibility = model.Visibility
+	dto.Kind = model.Kind
+	dto.CountViews = model.CountViews
+
+	if model.Kind == models.ChatKindChannel {
+		count, err := chat.CountMembers(ctx)
+		if err != nil {
+			return err
+		}
+		dto.SubscribersCount = count
+	}
+
+	return nil
+}
+
+func (dto *PublicChat) Load(ctx *app.Context, chat app.Chat) error {
+	if err := dto.Chat.Load(ctx, chat); err != nil {
+		return err
+	}
+
+	count, err := chat.CountMembers(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	dto.MembersCount = count
 
 	return nil
 }
@@ -0,0 +1,36 @@
+package dto
+
+import (
+	"github.com/ischenkx/vk-test-task/internal/app"
+	"time"
+)
+
+type ChatInvite struct {
+	Code             string    `json:"code"`
+	ChatID           string    `json:"chat_id"`
+	CreatorID        string    `json:"creator_id"`
+	MaxUses          int       `json:"max_uses"`
+	Uses             int       `json:"uses"`
+	ExpiresAt        time.Time `json:"expires_at"`
+	RequiresApproval bool      `json:"requires_approval"`
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+func (dto *ChatInvite) Load(ctx *app.Context, invite app.ChatInvite) error {
+	model, err := invite.Model(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	dto.Code = model.Code
+	dto.ChatID = model.ChatID
+	dto.CreatorID = model.CreatorID
+	dto.MaxUses = model.MaxUses
+	dto.Uses = model.Uses
+	dto.ExpiresAt = model.ExpiresAt
+	dto.RequiresApproval = model.RequiresApproval
+	dto.CreatedAt = model.CreatedAt
+
+	return nil
+}
@@ -0,0 +1,36 @@
+package dto
+
+import (
+	"github.com/ischenkx/vk-test-task/internal/app"
+	"time"
+)
+
+type ChatJoinRequest struct {
+	ID        string    `json:"id"`
+	ChatID    string    `json:"chat_id"`
+	UserID    string    `json:"user_id"`
+	TimeStamp time.Time `json:"time_stamp"`
+}
+
+func (dto *ChatJoinRequest) Load(ctx *app.Context, req app.ChatJoinRequest) error {
+	model, err := req.Model(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	dto.ID = model.ID
+	dto.ChatID = model.ChatID
+	dto.UserID = model.UserID
+	dto.TimeStamp = model.Time
+
+	return nil
+}
+
+// JoinResult describes the outcome of joining a chat: either the user
+// became a member right away or a join request is waiting for approval
+type JoinResult struct {
+	ChatID    string `json:"chat_id"`
+	Pending   bool   `json:"pending"`
+	RequestID string `json:"request_id"`
+}
@@ -0,0 +1,25 @@
+package dto
+
+import (
+	"github.com/ischenkx/vk-test-task/internal/app/data/models"
+	"time"
+)
+
+type Identity struct {
+	Provider  string    `json:"provider"`
+	Subject   string    `json:"subject"`
+	Email     string    `json:"email"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (dto *Identity) Load(model models.ExternalIdentity) {
+	dto.Provider = model.Provider
+	dto.Subject = model.Subject
+	dto.Email = model.Email
+	dto.CreatedAt = model.CreatedAt
+}
+
+// AuthURL is the page of an identity provider the user must be sent to
+type AuthURL struct {
+	URL string `json:"url"`
+}
@@ -0,0 +1,34 @@
+package dto
+
+import (
+	"github.com/ischenkx/vk-test-task/internal/app"
+	"time"
+)
+
+// IncomingWebhook is loaded without the token, it's only shown once on creation
+type IncomingWebhook struct {
+	ID        string    `json:"id"`
+	ChatID    string    `json:"chat_id"`
+	UserID    string    `json:"user_id"`
+	CreatorID string    `json:"creator_id"`
+	Name      string    `json:"name"`
+	Token     string    `json:"token,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (dto *IncomingWebhook) Load(ctx *app.Context, webhook app.IncomingWebhook) error {
+	model, err := webhook.Model(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	dto.ID = model.ID
+	dto.ChatID = model.ChatID
+	dto.UserID = model.UserID
+	dto.CreatorID = model.CreatorID
+	dto.Name = model.Name
+	dto.CreatedAt = model.CreatedAt
+
+	return nil
+}
@@ -2,16 +2,19 @@ package dto
 
 import (
 	"github.com/ischenkx/vk-test-task/internal/app"
+	"github.com/ischenkx/vk-test-task/internal/app/data/models"
 	"time"
 )
 
 type Message struct {
-	Payload    string    `json:"payload"`
-	ChatID     string    `json:"chat_id"`
-	UserID     string    `json:"user_id"`
-	LastUpdate time.Time `json:"last_update"`
-	TimeStamp  time.Time `json:"time_stamp"`
-	ID         string    `json:"id"`
+	Payload     string              `json:"payload"`
+	Attachments []MessageAttachment `json:"attachments,omitempty"`
+	ChatID      string              `json:"chat_id"`
+	UserID      string              `json:"user_id"`
+	LastUpdate  time.Time           `json:"last_update"`
+	TimeStamp   time.Time           `json:"time_stamp"`
+	ID          string              `json:"id"`
+	Views       int                 `json:"views"`
 }
 
 func (dto *Message) Load(ctx *app.Context, req app.Message) error {
@@ -26,5 +29,60 @@ func (dto *Message) Load(ctx *app.Context, req app.Message) error {
 	dto.Payload = string(model.Payload)
 	dto.TimeStamp = model.TimeStamp
 	dto.LastUpdate = model.LastUpdate
+	dto.Views = model.Views
+	dto.Attachments = nil
+	for _, attachment := range model.Attachments {
+		var attachmentDto MessageAttachment
+		attachmentDto.Load(attachment)
+		dto.Attachments = append(dto.Attachments, attachmentDto)
+	}
 	return nil
 }
+
+type MessageAttachmentField struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+	Short bool   `json:"short,omitempty"`
+}
+
+type MessageAttachment struct {
+	Title  string                   `json:"title,omitempty"`
+	Text   string                   `json:"text,omitempty"`
+	Link   string                   `json:"link,omitempty"`
+	Color  string                   `json:"color,omitempty"`
+	Fields []MessageAttachmentField `json:"fields,omitempty"`
+}
+
+func (dto *MessageAttachment) Load(model models.MessageAttachment) {
+	dto.Title = model.Title
+	dto.Text = model.Text
+	dto.Link = model.Link
+	dto.Color = model.Color
+	dto.Fields = nil
+	for _, field := range model.Fields {
+		dto.Fields = append(dto.Fields, MessageAttachmentField(field))
+	}
+}
+
+// Model converts the attachment back, it's used to pass the attachments of the forms to the app
+func (dto MessageAttachment) Model() models.MessageAttachment {
+	model := models.MessageAttachment{
+		Title: dto.Title,
+		Text:  dto.Text,
+		Link:  dto.Link,
+		Color: dto.Color,
+	}
+	for _, field := range dto.Fields {
+		model.Fields = append(model.Fields, models.MessageAttachmentField(field))
+	}
+	return model
+}
+
+// MessageAttachmentModels converts the attachments of a form
+func MessageAttachmentModels(attachments []MessageAttachment) []models.MessageAttachment {
+	var res []models.MessageAttachment
+	for _, attachment := range attachments {
+		res = append(res, attachment.Model())
+	}
+	return res
+}
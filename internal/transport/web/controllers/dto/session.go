@@ -0,0 +1,34 @@
+package dto
+
+import (
+	"github.com/ischenkx/vk-test-task/internal/app"
+	"time"
+)
+
+type Session struct {
+	ID         string    `json:"id"`
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `json:"ip"`
+	Current    bool      `json:"current"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+func (dto *Session) Load(ctx *app.Context, session app.Session) error {
+	model, err := session.Model(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	dto.ID = model.ID
+	dto.UserAgent = model.UserAgent
+	dto.IP = model.IP
+	dto.Current = session.Current(ctx)
+	dto.CreatedAt = model.CreatedAt
+	dto.LastSeenAt = model.LastSeenAt
+	dto.ExpiresAt = model.ExpiresAt
+
+	return nil
+}
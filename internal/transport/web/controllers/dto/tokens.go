@@ -0,0 +1,37 @@
+package dto
+
+import (
+	"github.com/ischenkx/vk-test-task/internal/app"
+	"time"
+)
+
+// Tokens are returned along with the cookies for the clients that send
+// the access token in the "Authorization: Bearer" header
+type Tokens struct {
+	TokenType        string    `json:"token_type"`
+	AccessToken      string    `json:"access_token"`
+	RefreshToken     string    `json:"refresh_token"`
+	RefreshExpiresAt time.Time `json:"refresh_expires_at"`
+}
+
+func (dto *Tokens) Load(tokens app.TokenPair) {
+	dto.TokenType = "Bearer"
+	dto.AccessToken = tokens.AccessToken
+	dto.RefreshToken = tokens.RefreshToken
+	dto.RefreshExpiresAt = tokens.RefreshExpiresAt
+}
+
+// AuthorizedUser is the result of login and registration,
+// the fields are flattened so the response is still a User
+type AuthorizedUser struct {
+	User
+	Tokens
+}
+
+// LoginResult is the user along with the tokens, or a challenge to pass with
+// the second factor (see /users/completeLogin) if TwoFactorRequired is set
+type LoginResult struct {
+	*AuthorizedUser
+	TwoFactorRequired bool   `json:"two_factor_required,omitempty"`
+	Challenge         string `json:"challenge,omitempty"`
+}
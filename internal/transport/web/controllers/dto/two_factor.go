@@ -0,0 +1,29 @@
+package dto
+
+import "github.com/ischenkx/vk-test-task/internal/app"
+
+type TwoFactorStatus struct {
+	Enabled           bool `json:"enabled"`
+	RecoveryCodesLeft int  `json:"recovery_codes_left"`
+}
+
+func (dto *TwoFactorStatus) Load(status app.TwoFactorStatus) {
+	dto.Enabled = status.Enabled
+	dto.RecoveryCodesLeft = status.RecoveryCodesLeft
+}
+
+// TwoFactorEnrollment is shown once, URI is meant to be turned into a QR code
+type TwoFactorEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+func (dto *TwoFactorEnrollment) Load(enrollment app.TwoFactorEnrollment) {
+	dto.Secret = enrollment.Secret
+	dto.URI = enrollment.URI
+}
+
+// RecoveryCodes are shown once, each of them can be used instead of a TOTP code once
+type RecoveryCodes struct {
+	Codes []string `json:"codes"`
+}
@@ -0,0 +1,63 @@
+package dto
+
+import (
+	"github.com/ischenkx/vk-test-task/internal/app"
+	"github.com/ischenkx/vk-test-task/internal/app/data/models"
+	"time"
+)
+
+// Webhook is loaded without the secret, it's only shown once on creation
+type Webhook struct {
+	ID        string    `json:"id"`
+	OwnerID   string    `json:"owner_id"`
+	ChatID    string    `json:"chat_id"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	Secret    string    `json:"secret,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (dto *Webhook) Load(ctx *app.Context, webhook app.Webhook) error {
+	model, err := webhook.Model(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	dto.ID = model.ID
+	dto.OwnerID = model.OwnerID
+	dto.ChatID = model.ChatID
+	dto.URL = model.URL
+	dto.Events = model.Events
+	dto.CreatedAt = model.CreatedAt
+
+	return nil
+}
+
+type WebhookDelivery struct {
+	ID            string    `json:"id"`
+	WebhookID     string    `json:"webhook_id"`
+	EventID       string    `json:"event_id"`
+	EventName     string    `json:"event_name"`
+	Status        int       `json:"status"`
+	Attempts      int       `json:"attempts"`
+	ResponseCode  int       `json:"response_code"`
+	Error         string    `json:"error"`
+	NextAttemptAt time.Time `json:"next_attempt_at"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+func (dto *WebhookDelivery) Load(model models.WebhookDelivery) {
+	dto.ID = model.ID
+	dto.WebhookID = model.WebhookID
+	dto.EventID = model.EventID
+	dto.EventName = model.EventName
+	dto.Status = model.Status
+	dto.Attempts = model.Attempts
+	dto.ResponseCode = model.ResponseCode
+	dto.Error = model.Error
+	dto.NextAttemptAt = model.NextAttemptAt
+	dto.CreatedAt = model.CreatedAt
+	dto.UpdatedAt = model.UpdatedAt
+}
@@ -0,0 +1,82 @@
+package hooks
+
+import (
+	"encoding/json"
+	"github.com/ischenkx/vk-test-task/internal/app"
+	appForms "github.com/ischenkx/vk-test-task/internal/app/forms"
+	"github.com/ischenkx/vk-test-task/internal/transport/web/controllers/common"
+	"github.com/ischenkx/vk-test-task/internal/transport/web/controllers/common/result"
+	"github.com/ischenkx/vk-test-task/internal/transport/web/controllers/dto"
+	"github.com/ischenkx/vk-test-task/internal/transport/web/controllers/hooks/forms"
+	"github.com/ischenkx/vk-test-task/internal/transport/web/util"
+	"net/http"
+	"strings"
+)
+
+// maxBodySize bounds the requests of the integrations, nobody is authorized here
+const maxBodySize = 64 << 10
+
+// Controller serves the endpoints that are called by external services
+// with the credentials in the url instead of the user's token
+type Controller struct {
+	app *app.App
+	mux *http.ServeMux
+}
+
+func (c *Controller) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	c.mux.ServeHTTP(writer, request)
+}
+
+// PostIncoming handles POST /incoming/{id}/{token}
+func (c *Controller) PostIncoming(w http.ResponseWriter, r *http.Request) {
+	ctx, ok := util.AppContext(r.Context())
+	if !ok {
+		result.WriteSilent(w, result.New(nil, common.InternalServerErr))
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		result.WriteSilent(w, result.New(nil, common.IncorrectInputErr))
+		return
+	}
+
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/incoming/"), "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		result.WriteSilent(w, result.New(nil, common.IncorrectInputErr))
+		return
+	}
+
+	var form forms.Post
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodySize)).Decode(&form); err != nil {
+		result.WriteSilent(w, result.New(nil, common.IncorrectInputErr))
+		return
+	}
+
+	mes, err := c.app.Chats().PostIncomingWebhook(ctx, parts[0], parts[1], appForms.SendMessage{
+		Payload:     form.Payload,
+		Attachments: dto.MessageAttachmentModels(form.Attachments),
+	})
+
+	if err != nil {
+		result.WriteSilent(w, result.Err(common.CustomErrorCode, err.Error()))
+		return
+	}
+
+	result.WriteSilent(w, result.Ok(struct {
+		ID string `json:"id"`
+	}{ID: mes.ID()}))
+}
+
+func (c *Controller) init() {
+	c.mux.HandleFunc("/incoming/", c.PostIncoming)
+}
+
+func NewController(app *app.App) *Controller {
+	controller := &Controller{
+		app: app,
+		mux: http.NewServeMux(),
+	}
+
+	controller.init()
+	return controller
+}
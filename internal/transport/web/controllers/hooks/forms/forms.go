@@ -0,0 +1,8 @@
+package forms
+
+import "github.com/ischenkx/vk-test-task/internal/transport/web/controllers/dto"
+
+type Post struct {
+	Payload     string                  `json:"payload"`
+	Attachments []dto.MessageAttachment `json:"attachments"`
+}
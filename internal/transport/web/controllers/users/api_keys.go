@@ -0,0 +1,130 @@
+package users
+
+import (
+	"encoding/json"
+	appForms "github.com/ischenkx/vk-test-task/internal/app/forms"
+	"github.com/ischenkx/vk-test-task/internal/transport/web/controllers/common"
+	"github.com/ischenkx/vk-test-task/internal/transport/web/controllers/common/result"
+	"github.com/ischenkx/vk-test-task/internal/transport/web/controllers/dto"
+	"github.com/ischenkx/vk-test-task/internal/transport/web/controllers/users/forms"
+	"github.com/ischenkx/vk-test-task/internal/transport/web/util"
+	"net/http"
+	"time"
+)
+
+func (c *Controller) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	ctx, ok := util.AppContext(r.Context())
+	if !ok {
+		result.WriteSilent(w, result.New(nil, common.InternalServerErr))
+		return
+	}
+
+	var form forms.CreateAPIKey
+	if err := json.NewDecoder(r.Body).Decode(&form); err != nil || form.ExpiresIn < 0 {
+		result.WriteSilent(w, result.New(nil, common.IncorrectInputErr))
+		return
+	}
+
+	if ctx.User() == nil {
+		result.WriteSilent(w, result.New(nil, common.UnauthorizedErr))
+		return
+	}
+
+	var expiresAt time.Time
+	if form.ExpiresIn > 0 {
+		expiresAt = time.Now().Add(time.Duration(form.ExpiresIn) * time.Second)
+	}
+
+	key, token, err := ctx.User().CreateAPIKey(ctx, appForms.APIKeyCreation{
+		Name:      form.Name,
+		Scopes:    form.Scopes,
+		ExpiresAt: expiresAt,
+	})
+
+	if err != nil {
+		result.WriteSilent(w, result.Err(common.CustomErrorCode, err.Error()))
+		return
+	}
+
+	var keyDto dto.APIKey
+
+	if err := keyDto.Load(ctx, key); err != nil {
+		result.WriteSilent(w, result.New(nil, common.FailedToLoadErr))
+		return
+	}
+	keyDto.Key = token
+
+	result.WriteSilent(w, result.Ok(keyDto))
+}
+
+func (c *Controller) GetAPIKeys(w http.ResponseWriter, r *http.Request) {
+	ctx, ok := util.AppContext(r.Context())
+	if !ok {
+		result.WriteSilent(w, result.New(nil, common.InternalServerErr))
+		return
+	}
+
+	var form forms.GetAPIKeys
+	if err := json.NewDecoder(r.Body).Decode(&form); err != nil {
+		result.WriteSilent(w, result.New(nil, common.IncorrectInputErr))
+		return
+	}
+
+	if ctx.User() == nil {
+		result.WriteSilent(w, result.New(nil, common.UnauthorizedErr))
+		return
+	}
+
+	keys, err := ctx.User().APIKeys(ctx, form.Offset, form.Count)
+
+	if err != nil {
+		result.WriteSilent(w, result.Err(common.CustomErrorCode, err.Error()))
+		return
+	}
+
+	var keysDto []dto.APIKey
+
+	for _, key := range keys {
+		var keyDto dto.APIKey
+		if err := keyDto.Load(ctx, key); err != nil {
+			result.WriteSilent(w, result.New(nil, common.FailedToLoadErr))
+			return
+		}
+		keysDto = append(keysDto, keyDto)
+	}
+
+	result.WriteSilent(w, result.Ok(keysDto))
+}
+
+func (c *Controller) DeleteAPIKey(w http.ResponseWriter, r *http.Request) {
+	ctx, ok := util.AppContext(r.Context())
+	if !ok {
+		result.WriteSilent(w, result.New(nil, common.InternalServerErr))
+		return
+	}
+
+	var form forms.DeleteAPIKey
+	if err := json.NewDecoder(r.Body).Decode(&form); err != nil {
+		result.WriteSilent(w, result.New(nil, common.IncorrectInputErr))
+		return
+	}
+
+	if ctx.User() == nil {
+		result.WriteSilent(w, result.New(nil, common.UnauthorizedErr))
+		return
+	}
+
+	key, err := ctx.User().APIKey(ctx, form.ID)
+
+	if err != nil {
+		result.WriteSilent(w, result.Err(common.CustomErrorCode, err.Error()))
+		return
+	}
+
+	if err := key.Delete(ctx); err != nil {
+		result.WriteSilent(w, result.Err(common.CustomErrorCode, err.Error()))
+		return
+	}
+
+	result.WriteSilent(w, result.Ok(nil))
+}
@@ -0,0 +1,282 @@
+package users
+
+import (
+	"encoding/json"
+	appForms "github.com/ischenkx/vk-test-task/internal/app/forms"
+	"github.com/ischenkx/vk-test-task/internal/transport/web/controllers/common"
+	"github.com/ischenkx/vk-test-task/internal/transport/web/controllers/common/result"
+	"github.com/ischenkx/vk-test-task/internal/transport/web/controllers/dto"
+	"github.com/ischenkx/vk-test-task/internal/transport/web/controllers/users/forms"
+	"github.com/ischenkx/vk-test-task/internal/transport/web/util"
+	"net/http"
+	"time"
+)
+
+func (c *Controller) CreateBot(w http.ResponseWriter, r *http.Request) {
+	ctx, ok := util.AppContext(r.Context())
+	if !ok {
+		result.WriteSilent(w, result.New(nil, common.InternalServerErr))
+		return
+	}
+
+	var form forms.CreateBot
+	if err := json.NewDecoder(r.Body).Decode(&form); err != nil {
+		result.WriteSilent(w, result.New(nil, common.IncorrectInputErr))
+		return
+	}
+
+	if ctx.User() == nil {
+		result.WriteSilent(w, result.New(nil, common.UnauthorizedErr))
+		return
+	}
+
+	bot, token, err := ctx.User().CreateBot(ctx, appForms.BotCreation{
+		Username: form.Username,
+	})
+
+	if err != nil {
+		result.WriteSilent(w, result.Err(common.CustomErrorCode, err.Error()))
+		return
+	}
+
+	var botDto dto.Bot
+
+	if err := botDto.Load(ctx, bot); err != nil {
+		result.WriteSilent(w, result.New(nil, common.FailedToLoadErr))
+		return
+	}
+	botDto.Token = token
+
+	result.WriteSilent(w, result.Ok(botDto))
+}
+
+func (c *Controller) GetBots(w http.ResponseWriter, r *http.Request) {
+	ctx, ok := util.AppContext(r.Context())
+	if !ok {
+		result.WriteSilent(w, result.New(nil, common.InternalServerErr))
+		return
+	}
+
+	var form forms.GetBots
+	if err := json.NewDecoder(r.Body).Decode(&form); err != nil {
+		result.WriteSilent(w, result.New(nil, common.IncorrectInputErr))
+		return
+	}
+
+	if ctx.User() == nil {
+		result.WriteSilent(w, result.New(nil, common.UnauthorizedErr))
+		return
+	}
+
+	bots, err := ctx.User().Bots(ctx, form.Offset, form.Count)
+
+	if err != nil {
+		result.WriteSilent(w, result.Err(common.CustomErrorCode, err.Error()))
+		return
+	}
+
+	var botsDto []dto.Bot
+
+	for _, bot := range bots {
+		var botDto dto.Bot
+		if err := botDto.Load(ctx, bot); err != nil {
+			result.WriteSilent(w, result.New(nil, common.FailedToLoadErr))
+			return
+		}
+		botsDto = append(botsDto, botDto)
+	}
+
+	result.WriteSilent(w, result.Ok(botsDto))
+}
+
+func (c *Controller) DeleteBot(w http.ResponseWriter, r *http.Request) {
+	ctx, ok := util.AppContext(r.Context())
+	if !ok {
+		result.WriteSilent(w, result.New(nil, common.InternalServerErr))
+		return
+	}
+
+	var form forms.DeleteBot
+	if err := json.NewDecoder(r.Body).Decode(&form); err != nil {
+		result.WriteSilent(w, result.New(nil, common.IncorrectInputErr))
+		return
+	}
+
+	bot, err := c.app.Users().GetBot(ctx, form.ID)
+
+	if err != nil {
+		result.WriteSilent(w, result.Err(common.CustomErrorCode, err.Error()))
+		return
+	}
+
+	if err := bot.Delete(ctx); err != nil {
+		result.WriteSilent(w, result.Err(common.CustomErrorCode, err.Error()))
+		return
+	}
+
+	result.WriteSilent(w, result.Ok(nil))
+}
+
+func (c *Controller) RegenerateBotToken(w http.ResponseWriter, r *http.Request) {
+	ctx, ok := util.AppContext(r.Context())
+	if !ok {
+		result.WriteSilent(w, result.New(nil, common.InternalServerErr))
+		return
+	}
+
+	var form forms.RegenerateBotToken
+	if err := json.NewDecoder(r.Body).Decode(&form); err != nil {
+		result.WriteSilent(w, result.New(nil, common.IncorrectInputErr))
+		return
+	}
+
+	bot, err := c.app.Users().GetBot(ctx, form.ID)
+
+	if err != nil {
+		result.WriteSilent(w, result.Err(common.CustomErrorCode, err.Error()))
+		return
+	}
+
+	token, err := bot.RegenerateToken(ctx)
+
+	if err != nil {
+		result.WriteSilent(w, result.Err(common.CustomErrorCode, err.Error()))
+		return
+	}
+
+	var botDto dto.Bot
+
+	if err := botDto.Load(ctx, bot); err != nil {
+		result.WriteSilent(w, result.New(nil, common.FailedToLoadErr))
+		return
+	}
+	botDto.Token = token
+
+	result.WriteSilent(w, result.Ok(botDto))
+}
+
+func (c *Controller) SetBotCommands(w http.ResponseWriter, r *http.Request) {
+	ctx, ok := util.AppContext(r.Context())
+	if !ok {
+		result.WriteSilent(w, result.New(nil, common.InternalServerErr))
+		return
+	}
+
+	var form forms.SetBotCommands
+	if err := json.NewDecoder(r.Body).Decode(&form); err != nil {
+		result.WriteSilent(w, result.New(nil, common.IncorrectInputErr))
+		return
+	}
+
+	if form.ID == "" && ctx.User() != nil {
+		form.ID = ctx.User().ID()
+	}
+
+	bot, err := c.app.Users().GetBot(ctx, form.ID)
+
+	if err != nil {
+		result.WriteSilent(w, result.Err(common.CustomErrorCode, err.Error()))
+		return
+	}
+
+	var update appForms.BotCommandsUpdate
+	for _, command := range form.Commands {
+		update.Commands = append(update.Commands, appForms.BotCommand{
+			Command:     command.Command,
+			Description: command.Description,
+		})
+	}
+
+	if err := bot.SetCommands(ctx, update); err != nil {
+		result.WriteSilent(w, result.Err(common.CustomErrorCode, err.Error()))
+		return
+	}
+
+	result.WriteSilent(w, result.Ok(nil))
+}
+
+func (c *Controller) GetBotCommands(w http.ResponseWriter, r *http.Request) {
+	ctx, ok := util.AppContext(r.Context())
+	if !ok {
+		result.WriteSilent(w, result.New(nil, common.InternalServerErr))
+		return
+	}
+
+	var form forms.GetBotCommands
+	if err := json.NewDecoder(r.Body).Decode(&form); err != nil {
+		result.WriteSilent(w, result.New(nil, common.IncorrectInputErr))
+		return
+	}
+
+	if form.ID == "" && ctx.User() != nil {
+		form.ID = ctx.User().ID()
+	}
+
+	bot, err := c.app.Users().GetBot(ctx, form.ID)
+
+	if err != nil {
+		result.WriteSilent(w, result.Err(common.CustomErrorCode, err.Error()))
+		return
+	}
+
+	commands, err := bot.Commands(ctx)
+
+	if err != nil {
+		result.WriteSilent(w, result.Err(common.CustomErrorCode, err.Error()))
+		return
+	}
+
+	var commandsDto []dto.BotCommand
+
+	for _, command := range commands {
+		var commandDto dto.BotCommand
+		commandDto.Load(command)
+		commandsDto = append(commandsDto, commandDto)
+	}
+
+	result.WriteSilent(w, result.Ok(commandsDto))
+}
+
+// GetUpdates long-polls the updates of the current bot
+func (c *Controller) GetUpdates(w http.ResponseWriter, r *http.Request) {
+	ctx, ok := util.AppContext(r.Context())
+	if !ok {
+		result.WriteSilent(w, result.New(nil, common.InternalServerErr))
+		return
+	}
+
+	var form forms.GetUpdates
+	if err := json.NewDecoder(r.Body).Decode(&form); err != nil {
+		result.WriteSilent(w, result.New(nil, common.IncorrectInputErr))
+		return
+	}
+
+	if ctx.User() == nil {
+		result.WriteSilent(w, result.New(nil, common.UnauthorizedErr))
+		return
+	}
+
+	bot, err := c.app.Users().GetBot(ctx, ctx.User().ID())
+
+	if err != nil {
+		result.WriteSilent(w, result.Err(common.CustomErrorCode, err.Error()))
+		return
+	}
+
+	updates, err := bot.Updates(ctx, form.Offset, form.Count, time.Duration(form.Timeout)*time.Second)
+
+	if err != nil {
+		result.WriteSilent(w, result.Err(common.CustomErrorCode, err.Error()))
+		return
+	}
+
+	var updatesDto []dto.BotUpdate
+
+	for _, update := range updates {
+		var updateDto dto.BotUpdate
+		updateDto.Load(update)
+		updatesDto = append(updatesDto, updateDto)
+	}
+
+	result.WriteSilent(w, result.Ok(updatesDto))
+}
@@ -4,12 +4,15 @@ import (
 	"encoding/json"
 	"github.com/ischenkx/vk-test-task/internal/app"
 	appForms "github.com/ischenkx/vk-test-task/internal/app/forms"
+	"github.com/ischenkx/vk-test-task/internal/app/security"
 	"github.com/ischenkx/vk-test-task/internal/transport/web/controllers/common"
 	"github.com/ischenkx/vk-test-task/internal/transport/web/controllers/common/auth"
+	"github.com/ischenkx/vk-test-task/internal/transport/web/controllers/common/middlewares"
 	"github.com/ischenkx/vk-test-task/internal/transport/web/controllers/common/result"
 	"github.com/ischenkx/vk-test-task/internal/transport/web/controllers/dto"
 	"github.com/ischenkx/vk-test-task/internal/transport/web/controllers/users/forms"
 	"github.com/ischenkx/vk-test-task/internal/transport/web/util"
+	"io"
 	"net/http"
 )
 
@@ -316,12 +319,25 @@ func (c *Controller) Logout(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if ctx.User() == nil {
+	// the access token could have expired, the refresh token is enough to log out
+	refreshToken, refreshErr := auth.LoadRefreshToken(r)
+
+	if ctx.User() == nil && refreshErr != nil {
 		result.WriteSilent(w, result.Err(common.CustomErrorCode, "not authorized"))
 		return
 	}
 
+	// the session could have been revoked already, the user is logged out anyway
+	if ctx.User() != nil && ctx.Session() != "" {
+		if session, err := ctx.User().Session(ctx, ctx.Session()); err == nil {
+			_ = session.Revoke(ctx)
+		}
+	} else if refreshErr == nil {
+		_ = c.app.Users().RevokeRefreshToken(ctx, refreshToken)
+	}
+
 	auth.DeleteVerificationToken(w, r)
+	auth.DeleteRefreshToken(w, r)
 	ctx.SetUser(nil)
 
 	result.WriteSilent(w, result.Ok(nil))
@@ -345,7 +361,7 @@ func (c *Controller) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user, err := c.app.Users().Login(ctx, appForms.UserLogin{
+	user, challenge, err := c.app.Users().Login(ctx, appForms.UserLogin{
 		Username: form.Username,
 		Password: form.Password,
 	})
@@ -355,23 +371,33 @@ func (c *Controller) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if user == nil {
+		result.WriteSilent(w, result.Ok(dto.LoginResult{
+			TwoFactorRequired: true,
+			Challenge:         challenge,
+		}))
+		return
+	}
+
 	ctx.SetUser(user)
-	token, err := c.app.Auth().GenerateToken(ctx, user.ID())
+	tokens, err := c.app.Users().IssueTokens(ctx)
 
 	if err != nil {
 		result.WriteSilent(w, result.Err(common.CustomErrorCode, "failed to generate a jwt token"))
 		return
 	}
 
-	auth.StoreVerificationToken(w, r, token)
+	auth.StoreVerificationToken(w, r, tokens.AccessToken)
+	auth.StoreRefreshToken(w, r, tokens.RefreshToken, tokens.RefreshExpiresAt)
 
-	var userDto dto.User
-	if err := userDto.Load(ctx, user); err != nil {
+	var userDto dto.AuthorizedUser
+	if err := userDto.User.Load(ctx, user); err != nil {
 		result.WriteSilent(w, result.New(nil, common.FailedToLoadErr))
 		return
 	}
+	userDto.Tokens.Load(tokens)
 
-	result.WriteSilent(w, result.Ok(userDto))
+	result.WriteSilent(w, result.Ok(dto.LoginResult{AuthorizedUser: &userDto}))
 }
 
 func (c *Controller) Register(w http.ResponseWriter, r *http.Request) {
@@ -403,36 +429,116 @@ func (c *Controller) Register(w http.ResponseWriter, r *http.Request) {
 	}
 
 	ctx.SetUser(user)
-	token, err := c.app.Auth().GenerateToken(ctx, user.ID())
+	tokens, err := c.app.Users().IssueTokens(ctx)
 
 	if err != nil {
 		result.WriteSilent(w, result.Err(common.CustomErrorCode, "failed to generate a jwt token"))
 		return
 	}
 
-	auth.StoreVerificationToken(w, r, token)
+	auth.StoreVerificationToken(w, r, tokens.AccessToken)
+	auth.StoreRefreshToken(w, r, tokens.RefreshToken, tokens.RefreshExpiresAt)
 
-	var userDto dto.User
-	if err := userDto.Load(ctx, user); err != nil {
+	var userDto dto.AuthorizedUser
+	if err := userDto.User.Load(ctx, user); err != nil {
 		result.WriteSilent(w, result.New(nil, common.FailedToLoadErr))
 		return
 	}
+	userDto.Tokens.Load(tokens)
 
 	result.WriteSilent(w, result.Ok(userDto))
 }
 
+// Refresh exchanges the refresh token (from the body or the cookie) for a new pair of tokens,
+// it works with an expired access token
+func (c *Controller) Refresh(w http.ResponseWriter, r *http.Request) {
+	ctx, ok := util.AppContext(r.Context())
+	if !ok {
+		result.WriteSilent(w, result.New(nil, common.InternalServerErr))
+		return
+	}
+
+	var form forms.Refresh
+	if err := json.NewDecoder(r.Body).Decode(&form); err != nil && err != io.EOF {
+		result.WriteSilent(w, result.New(nil, common.IncorrectInputErr))
+		return
+	}
+
+	token := form.RefreshToken
+	if token == "" {
+		cookieToken, err := auth.LoadRefreshToken(r)
+		if err != nil {
+			result.WriteSilent(w, result.New(nil, common.UnauthorizedErr))
+			return
+		}
+		token = cookieToken
+	}
+
+	tokens, err := c.app.Users().Refresh(ctx, token)
+
+	if err != nil {
+		auth.DeleteVerificationToken(w, r)
+		auth.DeleteRefreshToken(w, r)
+		result.WriteSilent(w, result.Err(common.CustomErrorCode, err.Error()))
+		return
+	}
+
+	auth.StoreVerificationToken(w, r, tokens.AccessToken)
+	auth.StoreRefreshToken(w, r, tokens.RefreshToken, tokens.RefreshExpiresAt)
+
+	var tokensDto dto.Tokens
+	tokensDto.Load(tokens)
+
+	result.WriteSilent(w, result.Ok(tokensDto))
+}
+
 func (c *Controller) init() {
 	c.mux.HandleFunc("/register", c.Register)
 	c.mux.HandleFunc("/login", c.Login)
+	c.mux.HandleFunc("/completeLogin", c.CompleteLogin)
+	c.mux.HandleFunc("/getIdentityProviders", c.GetIdentityProviders)
+	c.mux.HandleFunc("/startOIDCLogin", c.StartOIDCLogin)
+	c.mux.HandleFunc("/completeOIDCLogin", c.CompleteOIDCLogin)
 	c.mux.HandleFunc("/logout", c.Logout)
-	c.mux.HandleFunc("/getInfo", c.GetInfo)
-	c.mux.HandleFunc("/getFriends", c.GetFriends)
-	c.mux.HandleFunc("/getChats", c.GetChats)
-	c.mux.HandleFunc("/getIncomingFriendRequests", c.GetIncomingFriendRequests)
-	c.mux.HandleFunc("/getOutgoingFriendRequests", c.GetOutgoingFriendRequests)
-	c.mux.HandleFunc("/sendFriendRequest", c.SendFriendRequest)
-	c.mux.HandleFunc("/declineFriendRequest", c.DeclineFriendRequest)
-	c.mux.HandleFunc("/acceptFriendRequest", c.AcceptFriendRequest)
+	c.mux.HandleFunc("/refresh", c.Refresh)
+	c.mux.HandleFunc("/logoutEverywhere", c.LogoutEverywhere)
+	c.mux.HandleFunc("/getSessions", c.GetSessions)
+	c.mux.HandleFunc("/revokeSession", c.RevokeSession)
+	c.mux.HandleFunc("/createAPIKey", c.CreateAPIKey)
+	c.mux.HandleFunc("/getAPIKeys", c.GetAPIKeys)
+	c.mux.HandleFunc("/deleteAPIKey", c.DeleteAPIKey)
+	c.mux.HandleFunc("/getIdentities", c.GetIdentities)
+	c.mux.HandleFunc("/linkIdentity", c.LinkIdentity)
+	c.mux.HandleFunc("/completeIdentityLink", c.CompleteIdentityLink)
+	c.mux.HandleFunc("/unlinkIdentity", c.UnlinkIdentity)
+	c.mux.HandleFunc("/getTwoFactor", c.GetTwoFactor)
+	c.mux.HandleFunc("/enrollTwoFactor", c.EnrollTwoFactor)
+	c.mux.HandleFunc("/confirmTwoFactor", c.ConfirmTwoFactor)
+	c.mux.HandleFunc("/disableTwoFactor", c.DisableTwoFactor)
+	c.mux.HandleFunc("/regenerateRecoveryCodes", c.RegenerateRecoveryCodes)
+	c.mux.HandleFunc("/changePassword", c.ChangePassword)
+	c.mux.HandleFunc("/requestPasswordReset", c.RequestPasswordReset)
+	c.mux.HandleFunc("/resetPassword", c.ResetPassword)
+	c.mux.HandleFunc("/getInfo", middlewares.RequireScope(security.ScopeUsersRead, c.GetInfo))
+	c.mux.HandleFunc("/getFriends", middlewares.RequireScope(security.ScopeFriendsRead, c.GetFriends))
+	c.mux.HandleFunc("/getChats", middlewares.RequireScope(security.ScopeChatsRead, c.GetChats))
+	c.mux.HandleFunc("/getIncomingFriendRequests", middlewares.RequireScope(security.ScopeFriendsRead, c.GetIncomingFriendRequests))
+	c.mux.HandleFunc("/getOutgoingFriendRequests", middlewares.RequireScope(security.ScopeFriendsRead, c.GetOutgoingFriendRequests))
+	c.mux.HandleFunc("/sendFriendRequest", middlewares.RequireScope(security.ScopeFriendsWrite, c.SendFriendRequest))
+	c.mux.HandleFunc("/declineFriendRequest", middlewares.RequireScope(security.ScopeFriendsWrite, c.DeclineFriendRequest))
+	c.mux.HandleFunc("/acceptFriendRequest", middlewares.RequireScope(security.ScopeFriendsWrite, c.AcceptFriendRequest))
+	c.mux.HandleFunc("/createWebhook", middlewares.RequireScope(security.ScopeWebhooks, c.CreateWebhook))
+	c.mux.HandleFunc("/getWebhooks", middlewares.RequireScope(security.ScopeWebhooks, c.GetWebhooks))
+	c.mux.HandleFunc("/deleteWebhook", middlewares.RequireScope(security.ScopeWebhooks, c.DeleteWebhook))
+	c.mux.HandleFunc("/getWebhookDeliveries", middlewares.RequireScope(security.ScopeWebhooks, c.GetWebhookDeliveries))
+	c.mux.HandleFunc("/redeliverWebhook", middlewares.RequireScope(security.ScopeWebhooks, c.RedeliverWebhook))
+	c.mux.HandleFunc("/createBot", middlewares.RequireScope(security.ScopeBots, c.CreateBot))
+	c.mux.HandleFunc("/getBots", middlewares.RequireScope(security.ScopeBots, c.GetBots))
+	c.mux.HandleFunc("/deleteBot", middlewares.RequireScope(security.ScopeBots, c.DeleteBot))
+	c.mux.HandleFunc("/regenerateBotToken", middlewares.RequireScope(security.ScopeBots, c.RegenerateBotToken))
+	c.mux.HandleFunc("/setBotCommands", middlewares.RequireScope(security.ScopeBots, c.SetBotCommands))
+	c.mux.HandleFunc("/getBotCommands", middlewares.RequireScope(security.ScopeBots, c.GetBotCommands))
+	c.mux.HandleFunc("/getUpdates", middlewares.RequireScope(security.ScopeBots, c.GetUpdates))
 }
 
 func NewController(app *app.App) *Controller {
@@ -41,3 +41,160 @@ type Register struct {
 	Username string `json:"username"`
 	Password string `json:"password"`
 }
+
+type CreateWebhook struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+}
+
+type GetWebhooks struct {
+	Offset int `json:"offset"`
+	Count  int `json:"count"`
+}
+
+type DeleteWebhook struct {
+	ID string `json:"id"`
+}
+
+// GetWebhookDeliveries returns the delivery log of the webhook,
+// or only its dead-letter list if Dead is set
+type GetWebhookDeliveries struct {
+	ID     string `json:"id"`
+	Dead   bool   `json:"dead"`
+	Offset int    `json:"offset"`
+	Count  int    `json:"count"`
+}
+
+type RedeliverWebhook struct {
+	ID         string `json:"id"`
+	DeliveryID string `json:"delivery_id"`
+}
+
+type CreateBot struct {
+	Username string `json:"username"`
+}
+
+type GetBots struct {
+	Offset int `json:"offset"`
+	Count  int `json:"count"`
+}
+
+type DeleteBot struct {
+	ID string `json:"id"`
+}
+
+type RegenerateBotToken struct {
+	ID string `json:"id"`
+}
+
+type BotCommand struct {
+	Command     string `json:"command"`
+	Description string `json:"description"`
+}
+
+// SetBotCommands changes the commands of the bot, ID can be omitted
+// when the bot itself is calling it
+type SetBotCommands struct {
+	ID       string       `json:"id"`
+	Commands []BotCommand `json:"commands"`
+}
+
+type GetBotCommands struct {
+	ID string `json:"id"`
+}
+
+// GetUpdates is called by bots, Timeout is in seconds
+type GetUpdates struct {
+	Offset  int64 `json:"offset"`
+	Count   int   `json:"count"`
+	Timeout int   `json:"timeout"`
+}
+
+type GetSessions struct {
+	Offset int `json:"offset"`
+	Count  int `json:"count"`
+}
+
+type RevokeSession struct {
+	ID string `json:"id"`
+}
+
+// Refresh takes the refresh token from the cookie if it's omitted
+type Refresh struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// CreateAPIKey creates a key limited to Scopes, ExpiresIn is in seconds,
+// the key never expires if it's omitted
+type CreateAPIKey struct {
+	Name      string   `json:"name"`
+	Scopes    []string `json:"scopes"`
+	ExpiresIn int64    `json:"expires_in"`
+}
+
+type GetAPIKeys struct {
+	Offset int `json:"offset"`
+	Count  int `json:"count"`
+}
+
+type DeleteAPIKey struct {
+	ID string `json:"id"`
+}
+
+type StartOIDCLogin struct {
+	Provider string `json:"provider"`
+}
+
+// CompleteOIDCLogin carries the parameters the provider has redirected the user back with
+type CompleteOIDCLogin struct {
+	State string `json:"state"`
+	Code  string `json:"code"`
+}
+
+type LinkIdentity struct {
+	Provider string `json:"provider"`
+}
+
+type CompleteIdentityLink struct {
+	State string `json:"state"`
+	Code  string `json:"code"`
+}
+
+type UnlinkIdentity struct {
+	Provider string `json:"provider"`
+}
+
+// CompleteLogin passes the challenge of login, Code is a TOTP code or a recovery code
+type CompleteLogin struct {
+	Challenge string `json:"challenge"`
+	Code      string `json:"code"`
+}
+
+type ConfirmTwoFactor struct {
+	Code string `json:"code"`
+}
+
+// DisableTwoFactor takes a TOTP code or a recovery code
+type DisableTwoFactor struct {
+	Code string `json:"code"`
+}
+
+// RegenerateRecoveryCodes takes a TOTP code or a recovery code
+type RegenerateRecoveryCodes struct {
+	Code string `json:"code"`
+}
+
+// ChangePassword takes an empty OldPassword if the user has no password yet
+type ChangePassword struct {
+	OldPassword string `json:"old_password"`
+	NewPassword string `json:"new_password"`
+}
+
+type RequestPasswordReset struct {
+	Username string `json:"username"`
+}
+
+type ResetPassword struct {
+	Token       string `json:"token"`
+	NewPassword string `json:"new_password"`
+}
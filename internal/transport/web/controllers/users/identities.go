@@ -0,0 +1,217 @@
+package users
+
+import (
+	"encoding/json"
+	"github.com/ischenkx/vk-test-task/internal/transport/web/controllers/common"
+	"github.com/ischenkx/vk-test-task/internal/transport/web/controllers/common/auth"
+	"github.com/ischenkx/vk-test-task/internal/transport/web/controllers/common/result"
+	"github.com/ischenkx/vk-test-task/internal/transport/web/controllers/dto"
+	"github.com/ischenkx/vk-test-task/internal/transport/web/controllers/users/forms"
+	"github.com/ischenkx/vk-test-task/internal/transport/web/util"
+	"net/http"
+)
+
+// GetIdentityProviders returns the names of the providers users can log in with
+func (c *Controller) GetIdentityProviders(w http.ResponseWriter, r *http.Request) {
+	result.WriteSilent(w, result.Ok(c.app.Users().IdentityProviders()))
+}
+
+func (c *Controller) StartOIDCLogin(w http.ResponseWriter, r *http.Request) {
+	ctx, ok := util.AppContext(r.Context())
+	if !ok {
+		result.WriteSilent(w, result.New(nil, common.InternalServerErr))
+		return
+	}
+
+	var form forms.StartOIDCLogin
+	if err := json.NewDecoder(r.Body).Decode(&form); err != nil {
+		result.WriteSilent(w, result.New(nil, common.IncorrectInputErr))
+		return
+	}
+
+	if ctx.User() != nil {
+		result.WriteSilent(w, result.Err(common.CustomErrorCode, "already authorized"))
+		return
+	}
+
+	url, err := c.app.Users().StartExternalLogin(ctx, form.Provider)
+
+	if err != nil {
+		result.WriteSilent(w, result.Err(common.CustomErrorCode, err.Error()))
+		return
+	}
+
+	result.WriteSilent(w, result.Ok(dto.AuthURL{URL: url}))
+}
+
+// CompleteOIDCLogin logs the user in with the code the provider has redirected the user back with,
+// the account is created on the first login. Users with two-factor authentication get a challenge
+// for /users/completeLogin instead of the tokens.
+func (c *Controller) CompleteOIDCLogin(w http.ResponseWriter, r *http.Request) {
+	ctx, ok := util.AppContext(r.Context())
+	if !ok {
+		result.WriteSilent(w, result.New(nil, common.InternalServerErr))
+		return
+	}
+
+	var form forms.CompleteOIDCLogin
+	if err := json.NewDecoder(r.Body).Decode(&form); err != nil {
+		result.WriteSilent(w, result.New(nil, common.IncorrectInputErr))
+		return
+	}
+
+	if ctx.User() != nil {
+		result.WriteSilent(w, result.Err(common.CustomErrorCode, "already authorized"))
+		return
+	}
+
+	user, challenge, err := c.app.Users().CompleteExternalLogin(ctx, form.State, form.Code)
+
+	if err != nil {
+		result.WriteSilent(w, result.Err(common.CustomErrorCode, err.Error()))
+		return
+	}
+
+	if user == nil {
+		result.WriteSilent(w, result.Ok(dto.LoginResult{
+			TwoFactorRequired: true,
+			Challenge:         challenge,
+		}))
+		return
+	}
+
+	ctx.SetUser(user)
+	tokens, err := c.app.Users().IssueTokens(ctx)
+
+	if err != nil {
+		result.WriteSilent(w, result.Err(common.CustomErrorCode, "failed to generate a jwt token"))
+		return
+	}
+
+	auth.StoreVerificationToken(w, r, tokens.AccessToken)
+	auth.StoreRefreshToken(w, r, tokens.RefreshToken, tokens.RefreshExpiresAt)
+
+	var userDto dto.AuthorizedUser
+	if err := userDto.User.Load(ctx, user); err != nil {
+		result.WriteSilent(w, result.New(nil, common.FailedToLoadErr))
+		return
+	}
+	userDto.Tokens.Load(tokens)
+
+	result.WriteSilent(w, result.Ok(dto.LoginResult{AuthorizedUser: &userDto}))
+}
+
+func (c *Controller) GetIdentities(w http.ResponseWriter, r *http.Request) {
+	ctx, ok := util.AppContext(r.Context())
+	if !ok {
+		result.WriteSilent(w, result.New(nil, common.InternalServerErr))
+		return
+	}
+
+	if ctx.User() == nil {
+		result.WriteSilent(w, result.New(nil, common.UnauthorizedErr))
+		return
+	}
+
+	identities, err := ctx.User().Identities(ctx)
+
+	if err != nil {
+		result.WriteSilent(w, result.Err(common.CustomErrorCode, err.Error()))
+		return
+	}
+
+	var identitiesDto []dto.Identity
+
+	for _, identity := range identities {
+		var identityDto dto.Identity
+		identityDto.Load(identity)
+		identitiesDto = append(identitiesDto, identityDto)
+	}
+
+	result.WriteSilent(w, result.Ok(identitiesDto))
+}
+
+func (c *Controller) LinkIdentity(w http.ResponseWriter, r *http.Request) {
+	ctx, ok := util.AppContext(r.Context())
+	if !ok {
+		result.WriteSilent(w, result.New(nil, common.InternalServerErr))
+		return
+	}
+
+	var form forms.LinkIdentity
+	if err := json.NewDecoder(r.Body).Decode(&form); err != nil {
+		result.WriteSilent(w, result.New(nil, common.IncorrectInputErr))
+		return
+	}
+
+	if ctx.User() == nil {
+		result.WriteSilent(w, result.New(nil, common.UnauthorizedErr))
+		return
+	}
+
+	url, err := ctx.User().StartIdentityLink(ctx, form.Provider)
+
+	if err != nil {
+		result.WriteSilent(w, result.Err(common.CustomErrorCode, err.Error()))
+		return
+	}
+
+	result.WriteSilent(w, result.Ok(dto.AuthURL{URL: url}))
+}
+
+func (c *Controller) CompleteIdentityLink(w http.ResponseWriter, r *http.Request) {
+	ctx, ok := util.AppContext(r.Context())
+	if !ok {
+		result.WriteSilent(w, result.New(nil, common.InternalServerErr))
+		return
+	}
+
+	var form forms.CompleteIdentityLink
+	if err := json.NewDecoder(r.Body).Decode(&form); err != nil {
+		result.WriteSilent(w, result.New(nil, common.IncorrectInputErr))
+		return
+	}
+
+	if ctx.User() == nil {
+		result.WriteSilent(w, result.New(nil, common.UnauthorizedErr))
+		return
+	}
+
+	identity, err := ctx.User().CompleteIdentityLink(ctx, form.State, form.Code)
+
+	if err != nil {
+		result.WriteSilent(w, result.Err(common.CustomErrorCode, err.Error()))
+		return
+	}
+
+	var identityDto dto.Identity
+	identityDto.Load(identity)
+
+	result.WriteSilent(w, result.Ok(identityDto))
+}
+
+func (c *Controller) UnlinkIdentity(w http.ResponseWriter, r *http.Request) {
+	ctx, ok := util.AppContext(r.Context())
+	if !ok {
+		result.WriteSilent(w, result.New(nil, common.InternalServerErr))
+		return
+	}
+
+	var form forms.UnlinkIdentity
+	if err := json.NewDecoder(r.Body).Decode(&form); err != nil {
+		result.WriteSilent(w, result.New(nil, common.IncorrectInputErr))
+		return
+	}
+
+	if ctx.User() == nil {
+		result.WriteSilent(w, result.New(nil, common.UnauthorizedErr))
+		return
+	}
+
+	if err := ctx.User().UnlinkIdentity(ctx, form.Provider); err != nil {
+		result.WriteSilent(w, result.Err(common.CustomErrorCode, err.Error()))
+		return
+	}
+
+	result.WriteSilent(w, result.Ok(nil))
+}
@@ -0,0 +1,97 @@
+package users
+
+import (
+	"encoding/json"
+	appForms "github.com/ischenkx/vk-test-task/internal/app/forms"
+	"github.com/ischenkx/vk-test-task/internal/transport/web/controllers/common"
+	"github.com/ischenkx/vk-test-task/internal/transport/web/controllers/common/result"
+	"github.com/ischenkx/vk-test-task/internal/transport/web/controllers/users/forms"
+	"github.com/ischenkx/vk-test-task/internal/transport/web/util"
+	"net/http"
+)
+
+// ChangePassword keeps the current session, the other ones are revoked
+func (c *Controller) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	ctx, ok := util.AppContext(r.Context())
+	if !ok {
+		result.WriteSilent(w, result.New(nil, common.InternalServerErr))
+		return
+	}
+
+	var form forms.ChangePassword
+	if err := json.NewDecoder(r.Body).Decode(&form); err != nil {
+		result.WriteSilent(w, result.New(nil, common.IncorrectInputErr))
+		return
+	}
+
+	if ctx.User() == nil {
+		result.WriteSilent(w, result.New(nil, common.UnauthorizedErr))
+		return
+	}
+
+	err := ctx.User().ChangePassword(ctx, appForms.PasswordChange{
+		OldPassword: form.OldPassword,
+		NewPassword: form.NewPassword,
+	})
+
+	if err != nil {
+		result.WriteSilent(w, result.Err(common.CustomErrorCode, err.Error()))
+		return
+	}
+
+	result.WriteSilent(w, result.Ok(nil))
+}
+
+// RequestPasswordReset responds the same whether there is such a user or not
+func (c *Controller) RequestPasswordReset(w http.ResponseWriter, r *http.Request) {
+	ctx, ok := util.AppContext(r.Context())
+	if !ok {
+		result.WriteSilent(w, result.New(nil, common.InternalServerErr))
+		return
+	}
+
+	var form forms.RequestPasswordReset
+	if err := json.NewDecoder(r.Body).Decode(&form); err != nil {
+		result.WriteSilent(w, result.New(nil, common.IncorrectInputErr))
+		return
+	}
+
+	if ctx.User() != nil {
+		result.WriteSilent(w, result.Err(common.CustomErrorCode, "already authorized"))
+		return
+	}
+
+	if err := c.app.Users().RequestPasswordReset(ctx, form.Username); err != nil {
+		result.WriteSilent(w, result.Err(common.CustomErrorCode, err.Error()))
+		return
+	}
+
+	result.WriteSilent(w, result.Ok(nil))
+}
+
+// ResetPassword doesn't log the user in, the new password is used to log in as usual
+func (c *Controller) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	ctx, ok := util.AppContext(r.Context())
+	if !ok {
+		result.WriteSilent(w, result.New(nil, common.InternalServerErr))
+		return
+	}
+
+	var form forms.ResetPassword
+	if err := json.NewDecoder(r.Body).Decode(&form); err != nil {
+		result.WriteSilent(w, result.New(nil, common.IncorrectInputErr))
+		return
+	}
+
+	err := c.app.Users().ResetPassword(ctx, appForms.PasswordReset{
+		Token:       form.Token,
+		NewPassword: form.NewPassword,
+	})
+
+	if err != nil {
+		result.WriteSilent(w, result.Err(common.CustomErrorCode, err.Error()))
+		return
+	}
+
+	result.WriteSilent(w, result.Ok(nil))
+}
@@ -0,0 +1,116 @@
+package users
+
+import (
+	"encoding/json"
+	"github.com/ischenkx/vk-test-task/internal/transport/web/controllers/common"
+	"github.com/ischenkx/vk-test-task/internal/transport/web/controllers/common/auth"
+	"github.com/ischenkx/vk-test-task/internal/transport/web/controllers/common/result"
+	"github.com/ischenkx/vk-test-task/internal/transport/web/controllers/dto"
+	"github.com/ischenkx/vk-test-task/internal/transport/web/controllers/users/forms"
+	"github.com/ischenkx/vk-test-task/internal/transport/web/util"
+	"net/http"
+)
+
+func (c *Controller) GetSessions(w http.ResponseWriter, r *http.Request) {
+	ctx, ok := util.AppContext(r.Context())
+	if !ok {
+		result.WriteSilent(w, result.New(nil, common.InternalServerErr))
+		return
+	}
+
+	var form forms.GetSessions
+	if err := json.NewDecoder(r.Body).Decode(&form); err != nil {
+		result.WriteSilent(w, result.New(nil, common.IncorrectInputErr))
+		return
+	}
+
+	if ctx.User() == nil {
+		result.WriteSilent(w, result.New(nil, common.UnauthorizedErr))
+		return
+	}
+
+	sessions, err := ctx.User().Sessions(ctx, form.Offset, form.Count)
+
+	if err != nil {
+		result.WriteSilent(w, result.Err(common.CustomErrorCode, err.Error()))
+		return
+	}
+
+	var sessionsDto []dto.Session
+
+	for _, session := range sessions {
+		var sessionDto dto.Session
+		if err := sessionDto.Load(ctx, session); err != nil {
+			result.WriteSilent(w, result.New(nil, common.FailedToLoadErr))
+			return
+		}
+		sessionsDto = append(sessionsDto, sessionDto)
+	}
+
+	result.WriteSilent(w, result.Ok(sessionsDto))
+}
+
+func (c *Controller) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	ctx, ok := util.AppContext(r.Context())
+	if !ok {
+		result.WriteSilent(w, result.New(nil, common.InternalServerErr))
+		return
+	}
+
+	var form forms.RevokeSession
+	if err := json.NewDecoder(r.Body).Decode(&form); err != nil {
+		result.WriteSilent(w, result.New(nil, common.IncorrectInputErr))
+		return
+	}
+
+	if ctx.User() == nil {
+		result.WriteSilent(w, result.New(nil, common.UnauthorizedErr))
+		return
+	}
+
+	session, err := ctx.User().Session(ctx, form.ID)
+
+	if err != nil {
+		result.WriteSilent(w, result.Err(common.CustomErrorCode, err.Error()))
+		return
+	}
+
+	current := session.Current(ctx)
+
+	if err := session.Revoke(ctx); err != nil {
+		result.WriteSilent(w, result.Err(common.CustomErrorCode, err.Error()))
+		return
+	}
+
+	if current {
+		auth.DeleteVerificationToken(w, r)
+		auth.DeleteRefreshToken(w, r)
+	}
+
+	result.WriteSilent(w, result.Ok(nil))
+}
+
+// LogoutEverywhere revokes all the sessions of the user, including the current one
+func (c *Controller) LogoutEverywhere(w http.ResponseWriter, r *http.Request) {
+	ctx, ok := util.AppContext(r.Context())
+	if !ok {
+		result.WriteSilent(w, result.New(nil, common.InternalServerErr))
+		return
+	}
+
+	if ctx.User() == nil {
+		result.WriteSilent(w, result.New(nil, common.UnauthorizedErr))
+		return
+	}
+
+	if err := ctx.User().RevokeSessions(ctx); err != nil {
+		result.WriteSilent(w, result.Err(common.CustomErrorCode, err.Error()))
+		return
+	}
+
+	auth.DeleteVerificationToken(w, r)
+	auth.DeleteRefreshToken(w, r)
+	ctx.SetUser(nil)
+
+	result.WriteSilent(w, result.Ok(nil))
+}
@@ -0,0 +1,191 @@
+package users
+
+import (
+	"encoding/json"
+	"github.com/ischenkx/vk-test-task/internal/transport/web/controllers/common"
+	"github.com/ischenkx/vk-test-task/internal/transport/web/controllers/common/auth"
+	"github.com/ischenkx/vk-test-task/internal/transport/web/controllers/common/result"
+	"github.com/ischenkx/vk-test-task/internal/transport/web/controllers/dto"
+	"github.com/ischenkx/vk-test-task/internal/transport/web/controllers/users/forms"
+	"github.com/ischenkx/vk-test-task/internal/transport/web/util"
+	"net/http"
+)
+
+// CompleteLogin issues the tokens once the challenge of login is passed with the second factor
+func (c *Controller) CompleteLogin(w http.ResponseWriter, r *http.Request) {
+	ctx, ok := util.AppContext(r.Context())
+	if !ok {
+		result.WriteSilent(w, result.New(nil, common.InternalServerErr))
+		return
+	}
+
+	var form forms.CompleteLogin
+	if err := json.NewDecoder(r.Body).Decode(&form); err != nil {
+		result.WriteSilent(w, result.New(nil, common.IncorrectInputErr))
+		return
+	}
+
+	if ctx.User() != nil {
+		result.WriteSilent(w, result.Err(common.CustomErrorCode, "already authorized"))
+		return
+	}
+
+	user, err := c.app.Users().CompleteLogin(ctx, form.Challenge, form.Code)
+
+	if err != nil {
+		result.WriteSilent(w, result.Err(common.CustomErrorCode, err.Error()))
+		return
+	}
+
+	ctx.SetUser(user)
+	tokens, err := c.app.Users().IssueTokens(ctx)
+
+	if err != nil {
+		result.WriteSilent(w, result.Err(common.CustomErrorCode, "failed to generate a jwt token"))
+		return
+	}
+
+	auth.StoreVerificationToken(w, r, tokens.AccessToken)
+	auth.StoreRefreshToken(w, r, tokens.RefreshToken, tokens.RefreshExpiresAt)
+
+	var userDto dto.AuthorizedUser
+	if err := userDto.User.Load(ctx, user); err != nil {
+		result.WriteSilent(w, result.New(nil, common.FailedToLoadErr))
+		return
+	}
+	userDto.Tokens.Load(tokens)
+
+	result.WriteSilent(w, result.Ok(userDto))
+}
+
+func (c *Controller) GetTwoFactor(w http.ResponseWriter, r *http.Request) {
+	ctx, ok := util.AppContext(r.Context())
+	if !ok {
+		result.WriteSilent(w, result.New(nil, common.InternalServerErr))
+		return
+	}
+
+	if ctx.User() == nil {
+		result.WriteSilent(w, result.New(nil, common.UnauthorizedErr))
+		return
+	}
+
+	status, err := ctx.User().TwoFactor(ctx)
+
+	if err != nil {
+		result.WriteSilent(w, result.Err(common.CustomErrorCode, err.Error()))
+		return
+	}
+
+	var statusDto dto.TwoFactorStatus
+	statusDto.Load(status)
+
+	result.WriteSilent(w, result.Ok(statusDto))
+}
+
+func (c *Controller) EnrollTwoFactor(w http.ResponseWriter, r *http.Request) {
+	ctx, ok := util.AppContext(r.Context())
+	if !ok {
+		result.WriteSilent(w, result.New(nil, common.InternalServerErr))
+		return
+	}
+
+	if ctx.User() == nil {
+		result.WriteSilent(w, result.New(nil, common.UnauthorizedErr))
+		return
+	}
+
+	enrollment, err := ctx.User().EnrollTwoFactor(ctx)
+
+	if err != nil {
+		result.WriteSilent(w, result.Err(common.CustomErrorCode, err.Error()))
+		return
+	}
+
+	var enrollmentDto dto.TwoFactorEnrollment
+	enrollmentDto.Load(enrollment)
+
+	result.WriteSilent(w, result.Ok(enrollmentDto))
+}
+
+func (c *Controller) ConfirmTwoFactor(w http.ResponseWriter, r *http.Request) {
+	ctx, ok := util.AppContext(r.Context())
+	if !ok {
+		result.WriteSilent(w, result.New(nil, common.InternalServerErr))
+		return
+	}
+
+	var form forms.ConfirmTwoFactor
+	if err := json.NewDecoder(r.Body).Decode(&form); err != nil {
+		result.WriteSilent(w, result.New(nil, common.IncorrectInputErr))
+		return
+	}
+
+	if ctx.User() == nil {
+		result.WriteSilent(w, result.New(nil, common.UnauthorizedErr))
+		return
+	}
+
+	codes, err := ctx.User().ConfirmTwoFactor(ctx, form.Code)
+
+	if err != nil {
+		result.WriteSilent(w, result.Err(common.CustomErrorCode, err.Error()))
+		return
+	}
+
+	result.WriteSilent(w, result.Ok(dto.RecoveryCodes{Codes: codes}))
+}
+
+func (c *Controller) DisableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	ctx, ok := util.AppContext(r.Context())
+	if !ok {
+		result.WriteSilent(w, result.New(nil, common.InternalServerErr))
+		return
+	}
+
+	var form forms.DisableTwoFactor
+	if err := json.NewDecoder(r.Body).Decode(&form); err != nil {
+		result.WriteSilent(w, result.New(nil, common.IncorrectInputErr))
+		return
+	}
+
+	if ctx.User() == nil {
+		result.WriteSilent(w, result.New(nil, common.UnauthorizedErr))
+		return
+	}
+
+	if err := ctx.User().DisableTwoFactor(ctx, form.Code); err != nil {
+		result.WriteSilent(w, result.Err(common.CustomErrorCode, err.Error()))
+		return
+	}
+
+	result.WriteSilent(w, result.Ok(nil))
+}
+
+func (c *Controller) RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	ctx, ok := util.AppContext(r.Context())
+	if !ok {
+		result.WriteSilent(w, result.New(nil, common.InternalServerErr))
+		return
+	}
+
+	var form forms.RegenerateRecoveryCodes
+	if err := json.NewDecoder(r.Body).Decode(&form); err != nil {
+		result.WriteSilent(w, result.New(nil, common.IncorrectInputErr))
+		return
+	}
+
+	if ctx.User() == nil {
+		result.WriteSilent(w, result.New(nil, common.UnauthorizedErr))
+		return
+	}
+
+	codes, err := ctx.User().RegenerateRecoveryCodes(ctx, form.Code)
+
+	if err != nil {
+		result.WriteSilent(w, result.Err(common.CustomErrorCode, err.Error()))
+		return
+	}
+
+	result.WriteSilent(w, result.Ok(dto.RecoveryCodes{Codes: codes}))
+}
@@ -0,0 +1,50 @@
+package wellknown
+
+import (
+	"encoding/json"
+	"github.com/ischenkx/vk-test-task/internal/app"
+	"github.com/ischenkx/vk-test-task/internal/app/security"
+	"net/http"
+)
+
+// Controller serves the standard /.well-known/ documents,
+// they are written as is, without the result envelope
+type Controller struct {
+	app *app.App
+	mux *http.ServeMux
+}
+
+func (c *Controller) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	c.mux.ServeHTTP(writer, request)
+}
+
+type jwks struct {
+	Keys []security.JWK `json:"keys"`
+}
+
+// JWKS serves the public keys tokens can be verified with,
+// the set is empty if the tokens are signed with a shared secret
+func (c *Controller) JWKS(w http.ResponseWriter, r *http.Request) {
+	keys := []security.JWK{}
+	if publisher, ok := c.app.Auth().(security.KeyPublisher); ok {
+		keys = append(keys, publisher.PublicKeys()...)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	_ = json.NewEncoder(w).Encode(jwks{Keys: keys})
+}
+
+func (c *Controller) init() {
+	c.mux.HandleFunc("/jwks.json", c.JWKS)
+}
+
+func NewController(app *app.App) *Controller {
+	controller := &Controller{
+		app: app,
+		mux: http.NewServeMux(),
+	}
+
+	controller.init()
+	return controller
+}
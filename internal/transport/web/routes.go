@@ -4,19 +4,26 @@ import (
 	"github.com/ischenkx/vk-test-task/internal/app"
 	"github.com/ischenkx/vk-test-task/internal/transport/web/controllers/chats"
 	"github.com/ischenkx/vk-test-task/internal/transport/web/controllers/common/middlewares"
+	"github.com/ischenkx/vk-test-task/internal/transport/web/controllers/hooks"
 	"github.com/ischenkx/vk-test-task/internal/transport/web/controllers/users"
+	"github.com/ischenkx/vk-test-task/internal/transport/web/controllers/wellknown"
 	"net/http"
 )
 
-func NewRouter(a *app.App) http.Handler {
+// NewRouter builds the handler of the API, the pages of allowedOrigins
+// can make requests authorized with the user's cookies
+func NewRouter(a *app.App, allowedOrigins []string) http.Handler {
 	mux := http.NewServeMux()
 
 	// routes
 	mux.Handle("/users/", http.StripPrefix("/users", users.NewController(a)))
 	mux.Handle("/chats/", http.StripPrefix("/chats", chats.NewController(a)))
+	mux.Handle("/hooks/", http.StripPrefix("/hooks", hooks.NewController(a)))
+	mux.Handle("/.well-known/", http.StripPrefix("/.well-known", wellknown.NewController(a)))
 
 	// middlewares
 	handler := middlewares.Auth(a, mux)
+	handler = middlewares.CSRF(allowedOrigins, handler)
 	handler = middlewares.ContextInitializer(handler)
 
 	return handler
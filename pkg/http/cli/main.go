@@ -7,8 +7,10 @@ import (
 	"github.com/ischenkx/vk-test-task/internal/transport/web/client"
 	chatForms "github.com/ischenkx/vk-test-task/internal/transport/web/controllers/chats/forms"
 	"github.com/ischenkx/vk-test-task/internal/transport/web/controllers/dto"
+	hookForms "github.com/ischenkx/vk-test-task/internal/transport/web/controllers/hooks/forms"
 	"github.com/ischenkx/vk-test-task/internal/transport/web/controllers/users/forms"
 	"net/http"
+	"net/url"
 	"os"
 	"strconv"
 	"strings"
@@ -70,15 +72,124 @@ func output(data interface{}, prefixTabs int) {
 		fmt.Printf(prefix+"name: '%s'\n", obj.Name)
 		fmt.Printf(prefix+"description: '%s'\n", obj.Description)
 		fmt.Printf(prefix+"owner id: '%s'\n", obj.OwnerID)
+		fmt.Printf(prefix+"visibility: %d\n", obj.Visibility)
+		fmt.Printf(prefix+"kind: %d\n", obj.Kind)
+		if obj.Kind == 1 {
+			fmt.Printf(prefix+"subscribers: %d\n", obj.SubscribersCount)
+		}
 		fmt.Printf(prefix+"id: '%s'\n", obj.ID)
+	case dto.PublicChat:
+		output(obj.Chat, prefixTabs)
+		fmt.Printf(prefix+"members: %d\n", obj.MembersCount)
 	case dto.Message:
 		fmt.Printf(prefix+"user id: '%s'\n", obj.UserID)
 		fmt.Printf(prefix+"chat id: '%s'\n", obj.ChatID)
 		fmt.Printf(prefix+"payload: '%s'\n", obj.Payload)
 		fmt.Printf(prefix+"time: '%s'\n", obj.TimeStamp)
 		fmt.Printf(prefix+"last update: '%s'\n", obj.LastUpdate)
+		fmt.Printf(prefix+"views: %d\n", obj.Views)
+		if len(obj.Attachments) > 0 {
+			fmt.Printf(prefix+"attachments: %d\n", len(obj.Attachments))
+		}
 		fmt.Printf(prefix+"id: '%s'\n", obj.ID)
 
+	case dto.ChatInvite:
+		fmt.Printf(prefix+"code: '%s'\n", obj.Code)
+		fmt.Printf(prefix+"chat id: '%s'\n", obj.ChatID)
+		fmt.Printf(prefix+"uses: %d/%d\n", obj.Uses, obj.MaxUses)
+		fmt.Printf(prefix+"expires at: '%s'\n", obj.ExpiresAt)
+		fmt.Printf(prefix+"requires approval: %t\n", obj.RequiresApproval)
+	case dto.ChatJoinRequest:
+		fmt.Printf(prefix+"chat id: '%s'\n", obj.ChatID)
+		fmt.Printf(prefix+"user id: '%s'\n", obj.UserID)
+		fmt.Printf(prefix+"time: '%s'\n", obj.TimeStamp)
+		fmt.Printf(prefix+"id: '%s'\n", obj.ID)
+	case dto.Webhook:
+		fmt.Printf(prefix+"url: '%s'\n", obj.URL)
+		fmt.Printf(prefix+"events: '%s'\n", strings.Join(obj.Events, ","))
+		if obj.ChatID != "" {
+			fmt.Printf(prefix+"chat id: '%s'\n", obj.ChatID)
+		}
+		if obj.Secret != "" {
+			fmt.Printf(prefix+"secret: '%s'\n", obj.Secret)
+		}
+		fmt.Printf(prefix+"id: '%s'\n", obj.ID)
+	case dto.WebhookDelivery:
+		fmt.Printf(prefix+"event: '%s' ('%s')\n", obj.EventName, obj.EventID)
+		fmt.Printf(prefix+"status: %d\n", obj.Status)
+		fmt.Printf(prefix+"attempts: %d\n", obj.Attempts)
+		fmt.Printf(prefix+"response code: %d\n", obj.ResponseCode)
+		if obj.Error != "" {
+			fmt.Printf(prefix+"error: '%s'\n", obj.Error)
+		}
+		fmt.Printf(prefix+"next attempt at: '%s'\n", obj.NextAttemptAt)
+		fmt.Printf(prefix+"id: '%s'\n", obj.ID)
+	case dto.IncomingWebhook:
+		fmt.Printf(prefix+"name: '%s'\n", obj.Name)
+		fmt.Printf(prefix+"chat id: '%s'\n", obj.ChatID)
+		fmt.Printf(prefix+"user id: '%s'\n", obj.UserID)
+		if obj.Token != "" {
+			fmt.Printf(prefix+"token: '%s'\n", obj.Token)
+		}
+		fmt.Printf(prefix+"id: '%s'\n", obj.ID)
+	case dto.Bot:
+		fmt.Printf(prefix+"username: '%s'\n", obj.Username)
+		fmt.Printf(prefix+"owner id: '%s'\n", obj.OwnerID)
+		if obj.Token != "" {
+			fmt.Printf(prefix+"token: '%s'\n", obj.Token)
+		}
+		fmt.Printf(prefix+"id: '%s'\n", obj.ID)
+	case dto.APIKey:
+		fmt.Printf(prefix+"name: '%s'\n", obj.Name)
+		if obj.Key != "" {
+			fmt.Printf(prefix+"key: '%s'\n", obj.Key)
+		}
+		fmt.Printf(prefix+"scopes: '%s'\n", strings.Join(obj.Scopes, ", "))
+		if obj.ExpiresAt != nil {
+			fmt.Printf(prefix+"expires at: '%s'\n", *obj.ExpiresAt)
+		}
+		if obj.LastUsedAt != nil {
+			fmt.Printf(prefix+"last used at: '%s'\n", *obj.LastUsedAt)
+		}
+		fmt.Printf(prefix+"id: '%s'\n", obj.ID)
+	case dto.TwoFactorStatus:
+		fmt.Printf(prefix+"enabled: %t\n", obj.Enabled)
+		if obj.Enabled {
+			fmt.Printf(prefix+"recovery codes left: %d\n", obj.RecoveryCodesLeft)
+		}
+	case dto.TwoFactorEnrollment:
+		fmt.Printf(prefix+"key: '%s'\n", obj.Secret)
+		fmt.Printf(prefix+"uri: '%s'\n", obj.URI)
+	case dto.RecoveryCodes:
+		for _, code := range obj.Codes {
+			fmt.Printf(prefix+"%s\n", code)
+		}
+	case dto.Identity:
+		fmt.Printf(prefix+"provider: '%s'\n", obj.Provider)
+		fmt.Printf(prefix+"subject: '%s'\n", obj.Subject)
+		fmt.Printf(prefix+"email: '%s'\n", obj.Email)
+		fmt.Printf(prefix+"created at: '%s'\n", obj.CreatedAt)
+	case dto.Session:
+		fmt.Printf(prefix+"user agent: '%s'\n", obj.UserAgent)
+		fmt.Printf(prefix+"ip: '%s'\n", obj.IP)
+		fmt.Printf(prefix+"created at: '%s'\n", obj.CreatedAt)
+		fmt.Printf(prefix+"last seen at: '%s'\n", obj.LastSeenAt)
+		if obj.Current {
+			fmt.Printf(prefix + "current\n")
+		}
+		fmt.Printf(prefix+"id: '%s'\n", obj.ID)
+	case dto.BotCommand:
+		fmt.Printf(prefix+"/%s - %s\n", obj.Command, obj.Description)
+	case dto.BotUpdate:
+		fmt.Printf(prefix+"id: %d\n", obj.ID)
+		fmt.Printf(prefix+"name: '%s'\n", obj.Name)
+		fmt.Printf(prefix+"event: %s\n", obj.Event)
+	case dto.JoinResult:
+		fmt.Printf(prefix+"chat id: '%s'\n", obj.ChatID)
+		fmt.Printf(prefix+"pending: %t\n", obj.Pending)
+		if obj.Pending {
+			fmt.Printf(prefix+"request id: '%s'\n", obj.RequestID)
+		}
 	case dto.FriendRequest:
 		fmt.Printf(prefix+"from: '%s'\n", obj.FromID)
 		fmt.Printf(prefix+"to: '%s'\n", obj.ToID)
@@ -92,6 +203,38 @@ func output(data interface{}, prefixTabs int) {
 	}
 }
 
+func splitList(input string) []string {
+	var res []string
+	for _, item := range strings.Split(input, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			res = append(res, item)
+		}
+	}
+	return res
+}
+
+// promptRedirect asks for the url the identity provider has redirected to
+// and returns the state and the code from it
+func promptRedirect(authURL string) (string, string, error) {
+	output("open the page and log in: "+authURL, 1)
+
+	redirect, err := promptString("the url you have been redirected to").Run()
+	if err != nil {
+		return "", "", err
+	}
+
+	u, err := url.Parse(redirect)
+	if err != nil {
+		return "", "", err
+	}
+
+	if errCode := u.Query().Get("error"); errCode != "" {
+		return "", "", errors.New("the provider has returned an error: " + errCode)
+	}
+
+	return u.Query().Get("state"), u.Query().Get("code"), nil
+}
+
 func outputBreakLine(tabs int) {
 	output(strings.Repeat("-", 27), tabs)
 }
@@ -146,6 +289,20 @@ func main() {
 				Password: password,
 			})
 
+			var twoFactorErr *client.TwoFactorRequiredError
+			if errors.As(err, &twoFactorErr) {
+				code, promptErr := promptString("two-factor code (or a recovery code)").Run()
+				if promptErr != nil {
+					output(promptErr, 1)
+					continue
+				}
+
+				user, token, err = appClient.CompleteLogin(forms.CompleteLogin{
+					Challenge: twoFactorErr.Challenge,
+					Code:      code,
+				})
+			}
+
 			if err != nil {
 				output(err, 1)
 				continue
@@ -154,95 +311,85 @@ func main() {
 			appClient.SetToken(token)
 			output(user, 1)
 
-		case "logout":
-			err := appClient.Logout(ctx)
+		case "2fa":
+			status, err := appClient.TwoFactor()
 
 			if err != nil {
 				output(err, 1)
 				continue
 			}
 
-			appClient.SetToken("")
+			output(status, 1)
 
-		case "register":
-			username, err := promptString("username").Run()
+		case "enable-2fa":
+			enrollment, err := appClient.EnrollTwoFactor()
 			if err != nil {
 				output(err, 1)
 				continue
 			}
-			password, err := promptString("password").Run()
+
+			output("add the key to your authenticator app (or make a QR code of the uri)", 1)
+			output(enrollment, 1)
+
+			code, err := promptString("code from the app").Run()
 			if err != nil {
 				output(err, 1)
 				continue
 			}
 
-			user, token, err := appClient.Register(forms.Register{
-				Username: username,
-				Password: password,
-			})
+			codes, err := appClient.ConfirmTwoFactor(forms.ConfirmTwoFactor{Code: code})
 
 			if err != nil {
 				output(err, 1)
 				continue
 			}
 
-			appClient.SetToken(token)
-			output(user, 1)
+			output("keep the recovery codes, each of them can be used once instead of a code", 1)
+			output(codes, 1)
 
-		case "chats":
-			offset, count, err := promptOffsetCount()
+		case "disable-2fa":
+			code, err := promptString("two-factor code (or a recovery code)").Run()
 			if err != nil {
 				output(err, 1)
 				continue
 			}
 
-			chats, err := appClient.Chats(forms.GetChats{
-				Offset: offset,
-				Count:  count,
-			})
+			if err := appClient.DisableTwoFactor(forms.DisableTwoFactor{Code: code}); err != nil {
+				output(err, 1)
+				continue
+			}
 
+		case "recovery-codes":
+			code, err := promptString("two-factor code (or a recovery code)").Run()
 			if err != nil {
 				output(err, 1)
 				continue
 			}
 
-			for _, chat := range chats {
-				output(chat, 1)
-				outputBreakLine(1)
-			}
+			codes, err := appClient.RegenerateRecoveryCodes(forms.RegenerateRecoveryCodes{Code: code})
 
-		case "friends":
-			offset, count, err := promptOffsetCount()
 			if err != nil {
 				output(err, 1)
 				continue
 			}
 
-			friends, err := appClient.Friends(forms.GetFriends{
-				Offset: offset,
-				Count:  count,
-			})
+			output(codes, 1)
 
+		case "change-password":
+			oldPassword, err := promptString("old password (empty if there is none)").Run()
 			if err != nil {
 				output(err, 1)
 				continue
 			}
-
-			for _, friend := range friends {
-				output(friend, 1)
-				outputBreakLine(1)
-			}
-
-		case "incoming-fr":
-			offset, count, err := promptOffsetCount()
+			newPassword, err := promptString("new password").Run()
 			if err != nil {
 				output(err, 1)
 				continue
 			}
 
-			requests, err := appClient.IncomingFriendRequests(forms.GetIncomingFriendRequests{
-				Offset: offset,
-				Count:  count,
+			err = appClient.ChangePassword(forms.ChangePassword{
+				OldPassword: oldPassword,
+				NewPassword: newPassword,
 			})
 
 			if err != nil {
@@ -250,107 +397,127 @@ func main() {
 				continue
 			}
 
-			for _, req := range requests {
-				output(req, 1)
-				outputBreakLine(1)
-			}
-
-		case "outgoing-fr":
-			offset, count, err := promptOffsetCount()
+		case "forgot-password":
+			username, err := promptString("username").Run()
 			if err != nil {
 				output(err, 1)
 				continue
 			}
 
-			requests, err := appClient.OutgoingFriendRequests(forms.GetOutgoingFriendRequests{
-				Offset: offset,
-				Count:  count,
-			})
-
-			if err != nil {
+			if err := appClient.RequestPasswordReset(forms.RequestPasswordReset{Username: username}); err != nil {
 				output(err, 1)
 				continue
 			}
 
-			for _, req := range requests {
-				output(req, 1)
-				outputBreakLine(1)
+		case "reset-password":
+			token, err := promptString("reset token").Run()
+			if err != nil {
+				output(err, 1)
+				continue
 			}
-
-		case "send-fr":
-			to, err := promptString("to").Run()
+			newPassword, err := promptString("new password").Run()
 			if err != nil {
 				output(err, 1)
 				continue
 			}
 
-			req, err := appClient.SendFriendRequest(forms.SendFriendRequest{
-				To: to,
+			err = appClient.ResetPassword(forms.ResetPassword{
+				Token:       token,
+				NewPassword: newPassword,
 			})
 
 			if err != nil {
 				output(err, 1)
 				continue
 			}
-			output(req, 1)
 
-		case "accept-fr":
-			id, err := promptString("id").Run()
+		case "oidc-login":
+			providers, err := appClient.IdentityProviders()
 			if err != nil {
 				output(err, 1)
 				continue
 			}
 
-			err = appClient.AcceptFriendRequest(forms.AcceptFriendRequest{
-				ID: id,
-			})
+			_, provider, err := (&promptui.Select{Label: "provider", Items: providers}).Run()
+			if err != nil {
+				output(err, 1)
+				continue
+			}
 
+			authURL, err := appClient.StartOIDCLogin(forms.StartOIDCLogin{Provider: provider})
 			if err != nil {
 				output(err, 1)
 				continue
 			}
 
-		case "decline-fr":
-			id, err := promptString("id").Run()
+			state, code, err := promptRedirect(authURL.URL)
 			if err != nil {
 				output(err, 1)
 				continue
 			}
 
-			err = appClient.DeclineFriendRequest(forms.DeclineFriendRequest{
-				ID: id,
+			user, token, err := appClient.CompleteOIDCLogin(forms.CompleteOIDCLogin{
+				State: state,
+				Code:  code,
 			})
 
+			var twoFactorErr *client.TwoFactorRequiredError
+			if errors.As(err, &twoFactorErr) {
+				code, promptErr := promptString("two-factor code (or a recovery code)").Run()
+				if promptErr != nil {
+					output(promptErr, 1)
+					continue
+				}
+
+				user, token, err = appClient.CompleteLogin(forms.CompleteLogin{
+					Challenge: twoFactorErr.Challenge,
+					Code:      code,
+				})
+			}
+
 			if err != nil {
 				output(err, 1)
 				continue
 			}
 
-		case "chat":
-			id, err := promptString("id").Run()
+			appClient.SetToken(token)
+			output(user, 1)
+
+		case "identities":
+			identities, err := appClient.Identities()
+
 			if err != nil {
 				output(err, 1)
 				continue
 			}
 
-			res, err := appClient.Chat(chatForms.GetChat{ID: id})
+			for _, identity := range identities {
+				output(identity, 1)
+				outputBreakLine(1)
+			}
 
+		case "link-identity":
+			provider, err := promptString("provider").Run()
 			if err != nil {
 				output(err, 1)
 				continue
 			}
 
-			output(res, 1)
+			authURL, err := appClient.LinkIdentity(forms.LinkIdentity{Provider: provider})
+			if err != nil {
+				output(err, 1)
+				continue
+			}
 
-		case "delete-chat":
-			chatId, err := promptString("chat id").Run()
+			state, code, err := promptRedirect(authURL.URL)
 			if err != nil {
 				output(err, 1)
 				continue
 			}
 
-			err = appClient.DeleteChat(chatForms.DeleteChat{
-				ID: chatId,
+			identity, err := appClient.CompleteIdentityLink(forms.CompleteIdentityLink{
+				State: state,
+				Code:  code,
 			})
 
 			if err != nil {
@@ -358,47 +525,48 @@ func main() {
 				continue
 			}
 
-		case "create-chat":
-			name, err := promptString("name").Run()
+			output(identity, 1)
+
+		case "unlink-identity":
+			provider, err := promptString("provider").Run()
 			if err != nil {
 				output(err, 1)
 				continue
 			}
 
-			desc, err := promptString("description").Run()
-			if err != nil {
+			if err := appClient.UnlinkIdentity(forms.UnlinkIdentity{Provider: provider}); err != nil {
 				output(err, 1)
 				continue
 			}
 
-			chat, err := appClient.CreateChat(chatForms.CreateChat{
-				Name:        name,
-				Description: desc,
-			})
+		case "logout":
+			err := appClient.Logout(ctx)
 
 			if err != nil {
 				output(err, 1)
 				continue
 			}
 
-			output(chat, 1)
+			appClient.SetToken("")
 
-		case "create-chat-member":
-			chatID, err := promptString("chat id").Run()
-			if err != nil {
+		case "logout-everywhere":
+			if err := appClient.LogoutEverywhere(); err != nil {
 				output(err, 1)
 				continue
 			}
 
-			userID, err := promptString("user id").Run()
+			appClient.SetToken("")
+
+		case "sessions":
+			offset, count, err := promptOffsetCount()
 			if err != nil {
 				output(err, 1)
 				continue
 			}
 
-			err = appClient.CreateChatMember(chatForms.CreateChatMember{
-				ChatID: chatID,
-				UserID: userID,
+			sessions, err := appClient.Sessions(forms.GetSessions{
+				Offset: offset,
+				Count:  count,
 			})
 
 			if err != nil {
@@ -406,46 +574,48 @@ func main() {
 				continue
 			}
 
-		case "delete-chat-member":
-			chatID, err := promptString("chat id").Run()
+			for _, session := range sessions {
+				output(session, 1)
+				outputBreakLine(1)
+			}
+
+		case "revoke-session":
+			id, err := promptString("session id").Run()
 			if err != nil {
 				output(err, 1)
 				continue
 			}
 
-			userID, err := promptString("user id").Run()
-			if err != nil {
+			if err := appClient.RevokeSession(forms.RevokeSession{ID: id}); err != nil {
 				output(err, 1)
 				continue
 			}
 
-			err = appClient.DeleteChatMember(chatForms.DeleteChatMember{
-				ChatID: chatID,
-				UserID: userID,
-			})
-
+		case "create-api-key":
+			name, err := promptString("name").Run()
 			if err != nil {
 				output(err, 1)
 				continue
 			}
 
-		case "get-chat-members":
-			chatID, err := promptString("chat id").Run()
+			scopes, err := promptString("scopes (comma separated, e.g. chats:read,messages:write)").Run()
 			if err != nil {
 				output(err, 1)
 				continue
 			}
 
-			offset, count, err := promptOffsetCount()
+			expiresInStr, err := promptInt("expires in seconds (0 - never)").Run()
 			if err != nil {
 				output(err, 1)
 				continue
 			}
 
-			members, err := appClient.GetChatMembers(chatForms.GetChatMembers{
-				ChatID: chatID,
-				Offset: offset,
-				Count:  count,
+			expiresIn, _ := strconv.ParseInt(expiresInStr, 10, 64)
+
+			key, err := appClient.CreateAPIKey(forms.CreateAPIKey{
+				Name:      name,
+				Scopes:    splitList(scopes),
+				ExpiresIn: expiresIn,
 			})
 
 			if err != nil {
@@ -453,92 +623,532 @@ func main() {
 				continue
 			}
 
-			for _, mem := range members {
-				output(mem, 1)
-				outputBreakLine(1)
-			}
+			output(key, 1)
 
-		case "send-message":
-			chatId, err := promptString("chat-id").Run()
+		case "api-keys":
+			offset, count, err := promptOffsetCount()
 			if err != nil {
 				output(err, 1)
 				continue
 			}
 
-			payload, err := promptString("payload").Run()
+			keys, err := appClient.APIKeys(forms.GetAPIKeys{
+				Offset: offset,
+				Count:  count,
+			})
+
 			if err != nil {
 				output(err, 1)
 				continue
 			}
 
-			res, err := appClient.SendMessage(chatForms.SendMessage{
-				ChatID:  chatId,
-				Payload: payload,
-			})
+			for _, key := range keys {
+				output(key, 1)
+				outputBreakLine(1)
+			}
 
+		case "delete-api-key":
+			id, err := promptString("api key id").Run()
 			if err != nil {
 				output(err, 1)
 				continue
 			}
 
-			output(res, 1)
-
-		case "update-message":
-			id, err := promptString("id").Run()
-			if err != nil {
+			if err := appClient.DeleteAPIKey(forms.DeleteAPIKey{ID: id}); err != nil {
 				output(err, 1)
 				continue
 			}
 
-			payload, err := promptString("payload").Run()
+		case "use-api-key":
+			key, err := promptString("api key (empty - stop using it)").Run()
 			if err != nil {
 				output(err, 1)
 				continue
 			}
 
-			res, err := appClient.UpdateMessage(chatForms.UpdateMessage{
-				ID:      id,
-				Payload: payload,
-			})
+			appClient.SetAPIKey(key)
 
-			if err != nil {
+		case "refresh":
+			if err := appClient.Refresh(); err != nil {
 				output(err, 1)
 				continue
 			}
+			output("refreshed", 1)
 
-			output(res, 1)
-
-		case "delete-message":
-			id, err := promptString("id").Run()
+		case "register":
+			username, err := promptString("username").Run()
 			if err != nil {
 				output(err, 1)
 				continue
 			}
-
-			err = appClient.DeleteMessage(chatForms.DeleteMessage{
-				ID: id,
-			})
-
+			password, err := promptString("password").Run()
 			if err != nil {
 				output(err, 1)
 				continue
 			}
 
-		case "messages":
+			user, token, err := appClient.Register(forms.Register{
+				Username: username,
+				Password: password,
+			})
 
-			offset, count, err := promptOffsetCount()
 			if err != nil {
 				output(err, 1)
 				continue
 			}
-			chatID, err := promptString("chat id").Run()
+
+			appClient.SetToken(token)
+			output(user, 1)
+
+		case "chats":
+			offset, count, err := promptOffsetCount()
 			if err != nil {
 				output(err, 1)
 				continue
 			}
 
-			messages, err := appClient.GetMessages(chatForms.GetMessages{
-				ChatID: chatID,
+			chats, err := appClient.Chats(forms.GetChats{
+				Offset: offset,
+				Count:  count,
+			})
+
+			if err != nil {
+				output(err, 1)
+				continue
+			}
+
+			for _, chat := range chats {
+				output(chat, 1)
+				outputBreakLine(1)
+			}
+
+		case "friends":
+			offset, count, err := promptOffsetCount()
+			if err != nil {
+				output(err, 1)
+				continue
+			}
+
+			friends, err := appClient.Friends(forms.GetFriends{
+				Offset: offset,
+				Count:  count,
+			})
+
+			if err != nil {
+				output(err, 1)
+				continue
+			}
+
+			for _, friend := range friends {
+				output(friend, 1)
+				outputBreakLine(1)
+			}
+
+		case "incoming-fr":
+			offset, count, err := promptOffsetCount()
+			if err != nil {
+				output(err, 1)
+				continue
+			}
+
+			requests, err := appClient.IncomingFriendRequests(forms.GetIncomingFriendRequests{
+				Offset: offset,
+				Count:  count,
+			})
+
+			if err != nil {
+				output(err, 1)
+				continue
+			}
+
+			for _, req := range requests {
+				output(req, 1)
+				outputBreakLine(1)
+			}
+
+		case "outgoing-fr":
+			offset, count, err := promptOffsetCount()
+			if err != nil {
+				output(err, 1)
+				continue
+			}
+
+			requests, err := appClient.OutgoingFriendRequests(forms.GetOutgoingFriendRequests{
+				Offset: offset,
+				Count:  count,
+			})
+
+			if err != nil {
+				output(err, 1)
+				continue
+			}
+
+			for _, req := range requests {
+				output(req, 1)
+				outputBreakLine(1)
+			}
+
+		case "send-fr":
+			to, err := promptString("to").Run()
+			if err != nil {
+				output(err, 1)
+				continue
+			}
+
+			req, err := appClient.SendFriendRequest(forms.SendFriendRequest{
+				To: to,
+			})
+
+			if err != nil {
+				output(err, 1)
+				continue
+			}
+			output(req, 1)
+
+		case "accept-fr":
+			id, err := promptString("id").Run()
+			if err != nil {
+				output(err, 1)
+				continue
+			}
+
+			err = appClient.AcceptFriendRequest(forms.AcceptFriendRequest{
+				ID: id,
+			})
+
+			if err != nil {
+				output(err, 1)
+				continue
+			}
+
+		case "decline-fr":
+			id, err := promptString("id").Run()
+			if err != nil {
+				output(err, 1)
+				continue
+			}
+
+			err = appClient.DeclineFriendRequest(forms.DeclineFriendRequest{
+				ID: id,
+			})
+
+			if err != nil {
+				output(err, 1)
+				continue
+			}
+
+		case "chat":
+			id, err := promptString("id").Run()
+			if err != nil {
+				output(err, 1)
+				continue
+			}
+
+			res, err := appClient.Chat(chatForms.GetChat{ID: id})
+
+			if err != nil {
+				output(err, 1)
+				continue
+			}
+
+			output(res, 1)
+
+		case "delete-chat":
+			chatId, err := promptString("chat id").Run()
+			if err != nil {
+				output(err, 1)
+				continue
+			}
+
+			err = appClient.DeleteChat(chatForms.DeleteChat{
+				ID: chatId,
+			})
+
+			if err != nil {
+				output(err, 1)
+				continue
+			}
+
+		case "leave-chat":
+			chatId, err := promptString("chat id").Run()
+			if err != nil {
+				output(err, 1)
+				continue
+			}
+
+			err = appClient.LeaveChat(chatForms.LeaveChat{
+				ID: chatId,
+			})
+
+			if err != nil {
+				output(err, 1)
+				continue
+			}
+
+		case "create-chat":
+			name, err := promptString("name").Run()
+			if err != nil {
+				output(err, 1)
+				continue
+			}
+
+			desc, err := promptString("description").Run()
+			if err != nil {
+				output(err, 1)
+				continue
+			}
+
+			visibilityStr, err := promptInt("visibility (0 - private, 1 - public, 2 - public with approval)").Run()
+			if err != nil {
+				output(err, 1)
+				continue
+			}
+
+			kindStr, err := promptInt("kind (0 - group, 1 - channel)").Run()
+			if err != nil {
+				output(err, 1)
+				continue
+			}
+
+			visibility, _ := strconv.Atoi(visibilityStr)
+			kind, _ := strconv.Atoi(kindStr)
+
+			countViews := false
+			if kind == 1 {
+				answer, err := promptString("count views (y/n)").Run()
+				if err != nil {
+					output(err, 1)
+					continue
+				}
+				countViews = answer == "y"
+			}
+
+			chat, err := appClient.CreateChat(chatForms.CreateChat{
+				Name:        name,
+				Description: desc,
+				Visibility:  visibility,
+				Kind:        kind,
+				CountViews:  countViews,
+			})
+
+			if err != nil {
+				output(err, 1)
+				continue
+			}
+
+			output(chat, 1)
+
+		case "update-chat":
+			id, err := promptString("chat id").Run()
+			if err != nil {
+				output(err, 1)
+				continue
+			}
+
+			name, err := promptString("name").Run()
+			if err != nil {
+				output(err, 1)
+				continue
+			}
+
+			desc, err := promptString("description").Run()
+			if err != nil {
+				output(err, 1)
+				continue
+			}
+
+			chat, err := appClient.UpdateChat(chatForms.UpdateChat{
+				ID:          id,
+				Name:        name,
+				Description: desc,
+			})
+
+			if err != nil {
+				output(err, 1)
+				continue
+			}
+
+			output(chat, 1)
+
+		case "transfer-ownership":
+			chatID, err := promptString("chat id").Run()
+			if err != nil {
+				output(err, 1)
+				continue
+			}
+
+			userID, err := promptString("new owner id").Run()
+			if err != nil {
+				output(err, 1)
+				continue
+			}
+
+			err = appClient.TransferOwnership(chatForms.TransferOwnership{
+				ChatID: chatID,
+				UserID: userID,
+			})
+
+			if err != nil {
+				output(err, 1)
+				continue
+			}
+
+		case "create-chat-member":
+			chatID, err := promptString("chat id").Run()
+			if err != nil {
+				output(err, 1)
+				continue
+			}
+
+			userID, err := promptString("user id").Run()
+			if err != nil {
+				output(err, 1)
+				continue
+			}
+
+			err = appClient.CreateChatMember(chatForms.CreateChatMember{
+				ChatID: chatID,
+				UserID: userID,
+			})
+
+			if err != nil {
+				output(err, 1)
+				continue
+			}
+
+		case "delete-chat-member":
+			chatID, err := promptString("chat id").Run()
+			if err != nil {
+				output(err, 1)
+				continue
+			}
+
+			userID, err := promptString("user id").Run()
+			if err != nil {
+				output(err, 1)
+				continue
+			}
+
+			err = appClient.DeleteChatMember(chatForms.DeleteChatMember{
+				ChatID: chatID,
+				UserID: userID,
+			})
+
+			if err != nil {
+				output(err, 1)
+				continue
+			}
+
+		case "get-chat-members":
+			chatID, err := promptString("chat id").Run()
+			if err != nil {
+				output(err, 1)
+				continue
+			}
+
+			offset, count, err := promptOffsetCount()
+			if err != nil {
+				output(err, 1)
+				continue
+			}
+
+			members, err := appClient.GetChatMembers(chatForms.GetChatMembers{
+				ChatID: chatID,
+				Offset: offset,
+				Count:  count,
+			})
+
+			if err != nil {
+				output(err, 1)
+				continue
+			}
+
+			for _, mem := range members {
+				output(mem, 1)
+				outputBreakLine(1)
+			}
+
+		case "send-message":
+			chatId, err := promptString("chat-id").Run()
+			if err != nil {
+				output(err, 1)
+				continue
+			}
+
+			payload, err := promptString("payload").Run()
+			if err != nil {
+				output(err, 1)
+				continue
+			}
+
+			res, err := appClient.SendMessage(chatForms.SendMessage{
+				ChatID:  chatId,
+				Payload: payload,
+			})
+
+			if err != nil {
+				output(err, 1)
+				continue
+			}
+
+			output(res, 1)
+
+		case "update-message":
+			id, err := promptString("id").Run()
+			if err != nil {
+				output(err, 1)
+				continue
+			}
+
+			payload, err := promptString("payload").Run()
+			if err != nil {
+				output(err, 1)
+				continue
+			}
+
+			res, err := appClient.UpdateMessage(chatForms.UpdateMessage{
+				ID:      id,
+				Payload: payload,
+			})
+
+			if err != nil {
+				output(err, 1)
+				continue
+			}
+
+			output(res, 1)
+
+		case "delete-message":
+			id, err := promptString("id").Run()
+			if err != nil {
+				output(err, 1)
+				continue
+			}
+
+			err = appClient.DeleteMessage(chatForms.DeleteMessage{
+				ID: id,
+			})
+
+			if err != nil {
+				output(err, 1)
+				continue
+			}
+
+		case "messages":
+
+			offset, count, err := promptOffsetCount()
+			if err != nil {
+				output(err, 1)
+				continue
+			}
+			chatID, err := promptString("chat id").Run()
+			if err != nil {
+				output(err, 1)
+				continue
+			}
+
+			messages, err := appClient.GetMessages(chatForms.GetMessages{
+				ChatID: chatID,
 				Offset: offset,
 				Count:  count,
 			})
@@ -552,6 +1162,655 @@ func main() {
 				output(mes, 1)
 				outputBreakLine(1)
 			}
+		case "create-invite":
+			chatID, err := promptString("chat id").Run()
+			if err != nil {
+				output(err, 1)
+				continue
+			}
+
+			maxUsesStr, err := promptInt("max uses (0 - unlimited)").Run()
+			if err != nil {
+				output(err, 1)
+				continue
+			}
+
+			expiresInStr, err := promptInt("expires in seconds (0 - never)").Run()
+			if err != nil {
+				output(err, 1)
+				continue
+			}
+
+			approval, err := promptString("requires approval (y/n)").Run()
+			if err != nil {
+				output(err, 1)
+				continue
+			}
+
+			maxUses, _ := strconv.Atoi(maxUsesStr)
+			expiresIn, _ := strconv.ParseInt(expiresInStr, 10, 64)
+
+			invite, err := appClient.CreateInvite(chatForms.CreateInvite{
+				ChatID:           chatID,
+				MaxUses:          maxUses,
+				ExpiresIn:        expiresIn,
+				RequiresApproval: approval == "y",
+			})
+
+			if err != nil {
+				output(err, 1)
+				continue
+			}
+
+			output(invite, 1)
+
+		case "revoke-invite":
+			chatID, err := promptString("chat id").Run()
+			if err != nil {
+				output(err, 1)
+				continue
+			}
+
+			code, err := promptString("code").Run()
+			if err != nil {
+				output(err, 1)
+				continue
+			}
+
+			err = appClient.RevokeInvite(chatForms.RevokeInvite{
+				ChatID: chatID,
+				Code:   code,
+			})
+
+			if err != nil {
+				output(err, 1)
+				continue
+			}
+
+		case "invites":
+			chatID, err := promptString("chat id").Run()
+			if err != nil {
+				output(err, 1)
+				continue
+			}
+
+			offset, count, err := promptOffsetCount()
+			if err != nil {
+				output(err, 1)
+				continue
+			}
+
+			invites, err := appClient.GetInvites(chatForms.GetInvites{
+				ChatID: chatID,
+				Offset: offset,
+				Count:  count,
+			})
+
+			if err != nil {
+				output(err, 1)
+				continue
+			}
+
+			for _, invite := range invites {
+				output(invite, 1)
+				outputBreakLine(1)
+			}
+
+		case "join":
+			code, err := promptString("code (empty to join a public chat)").Run()
+			if err != nil {
+				output(err, 1)
+				continue
+			}
+
+			var chatID string
+			if code == "" {
+				chatID, err = promptString("chat id").Run()
+				if err != nil {
+					output(err, 1)
+					continue
+				}
+			}
+
+			res, err := appClient.Join(chatForms.JoinChat{Code: code, ChatID: chatID})
+
+			if err != nil {
+				output(err, 1)
+				continue
+			}
+
+			output(res, 1)
+
+		case "join-requests":
+			chatID, err := promptString("chat id").Run()
+			if err != nil {
+				output(err, 1)
+				continue
+			}
+
+			offset, count, err := promptOffsetCount()
+			if err != nil {
+				output(err, 1)
+				continue
+			}
+
+			requests, err := appClient.GetJoinRequests(chatForms.GetJoinRequests{
+				ChatID: chatID,
+				Offset: offset,
+				Count:  count,
+			})
+
+			if err != nil {
+				output(err, 1)
+				continue
+			}
+
+			for _, req := range requests {
+				output(req, 1)
+				outputBreakLine(1)
+			}
+
+		case "approve-jr", "reject-jr":
+			chatID, err := promptString("chat id").Run()
+			if err != nil {
+				output(err, 1)
+				continue
+			}
+
+			id, err := promptString("id").Run()
+			if err != nil {
+				output(err, 1)
+				continue
+			}
+
+			if res == "approve-jr" {
+				err = appClient.ApproveJoinRequest(chatForms.ApproveJoinRequest{ChatID: chatID, ID: id})
+			} else {
+				err = appClient.RejectJoinRequest(chatForms.RejectJoinRequest{ChatID: chatID, ID: id})
+			}
+
+			if err != nil {
+				output(err, 1)
+				continue
+			}
+
+		case "set-visibility":
+			chatID, err := promptString("chat id").Run()
+			if err != nil {
+				output(err, 1)
+				continue
+			}
+
+			visibilityStr, err := promptInt("visibility (0 - private, 1 - public, 2 - public with approval)").Run()
+			if err != nil {
+				output(err, 1)
+				continue
+			}
+
+			visibility, _ := strconv.Atoi(visibilityStr)
+
+			err = appClient.SetChatVisibility(chatForms.SetChatVisibility{
+				ChatID:     chatID,
+				Visibility: visibility,
+			})
+
+			if err != nil {
+				output(err, 1)
+				continue
+			}
+
+		case "search-chats":
+			query, err := promptString("query").Run()
+			if err != nil {
+				output(err, 1)
+				continue
+			}
+
+			offset, count, err := promptOffsetCount()
+			if err != nil {
+				output(err, 1)
+				continue
+			}
+
+			chats, err := appClient.SearchChats(chatForms.SearchChats{
+				Query:  query,
+				Offset: offset,
+				Count:  count,
+			})
+
+			if err != nil {
+				output(err, 1)
+				continue
+			}
+
+			for _, chat := range chats {
+				output(chat, 1)
+				outputBreakLine(1)
+			}
+
+		case "create-webhook":
+			url, err := promptString("url").Run()
+			if err != nil {
+				output(err, 1)
+				continue
+			}
+
+			events, err := promptString("events (comma separated, empty - all)").Run()
+			if err != nil {
+				output(err, 1)
+				continue
+			}
+
+			webhook, err := appClient.CreateWebhook(forms.CreateWebhook{
+				URL:    url,
+				Events: splitList(events),
+			})
+
+			if err != nil {
+				output(err, 1)
+				continue
+			}
+
+			output(webhook, 1)
+
+		case "webhooks":
+			offset, count, err := promptOffsetCount()
+			if err != nil {
+				output(err, 1)
+				continue
+			}
+
+			webhooks, err := appClient.Webhooks(forms.GetWebhooks{
+				Offset: offset,
+				Count:  count,
+			})
+
+			if err != nil {
+				output(err, 1)
+				continue
+			}
+
+			for _, webhook := range webhooks {
+				output(webhook, 1)
+				outputBreakLine(1)
+			}
+
+		case "delete-webhook":
+			id, err := promptString("webhook id").Run()
+			if err != nil {
+				output(err, 1)
+				continue
+			}
+
+			if err := appClient.DeleteWebhook(forms.DeleteWebhook{ID: id}); err != nil {
+				output(err, 1)
+				continue
+			}
+
+		case "webhook-deliveries":
+			id, err := promptString("webhook id").Run()
+			if err != nil {
+				output(err, 1)
+				continue
+			}
+
+			dead, err := promptString("dead letters only (y/n)").Run()
+			if err != nil {
+				output(err, 1)
+				continue
+			}
+
+			offset, count, err := promptOffsetCount()
+			if err != nil {
+				output(err, 1)
+				continue
+			}
+
+			deliveries, err := appClient.WebhookDeliveries(forms.GetWebhookDeliveries{
+				ID:     id,
+				Dead:   dead == "y",
+				Offset: offset,
+				Count:  count,
+			})
+
+			if err != nil {
+				output(err, 1)
+				continue
+			}
+
+			for _, delivery := range deliveries {
+				output(delivery, 1)
+				outputBreakLine(1)
+			}
+
+		case "redeliver-webhook":
+			id, err := promptString("webhook id").Run()
+			if err != nil {
+				output(err, 1)
+				continue
+			}
+
+			deliveryID, err := promptString("delivery id").Run()
+			if err != nil {
+				output(err, 1)
+				continue
+			}
+
+			if err := appClient.RedeliverWebhook(forms.RedeliverWebhook{
+				ID:         id,
+				DeliveryID: deliveryID,
+			}); err != nil {
+				output(err, 1)
+				continue
+			}
+
+		case "create-chat-webhook":
+			chatID, err := promptString("chat id").Run()
+			if err != nil {
+				output(err, 1)
+				continue
+			}
+
+			url, err := promptString("url").Run()
+			if err != nil {
+				output(err, 1)
+				continue
+			}
+
+			events, err := promptString("events (comma separated, empty - all)").Run()
+			if err != nil {
+				output(err, 1)
+				continue
+			}
+
+			webhook, err := appClient.CreateChatWebhook(chatForms.CreateWebhook{
+				ChatID: chatID,
+				URL:    url,
+				Events: splitList(events),
+			})
+
+			if err != nil {
+				output(err, 1)
+				continue
+			}
+
+			output(webhook, 1)
+
+		case "chat-webhooks":
+			chatID, err := promptString("chat id").Run()
+			if err != nil {
+				output(err, 1)
+				continue
+			}
+
+			offset, count, err := promptOffsetCount()
+			if err != nil {
+				output(err, 1)
+				continue
+			}
+
+			webhooks, err := appClient.ChatWebhooks(chatForms.GetWebhooks{
+				ChatID: chatID,
+				Offset: offset,
+				Count:  count,
+			})
+
+			if err != nil {
+				output(err, 1)
+				continue
+			}
+
+			for _, webhook := range webhooks {
+				output(webhook, 1)
+				outputBreakLine(1)
+			}
+
+		case "create-incoming-webhook":
+			chatID, err := promptString("chat id").Run()
+			if err != nil {
+				output(err, 1)
+				continue
+			}
+
+			name, err := promptString("name").Run()
+			if err != nil {
+				output(err, 1)
+				continue
+			}
+
+			webhook, err := appClient.CreateIncomingWebhook(chatForms.CreateIncomingWebhook{
+				ChatID: chatID,
+				Name:   name,
+			})
+
+			if err != nil {
+				output(err, 1)
+				continue
+			}
+
+			output(webhook, 1)
+
+		case "incoming-webhooks":
+			chatID, err := promptString("chat id").Run()
+			if err != nil {
+				output(err, 1)
+				continue
+			}
+
+			offset, count, err := promptOffsetCount()
+			if err != nil {
+				output(err, 1)
+				continue
+			}
+
+			webhooks, err := appClient.IncomingWebhooks(chatForms.GetIncomingWebhooks{
+				ChatID: chatID,
+				Offset: offset,
+				Count:  count,
+			})
+
+			if err != nil {
+				output(err, 1)
+				continue
+			}
+
+			for _, webhook := range webhooks {
+				output(webhook, 1)
+				outputBreakLine(1)
+			}
+
+		case "delete-incoming-webhook":
+			chatID, err := promptString("chat id").Run()
+			if err != nil {
+				output(err, 1)
+				continue
+			}
+
+			id, err := promptString("webhook id").Run()
+			if err != nil {
+				output(err, 1)
+				continue
+			}
+
+			if err := appClient.DeleteIncomingWebhook(chatForms.DeleteIncomingWebhook{
+				ChatID: chatID,
+				ID:     id,
+			}); err != nil {
+				output(err, 1)
+				continue
+			}
+
+		case "post-incoming-webhook":
+			id, err := promptString("webhook id").Run()
+			if err != nil {
+				output(err, 1)
+				continue
+			}
+
+			token, err := promptString("token").Run()
+			if err != nil {
+				output(err, 1)
+				continue
+			}
+
+			payload, err := promptString("payload").Run()
+			if err != nil {
+				output(err, 1)
+				continue
+			}
+
+			if err := appClient.PostIncomingWebhook(id, token, hookForms.Post{Payload: payload}); err != nil {
+				output(err, 1)
+				continue
+			}
+
+		case "create-bot":
+			username, err := promptString("username").Run()
+			if err != nil {
+				output(err, 1)
+				continue
+			}
+
+			bot, err := appClient.CreateBot(forms.CreateBot{Username: username})
+
+			if err != nil {
+				output(err, 1)
+				continue
+			}
+
+			output(bot, 1)
+
+		case "bots":
+			offset, count, err := promptOffsetCount()
+			if err != nil {
+				output(err, 1)
+				continue
+			}
+
+			bots, err := appClient.Bots(forms.GetBots{
+				Offset: offset,
+				Count:  count,
+			})
+
+			if err != nil {
+				output(err, 1)
+				continue
+			}
+
+			for _, bot := range bots {
+				output(bot, 1)
+				outputBreakLine(1)
+			}
+
+		case "delete-bot":
+			id, err := promptString("bot id").Run()
+			if err != nil {
+				output(err, 1)
+				continue
+			}
+
+			if err := appClient.DeleteBot(forms.DeleteBot{ID: id}); err != nil {
+				output(err, 1)
+				continue
+			}
+
+		case "regenerate-bot-token":
+			id, err := promptString("bot id").Run()
+			if err != nil {
+				output(err, 1)
+				continue
+			}
+
+			bot, err := appClient.RegenerateBotToken(forms.RegenerateBotToken{ID: id})
+
+			if err != nil {
+				output(err, 1)
+				continue
+			}
+
+			output(bot, 1)
+
+		case "set-bot-commands":
+			id, err := promptString("bot id (empty - the current bot)").Run()
+			if err != nil {
+				output(err, 1)
+				continue
+			}
+
+			commands, err := promptString("commands (comma separated)").Run()
+			if err != nil {
+				output(err, 1)
+				continue
+			}
+
+			form := forms.SetBotCommands{ID: id}
+			for _, command := range splitList(commands) {
+				form.Commands = append(form.Commands, forms.BotCommand{Command: command})
+			}
+
+			if err := appClient.SetBotCommands(form); err != nil {
+				output(err, 1)
+				continue
+			}
+
+		case "bot-commands":
+			id, err := promptString("bot id (empty - the current bot)").Run()
+			if err != nil {
+				output(err, 1)
+				continue
+			}
+
+			commands, err := appClient.BotCommands(forms.GetBotCommands{ID: id})
+
+			if err != nil {
+				output(err, 1)
+				continue
+			}
+
+			for _, command := range commands {
+				output(command, 1)
+			}
+
+		case "use-bot":
+			token, err := promptString("bot token (empty - stop acting as a bot)").Run()
+			if err != nil {
+				output(err, 1)
+				continue
+			}
+
+			appClient.SetBotToken(token)
+
+		case "updates":
+			offsetStr, err := promptInt("offset").Run()
+			if err != nil {
+				output(err, 1)
+				continue
+			}
+
+			timeoutStr, err := promptInt("timeout in seconds").Run()
+			if err != nil {
+				output(err, 1)
+				continue
+			}
+
+			offset, _ := strconv.ParseInt(offsetStr, 10, 64)
+			timeout, _ := strconv.Atoi(timeoutStr)
+
+			updates, err := appClient.Updates(forms.GetUpdates{
+				Offset:  offset,
+				Timeout: timeout,
+			})
+
+			if err != nil {
+				output(err, 1)
+				continue
+			}
+
+			for _, update := range updates {
+				output(update, 1)
+				outputBreakLine(1)
+			}
+
 		case "kill":
 			return
 		}